@@ -14,7 +14,9 @@ import (
 	"github.com/vytor/chessflash/internal/chesscom"
 	"github.com/vytor/chessflash/internal/config"
 	"github.com/vytor/chessflash/internal/db"
+	"github.com/vytor/chessflash/internal/gamesource"
 	"github.com/vytor/chessflash/internal/jobs"
+	"github.com/vytor/chessflash/internal/lichess"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/repository/sqlite"
 	"github.com/vytor/chessflash/internal/services"
@@ -47,6 +49,7 @@ func main() {
 	log.Debug("stockfish_path=%s", cfg.StockfishPath)
 	log.Debug("stockfish_depth=%d", cfg.StockfishDepth)
 	log.Debug("stockfish_max_time=%d", cfg.StockfishMaxTime)
+	log.Debug("stockfish_multipv=%d", cfg.StockfishMultiPV)
 	log.Debug("log_level=%s", cfg.LogLevel)
 	log.Debug("analysis_worker_count=%d", cfg.AnalysisWorkerCount)
 	log.Debug("analysis_queue_size=%d", cfg.AnalysisQueueSize)
@@ -75,8 +78,20 @@ func main() {
 	}
 	log.Debug("templates loaded successfully")
 
-	// Create engine pool
-	enginePool, err := analysis.NewEnginePool(cfg.StockfishPath, cfg.AnalysisWorkerCount)
+	// Create engine pool with one group of processes per engine profile
+	var engineConfigs []analysis.EngineConfig
+	for _, p := range cfg.EngineProfiles() {
+		log.Debug("engine profile: name=%s, path=%s, instances=%d, options=%v", p.Name, p.Path, p.Instances, p.Options)
+		engineConfigs = append(engineConfigs, analysis.EngineConfig{
+			Name:      p.Name,
+			Path:      p.Path,
+			Options:   p.Options,
+			Depth:     p.Depth,
+			MaxTimeMs: p.MaxTimeMs,
+			Instances: p.Instances,
+		})
+	}
+	enginePool, err := analysis.NewEnginePool(engineConfigs, cfg.AnalysisEngine)
 	if err != nil {
 		log.Error("failed to create engine pool: %v", err)
 		os.Exit(1)
@@ -85,6 +100,7 @@ func main() {
 		log.Debug("closing engine pool")
 		enginePool.Close()
 	}()
+	enginePool.SetCache(analysis.NewEvalCache(sqlite.NewEvalCacheRepository(database.DB), cfg.EvalCacheSize))
 
 	// Initialize worker pools
 	analysisPool := worker.NewPool(cfg.AnalysisWorkerCount, cfg.AnalysisQueueSize)
@@ -97,52 +113,73 @@ func main() {
 	profileRepo := sqlite.NewProfileRepository(database.DB)
 	statsRepo := sqlite.NewStatsRepository(database.DB)
 	puzzleRushRepo := sqlite.NewPuzzleRushRepository(database)
+	fsrsRepo := sqlite.NewFSRSOptimizationRepository(database.DB)
+	repertoireRepo := sqlite.NewRepertoireRepository(database.DB)
+	openingTreeRepo := sqlite.NewOpeningTreeRepository(database.DB)
+	jobRepo := sqlite.NewJobRepository(database.DB)
+	deckRepo := sqlite.NewDeckRepository(database.DB)
 
 	// Initialize services (order matters - analysisService needs to be created before jobQueue)
 	profileService := services.NewProfileService(profileRepo)
 	analysisConfig := services.AnalysisConfig{
 		StockfishDepth:  cfg.StockfishDepth,
 		StockfishMaxTime: cfg.StockfishMaxTime,
+		StockfishMultiPV: cfg.StockfishMultiPV,
+		Classifier:       cfg.MoveClassifier,
 	}
 	analysisService := services.NewAnalysisService(
 		gameRepo,
 		positionRepo,
 		flashcardRepo,
 		statsRepo,
+		repertoireRepo,
 		analysisConfig,
 		enginePool,
 	)
-	flashcardService := services.NewFlashcardService(flashcardRepo)
-	puzzleRushService := services.NewPuzzleRushService(puzzleRushRepo, flashcardRepo, flashcardService)
 	statsService := services.NewStatsService(statsRepo)
+	repertoireService := services.NewRepertoireService(repertoireRepo)
+	explorerService := services.NewExplorerService(openingTreeRepo)
 
 	// Initialize job queue
-	chessClient := chesscom.New()
-	jobQueue := jobs.NewWorkerQueue(
+	gameSources := []gamesource.Source{
+		chesscom.NewSource(chesscom.New(), cfg.ArchiveLimit, cfg.MaxConcurrentArchive),
+		lichess.NewSource(lichess.New()),
+	}
+	jobQueue := jobs.NewSQLiteQueue(
+		jobRepo,
 		analysisPool,
 		importPool,
 		profileRepo,
 		gameRepo,
 		statsRepo,
+		flashcardRepo,
+		fsrsRepo,
 		analysisService,
-		chessClient,
+		explorerService,
+		gameSources,
 		cfg.StockfishPath,
 		cfg.StockfishDepth,
-		cfg.ArchiveLimit,
-		cfg.MaxConcurrentArchive,
 	)
 
+	flashcardService := services.NewFlashcardService(flashcardRepo, profileRepo, fsrsRepo, jobQueue)
+	puzzleRushService := services.NewPuzzleRushService(puzzleRushRepo, flashcardRepo, flashcardService)
 	gameService := services.NewGameService(gameRepo, positionRepo, jobQueue)
-	importService := services.NewImportService(jobQueue)
+	importService := services.NewImportService(jobQueue, gameRepo, statsRepo, explorerService)
 
 	srv := &api.Server{
 		ProfileService:       profileService,
 		GameService:          gameService,
 		FlashcardService:     flashcardService,
+		DeckService:          services.NewDeckService(deckRepo, flashcardRepo),
 		PuzzleRushService:    puzzleRushService,
+		DrillService:         services.NewDrillService(positionRepo),
 		StatsService:         statsService,
+		RepertoireService:    repertoireService,
+		ExplorerService:      explorerService,
 		ImportService:        importService,
 		AnalysisService:      analysisService,
+		JobService:           services.NewJobService(jobRepo),
+		EnginePool:           enginePool,
 		AnalysisPool:         analysisPool,
 		ImportPool:           importPool,
 		ChessClient:          chesscom.New(),
@@ -156,6 +193,30 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	analysisPool.Start(ctx)
 	importPool.Start(ctx)
+	jobQueue.Start(ctx)
+
+	// Index games stored before the opening tree was kept up to date at import,
+	// tag flashcards created before motif detection and measure the hung
+	// material of errors analyzed before it was recorded
+	go func() {
+		if _, err := analysisService.DetectMissingMotifs(ctx); err != nil {
+			log.Warn("failed to detect motifs of existing flashcards: %v", err)
+		}
+		if _, err := analysisService.DetectMissingMaterialHung(ctx); err != nil {
+			log.Warn("failed to detect hung material of existing positions: %v", err)
+		}
+
+		profiles, err := profileRepo.List(ctx)
+		if err != nil {
+			log.Error("failed to list profiles for opening tree indexing: %v", err)
+			return
+		}
+		for _, p := range profiles {
+			if _, err := explorerService.IndexGames(ctx, p.ID); err != nil {
+				log.Warn("failed to index opening tree: profile_id=%d, error=%v", p.ID, err)
+			}
+		}
+	}()
 
 	// Configure HTTP server
 	httpServer := &http.Server{
@@ -197,6 +258,8 @@ func main() {
 	}
 
 	// Wait for workers to finish
+	log.Debug("stopping job queue")
+	jobQueue.Wait()
 	log.Debug("stopping analysis pool")
 	analysisPool.Stop()
 	log.Debug("stopping import pool")
@@ -0,0 +1,165 @@
+package analysis
+
+import "math"
+
+// Evaluations beyond this are treated as decided when computing centipawn loss.
+const accuracyCPCap = 1000
+
+// MoveEval is the evaluation before and after one move, from white's
+// perspective. A non-nil mate takes precedence over the centipawn score.
+type MoveEval struct {
+	WhiteMove  bool
+	EvalBefore float64
+	MateBefore *int
+	EvalAfter  float64
+	MateAfter  *int
+}
+
+// SideAccuracy summarizes how accurately one side played a game.
+type SideAccuracy struct {
+	Moves    int
+	ACPL     float64 // average centipawn loss
+	Accuracy float64 // 0-100
+}
+
+// WinPercent converts an evaluation from white's perspective into white's
+// winning chances (0-100), using the logistic curve fitted by Lichess.
+func WinPercent(cp float64, mate *int) float64 {
+	if mate != nil {
+		if *mate > 0 {
+			return 100
+		}
+		if *mate < 0 {
+			return 0
+		}
+	}
+	cp = clampCP(cp)
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*cp))-1)
+}
+
+// MoveAccuracy scores a move 0-100 from the drop in the mover's winning chances.
+func MoveAccuracy(winBefore, winAfter float64) float64 {
+	drop := math.Max(0, winBefore-winAfter)
+	acc := 103.1668*math.Exp(-0.04354*drop) - 3.1669
+	return math.Min(math.Max(acc, 0), 100)
+}
+
+// GameAccuracy computes ACPL and accuracy per side from the game's moves in
+// order. Accuracy averages a volatility-weighted mean and the harmonic mean
+// of move accuracies, so both sharp positions and single big mistakes weigh in.
+func GameAccuracy(moves []MoveEval) (white, black SideAccuracy) {
+	if len(moves) == 0 {
+		return white, black
+	}
+
+	// White's winning chances before each move and after the last one
+	wins := make([]float64, 0, len(moves)+1)
+	for _, m := range moves {
+		wins = append(wins, WinPercent(m.EvalBefore, m.MateBefore))
+	}
+	last := moves[len(moves)-1]
+	wins = append(wins, WinPercent(last.after()))
+
+	window := len(moves) / 10
+	if window < 2 {
+		window = 2
+	}
+	if window > 8 {
+		window = 8
+	}
+
+	type sideTotals struct {
+		lossSum     float64
+		weighted    float64
+		weightSum   float64
+		harmonicSum float64
+		n           int
+	}
+	var totals [2]sideTotals
+
+	for i, m := range moves {
+		before := WinPercent(m.EvalBefore, m.MateBefore)
+		after := WinPercent(m.after())
+		loss := centipawnValue(m.after()) - centipawnValue(m.EvalBefore, m.MateBefore)
+		side := 1
+		if m.WhiteMove {
+			side = 0
+			loss = -loss
+		} else {
+			before, after = 100-before, 100-after
+		}
+
+		acc := MoveAccuracy(before, after)
+		weight := volatility(wins, i, window)
+
+		t := &totals[side]
+		t.lossSum += math.Max(0, loss)
+		t.weighted += acc * weight
+		t.weightSum += weight
+		t.harmonicSum += 1 / math.Max(acc, 1)
+		t.n++
+	}
+
+	summarize := func(t sideTotals) SideAccuracy {
+		if t.n == 0 {
+			return SideAccuracy{}
+		}
+		weightedMean := t.weighted / t.weightSum
+		harmonicMean := float64(t.n) / t.harmonicSum
+		return SideAccuracy{
+			Moves:    t.n,
+			ACPL:     t.lossSum / float64(t.n),
+			Accuracy: math.Min((weightedMean+harmonicMean)/2, 100),
+		}
+	}
+	return summarize(totals[0]), summarize(totals[1])
+}
+
+// after returns the evaluation after the move, with a delivered checkmate
+// resolved in favour of the mover.
+func (m MoveEval) after() (float64, *int) {
+	s := resolveDeliveredMate(Score{CP: m.EvalAfter, Mate: m.MateAfter}, m.WhiteMove)
+	return s.CP, s.Mate
+}
+
+// volatility is the standard deviation of winning chances in the window
+// ending after move i, bounded so quiet positions still count.
+func volatility(wins []float64, i, window int) float64 {
+	end := i + 2
+	start := end - window
+	if start < 0 {
+		start = 0
+		end = window
+	}
+	if end > len(wins) {
+		end = len(wins)
+	}
+	vals := wins[start:end]
+
+	var mean float64
+	for _, v := range vals {
+		mean += v
+	}
+	mean /= float64(len(vals))
+	var variance float64
+	for _, v := range vals {
+		variance += (v - mean) * (v - mean)
+	}
+	std := math.Sqrt(variance / float64(len(vals)))
+	return math.Min(math.Max(std, 0.5), 12)
+}
+
+// centipawnValue maps an evaluation to capped centipawns, treating mate as the cap.
+func centipawnValue(cp float64, mate *int) float64 {
+	if mate != nil && *mate != 0 {
+		if *mate > 0 {
+			return accuracyCPCap
+		}
+		return -accuracyCPCap
+	}
+	return clampCP(cp)
+}
+
+func clampCP(cp float64) float64 {
+	return math.Min(math.Max(cp, -accuracyCPCap), accuracyCPCap)
+}
@@ -0,0 +1,66 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestWinPercent(t *testing.T) {
+	assert.InDelta(t, 50.0, analysis.WinPercent(0, nil), 0.001)
+	assert.Greater(t, analysis.WinPercent(100, nil), 50.0)
+	assert.Less(t, analysis.WinPercent(-100, nil), 50.0)
+	assert.InDelta(t, 100-analysis.WinPercent(300, nil), analysis.WinPercent(-300, nil), 0.001)
+	assert.Equal(t, analysis.WinPercent(1000, nil), analysis.WinPercent(5000, nil), "evaluations are capped")
+	assert.Equal(t, 100.0, analysis.WinPercent(0, intPtr(3)))
+	assert.Equal(t, 0.0, analysis.WinPercent(0, intPtr(-2)))
+}
+
+func TestMoveAccuracy(t *testing.T) {
+	assert.InDelta(t, 100.0, analysis.MoveAccuracy(60, 60), 0.01)
+	assert.InDelta(t, 100.0, analysis.MoveAccuracy(40, 70), 0.01, "improving never scores above 100")
+	assert.Less(t, analysis.MoveAccuracy(60, 30), analysis.MoveAccuracy(60, 50))
+	assert.GreaterOrEqual(t, analysis.MoveAccuracy(100, 0), 0.0)
+}
+
+func TestGameAccuracy(t *testing.T) {
+	t.Run("no moves", func(t *testing.T) {
+		white, black := analysis.GameAccuracy(nil)
+		assert.Equal(t, analysis.SideAccuracy{}, white)
+		assert.Equal(t, analysis.SideAccuracy{}, black)
+	})
+
+	t.Run("black blunders", func(t *testing.T) {
+		moves := []analysis.MoveEval{
+			{WhiteMove: true, EvalBefore: 20, EvalAfter: 25},
+			{WhiteMove: false, EvalBefore: 25, EvalAfter: 30},
+			{WhiteMove: true, EvalBefore: 30, EvalAfter: 20},
+			{WhiteMove: false, EvalBefore: 20, EvalAfter: 420}, // hangs a piece
+			{WhiteMove: true, EvalBefore: 420, EvalAfter: 410},
+			{WhiteMove: false, EvalBefore: 410, EvalAfter: 400},
+		}
+		white, black := analysis.GameAccuracy(moves)
+
+		assert.Equal(t, 3, white.Moves)
+		assert.Equal(t, 3, black.Moves)
+		assert.InDelta(t, 20.0/3, white.ACPL, 0.01)
+		assert.InDelta(t, 405.0/3, black.ACPL, 0.01)
+		assert.Greater(t, white.Accuracy, 90.0)
+		assert.Less(t, black.Accuracy, white.Accuracy)
+	})
+
+	t.Run("delivering mate is not a loss", func(t *testing.T) {
+		moves := []analysis.MoveEval{
+			{WhiteMove: true, MateBefore: intPtr(2), MateAfter: intPtr(1)},
+			{WhiteMove: false, MateBefore: intPtr(1), MateAfter: intPtr(1)},
+			{WhiteMove: true, MateBefore: intPtr(1), MateAfter: intPtr(0)},
+		}
+		white, _ := analysis.GameAccuracy(moves)
+
+		assert.Equal(t, 0.0, white.ACPL)
+		assert.InDelta(t, 100.0, white.Accuracy, 0.01)
+	})
+}
@@ -53,3 +53,141 @@ func ClassifyMoveWithThresholds(thresholds ClassificationThresholds, evalBefore,
 		return "good"
 	}
 }
+
+// Mate distances are mapped onto the centipawn scale so they compare with
+// ordinary evaluations: any forced mate outweighs material, and a shorter
+// mate outweighs a longer one.
+const (
+	MateScoreCP = 10000
+	mateStepCP  = 10
+)
+
+// Score is an engine evaluation from white's perspective. A non-nil Mate
+// takes precedence over CP.
+type Score struct {
+	CP   float64
+	Mate *int
+}
+
+// ScoreFromEval returns the score of the engine's best line.
+func ScoreFromEval(e EvalResult) Score {
+	if e.Mate != nil {
+		return Score{Mate: e.Mate}
+	}
+	return Score{CP: e.CP}
+}
+
+// Centipawns returns the score on the centipawn scale, mapping mate in N to
+// ±(MateScoreCP - 10N).
+func (s Score) Centipawns() float64 {
+	if s.Mate == nil {
+		return s.CP
+	}
+	n := *s.Mate
+	if n < 0 {
+		return -float64(MateScoreCP + mateStepCP*n)
+	}
+	return float64(MateScoreCP - mateStepCP*n)
+}
+
+// ExpectedScore converts the evaluation into white's expected score (0-1).
+func (s Score) ExpectedScore() float64 {
+	return WinPercent(s.CP, s.Mate) / 100
+}
+
+// resolveDeliveredMate turns the engine's mate 0 after a checkmating move,
+// which carries no sign, into a mate for the mover.
+func resolveDeliveredMate(s Score, isWhiteMove bool) Score {
+	if s.Mate == nil || *s.Mate != 0 {
+		return s
+	}
+	mate := -1
+	if isWhiteMove {
+		mate = 1
+	}
+	return Score{Mate: &mate}
+}
+
+// MoveInput is everything a classifier may use to judge a move.
+type MoveInput struct {
+	FEN         string // position before the move
+	MovePlayed  string
+	BestMove    string
+	IsWhiteMove bool
+	Before      Score
+	After       Score
+	SecondBest  *Score // second engine line before the move, when MultiPV was used
+	PrevBefore  *Score // evaluation before the opponent's previous move
+}
+
+// NewMoveInput builds a classifier input from the full engine results before
+// and after the move. prevBefore is the evaluation before the opponent's
+// previous move, or nil for the first move.
+func NewMoveInput(fen, movePlayed string, isWhiteMove bool, before, after EvalResult, prevBefore *EvalResult) MoveInput {
+	in := MoveInput{
+		FEN:         fen,
+		MovePlayed:  movePlayed,
+		BestMove:    before.BestMove,
+		IsWhiteMove: isWhiteMove,
+		Before:      ScoreFromEval(before),
+		After:       ScoreFromEval(after),
+	}
+	for _, line := range before.Lines {
+		if line.Rank == 2 {
+			in.SecondBest = &Score{CP: line.CP, Mate: line.Mate}
+		}
+	}
+	if prevBefore != nil {
+		prev := ScoreFromEval(*prevBefore)
+		in.PrevBefore = &prev
+	}
+	return in
+}
+
+// Classifier labels a move given its evaluations.
+type Classifier interface {
+	Name() string
+	Classify(in MoveInput) string
+}
+
+// CentipawnClassifier classifies moves by centipawn loss, with mates mapped
+// onto the centipawn scale.
+type CentipawnClassifier struct {
+	Thresholds ClassificationThresholds
+}
+
+// Name implements Classifier.
+func (c CentipawnClassifier) Name() string { return ClassifierCentipawn }
+
+// Classify implements Classifier.
+func (c CentipawnClassifier) Classify(in MoveInput) string {
+	if class := classifyMate(in); class != "" {
+		return class
+	}
+	after := resolveDeliveredMate(in.After, in.IsWhiteMove)
+	return ClassifyMoveWithThresholds(c.Thresholds, in.Before.Centipawns(), after.Centipawns(), in.IsWhiteMove, in.MovePlayed, in.BestMove)
+}
+
+// classifyMate returns "allowed_mate" when the move lets the opponent force
+// mate and "missed_mate" when it throws away the mover's own forced mate, or
+// "" when neither applies. The engine's best move is never a mate error.
+func classifyMate(in MoveInput) string {
+	if in.MovePlayed != "" && in.MovePlayed == in.BestMove {
+		return ""
+	}
+	after := resolveDeliveredMate(in.After, in.IsWhiteMove)
+	sign := 1
+	if !in.IsWhiteMove {
+		sign = -1
+	}
+	mates := func(s Score) bool { return s.Mate != nil && *s.Mate*sign > 0 }
+	mated := func(s Score) bool { return s.Mate != nil && *s.Mate*sign < 0 }
+
+	switch {
+	case mated(after) && !mated(in.Before):
+		return "allowed_mate"
+	case mates(in.Before) && !mates(after):
+		return "missed_mate"
+	}
+	return ""
+}
@@ -224,3 +224,253 @@ func TestClassifyMove_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+const knightSacFEN = "rnbqkb1r/pppp1pp1/5n1p/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 4"
+
+func scorePtr(cp float64, mate *int) *analysis.Score {
+	return &analysis.Score{CP: cp, Mate: mate}
+}
+
+func TestWinProbabilityClassifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    analysis.MoveInput
+		expected string
+	}{
+		{
+			name:     "white drops +900 to +600 in a decided position - only an inaccuracy",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "e2e4", BestMove: "d2d4", Before: analysis.Score{CP: 900}, After: analysis.Score{CP: 600}},
+			expected: "inaccuracy",
+		},
+		{
+			name:     "white drops +50 to -250 in a balanced position - blunder",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "e2e4", BestMove: "d2d4", Before: analysis.Score{CP: 50}, After: analysis.Score{CP: -250}},
+			expected: "blunder",
+		},
+		{
+			name:     "black drops -50 to +150 - mistake",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "e7e5", BestMove: "d7d5", Before: analysis.Score{CP: -50}, After: analysis.Score{CP: 150}},
+			expected: "mistake",
+		},
+		{
+			name:     "black improves position - good",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "e7e5", BestMove: "d7d5", Before: analysis.Score{CP: -50}, After: analysis.Score{CP: -80}},
+			expected: "good",
+		},
+		{
+			name:     "white throws away a forced mate - missed mate",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "e2e4", BestMove: "d1h5", Before: analysis.Score{Mate: intPtr(3)}, After: analysis.Score{CP: 200}},
+			expected: "missed_mate",
+		},
+		{
+			name:     "black walks into a forced mate - allowed mate",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "g7g5", BestMove: "e7e5", Before: analysis.Score{CP: 0}, After: analysis.Score{Mate: intPtr(1)}},
+			expected: "allowed_mate",
+		},
+		{
+			name:     "best move with no alternatives known - best",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "d2d4", BestMove: "d2d4", Before: analysis.Score{CP: 30}, After: analysis.Score{CP: 25}},
+			expected: "best",
+		},
+		{
+			name:     "best move when the second line is close - best",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "d2d4", BestMove: "d2d4", Before: analysis.Score{CP: 30}, After: analysis.Score{CP: 25}, SecondBest: scorePtr(10, nil)},
+			expected: "best",
+		},
+		{
+			name:     "only move that holds - great",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "d2d4", BestMove: "d2d4", Before: analysis.Score{CP: 100}, After: analysis.Score{CP: 100}, SecondBest: scorePtr(-200, nil)},
+			expected: "great",
+		},
+		{
+			name:     "only move for black - great",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "d7d5", BestMove: "d7d5", Before: analysis.Score{CP: -20}, After: analysis.Score{CP: -20}, SecondBest: scorePtr(250, nil)},
+			expected: "great",
+		},
+		{
+			name:     "sound knight sacrifice - brilliant",
+			input:    analysis.MoveInput{FEN: knightSacFEN, IsWhiteMove: true, MovePlayed: "f3g5", BestMove: "f3g5", Before: analysis.Score{CP: 20}, After: analysis.Score{CP: 30}},
+			expected: "brilliant",
+		},
+		{
+			name:     "unsound knight sacrifice - blunder",
+			input:    analysis.MoveInput{FEN: knightSacFEN, IsWhiteMove: true, MovePlayed: "f3g5", BestMove: "b1c3", Before: analysis.Score{CP: 20}, After: analysis.Score{CP: -280}},
+			expected: "blunder",
+		},
+		{
+			name:     "sacrifice in an already winning position - best",
+			input:    analysis.MoveInput{FEN: knightSacFEN, IsWhiteMove: true, MovePlayed: "f3g5", BestMove: "f3g5", Before: analysis.Score{CP: 1200}, After: analysis.Score{CP: 1200}},
+			expected: "best",
+		},
+		{
+			name:     "failing to punish the opponent's blunder - miss",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "a2a3", BestMove: "d1h5", Before: analysis.Score{CP: 400}, After: analysis.Score{CP: 20}, PrevBefore: scorePtr(0, nil)},
+			expected: "miss",
+		},
+		{
+			name:     "giving back more than the opponent's blunder handed over - blunder",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "a2a3", BestMove: "d1h5", Before: analysis.Score{CP: 400}, After: analysis.Score{CP: -300}, PrevBefore: scorePtr(0, nil)},
+			expected: "blunder",
+		},
+		{
+			name:     "black misses a won position handed over by white - miss",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "a7a6", BestMove: "d8h4", Before: analysis.Score{CP: -600}, After: analysis.Score{CP: -50}, PrevBefore: scorePtr(-30, nil)},
+			expected: "miss",
+		},
+		{
+			name:     "mistake without an opportunity - mistake",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "a2a3", BestMove: "d1h5", Before: analysis.Score{CP: 20}, After: analysis.Score{CP: -130}, PrevBefore: scorePtr(30, nil)},
+			expected: "mistake",
+		},
+		{
+			name:     "delivering checkmate - best",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "d1h5", BestMove: "d1h5", Before: analysis.Score{Mate: intPtr(1)}, After: analysis.Score{Mate: intPtr(0)}},
+			expected: "best",
+		},
+	}
+
+	classifier := analysis.NewClassifier(analysis.ClassifierWinProbability)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifier.Classify(tt.input))
+		})
+	}
+}
+
+func TestNewClassifier(t *testing.T) {
+	assert.Equal(t, analysis.ClassifierCentipawn, analysis.NewClassifier("").Name())
+	assert.Equal(t, analysis.ClassifierWinProbability, analysis.NewClassifier(analysis.ClassifierWinProbability).Name())
+	assert.True(t, analysis.IsValidClassifier(analysis.ClassifierCentipawn))
+	assert.False(t, analysis.IsValidClassifier("engine"))
+
+	// The centipawn classifier keeps the original behaviour: +900 to +600 is a blunder
+	in := analysis.MoveInput{IsWhiteMove: true, MovePlayed: "e2e4", BestMove: "d2d4", Before: analysis.Score{CP: 900}, After: analysis.Score{CP: 600}}
+	assert.Equal(t, "blunder", analysis.NewClassifier(analysis.ClassifierCentipawn).Classify(in))
+}
+
+func TestIsSacrifice(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected bool
+	}{
+		{name: "knight left en prise to a pawn", fen: knightSacFEN, move: "f3g5", expected: true},
+		{name: "knight takes an undefended pawn", fen: knightSacFEN, move: "f3e5", expected: false},
+		{name: "knight retreats to safety", fen: knightSacFEN, move: "f3h4", expected: false},
+		{name: "pawn moves are never sacrifices", fen: knightSacFEN, move: "d2d4", expected: false},
+		{name: "knight takes a pawn defended by a pawn", fen: "rnbqkb1r/ppp2ppp/3p1n2/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 4", move: "f3e5", expected: true},
+		{name: "illegal move", fen: knightSacFEN, move: "f3f5", expected: false},
+		{name: "invalid FEN", fen: "not a fen", move: "f3g5", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, analysis.IsSacrifice(tt.fen, tt.move))
+		})
+	}
+}
+
+func TestScoreCentipawns(t *testing.T) {
+	assert.Equal(t, 35.0, analysis.Score{CP: 35}.Centipawns())
+	assert.Equal(t, 9990.0, analysis.Score{Mate: intPtr(1)}.Centipawns())
+	assert.Equal(t, -9970.0, analysis.Score{Mate: intPtr(-3)}.Centipawns())
+	assert.Greater(t, analysis.Score{Mate: intPtr(2)}.Centipawns(), analysis.Score{Mate: intPtr(5)}.Centipawns(), "shorter mates score higher")
+	assert.Greater(t, analysis.Score{Mate: intPtr(40)}.Centipawns(), analysis.Score{CP: 3000}.Centipawns(), "any mate outweighs material")
+}
+
+func TestNewMoveInput(t *testing.T) {
+	before := analysis.EvalResult{
+		BestMove: "d1h5",
+		Mate:     intPtr(2),
+		Lines: []analysis.Line{
+			{Rank: 1, Move: "d1h5", Mate: intPtr(2)},
+			{Rank: 2, Move: "f1c4", CP: 150},
+		},
+	}
+	after := analysis.EvalResult{CP: 120}
+	prev := analysis.EvalResult{CP: -10}
+
+	in := analysis.NewMoveInput(knightSacFEN, "f1c4", true, before, after, &prev)
+
+	assert.Equal(t, "d1h5", in.BestMove)
+	assert.Equal(t, 2, *in.Before.Mate)
+	assert.Nil(t, in.After.Mate)
+	assert.Equal(t, 120.0, in.After.CP)
+	if assert.NotNil(t, in.SecondBest) {
+		assert.Equal(t, 150.0, in.SecondBest.CP)
+	}
+	if assert.NotNil(t, in.PrevBefore) {
+		assert.Equal(t, -10.0, in.PrevBefore.CP)
+	}
+
+	first := analysis.NewMoveInput(knightSacFEN, "f1c4", true, analysis.EvalResult{CP: 20}, after, nil)
+	assert.Nil(t, first.SecondBest)
+	assert.Nil(t, first.PrevBefore)
+}
+
+func TestMateAwareClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    analysis.MoveInput
+		expected string
+	}{
+		{
+			name:     "white lets a forced mate slip to a won ending",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "e1e2", BestMove: "d1h5", Before: analysis.Score{Mate: intPtr(2)}, After: analysis.Score{CP: 800}},
+			expected: "missed_mate",
+		},
+		{
+			name:     "black lets a forced mate slip",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "a7a6", BestMove: "d8h4", Before: analysis.Score{Mate: intPtr(-1)}, After: analysis.Score{CP: -300}},
+			expected: "missed_mate",
+		},
+		{
+			name:     "white allows mate from an equal position",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "f2f3", BestMove: "d2d4", Before: analysis.Score{CP: 0}, After: analysis.Score{Mate: intPtr(-1)}},
+			expected: "allowed_mate",
+		},
+		{
+			name:     "black allows mate from a winning position",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "g7g5", BestMove: "e7e5", Before: analysis.Score{CP: -500}, After: analysis.Score{Mate: intPtr(2)}},
+			expected: "allowed_mate",
+		},
+		{
+			name:     "turning your own mate into being mated - allowed mate",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "d1d8", BestMove: "d1h5", Before: analysis.Score{Mate: intPtr(3)}, After: analysis.Score{Mate: intPtr(-2)}},
+			expected: "allowed_mate",
+		},
+		{
+			name:     "slower mate is not a mate error",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "f1c4", BestMove: "d1h5", Before: analysis.Score{Mate: intPtr(2)}, After: analysis.Score{Mate: intPtr(4)}},
+			expected: "good",
+		},
+		{
+			name:     "already being mated is not a mate error",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "g1h1", BestMove: "g1f1", Before: analysis.Score{Mate: intPtr(-3)}, After: analysis.Score{Mate: intPtr(-1)}},
+			expected: "good",
+		},
+		{
+			name:     "best move into a mate is not an error",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "g1f1", BestMove: "g1f1", Before: analysis.Score{CP: -900}, After: analysis.Score{Mate: intPtr(-5)}},
+			expected: "good",
+		},
+		{
+			name:     "delivering checkmate",
+			input:    analysis.MoveInput{IsWhiteMove: false, MovePlayed: "d8h4", BestMove: "d8h4", Before: analysis.Score{Mate: intPtr(-1)}, After: analysis.Score{Mate: intPtr(0)}},
+			expected: "good",
+		},
+		{
+			name:     "mates are compared on the centipawn scale - big cp loss still a blunder",
+			input:    analysis.MoveInput{IsWhiteMove: true, MovePlayed: "e2e4", BestMove: "d2d4", Before: analysis.Score{CP: 300}, After: analysis.Score{CP: -50}},
+			expected: "blunder",
+		},
+	}
+
+	classifier := analysis.NewClassifier(analysis.ClassifierCentipawn)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifier.Classify(tt.input))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/vytor/chessflash/internal/logger"
+)
+
+// EvalCacheStore persists cached evaluations as opaque data. Get returns nil
+// data when nothing is stored for the key. Prune deletes all but the
+// maxEntries most recently stored evaluations.
+type EvalCacheStore interface {
+	Get(ctx context.Context, engine, fen string, depth, multiPV int) ([]byte, error)
+	Put(ctx context.Context, engine, fen string, depth, multiPV int, data []byte) error
+	Prune(ctx context.Context, maxEntries int) (int, error)
+}
+
+// pruneEvery is how many evaluations are stored between prunes of a bounded
+// cache, so it overshoots its size by at most that many entries.
+const pruneEvery = 1000
+
+// EvalCacheStats reports how often the cache saved an engine search.
+type EvalCacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // 0-1
+}
+
+// EvalCache remembers engine evaluations keyed by engine, normalized FEN,
+// depth and number of lines, so transpositions and positions shared between games are
+// only searched once. A nil *EvalCache is valid and never hits.
+type EvalCache struct {
+	store      EvalCacheStore
+	maxEntries int
+	log        *logger.Logger
+	hits       atomic.Int64
+	misses     atomic.Int64
+	puts       atomic.Int64
+}
+
+// NewEvalCache creates a cache backed by store that keeps the maxEntries
+// most recent evaluations (0 = unbounded).
+func NewEvalCache(store EvalCacheStore, maxEntries int) *EvalCache {
+	return &EvalCache{
+		store:      store,
+		maxEntries: maxEntries,
+		log:        logger.Default().WithPrefix("eval-cache"),
+	}
+}
+
+// Get returns engine's cached evaluation of fen, if any. Store errors count as misses.
+func (c *EvalCache) Get(ctx context.Context, engine, fen string, depth, multiPV int) (EvalResult, bool) {
+	if c == nil {
+		return EvalResult{}, false
+	}
+
+	data, err := c.store.Get(ctx, engine, NormalizeFEN(fen), depth, multiPV)
+	if err != nil {
+		c.log.Warn("failed to read cached evaluation: %v", err)
+	}
+	if err != nil || data == nil {
+		c.misses.Add(1)
+		return EvalResult{}, false
+	}
+
+	var result EvalResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		c.log.Warn("failed to decode cached evaluation: %v", err)
+		c.misses.Add(1)
+		return EvalResult{}, false
+	}
+	c.hits.Add(1)
+	return result, true
+}
+
+// Put stores an evaluation. Failures are logged and otherwise ignored since
+// the cache is only an optimization.
+func (c *EvalCache) Put(ctx context.Context, engine, fen string, depth, multiPV int, result EvalResult) {
+	if c == nil {
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		c.log.Warn("failed to encode evaluation: %v", err)
+		return
+	}
+	if err := c.store.Put(ctx, engine, NormalizeFEN(fen), depth, multiPV, data); err != nil {
+		c.log.Warn("failed to store evaluation: %v", err)
+		return
+	}
+
+	if c.maxEntries > 0 && c.puts.Add(1)%pruneEvery == 0 {
+		pruned, err := c.store.Prune(ctx, c.maxEntries)
+		if err != nil {
+			c.log.Warn("failed to prune evaluations: %v", err)
+			return
+		}
+		c.log.Debug("pruned %d cached evaluations", pruned)
+	}
+}
+
+// Stats returns the hits and misses since the cache was created.
+func (c *EvalCache) Stats() EvalCacheStats {
+	if c == nil {
+		return EvalCacheStats{}
+	}
+
+	stats := EvalCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,95 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+type memoryStore struct {
+	data   map[string][]byte
+	err    error
+	prunes int
+}
+
+func (m *memoryStore) key(engine, fen string, depth, multiPV int) string {
+	return fmt.Sprintf("%s|%s|%d|%d", engine, fen, depth, multiPV)
+}
+
+func (m *memoryStore) Get(_ context.Context, engine, fen string, depth, multiPV int) ([]byte, error) {
+	return m.data[m.key(engine, fen, depth, multiPV)], m.err
+}
+
+func (m *memoryStore) Put(_ context.Context, engine, fen string, depth, multiPV int, data []byte) error {
+	m.data[m.key(engine, fen, depth, multiPV)] = data
+	return m.err
+}
+
+func (m *memoryStore) Prune(_ context.Context, maxEntries int) (int, error) {
+	m.prunes++
+	return 0, m.err
+}
+
+func TestEvalCache(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{data: map[string][]byte{}}
+	cache := analysis.NewEvalCache(store, 0)
+
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	_, ok := cache.Get(ctx, "stockfish", fen, 18, 3)
+	assert.False(t, ok)
+
+	mate := 3
+	result := analysis.EvalResult{BestMove: "e7e5", Mate: &mate, PV: []string{"e7e5", "g1f3"}, Lines: []analysis.Line{{Rank: 1, Move: "e7e5", CP: 25}}}
+	cache.Put(ctx, "stockfish", fen, 18, 3, result)
+
+	// Same position at another move number, with the uncapturable en passant square dropped
+	cached, ok := cache.Get(ctx, "stockfish", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 4 9", 18, 3)
+	require.True(t, ok)
+	assert.Equal(t, result, cached)
+
+	// Engine, depth and line count are part of the key
+	_, ok = cache.Get(ctx, "lc0", fen, 18, 3)
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "stockfish", fen, 20, 3)
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "stockfish", fen, 18, 1)
+	assert.False(t, ok)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(4), stats.Misses)
+	assert.InDelta(t, 0.2, stats.HitRate, 0.0001)
+}
+
+func TestEvalCache_StoreErrorIsMiss(t *testing.T) {
+	store := &memoryStore{data: map[string][]byte{}, err: errors.New("disk full")}
+	cache := analysis.NewEvalCache(store, 0)
+
+	cache.Put(context.Background(), "stockfish", "8/8/8/8/8/8/8/K1k5 w - - 0 1", 10, 1, analysis.EvalResult{BestMove: "a1a2"})
+	_, ok := cache.Get(context.Background(), "stockfish", "8/8/8/8/8/8/8/K1k5 w - - 0 1", 10, 1)
+	assert.False(t, ok)
+}
+
+func TestEvalCache_Nil(t *testing.T) {
+	var cache *analysis.EvalCache
+	_, ok := cache.Get(context.Background(), "stockfish", "8/8/8/8/8/8/8/K1k5 w - - 0 1", 10, 1)
+	assert.False(t, ok)
+	cache.Put(context.Background(), "stockfish", "8/8/8/8/8/8/8/K1k5 w - - 0 1", 10, 1, analysis.EvalResult{})
+	assert.Equal(t, analysis.EvalCacheStats{}, cache.Stats())
+}
+
+func TestEvalCache_PrunesWhenBounded(t *testing.T) {
+	store := &memoryStore{data: map[string][]byte{}}
+	cache := analysis.NewEvalCache(store, 10)
+
+	for i := 0; i < 1500; i++ {
+		cache.Put(context.Background(), "stockfish", fmt.Sprintf("8/8/8/8/8/8/8/K1k5 w - - 0 %d", i), i, 1, analysis.EvalResult{})
+	}
+	assert.Equal(t, 1, store.prunes)
+}
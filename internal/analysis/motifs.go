@@ -0,0 +1,386 @@
+package analysis
+
+import (
+	"github.com/corentings/chess/v2"
+)
+
+// Tactical motifs found in the best move of a position and its engine line
+const (
+	MotifFork             = "fork"
+	MotifPin              = "pin"
+	MotifSkewer           = "skewer"
+	MotifDiscoveredAttack = "discovered_attack"
+	MotifHangingPiece     = "hanging_piece"
+	MotifBackRankMate     = "back_rank_mate"
+)
+
+// Motifs lists every motif, in the order DetectMotifs reports them.
+var Motifs = []string{
+	MotifFork,
+	MotifPin,
+	MotifSkewer,
+	MotifDiscoveredAttack,
+	MotifHangingPiece,
+	MotifBackRankMate,
+}
+
+// motifPlies bounds how deep into the engine line the solver's moves are
+// inspected for motifs: its first three moves. Deeper moves are the
+// consequences of the tactic rather than the tactic itself.
+const motifPlies = 5
+
+var (
+	rookDirections   = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	queenDirections  = append(append([][2]int{}, rookDirections...), bishopDirections...)
+	knightJumps      = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+)
+
+// DetectMotifs returns the tactical motifs of playing bestMove in the
+// position given by fen, following the engine line pv when it starts with
+// bestMove: forks, pins, skewers and discovered attacks made by the side to
+// move within its first moves, a hanging piece taken by the best move, and a
+// back-rank mate at the end of the line.
+func DetectMotifs(fen, bestMove string, pv []string) []string {
+	if bestMove == "" {
+		return nil
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil
+	}
+	pos := chess.NewGame(opt).Position()
+	solver := pos.Turn()
+
+	line := pv
+	if len(line) == 0 || line[0] != bestMove {
+		line = []string{bestMove}
+	}
+
+	found := map[string]bool{}
+	for ply, uci := range line {
+		move := findLegalMove(pos, uci)
+		if move == nil {
+			break
+		}
+		next := pos.Update(move)
+		if pos.Turn() == solver {
+			if ply == 0 && takesHangingPiece(pos, next, move) {
+				found[MotifHangingPiece] = true
+			}
+			if ply < motifPlies {
+				if isFork(next, move.S2()) {
+					found[MotifFork] = true
+				}
+				pin, skewer := pinsOrSkewers(next, move.S2())
+				found[MotifPin] = found[MotifPin] || pin
+				found[MotifSkewer] = found[MotifSkewer] || skewer
+				if discoversAttack(pos, next, move) {
+					found[MotifDiscoveredAttack] = true
+				}
+			}
+			if next.Status() == chess.Checkmate && isBackRankMate(next) {
+				found[MotifBackRankMate] = true
+			}
+		}
+		pos = next
+	}
+
+	motifs := []string{}
+	for _, m := range Motifs {
+		if found[m] {
+			motifs = append(motifs, m)
+		}
+	}
+	return motifs
+}
+
+// takesHangingPiece reports whether the move captures a piece the opponent
+// cannot win back on the capture square.
+func takesHangingPiece(before, after *chess.Position, move *chess.Move) bool {
+	if !move.HasTag(chess.Capture) || move.HasTag(chess.EnPassant) {
+		return false
+	}
+	if before.Board().Piece(move.S2()) == chess.NoPiece {
+		return false
+	}
+	board := after.Board()
+	defended := len(attackers(board, move.S2(), after.Turn().Other())) > 0
+	for _, sq := range attackers(board, move.S2(), after.Turn()) {
+		// The king cannot take back a defended piece
+		if board.Piece(sq).Type() != chess.King || !defended {
+			return false
+		}
+	}
+	return true
+}
+
+// isFork reports whether the piece on sq attacks two or more targets at once
+// from a square where it cannot be taken cheaply. Targets are the king,
+// pieces worth more than the attacker, and undefended minor or major pieces.
+func isFork(pos *chess.Position, sq chess.Square) bool {
+	board := pos.Board()
+	piece := board.Piece(sq)
+	them := piece.Color().Other()
+	worth := value(piece.Type())
+	if !isSafe(board, sq) {
+		return false
+	}
+
+	targets := 0
+	for _, target := range attackedSquares(board, sq) {
+		p := board.Piece(target)
+		if p == chess.NoPiece || p.Color() != them {
+			continue
+		}
+		v := value(p.Type())
+		if p.Type() == chess.King || v > worth || (v >= 3 && len(attackers(board, target, them)) == 0) {
+			targets++
+		}
+	}
+	return targets >= 2
+}
+
+// pinsOrSkewers looks along the lines of the slider on sq for two enemy
+// pieces in a row: a pin when the one behind is worth more (or is the king),
+// a skewer when the one in front is.
+func pinsOrSkewers(pos *chess.Position, sq chess.Square) (pin, skewer bool) {
+	board := pos.Board()
+	piece := board.Piece(sq)
+	them := piece.Color().Other()
+	for _, dir := range sliderDirections(piece.Type()) {
+		front, ok := firstPiece(board, sq, dir)
+		if !ok || board.Piece(front).Color() != them {
+			continue
+		}
+		behind, ok := firstPiece(board, front, dir)
+		if !ok || board.Piece(behind).Color() != them {
+			continue
+		}
+		f, b := board.Piece(front).Type(), board.Piece(behind).Type()
+		switch {
+		case f != chess.King && value(b) > value(f):
+			pin = true
+		case b != chess.Pawn && value(f) > value(b):
+			skewer = true
+		}
+	}
+	return pin, skewer
+}
+
+// discoversAttack reports whether moving a piece out of the way lets another
+// of the mover's sliders attack the king or a piece worth more than itself.
+func discoversAttack(before, after *chess.Position, move *chess.Move) bool {
+	board := after.Board()
+	us := board.Piece(move.S2()).Color()
+	them := us.Other()
+	for sq, p := range board.SquareMap() {
+		if p.Color() != us || sq == move.S2() {
+			continue
+		}
+		for _, dir := range sliderDirections(p.Type()) {
+			// The moved piece must have been the first piece on this line
+			if blocker, ok := firstPiece(before.Board(), sq, dir); !ok || blocker != move.S1() {
+				continue
+			}
+			target, ok := firstPiece(board, sq, dir)
+			if !ok || board.Piece(target).Color() != them {
+				continue
+			}
+			t := board.Piece(target).Type()
+			if t == chess.King || value(t) > value(p.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBackRankMate reports whether the mated king stands on its first rank,
+// checked along it by a rook or queen, and walled in by its own pieces.
+func isBackRankMate(pos *chess.Position) bool {
+	board := pos.Board()
+	them := pos.Turn()
+	backRank := 0
+	forward := 1
+	if them == chess.Black {
+		backRank, forward = 7, -1
+	}
+
+	var king chess.Square = chess.NoSquare
+	for sq, p := range board.SquareMap() {
+		if p.Type() == chess.King && p.Color() == them {
+			king = sq
+		}
+	}
+	if king == chess.NoSquare || int(king.Rank()) != backRank {
+		return false
+	}
+
+	checkedAlongRank := false
+	for _, sq := range attackers(board, king, them.Other()) {
+		t := board.Piece(sq).Type()
+		if (t == chess.Rook || t == chess.Queen) && int(sq.Rank()) == backRank {
+			checkedAlongRank = true
+		}
+	}
+	if !checkedAlongRank {
+		return false
+	}
+
+	walled := 0
+	for df := -1; df <= 1; df++ {
+		sq, ok := square(int(king.File())+df, backRank+forward)
+		if !ok {
+			continue
+		}
+		if p := board.Piece(sq); p != chess.NoPiece && p.Color() == them {
+			walled++
+		}
+	}
+	return walled > 0
+}
+
+// isSafe reports whether the piece on sq cannot be taken by a cheaper piece,
+// nor at all when undefended.
+func isSafe(board *chess.Board, sq chess.Square) bool {
+	piece := board.Piece(sq)
+	enemy := attackers(board, sq, piece.Color().Other())
+	if len(enemy) == 0 {
+		return true
+	}
+	if len(attackers(board, sq, piece.Color())) == 0 {
+		return false
+	}
+	for _, a := range enemy {
+		if value(board.Piece(a).Type()) < value(piece.Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// attackers returns the squares of the color's pieces attacking sq.
+func attackers(board *chess.Board, sq chess.Square, color chess.Color) []chess.Square {
+	var squares []chess.Square
+	for from, p := range board.SquareMap() {
+		if p.Color() == color && from != sq && attacks(board, from, sq) {
+			squares = append(squares, from)
+		}
+	}
+	return squares
+}
+
+// attackedSquares returns the squares the piece on from attacks.
+func attackedSquares(board *chess.Board, from chess.Square) []chess.Square {
+	var squares []chess.Square
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if sq != from && attacks(board, from, sq) {
+			squares = append(squares, sq)
+		}
+	}
+	return squares
+}
+
+// attacks reports whether the piece on from attacks the square to, whatever
+// stands there.
+func attacks(board *chess.Board, from, to chess.Square) bool {
+	piece := board.Piece(from)
+	df := int(to.File()) - int(from.File())
+	dr := int(to.Rank()) - int(from.Rank())
+
+	switch piece.Type() {
+	case chess.Pawn:
+		forward := 1
+		if piece.Color() == chess.Black {
+			forward = -1
+		}
+		return dr == forward && (df == 1 || df == -1)
+	case chess.Knight:
+		for _, j := range knightJumps {
+			if df == j[0] && dr == j[1] {
+				return true
+			}
+		}
+		return false
+	case chess.King:
+		return max(abs(df), abs(dr)) == 1
+	}
+
+	for _, dir := range sliderDirections(piece.Type()) {
+		if !alongDirection(df, dr, dir) {
+			continue
+		}
+		// Every square before the target must be empty
+		for i := 1; ; i++ {
+			sq, _ := square(int(from.File())+i*dir[0], int(from.Rank())+i*dir[1])
+			if sq == to {
+				return true
+			}
+			if board.Piece(sq) != chess.NoPiece {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// firstPiece returns the square of the first piece met going from sq in dir.
+func firstPiece(board *chess.Board, sq chess.Square, dir [2]int) (chess.Square, bool) {
+	for i := 1; ; i++ {
+		next, ok := square(int(sq.File())+i*dir[0], int(sq.Rank())+i*dir[1])
+		if !ok {
+			return chess.NoSquare, false
+		}
+		if board.Piece(next) != chess.NoPiece {
+			return next, true
+		}
+	}
+}
+
+func sliderDirections(t chess.PieceType) [][2]int {
+	switch t {
+	case chess.Rook:
+		return rookDirections
+	case chess.Bishop:
+		return bishopDirections
+	case chess.Queen:
+		return queenDirections
+	}
+	return nil
+}
+
+// alongDirection reports whether the offset (df, dr) is a positive multiple
+// of dir.
+func alongDirection(df, dr int, dir [2]int) bool {
+	if df == 0 && dr == 0 {
+		return false
+	}
+	n := max(abs(df), abs(dr))
+	return df == n*dir[0] && dr == n*dir[1]
+}
+
+// square returns the square at file f and rank r, both 0-based, reporting
+// false when they fall off the board.
+func square(f, r int) (chess.Square, bool) {
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return chess.NoSquare, false
+	}
+	return chess.NewSquare(chess.File(f), chess.Rank(r)), true
+}
+
+// value is the material value of a piece type, the king outweighing
+// everything else.
+func value(t chess.PieceType) int {
+	if t == chess.King {
+		return 100
+	}
+	return pieceValues[t]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+func TestDetectMotifs(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		bestMove string
+		pv       []string
+		expected []string
+	}{
+		{
+			name:     "knight forks king and queen",
+			fen:      "q3k3/8/8/1N6/8/8/8/4K3 w - - 0 1",
+			bestMove: "b5c7",
+			expected: []string{analysis.MotifFork},
+		},
+		{
+			name:     "fork on a square the opponent wins cheaply",
+			fen:      "q2bk3/8/8/1N6/8/8/8/4K3 w - - 0 1",
+			bestMove: "b5c7",
+			expected: []string{},
+		},
+		{
+			name:     "bishop pins knight to king",
+			fen:      "4k3/8/2n5/8/8/8/8/4KB2 w - - 0 1",
+			bestMove: "f1b5",
+			expected: []string{analysis.MotifPin},
+		},
+		{
+			name:     "rook skewers king and queen",
+			fen:      "8/8/8/8/q2k4/8/8/4K2R w - - 0 1",
+			bestMove: "h1h4",
+			expected: []string{analysis.MotifSkewer},
+		},
+		{
+			name:     "bishop uncovers the rook on the queen",
+			fen:      "4k3/4q3/8/8/4B3/8/8/K3R3 w - - 0 1",
+			bestMove: "e4d5",
+			expected: []string{analysis.MotifDiscoveredAttack},
+		},
+		{
+			name:     "undefended queen taken",
+			fen:      "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1",
+			bestMove: "d1d5",
+			expected: []string{analysis.MotifHangingPiece},
+		},
+		{
+			name:     "defended queen is not hanging",
+			fen:      "4k3/8/2p5/3q4/8/8/8/3RK3 w - - 0 1",
+			bestMove: "d1d5",
+			expected: []string{},
+		},
+		{
+			name:     "back-rank mate",
+			fen:      "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			bestMove: "a1a8",
+			expected: []string{analysis.MotifBackRankMate},
+		},
+		{
+			name:     "back-rank mate at the end of the line",
+			fen:      "6k1/3r1ppp/8/8/8/8/8/R3R1K1 w - - 0 1",
+			bestMove: "a1a8",
+			pv:       []string{"a1a8", "d7d8", "a8d8"},
+			expected: []string{analysis.MotifBackRankMate},
+		},
+		{
+			name:     "motifs of later solver moves in the line",
+			fen:      "q3k3/8/8/1N6/8/8/8/4K3 w - - 0 1",
+			bestMove: "e1d2",
+			pv:       []string{"e1d2", "a8a6", "b5c7"},
+			expected: []string{analysis.MotifFork},
+		},
+		{
+			name:     "quiet move",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			bestMove: "e2e4",
+			pv:       []string{"e2e4", "e7e5", "g1f3"},
+			expected: []string{},
+		},
+		{
+			name:     "black to move",
+			fen:      "4k3/8/8/8/1n6/8/8/Q3K3 b - - 0 1",
+			bestMove: "b4c2",
+			expected: []string{analysis.MotifFork},
+		},
+		{
+			name:     "illegal best move",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			bestMove: "e2e5",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, analysis.DetectMotifs(tt.fen, tt.bestMove, tt.pv))
+		})
+	}
+
+	assert.Nil(t, analysis.DetectMotifs("not a fen", "e2e4", nil))
+	assert.Nil(t, analysis.DetectMotifs("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "", nil))
+}
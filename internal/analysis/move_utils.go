@@ -2,6 +2,7 @@ package analysis
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/corentings/chess/v2"
 )
@@ -49,3 +50,132 @@ func squareToString(sq chess.Square) string {
 
 	return fmt.Sprintf("%c%c", fileChar, rankChar)
 }
+
+// UCIToSAN converts a sequence of UCI moves played from fen into standard
+// algebraic notation. Conversion stops at the first move that is not legal in
+// the resulting position, so a partially invalid line yields its valid prefix.
+func UCIToSAN(fen string, moves []string) []string {
+	if len(moves) == 0 {
+		return nil
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil
+	}
+	pos := chess.NewGame(opt).Position()
+
+	san := make([]string, 0, len(moves))
+	for _, uci := range moves {
+		move := findLegalMove(pos, uci)
+		if move == nil {
+			break
+		}
+		san = append(san, chess.AlgebraicNotation{}.Encode(pos, move))
+		pos = pos.Update(move)
+	}
+	return san
+}
+
+// IsLegalMove reports whether the UCI move can be played in the position
+// given by fen.
+func IsLegalMove(fen, uci string) bool {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return false
+	}
+	return findLegalMove(chess.NewGame(opt).Position(), uci) != nil
+}
+
+// PlayMoves plays the UCI moves from fen and returns the FEN of the
+// resulting position, failing at the first move that is not legal.
+func PlayMoves(fen string, moves []string) (string, error) {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return "", err
+	}
+	pos := chess.NewGame(opt).Position()
+	for i, uci := range moves {
+		move := findLegalMove(pos, uci)
+		if move == nil {
+			return "", fmt.Errorf("move %d (%s) is not legal", i+1, uci)
+		}
+		pos = pos.Update(move)
+	}
+	return pos.String(), nil
+}
+
+// GivesCheckmate reports whether playing the UCI move in fen mates.
+func GivesCheckmate(fen, uci string) bool {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return false
+	}
+	pos := chess.NewGame(opt).Position()
+	move := findLegalMove(pos, uci)
+	return move != nil && pos.Update(move).Status() == chess.Checkmate
+}
+
+// findLegalMove returns the legal move in pos matching the UCI string, or nil.
+func findLegalMove(pos *chess.Position, uci string) *chess.Move {
+	for _, m := range pos.ValidMoves() {
+		if MoveToUCI(&m) == uci {
+			return &m
+		}
+	}
+	return nil
+}
+
+// NormalizeFEN strips the halfmove clock and fullmove number from fen, and
+// the en passant square when no pawn could capture there, so the same
+// position reached by different move orders (or at different move numbers)
+// has the same key.
+func NormalizeFEN(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+	if len(fields) == 4 && fields[3] != "-" && !canCaptureEnPassant(fields[0], fields[3]) {
+		fields[3] = "-"
+	}
+	return strings.Join(fields, " ")
+}
+
+// canCaptureEnPassant reports whether a pawn stands beside the pawn that just
+// made a double step to ep, ready to capture it.
+func canCaptureEnPassant(placement, ep string) bool {
+	if len(ep) != 2 {
+		return false
+	}
+	file := int(ep[0] - 'a')
+	var rank int
+	var pawn rune
+	switch ep[1] {
+	case '6': // black pushed, white captures from the fifth rank
+		rank, pawn = 5, 'P'
+	case '3':
+		rank, pawn = 4, 'p'
+	default:
+		return false
+	}
+
+	rows := strings.Split(placement, "/")
+	if len(rows) != 8 {
+		return false
+	}
+	var squares []rune
+	for _, c := range rows[8-rank] {
+		if c >= '1' && c <= '8' {
+			for i := 0; i < int(c-'0'); i++ {
+				squares = append(squares, ' ')
+			}
+			continue
+		}
+		squares = append(squares, c)
+	}
+	for _, f := range []int{file - 1, file + 1} {
+		if f >= 0 && f < len(squares) && squares[f] == pawn {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+func TestUCIToSAN(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		moves    []string
+		expected []string
+	}{
+		{
+			name:     "opening line from start position",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			moves:    []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"},
+			expected: []string{"e4", "e5", "Nf3", "Nc6", "Bb5"},
+		},
+		{
+			name:     "black to move with capture and check",
+			fen:      "rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR b KQkq - 1 3",
+			moves:    []string{"d5e4", "f3f7"},
+			expected: []string{"dxe4", "Qxf7+"},
+		},
+		{
+			name:     "castling and promotion",
+			fen:      "7k/P7/8/8/8/8/8/4K2R w K - 0 1",
+			moves:    []string{"e1g1", "h8g7", "a7a8q"},
+			expected: []string{"O-O", "Kg7", "a8=Q"},
+		},
+		{
+			name:     "stops at first illegal move",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			moves:    []string{"e2e4", "e2e4", "g1f3"},
+			expected: []string{"e4"},
+		},
+		{
+			name:     "empty line",
+			fen:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			moves:    nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analysis.UCIToSAN(tt.fen, tt.moves)
+			if tt.expected == nil {
+				assert.Empty(t, result)
+				return
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNormalizeFEN(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		expected string
+	}{
+		{
+			name:     "drops move counters",
+			fen:      "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 6 9",
+			expected: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq -",
+		},
+		{
+			name:     "drops en passant square nobody can capture on",
+			fen:      "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			expected: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+		},
+		{
+			name:     "keeps en passant square with a pawn beside",
+			fen:      "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			expected: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6",
+		},
+		{
+			name:     "already normalized",
+			fen:      "  8/8/8/8/8/8/8/K1k5 w - -  ",
+			expected: "8/8/8/8/8/8/8/K1k5 w - -",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, analysis.NormalizeFEN(tt.fen))
+		})
+	}
+}
@@ -2,74 +2,182 @@ package analysis
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/vytor/chessflash/internal/logger"
 )
 
-// EnginePool manages a pool of reusable Stockfish engines.
+// EnginePool manages pools of reusable UCI engines, one per engine profile.
+// Engines that die or fail a search are replaced with fresh processes.
 type EnginePool struct {
-	path    string
-	size    int
-	engines chan *Engine
-	mu      sync.Mutex
-	closed  bool
-	log     *logger.Logger
+	groups      map[string]*engineGroup
+	names       []string // in configuration order
+	defaultName string
+	mu          sync.Mutex
+	closed      bool
+	cache       *EvalCache
+	log         *logger.Logger
 }
 
-// NewEnginePool creates a pool with the specified number of engines.
-func NewEnginePool(path string, size int) (*EnginePool, error) {
-	if size <= 0 {
-		size = 2
+// engineGroup holds the running processes of one engine profile.
+type engineGroup struct {
+	config   EngineConfig
+	engines  chan *Engine
+	refillMu sync.Mutex
+	restarts atomic.Int64
+	missing  atomic.Int64 // engines that failed to restart, retried on Acquire
+}
+
+// PoolStats describes the health of an engine pool.
+type PoolStats struct {
+	Size        int                  `json:"size"`
+	Available   int                  `json:"available"`
+	Unavailable int                  `json:"unavailable"` // engines that could not be restarted yet
+	Restarts    int64                `json:"restarts"`
+	Engines     map[string]PoolStats `json:"engines,omitempty"` // per profile, in the pool totals only
+}
+
+// NewEnginePool starts Instances processes of every configured engine.
+// defaultEngine names the engine used when none is requested; empty means
+// the first one.
+func NewEnginePool(configs []EngineConfig, defaultEngine string) (*EnginePool, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no engines configured")
 	}
-	log := logger.Default().WithPrefix("stockfish-pool")
+	log := logger.Default().WithPrefix("engine-pool")
 
 	pool := &EnginePool{
-		path:    path,
-		size:    size,
-		engines: make(chan *Engine, size),
-		log:     log,
+		groups:      make(map[string]*engineGroup, len(configs)),
+		defaultName: defaultEngine,
+		log:         log,
+	}
+	if pool.defaultName == "" {
+		pool.defaultName = configs[0].Name
 	}
 
-	// Pre-warm the pool
-	log.Info("initializing engine pool with %d engines", size)
-	for i := 0; i < size; i++ {
-		engine, err := NewEngine(path)
-		if err != nil {
-			pool.Close() // Clean up any already-created engines
-			return nil, err
+	for _, cfg := range configs {
+		if cfg.Instances <= 0 {
+			cfg.Instances = 2
+		}
+		if _, ok := pool.groups[cfg.Name]; ok {
+			pool.Close()
+			return nil, fmt.Errorf("engine %q configured twice", cfg.Name)
+		}
+		group := &engineGroup{config: cfg, engines: make(chan *Engine, cfg.Instances)}
+		pool.groups[cfg.Name] = group
+		pool.names = append(pool.names, cfg.Name)
+
+		// Pre-warm the pool
+		log.Info("starting %d %s engines", cfg.Instances, cfg.Name)
+		for i := 0; i < cfg.Instances; i++ {
+			engine, err := NewEngine(cfg)
+			if err != nil {
+				pool.Close() // Clean up any already-created engines
+				return nil, fmt.Errorf("engine %q: %w", cfg.Name, err)
+			}
+			group.engines <- engine
 		}
-		pool.engines <- engine
 	}
+	if _, ok := pool.groups[pool.defaultName]; !ok {
+		pool.Close()
+		return nil, fmt.Errorf("default engine %q is not configured", pool.defaultName)
+	}
+
 	log.Info("engine pool ready")
 	return pool, nil
 }
 
-// Acquire gets an engine from the pool, blocking if none are available.
+// Engines returns the configured engine names, in configuration order.
+func (p *EnginePool) Engines() []string {
+	return append([]string(nil), p.names...)
+}
+
+// DefaultEngine returns the name of the engine used when none is requested.
+func (p *EnginePool) DefaultEngine() string {
+	return p.defaultName
+}
+
+// Config returns the configuration of the named engine (the default when
+// name is empty).
+func (p *EnginePool) Config(name string) (EngineConfig, bool) {
+	group, err := p.group(name)
+	if err != nil {
+		return EngineConfig{}, false
+	}
+	return group.config, true
+}
+
+func (p *EnginePool) group(name string) (*engineGroup, error) {
+	if name == "" {
+		name = p.defaultName
+	}
+	group, ok := p.groups[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q", name)
+	}
+	return group, nil
+}
+
+// Acquire gets a default engine from the pool, blocking if none are available.
 func (p *EnginePool) Acquire(ctx context.Context) (*Engine, error) {
+	return p.AcquireEngine(ctx, "")
+}
+
+// AcquireEngine gets an engine of the named profile (the default when name
+// is empty), blocking if none are available. An engine whose process died
+// while idle is replaced first.
+func (p *EnginePool) AcquireEngine(ctx context.Context, name string) (*Engine, error) {
+	group, err := p.group(name)
+	if err != nil {
+		return nil, err
+	}
+	p.refill(group)
+
 	select {
-	case engine := <-p.engines:
+	case engine, ok := <-group.engines:
+		if !ok {
+			return nil, errors.New("engine pool closed")
+		}
+		if !engine.Healthy() {
+			if engine = p.restart(group, engine); engine == nil {
+				return nil, fmt.Errorf("engine %q unavailable", group.config.Name)
+			}
+		}
 		return engine, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
-// Release returns an engine to the pool.
+// Release returns an engine to the pool, replacing it when it is no longer
+// healthy so the next search does not inherit a crashed or out of sync process.
 func (p *EnginePool) Release(engine *Engine) {
 	if engine == nil {
 		return
 	}
+	group, ok := p.groups[engine.Name()]
+	if !ok {
+		engine.Close()
+		return
+	}
+	if !engine.Healthy() && !p.isClosed() {
+		if engine = p.restart(group, engine); engine == nil {
+			return
+		}
+	}
+
 	p.mu.Lock()
-	closed := p.closed
-	p.mu.Unlock()
-	if closed {
+	defer p.mu.Unlock()
+	if p.closed {
 		// Pool is closed, close the engine
 		engine.Close()
 		return
 	}
 	select {
-	case p.engines <- engine:
+	case group.engines <- engine:
 		// Returned to pool
 	default:
 		// Pool full, close the engine
@@ -77,15 +185,148 @@ func (p *EnginePool) Release(engine *Engine) {
 	}
 }
 
-// Evaluate acquires an engine, evaluates, and releases it back.
+func (p *EnginePool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// restart closes a broken engine and starts a new one in its place. On
+// failure the slot is left for refill and nil is returned.
+func (p *EnginePool) restart(group *engineGroup, old *Engine) *Engine {
+	p.log.Warn("replacing unhealthy %s engine", group.config.Name)
+	old.Close()
+
+	engine, err := NewEngine(group.config)
+	if err != nil {
+		p.log.Error("failed to restart %s engine: %v", group.config.Name, err)
+		group.missing.Add(1)
+		return nil
+	}
+	group.restarts.Add(1)
+	return engine
+}
+
+// refill retries starting engines that failed to restart earlier.
+func (p *EnginePool) refill(group *engineGroup) {
+	if group.missing.Load() == 0 {
+		return
+	}
+	group.refillMu.Lock()
+	defer group.refillMu.Unlock()
+
+	for group.missing.Load() > 0 && !p.isClosed() {
+		engine, err := NewEngine(group.config)
+		if err != nil {
+			p.log.Error("failed to restart %s engine: %v", group.config.Name, err)
+			return
+		}
+		group.missing.Add(-1)
+		group.restarts.Add(1)
+		p.Release(engine)
+	}
+}
+
+// Stats returns the pool size, idle engines and restart count, in total and
+// per engine.
+func (p *EnginePool) Stats() PoolStats {
+	total := PoolStats{Engines: make(map[string]PoolStats, len(p.groups))}
+	for name, group := range p.groups {
+		stats := PoolStats{
+			Size:        group.config.Instances,
+			Available:   len(group.engines),
+			Unavailable: int(group.missing.Load()),
+			Restarts:    group.restarts.Load(),
+		}
+		total.Engines[name] = stats
+		total.Size += stats.Size
+		total.Available += stats.Available
+		total.Unavailable += stats.Unavailable
+		total.Restarts += stats.Restarts
+	}
+	return total
+}
+
+// SetCache makes the pool consult cache before searching. Call it before
+// the pool is used.
+func (p *EnginePool) SetCache(cache *EvalCache) {
+	p.cache = cache
+}
+
+// CacheStats returns the evaluation cache hit rate (zero without a cache).
+func (p *EnginePool) CacheStats() EvalCacheStats {
+	return p.cache.Stats()
+}
+
+// Evaluate acquires a default engine, evaluates, and releases it back.
 func (p *EnginePool) Evaluate(ctx context.Context, fen string, depth int, maxTimeMs int) (EvalResult, error) {
+	return p.EvaluateMultiPV(ctx, fen, depth, maxTimeMs, 1)
+}
+
+// EvaluateMultiPV acquires a default engine, evaluates the top multiPV lines, and releases it back.
+// Cached evaluations are returned without waiting for an engine.
+func (p *EnginePool) EvaluateMultiPV(ctx context.Context, fen string, depth int, maxTimeMs int, multiPV int) (EvalResult, error) {
+	if result, ok := p.cache.Get(ctx, p.defaultName, fen, depth, multiPV); ok {
+		return result, nil
+	}
+
 	engine, err := p.Acquire(ctx)
 	if err != nil {
 		return EvalResult{}, err
 	}
 	defer p.Release(engine)
 
-	return engine.EvaluateFEN(ctx, fen, depth, maxTimeMs)
+	return p.search(ctx, engine, fen, depth, maxTimeMs, multiPV)
+}
+
+// EvaluateStream evaluates the best line on a default engine, calling onInfo
+// as the search deepens. A cached evaluation is returned without any updates.
+func (p *EnginePool) EvaluateStream(ctx context.Context, fen string, depth int, maxTimeMs int, onInfo func(SearchInfo)) (EvalResult, error) {
+	if result, ok := p.cache.Get(ctx, p.defaultName, fen, depth, 1); ok {
+		return result, nil
+	}
+
+	engine, err := p.Acquire(ctx)
+	if err != nil {
+		return EvalResult{}, err
+	}
+	defer p.Release(engine)
+
+	result, err := engine.EvaluateFENStream(ctx, fen, depth, maxTimeMs, onInfo)
+	if err != nil {
+		return EvalResult{}, err
+	}
+	p.cacheResult(ctx, engine, fen, depth, 1, result)
+	return result, nil
+}
+
+// EvaluateWith evaluates on an engine already acquired from the pool,
+// consulting the cache first.
+func (p *EnginePool) EvaluateWith(ctx context.Context, engine *Engine, fen string, depth int, maxTimeMs int, multiPV int) (EvalResult, error) {
+	if result, ok := p.cache.Get(ctx, engine.Name(), fen, depth, multiPV); ok {
+		return result, nil
+	}
+	return p.search(ctx, engine, fen, depth, maxTimeMs, multiPV)
+}
+
+// search runs the engine and caches a successful result.
+func (p *EnginePool) search(ctx context.Context, engine *Engine, fen string, depth int, maxTimeMs int, multiPV int) (EvalResult, error) {
+	result, err := engine.EvaluateFENMultiPV(ctx, fen, depth, maxTimeMs, multiPV)
+	if err != nil {
+		return EvalResult{}, err
+	}
+	p.cacheResult(ctx, engine, fen, depth, multiPV, result)
+	return result, nil
+}
+
+// cacheResult stores a search that reached the requested depth. One cut short
+// by movetime would otherwise answer later lookups at that depth.
+func (p *EnginePool) cacheResult(ctx context.Context, engine *Engine, fen string, depth int, multiPV int, result EvalResult) {
+	if result.Depth < depth {
+		p.log.Debug("not caching evaluation that reached depth %d of %d", result.Depth, depth)
+		return
+	}
+	p.cache.Put(ctx, engine.Name(), fen, depth, multiPV, result)
 }
 
 // Close shuts down all engines in the pool.
@@ -99,13 +340,15 @@ func (p *EnginePool) Close() {
 	p.closed = true
 
 	p.log.Info("closing engine pool")
-	close(p.engines)
-	for engine := range p.engines {
-		engine.Close()
+	for _, group := range p.groups {
+		close(group.engines)
+		for engine := range group.engines {
+			engine.Close()
+		}
 	}
 }
 
 // Available returns how many engines are currently idle.
 func (p *EnginePool) Available() int {
-	return len(p.engines)
+	return p.Stats().Available
 }
@@ -0,0 +1,242 @@
+package analysis_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+// fakeEngine is a minimal UCI engine: it crashes when searching the "crash"
+// position, hangs on "hang", searches "slow" until told to stop, and
+// otherwise answers e2e4 at once.
+const fakeEngine = `#!/bin/sh
+mode=""
+while read -r line; do
+  case "$line" in
+    uci)
+      echo "option name Threads type spin default 1 min 1 max 512"
+      echo "option name Skill Level type spin default 20 min 0 max 20"
+      echo "option name UCI_LimitStrength type check default false"
+      echo "uciok"
+      ;;
+    isready) echo "readyok" ;;
+    "position fen crash"*) mode=crash ;;
+    "position fen hang"*) mode=hang ;;
+    "position fen slow"*) mode=slow ;;
+    position*) mode="" ;;
+    go*)
+      if [ "$mode" = crash ]; then exit 1; fi
+      if [ "$mode" = hang ]; then sleep 30; fi
+      if [ "$mode" = slow ]; then continue; fi
+      echo "info depth 1 multipv 1 score cp 15 pv d2d4"
+      echo "info depth 2 seldepth 3 multipv 1 score cp 20 nodes 120 pv e2e4 e7e5"
+      echo "bestmove e2e4"
+      ;;
+    stop) if [ "$mode" = slow ]; then echo "bestmove e2e4"; fi ;;
+    quit) exit 0 ;;
+  esac
+done
+`
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func writeFakeEngine(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-stockfish")
+	require.NoError(t, os.WriteFile(path, []byte(fakeEngine), 0o755))
+	return path
+}
+
+func newFakePool(t *testing.T) *analysis.EnginePool {
+	t.Helper()
+	pool, err := analysis.NewEnginePool([]analysis.EngineConfig{{Name: "fake", Path: writeFakeEngine(t), Instances: 1}}, "")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestEnginePool_ReplacesCrashedEngine(t *testing.T) {
+	pool := newFakePool(t)
+	ctx := context.Background()
+
+	_, err := pool.Evaluate(ctx, "crash w - - 0 1", 10, 0)
+	require.Error(t, err)
+
+	result, err := pool.Evaluate(ctx, startFEN, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.BestMove)
+	assert.InDelta(t, 20.0, result.CP, 0.001)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats.Restarts)
+	assert.Equal(t, 1, stats.Available)
+	assert.Zero(t, stats.Unavailable)
+}
+
+func TestEnginePool_ReplacesTimedOutEngine(t *testing.T) {
+	pool := newFakePool(t)
+	ctx := context.Background()
+
+	// The hung search is killed after movetime plus the grace period
+	_, err := pool.Evaluate(ctx, "hang w - - 0 1", 10, 100)
+	require.Error(t, err)
+
+	engine, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, engine.Healthy())
+	result, err := engine.EvaluateFEN(ctx, startFEN, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.BestMove)
+	pool.Release(engine)
+
+	assert.Equal(t, int64(1), pool.Stats().Restarts)
+}
+
+func TestEngine_CancelStopsSearch(t *testing.T) {
+	pool := newFakePool(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	engine, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = engine.EvaluateFEN(ctx, "slow w - - 0 1", 10, 0)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, engine.Healthy())
+
+	// The stopped search's bestmove was read, so the next one is in sync
+	result, err := engine.EvaluateFEN(context.Background(), startFEN, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.BestMove)
+	assert.InDelta(t, 20.0, result.CP, 0.001)
+	pool.Release(engine)
+	assert.Zero(t, pool.Stats().Restarts)
+}
+
+// An engine that does not answer stop is killed and replaced
+func TestEnginePool_ReplacesEngineAfterCancel(t *testing.T) {
+	pool := newFakePool(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	engine, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = engine.EvaluateFEN(ctx, "hang w - - 0 1", 10, 0)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.False(t, engine.Healthy())
+	pool.Release(engine)
+
+	result, err := pool.Evaluate(context.Background(), startFEN, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.BestMove)
+	assert.Equal(t, int64(1), pool.Stats().Restarts)
+}
+
+func TestNewEngine_Options(t *testing.T) {
+	path := writeFakeEngine(t)
+
+	engine, err := analysis.NewEngine(analysis.EngineConfig{
+		Path:    path,
+		Options: map[string]string{"threads": "4", "Skill Level": "5", "UCI_LimitStrength": "true"},
+	})
+	require.NoError(t, err)
+	defer engine.Close()
+	assert.Equal(t, "stockfish", engine.Name())
+
+	for _, options := range []map[string]string{
+		{"Hash": "256"},                    // not advertised
+		{"Skill Level": "21"},              // out of range
+		{"Threads": "many"},                // not a number
+		{"UCI_LimitStrength": "sometimes"}, // not a boolean
+	} {
+		_, err := analysis.NewEngine(analysis.EngineConfig{Path: path, Options: options})
+		assert.Error(t, err, "options %v", options)
+	}
+}
+
+func TestEnginePool_NamedEngines(t *testing.T) {
+	path := writeFakeEngine(t)
+	pool, err := analysis.NewEnginePool([]analysis.EngineConfig{
+		{Name: "stockfish", Path: path, Instances: 1},
+		{Name: "weak", Path: path, Options: map[string]string{"Skill Level": "0"}, Depth: 8, Instances: 2},
+	}, "weak")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, []string{"stockfish", "weak"}, pool.Engines())
+	assert.Equal(t, "weak", pool.DefaultEngine())
+	cfg, ok := pool.Config("")
+	require.True(t, ok)
+	assert.Equal(t, 8, cfg.Depth)
+
+	engine, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "weak", engine.Name())
+	pool.Release(engine)
+
+	engine, err = pool.AcquireEngine(context.Background(), "stockfish")
+	require.NoError(t, err)
+	assert.Equal(t, "stockfish", engine.Name())
+	assert.Equal(t, 2, pool.Stats().Engines["weak"].Available)
+	assert.Zero(t, pool.Stats().Engines["stockfish"].Available)
+	pool.Release(engine)
+
+	_, err = pool.AcquireEngine(context.Background(), "lc0")
+	assert.Error(t, err)
+
+	stats := pool.Stats()
+	assert.Equal(t, 3, stats.Size)
+	assert.Equal(t, 3, stats.Available)
+}
+
+func TestNewEnginePool_UnknownDefault(t *testing.T) {
+	_, err := analysis.NewEnginePool([]analysis.EngineConfig{{Name: "fake", Path: writeFakeEngine(t), Instances: 1}}, "lc0")
+	assert.Error(t, err)
+}
+
+func TestEnginePool_EvaluateStream(t *testing.T) {
+	pool := newFakePool(t)
+
+	var infos []analysis.SearchInfo
+	result, err := pool.EvaluateStream(context.Background(), startFEN, 2, 0, func(info analysis.SearchInfo) {
+		infos = append(infos, info)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.BestMove)
+
+	require.Len(t, infos, 2)
+	assert.Equal(t, analysis.SearchInfo{Depth: 1, CP: 15, PV: []string{"d2d4"}}, infos[0])
+	assert.Equal(t, analysis.SearchInfo{Depth: 2, CP: 20, PV: []string{"e2e4", "e7e5"}}, infos[1])
+}
+
+func TestEnginePool_CachesOnlyFullDepthSearches(t *testing.T) {
+	pool := newFakePool(t)
+	store := &memoryStore{data: map[string][]byte{}}
+	pool.SetCache(analysis.NewEvalCache(store, 0))
+
+	// The fake engine stops at depth 2, as if movetime had cut the search short
+	result, err := pool.Evaluate(context.Background(), startFEN, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Depth)
+	assert.Empty(t, store.data)
+
+	_, err = pool.Evaluate(context.Background(), startFEN, 2, 0)
+	require.NoError(t, err)
+	assert.Len(t, store.data, 1)
+}
+
+func TestEngine_RejectsCommandsInFEN(t *testing.T) {
+	pool := newFakePool(t)
+
+	_, err := pool.Evaluate(context.Background(), startFEN+"\nquit", 10, 0)
+	require.Error(t, err)
+
+	_, err = pool.Evaluate(context.Background(), startFEN, 2, 0)
+	require.NoError(t, err)
+	assert.Zero(t, pool.Stats().Restarts)
+}
@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"github.com/corentings/chess/v2"
+)
+
+// SEE returns the static exchange evaluation of move in pos: the material,
+// in pawns, the mover ends up with when both sides keep capturing on the
+// destination square with their least valuable piece, each free to stop when
+// going on would lose material. A quiet move onto a square the opponent wins
+// scores below zero. Pins and checks are ignored, as in any static exchange.
+func SEE(pos *chess.Position, move *chess.Move) int {
+	board := pos.Board()
+	sq := move.S2()
+	pieces := board.SquareMap()
+
+	moved := pieces[move.S1()]
+	gain := []int{0}
+	if captured, ok := pieces[sq]; ok {
+		gain[0] = value(captured.Type())
+	}
+	if move.HasTag(chess.EnPassant) {
+		gain[0] = value(chess.Pawn)
+		behind, _ := square(int(sq.File()), int(move.S1().Rank()))
+		delete(pieces, behind)
+	}
+	if promo := move.Promo(); promo != chess.NoPieceType {
+		gain[0] += value(promo) - value(chess.Pawn)
+		moved = chess.NewPiece(promo, moved.Color())
+	}
+	delete(pieces, move.S1())
+	pieces[sq] = moved
+
+	// Play out the exchange, recording what each capture would win if the
+	// other side stopped there
+	side := moved.Color().Other()
+	for {
+		from, ok := leastValuableAttacker(chess.NewBoard(pieces), sq, side)
+		if !ok {
+			break
+		}
+		gain = append(gain, value(pieces[sq].Type())-gain[len(gain)-1])
+		pieces[sq] = pieces[from]
+		delete(pieces, from)
+		side = side.Other()
+	}
+
+	// Either side stops capturing when it would come out worse
+	for i := len(gain) - 1; i > 0; i-- {
+		gain[i-1] = -max(-gain[i-1], gain[i])
+	}
+	return gain[0]
+}
+
+// MaterialHung returns how much material, in pawns, playing moveUCI in fen
+// leaves en prise beyond what the position forced: the most the opponent can
+// win by an exchange afterwards, less the least any legal move would have
+// left. Unknown moves and checkmates hang nothing.
+func MaterialHung(fen, moveUCI string) int {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return 0
+	}
+	pos := chess.NewGame(opt).Position()
+	move := findLegalMove(pos, moveUCI)
+	if move == nil {
+		return 0
+	}
+
+	hung := exposedMaterial(pos, move)
+	if hung == 0 {
+		return 0
+	}
+	least := hung
+	for _, m := range pos.ValidMoves() {
+		least = min(least, exposedMaterial(pos, &m))
+		if least == 0 {
+			break
+		}
+	}
+	return hung - least
+}
+
+// exposedMaterial returns what the opponent can win by an exchange after
+// move: what the move loses in the exchange it starts on its destination
+// square, or the best the opponent's captures elsewhere win, whichever is
+// more.
+func exposedMaterial(pos *chess.Position, move *chess.Move) int {
+	after := pos.Update(move)
+	replies := after.ValidMoves()
+	if len(replies) == 0 {
+		return 0
+	}
+
+	lost := max(0, -SEE(pos, move))
+	for _, reply := range replies {
+		if reply.S2() == move.S2() || !reply.HasTag(chess.Capture) {
+			continue
+		}
+		lost = max(lost, SEE(after, &reply))
+	}
+	return lost
+}
+
+// leastValuableAttacker returns the square of the color's cheapest piece
+// attacking sq.
+func leastValuableAttacker(board *chess.Board, sq chess.Square, color chess.Color) (chess.Square, bool) {
+	best, found := chess.NoSquare, false
+	for _, from := range attackers(board, sq, color) {
+		if !found || value(board.Piece(from).Type()) < value(board.Piece(best).Type()) {
+			best, found = from, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,118 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected int
+	}{
+		{
+			name:     "rook takes undefended knight",
+			fen:      "4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1",
+			move:     "d1d5",
+			expected: 3,
+		},
+		{
+			name:     "rook takes knight defended by pawn",
+			fen:      "4k3/8/4p3/3n4/8/8/8/3RK3 w - - 0 1",
+			move:     "d1d5",
+			expected: -2,
+		},
+		{
+			name:     "rook behind rook joins the exchange",
+			fen:      "3rk3/8/8/3n4/8/8/3R4/3RK3 w - - 0 1",
+			move:     "d2d5",
+			expected: 3,
+		},
+		{
+			name:     "quiet move onto a square a pawn guards",
+			fen:      "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1",
+			move:     "f3e5",
+			expected: -3,
+		},
+		{
+			name:     "quiet move onto a safe square",
+			fen:      "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1",
+			move:     "f3g5",
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt, err := chess.FEN(tt.fen)
+			require.NoError(t, err)
+			pos := chess.NewGame(opt).Position()
+			var move *chess.Move
+			for _, m := range pos.ValidMoves() {
+				if m.String() == tt.move {
+					move = &m
+				}
+			}
+			require.NotNil(t, move)
+			assert.Equal(t, tt.expected, analysis.SEE(pos, move))
+		})
+	}
+}
+
+func TestMaterialHung(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected int
+	}{
+		{
+			name:     "knight moves where a pawn takes it",
+			fen:      "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1",
+			move:     "f3e5",
+			expected: 3,
+		},
+		{
+			name:     "knight moves to a safe square",
+			fen:      "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1",
+			move:     "f3g5",
+			expected: 0,
+		},
+		{
+			name:     "queen takes a defended pawn",
+			fen:      "4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1",
+			move:     "d1d5",
+			expected: 8,
+		},
+		{
+			name:     "defender walks away from a rook",
+			fen:      "r5k1/8/8/8/8/1N6/8/R5K1 w - - 0 1",
+			move:     "b3c5",
+			expected: 5,
+		},
+		{
+			name:     "rook lost to a fork whatever the king does",
+			fen:      "4k3/8/8/8/8/8/2n5/R3K3 w - - 0 1",
+			move:     "e1e2",
+			expected: 0,
+		},
+		{
+			name:     "illegal move",
+			fen:      "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1",
+			move:     "f3f5",
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, analysis.MaterialHung(tt.fen, tt.move))
+		})
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vytor/chessflash/internal/logger"
@@ -16,32 +19,81 @@ import (
 
 type EvalResult struct {
 	BestMove string
-	CP       float64 // centipawns from white perspective (only when Mate is nil)
-	Mate     *int    // mate in N (positive = white mates in N, negative = black mates in N)
+	CP       float64  // centipawns from white perspective (only when Mate is nil)
+	Mate     *int     // mate in N (positive = white mates in N, negative = black mates in N)
+	PV       []string // principal variation of the best line in UCI notation
+	Lines    []Line   // top N principal variations ordered by rank (only populated in MultiPV mode)
+	Depth    int      // depth the search reached, below the requested one when movetime cut it short
+}
+
+// Line is a single principal variation reported by the engine in MultiPV mode.
+// Scores are normalized to white's perspective, like EvalResult.
+type Line struct {
+	Rank int
+	Move string
+	CP   float64
+	Mate *int
+	PV   []string
+}
+
+// SearchInfo is an intermediate result of a search in progress: the best
+// line found at Depth, scored from white's perspective.
+type SearchInfo struct {
+	Depth int
+	CP    float64
+	Mate  *int
+	PV    []string
 }
 
 type Engine struct {
+	name string
 	path string
 	log  *logger.Logger
 
-	mu     sync.Mutex
-	cmd    *exec.Cmd
-	stdin  ioWriter
-	stdout *bufio.Reader
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	process *os.Process
+	stdin   ioWriter
+	stdout  *bufio.Reader
+	broken  bool // a search failed, so the output may be out of sync
+
+	exited  chan struct{} // closed once the process is gone
+	waitErr error         // exit status, set before exited is closed
 }
 
+// stopGrace is how long a cancelled search has to answer stop before the
+// engine is killed.
+const stopGrace = 2 * time.Second
+
 type ioWriter interface {
 	Write([]byte) (int, error)
 }
 
-func NewEngine(path string) (*Engine, error) {
-	log := logger.Default().WithPrefix("stockfish")
+// EngineConfig describes a named UCI engine: how to start it, which options
+// to set on it and the search limits analysis uses with it.
+type EngineConfig struct {
+	Name      string
+	Path      string
+	Options   map[string]string // applied with setoption once the engine confirms it supports them
+	Depth     int
+	MaxTimeMs int
+	Instances int // processes an EnginePool keeps running
+}
 
+// NewEngine starts the engine and sets its options. An option the engine does
+// not advertise, or a value outside what it accepts, fails the start.
+func NewEngine(cfg EngineConfig) (*Engine, error) {
+	path := cfg.Path
 	if path == "" {
 		path = "stockfish"
 	}
+	name := cfg.Name
+	if name == "" {
+		name = "stockfish"
+	}
+	log := logger.Default().WithPrefix(name)
 
-	log.Info("starting stockfish engine: %s", path)
+	log.Info("starting uci engine: %s", path)
 	cmd := exec.Command(path)
 
 	stdin, err := cmd.StdinPipe()
@@ -56,39 +108,83 @@ func NewEngine(path string) (*Engine, error) {
 	}
 
 	engine := &Engine{
+		name:   name,
 		path:   path,
 		log:    log,
 		cmd:    cmd,
 		stdin:  stdin,
 		stdout: bufio.NewReader(stdout),
+		exited: make(chan struct{}),
 	}
 
 	if err := cmd.Start(); err != nil {
-		log.Error("failed to start stockfish: %v", err)
+		log.Error("failed to start engine: %v", err)
 		return nil, err
 	}
+	engine.process = cmd.Process
+	go func() {
+		engine.waitErr = cmd.Wait()
+		close(engine.exited)
+	}()
 
 	log.Debug("initializing UCI protocol")
-	if err := engine.init(); err != nil {
+	if err := engine.init(cfg.Options); err != nil {
 		log.Error("failed to initialize UCI: %v", err)
+		engine.kill()
+		<-engine.exited
 		return nil, err
 	}
 
-	log.Info("stockfish engine ready")
+	log.Info("uci engine ready")
 	return engine, nil
 }
 
-func (e *Engine) init() error {
+func (e *Engine) init(options map[string]string) error {
 	if err := e.send("uci"); err != nil {
 		return err
 	}
-	if err := e.waitFor("uciok", 2*time.Second); err != nil {
+
+	supported := map[string]UCIOption{}
+	err := e.readUntil("uciok", 2*time.Second, func(line string) {
+		if opt, ok := parseOptionLine(line); ok {
+			supported[strings.ToLower(opt.Name)] = opt
+		}
+	})
+	if err != nil {
 		return err
 	}
+
+	// Sorted so engines see the options in a stable order
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opt, ok := supported[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("engine does not support option %q", name)
+		}
+		cmd, err := opt.setCommand(options[name])
+		if err != nil {
+			return err
+		}
+		e.log.Debug("setting option: %s", cmd)
+		if err := e.send(cmd); err != nil {
+			return err
+		}
+	}
+
 	if err := e.send("isready"); err != nil {
 		return err
 	}
-	return e.waitFor("readyok", 2*time.Second)
+	// Options such as Hash or a network file can take a while to apply
+	return e.waitFor("readyok", 10*time.Second)
+}
+
+// Name returns the engine profile name.
+func (e *Engine) Name() string {
+	return e.name
 }
 
 func (e *Engine) Close() error {
@@ -101,7 +197,14 @@ func (e *Engine) Close() error {
 
 	e.log.Debug("closing stockfish engine")
 	_ = e.sendLocked("quit")
-	err := e.cmd.Wait()
+	select {
+	case <-e.exited:
+	case <-time.After(2 * time.Second):
+		e.log.Warn("stockfish did not quit, killing it")
+		e.kill()
+		<-e.exited
+	}
+	err := e.waitErr
 	e.cmd = nil
 
 	if err != nil {
@@ -113,22 +216,84 @@ func (e *Engine) Close() error {
 	return err
 }
 
+// Healthy reports whether the engine can take another search: its process is
+// running and no earlier search was interrupted, which could leave a stray
+// bestmove in the output.
+func (e *Engine) Healthy() bool {
+	select {
+	case <-e.exited:
+		return false
+	default:
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cmd != nil && !e.broken
+}
+
+// kill stops the process immediately, unblocking any pending read.
+func (e *Engine) kill() {
+	if e.process != nil {
+		_ = e.process.Kill()
+	}
+}
+
 func (e *Engine) EvaluateFEN(ctx context.Context, fen string, depth int, maxTimeMs int) (EvalResult, error) {
+	return e.EvaluateFENMultiPV(ctx, fen, depth, maxTimeMs, 1)
+}
+
+// EvaluateFENMultiPV evaluates a position reporting the top multiPV lines.
+// BestMove, CP and Mate always describe the first (best) line.
+// A failed search marks the engine as broken; see Healthy.
+func (e *Engine) EvaluateFENMultiPV(ctx context.Context, fen string, depth int, maxTimeMs int, multiPV int) (EvalResult, error) {
+	return e.evaluate(ctx, fen, depth, maxTimeMs, multiPV, nil)
+}
+
+// EvaluateFENStream searches the best line like EvaluateFEN and calls onInfo
+// with every score the engine reports while it deepens the search.
+func (e *Engine) EvaluateFENStream(ctx context.Context, fen string, depth int, maxTimeMs int, onInfo func(SearchInfo)) (EvalResult, error) {
+	return e.evaluate(ctx, fen, depth, maxTimeMs, 1, onInfo)
+}
+
+func (e *Engine) evaluate(ctx context.Context, fen string, depth int, maxTimeMs int, multiPV int, onInfo func(SearchInfo)) (_ EvalResult, err error) {
+	if strings.ContainsAny(fen, "\r\n") {
+		// A line break would let the rest of the FEN through as commands
+		return EvalResult{}, errors.New("fen contains a line break")
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	drained := false // a cancelled search was stopped and its bestmove read
+	defer func() {
+		if err != nil && !drained {
+			e.broken = true
+		}
+	}()
+
+	if e.cmd == nil {
+		return EvalResult{}, errors.New("stockfish engine closed")
+	}
 
 	log := e.log.WithFields(map[string]any{
-		"depth":      depth,
+		"depth":       depth,
 		"max_time_ms": maxTimeMs,
+		"multipv":     multiPV,
 	})
 
 	if depth == 0 {
 		depth = 18
 	}
+	if multiPV <= 0 {
+		multiPV = 1
+	}
 
 	start := time.Now()
 	log.Debug("evaluating position")
 
+	if err := e.sendLocked(fmt.Sprintf("setoption name MultiPV value %d", multiPV)); err != nil {
+		log.Error("failed to set MultiPV: %v", err)
+		return EvalResult{}, err
+	}
 	if err := e.sendLocked("ucinewgame"); err != nil {
 		log.Error("failed to send ucinewgame: %v", err)
 		return EvalResult{}, err
@@ -156,67 +321,170 @@ func (e *Engine) EvaluateFEN(ctx context.Context, fen string, depth int, maxTime
 	}
 
 	var best EvalResult
-	// Use maxTimeMs + buffer for deadline, or default 8s if no limit
-	deadlineDuration := 8 * time.Second
-	if maxTimeMs > 0 {
-		deadlineDuration = time.Duration(maxTimeMs)*time.Millisecond + 500*time.Millisecond // Add 500ms buffer
-	}
-	deadline := time.Now().Add(deadlineDuration)
+	lines := make(map[int]Line, multiPV)
+	deadlineDuration := searchDeadline(depth, maxTimeMs, multiPV)
+	// A hung engine never writes the line we are waiting for, so the process is
+	// killed on timeout to unblock the read
+	var timedOut atomic.Bool
+	watchdog := time.AfterFunc(deadlineDuration, func() {
+		timedOut.Store(true)
+		e.kill()
+	})
+	defer watchdog.Stop()
+	// Cancelling stops the search instead, and the bestmove the engine still
+	// answers with is read so the engine stays in sync for the next search.
+	// One that ignores stop is left to the watchdog, brought forward.
+	stopSent := make(chan struct{})
+	stopOnCancel := context.AfterFunc(ctx, func() {
+		defer close(stopSent)
+		watchdog.Reset(stopGrace)
+		_ = e.sendLocked("stop")
+	})
+	defer func() {
+		if !stopOnCancel() {
+			// The stop must reach the engine before the next search does
+			<-stopSent
+		}
+	}()
+
 	for {
-		if ctx.Err() != nil {
-			log.Warn("evaluation cancelled: %v", ctx.Err())
+		line, err := e.stdout.ReadString('\n')
+		if (err != nil || timedOut.Load()) && ctx.Err() != nil {
+			log.Warn("evaluation cancelled, engine did not stop: %v", ctx.Err())
 			return EvalResult{}, ctx.Err()
 		}
-		if time.Now().After(deadline) {
+		if timedOut.Load() {
 			log.Error("evaluation timed out after %v", deadlineDuration)
 			return EvalResult{}, errors.New("stockfish timeout")
 		}
-		line, err := e.stdout.ReadString('\n')
 		if err != nil {
 			log.Error("failed to read from stockfish: %v", err)
 			return EvalResult{}, err
 		}
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "info") {
-			if cp, mate, ok := parseScore(line); ok {
-				if mate != nil {
-					best.Mate = mate
-					// Normalize mate to white's perspective by sign:
-					// mate > 0 -> side to move mates; mate < 0 -> side to move gets mated
-					// If black to move, flip sign to white perspective.
-					mateVal := *mate
-					if isBlackToMove {
-						mateVal = -mateVal
+			if pvLine, ok := parseInfoLine(line, isBlackToMove); ok {
+				// Deeper iterations overwrite shallower ones for the same rank
+				lines[pvLine.Rank] = pvLine
+				if pvLine.Rank == 1 {
+					best.CP = pvLine.CP
+					best.Mate = pvLine.Mate
+					best.Depth = max(best.Depth, parseInfoDepth(line))
+					if len(pvLine.PV) > 0 {
+						best.PV = pvLine.PV
 					}
-					best.Mate = &mateVal
-					// For mate, CP is not meaningful; keep CP at 0
-					best.CP = 0
-				} else {
-					// Normalize centipawns to white's perspective
-					if isBlackToMove {
-						best.CP = -cp
-					} else {
-						best.CP = cp
+					if onInfo != nil && len(pvLine.PV) > 0 {
+						onInfo(SearchInfo{Depth: parseInfoDepth(line), CP: pvLine.CP, Mate: pvLine.Mate, PV: pvLine.PV})
 					}
-					best.Mate = nil
 				}
 			}
 		}
 		if strings.HasPrefix(line, "bestmove") {
+			if ctx.Err() != nil {
+				log.Debug("evaluation cancelled: %v", ctx.Err())
+				drained = true
+				return EvalResult{}, ctx.Err()
+			}
 			parts := strings.Fields(line)
 			if len(parts) >= 2 {
 				best.BestMove = parts[1]
 			}
+			if multiPV > 1 {
+				best.Lines = orderedLines(lines, multiPV)
+			}
 			if best.Mate != nil {
-				log.Debug("evaluation completed in %v: mate=%d, bestmove=%s", time.Since(start), *best.Mate, best.BestMove)
+				log.Debug("evaluation completed in %v: mate=%d, bestmove=%s, lines=%d", time.Since(start), *best.Mate, best.BestMove, len(best.Lines))
 			} else {
-				log.Debug("evaluation completed in %v: cp=%.0f, bestmove=%s", time.Since(start), best.CP, best.BestMove)
+				log.Debug("evaluation completed in %v: cp=%.0f, bestmove=%s, lines=%d", time.Since(start), best.CP, best.BestMove, len(best.Lines))
 			}
 			return best, nil
 		}
 	}
 }
 
+// searchDeadline is how long a search may run before the engine is taken for
+// hung: movetime plus a buffer, or without a time limit 8s for a single line
+// at depth 18, doubling every two plies deeper and growing with the number of
+// lines, as search time does.
+func searchDeadline(depth, maxTimeMs, multiPV int) time.Duration {
+	if maxTimeMs > 0 {
+		return time.Duration(maxTimeMs)*time.Millisecond + 500*time.Millisecond
+	}
+	deadline := 8 * time.Second * time.Duration(multiPV)
+	for d := 18; d < depth; d += 2 {
+		deadline *= 2
+	}
+	return deadline
+}
+
+// parseInfoLine extracts a scored line from an "info" output line, normalizing
+// the score to white's perspective. Lines without a score are rejected.
+func parseInfoLine(line string, isBlackToMove bool) (Line, bool) {
+	cp, mate, ok := parseScore(line)
+	if !ok {
+		return Line{}, false
+	}
+
+	out := Line{Rank: 1}
+	parts := strings.Fields(line)
+	for i := 0; i < len(parts); i++ {
+		switch parts[i] {
+		case "multipv":
+			if i+1 < len(parts) {
+				if v, err := strconv.Atoi(parts[i+1]); err == nil && v > 0 {
+					out.Rank = v
+				}
+			}
+		case "pv":
+			out.PV = append([]string(nil), parts[i+1:]...)
+			i = len(parts)
+		}
+	}
+	if len(out.PV) > 0 {
+		out.Move = out.PV[0]
+	}
+
+	if mate != nil {
+		// Normalize mate to white's perspective by sign:
+		// mate > 0 -> side to move mates; mate < 0 -> side to move gets mated
+		// If black to move, flip sign to white perspective.
+		mateVal := *mate
+		if isBlackToMove {
+			mateVal = -mateVal
+		}
+		out.Mate = &mateVal
+	} else if isBlackToMove {
+		// Normalize centipawns to white's perspective
+		out.CP = -cp
+	} else {
+		out.CP = cp
+	}
+	return out, true
+}
+
+// parseInfoDepth returns the depth of an "info" line, or 0 when missing.
+func parseInfoDepth(line string) int {
+	parts := strings.Fields(line)
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "depth" {
+			depth, _ := strconv.Atoi(parts[i+1])
+			return depth
+		}
+	}
+	return 0
+}
+
+// orderedLines returns the collected lines sorted by rank, skipping gaps.
+func orderedLines(lines map[int]Line, multiPV int) []Line {
+	out := make([]Line, 0, len(lines))
+	for rank := 1; rank <= multiPV; rank++ {
+		if l, ok := lines[rank]; ok && l.Move != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
 // parseScore returns cp, mate, ok.
 // mate: nil if not mate; non-nil value is mate in N (positive: side to move mates in N, negative: side to move gets mated in N).
 func parseScore(line string) (float64, *int, bool) {
@@ -251,6 +519,11 @@ func (e *Engine) sendLocked(cmd string) error {
 }
 
 func (e *Engine) waitFor(marker string, timeout time.Duration) error {
+	return e.readUntil(marker, timeout, nil)
+}
+
+// readUntil reads output until a line contains marker, passing earlier lines to fn.
+func (e *Engine) readUntil(marker string, timeout time.Duration, fn func(line string)) error {
 	deadline := time.Now().Add(timeout)
 	for {
 		if time.Now().After(deadline) {
@@ -264,5 +537,8 @@ func (e *Engine) waitFor(marker string, timeout time.Duration) error {
 		if strings.Contains(line, marker) {
 			return nil
 		}
+		if fn != nil {
+			fn(strings.TrimSpace(line))
+		}
 	}
 }
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UCIOption is an option an engine advertises with an "option name" line.
+type UCIOption struct {
+	Name    string
+	Type    string // check, spin, combo, button or string
+	Default string
+	Min     int
+	Max     int
+	Vars    []string // allowed combo values
+}
+
+// parseOptionLine parses a line such as
+// "option name Skill Level type spin default 20 min 0 max 20".
+func parseOptionLine(line string) (UCIOption, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "option" || fields[1] != "name" {
+		return UCIOption{}, false
+	}
+
+	var opt UCIOption
+	key := "name"
+	var value []string
+	flush := func() {
+		v := strings.Join(value, " ")
+		switch key {
+		case "name":
+			opt.Name = v
+		case "type":
+			opt.Type = v
+		case "default":
+			opt.Default = v
+		case "min":
+			opt.Min, _ = strconv.Atoi(v)
+		case "max":
+			opt.Max, _ = strconv.Atoi(v)
+		case "var":
+			opt.Vars = append(opt.Vars, v)
+		}
+		value = nil
+	}
+	for _, f := range fields[2:] {
+		switch f {
+		case "type", "default", "min", "max", "var":
+			// Option names may contain spaces, so only "type" ends the name
+			if key != "name" || f == "type" {
+				flush()
+				key = f
+				continue
+			}
+		}
+		value = append(value, f)
+	}
+	flush()
+
+	if opt.Name == "" {
+		return UCIOption{}, false
+	}
+	return opt, true
+}
+
+// setCommand validates value against the option and returns the setoption
+// command that applies it.
+func (o UCIOption) setCommand(value string) (string, error) {
+	switch o.Type {
+	case "button":
+		return "setoption name " + o.Name, nil
+	case "check":
+		if value != "true" && value != "false" {
+			return "", fmt.Errorf("option %q must be true or false, got %q", o.Name, value)
+		}
+	case "spin":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("option %q must be a number, got %q", o.Name, value)
+		}
+		if n < o.Min || n > o.Max {
+			return "", fmt.Errorf("option %q must be %d-%d, got %d", o.Name, o.Min, o.Max, n)
+		}
+	case "combo":
+		valid := false
+		for _, v := range o.Vars {
+			if strings.EqualFold(v, value) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("option %q must be one of %s, got %q", o.Name, strings.Join(o.Vars, "/"), value)
+		}
+	}
+	return fmt.Sprintf("setoption name %s value %s", o.Name, value), nil
+}
@@ -0,0 +1,192 @@
+package analysis
+
+import (
+	"math"
+	"slices"
+
+	"github.com/corentings/chess/v2"
+)
+
+// Classifier names selectable via configuration.
+const (
+	ClassifierCentipawn      = "centipawn"
+	ClassifierWinProbability = "win_probability"
+)
+
+// Classifiers lists the classifier names NewClassifier knows.
+var Classifiers = []string{ClassifierCentipawn, ClassifierWinProbability}
+
+// IsValidClassifier reports whether name is a known classifier.
+func IsValidClassifier(name string) bool {
+	return slices.Contains(Classifiers, name)
+}
+
+// NewClassifier returns the classifier registered under name, defaulting to
+// the centipawn classifier.
+func NewClassifier(name string) Classifier {
+	if name == ClassifierWinProbability {
+		return WinProbabilityClassifier{Thresholds: DefaultWinProbabilityThresholds()}
+	}
+	return CentipawnClassifier{Thresholds: DefaultThresholds()}
+}
+
+// WinProbabilityThresholds are drops in the mover's expected score (0-1).
+type WinProbabilityThresholds struct {
+	Blunder    float64 // Default: 0.20
+	Mistake    float64 // Default: 0.10
+	Inaccuracy float64 // Default: 0.05
+	// Great is how much worse the second best move must be for the best move to count as the only good one.
+	Great float64 // Default: 0.10
+	// MissOpportunity is how much the opponent's previous move must have handed over for a failure to punish it to be a miss.
+	MissOpportunity float64 // Default: 0.10
+	// BrilliantMaxDrop is the largest drop a sacrifice may cost and still be brilliant.
+	BrilliantMaxDrop float64 // Default: 0.02
+}
+
+// DefaultWinProbabilityThresholds returns the standard win-probability thresholds.
+func DefaultWinProbabilityThresholds() WinProbabilityThresholds {
+	return WinProbabilityThresholds{
+		Blunder:          0.20,
+		Mistake:          0.10,
+		Inaccuracy:       0.05,
+		Great:            0.10,
+		MissOpportunity:  0.10,
+		BrilliantMaxDrop: 0.02,
+	}
+}
+
+// WinProbabilityClassifier classifies moves by the drop in the mover's
+// expected score, so the same centipawn loss weighs more in balanced positions
+// than in decided ones.
+type WinProbabilityClassifier struct {
+	Thresholds WinProbabilityThresholds
+}
+
+// Name implements Classifier.
+func (c WinProbabilityClassifier) Name() string { return ClassifierWinProbability }
+
+// Classify implements Classifier.
+func (c WinProbabilityClassifier) Classify(in MoveInput) string {
+	if class := classifyMate(in); class != "" {
+		return class
+	}
+
+	t := c.Thresholds
+	before := moverScore(in.Before, in.IsWhiteMove, false)
+	after := moverScore(in.After, in.IsWhiteMove, true)
+	drop := math.Max(0, before-after)
+	isBest := in.MovePlayed != "" && in.MovePlayed == in.BestMove
+
+	// A sound sacrifice that keeps the game at least level, unless it was already won anyway
+	if drop <= t.BrilliantMaxDrop && after >= 0.5 && before < 0.95 && IsSacrifice(in.FEN, in.MovePlayed) {
+		return "brilliant"
+	}
+
+	if isBest {
+		if in.SecondBest != nil && before-moverScore(*in.SecondBest, in.IsWhiteMove, false) >= t.Great {
+			return "great"
+		}
+		return "best"
+	}
+
+	if drop >= t.Mistake && in.PrevBefore != nil {
+		// The opponent's last move handed over an advantage and this move gave it back
+		prev := moverScore(*in.PrevBefore, in.IsWhiteMove, false)
+		if before-prev >= t.MissOpportunity && after >= prev-t.Inaccuracy {
+			return "miss"
+		}
+	}
+
+	switch {
+	case drop >= t.Blunder:
+		return "blunder"
+	case drop >= t.Mistake:
+		return "mistake"
+	case drop >= t.Inaccuracy:
+		return "inaccuracy"
+	default:
+		return "good"
+	}
+}
+
+// moverScore returns the mover's expected score.
+func moverScore(s Score, isWhiteMove, afterMove bool) float64 {
+	if afterMove {
+		s = resolveDeliveredMate(s, isWhiteMove)
+	}
+	score := s.ExpectedScore()
+	if !isWhiteMove {
+		score = 1 - score
+	}
+	return score
+}
+
+// pieceValues are the usual material values in pawns.
+var pieceValues = map[chess.PieceType]int{
+	chess.Pawn:   1,
+	chess.Knight: 3,
+	chess.Bishop: 3,
+	chess.Rook:   5,
+	chess.Queen:  9,
+}
+
+// IsSacrifice reports whether moving a piece (not a pawn or the king) from
+// fen with the UCI move leaves it where the opponent can take it and come out
+// ahead in material, even after a recapture.
+func IsSacrifice(fen, moveUCI string) bool {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return false
+	}
+	pos := chess.NewGame(opt).Position()
+	move := findLegalMove(pos, moveUCI)
+	if move == nil {
+		return false
+	}
+
+	moved := pos.Board().Piece(move.S1()).Type()
+	if move.Promo() != chess.NoPieceType {
+		moved = move.Promo()
+	}
+	value, ok := pieceValues[moved]
+	if !ok || moved == chess.Pawn {
+		return false
+	}
+	captured := pieceValues[pos.Board().Piece(move.S2()).Type()]
+	if captured >= value {
+		return false
+	}
+
+	after := pos.Update(move)
+	cheapest := capturesOn(after, move.S2())
+	if cheapest == nil {
+		return false
+	}
+	attacker := pieceValues[after.Board().Piece(cheapest.S1()).Type()]
+
+	recaptured := after.Update(cheapest)
+	if capturesOn(recaptured, move.S2()) == nil {
+		return true
+	}
+	return captured+attacker < value
+}
+
+// capturesOn returns the legal capture on sq by the cheapest piece, or nil.
+func capturesOn(pos *chess.Position, sq chess.Square) *chess.Move {
+	var best *chess.Move
+	bestValue := math.MaxInt
+	for _, m := range pos.ValidMoves() {
+		if m.S2() != sq || !m.HasTag(chess.Capture) {
+			continue
+		}
+		v, ok := pieceValues[pos.Board().Piece(m.S1()).Type()]
+		if !ok {
+			v = 100 // the king captures last
+		}
+		if v < bestValue {
+			m := m
+			best, bestValue = &m, v
+		}
+	}
+	return best
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/vytor/chessflash/internal/analysis"
 	"github.com/vytor/chessflash/internal/errors"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
@@ -42,6 +44,89 @@ func (s *Server) handleEvaluatePosition(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handleEvaluateStream streams the evaluation of a position as Server-Sent
+// Events: an "info" event for every depth the engine reports, then "done"
+// with the final result, or "error". The search stops when the client
+// disconnects.
+func (s *Server) handleEvaluateStream(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	fen := r.URL.Query().Get("fen")
+	if fen == "" {
+		handleError(w, r, errors.NewBadRequestError("fen parameter required"))
+		return
+	}
+	depth := 0
+	if v := r.URL.Query().Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil {
+			handleError(w, r, errors.NewBadRequestError("invalid depth"))
+			return
+		}
+		depth = d
+	}
+
+	// Headers are sent with the first event so that errors found before the
+	// search starts are still reported as plain JSON errors
+	rc := http.NewResponseController(w)
+	started := false
+	send := func(event string, data any) {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			// The server write timeout would cut long searches short
+			_ = rc.SetWriteDeadline(time.Time{})
+			w.WriteHeader(http.StatusOK)
+		}
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Error("failed to encode %s event: %v", event, err)
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		if err := rc.Flush(); err != nil {
+			log.Warn("failed to flush %s event: %v", event, err)
+		}
+	}
+
+	lastDepth := 0
+	result, err := s.AnalysisService.StreamEvaluation(r.Context(), fen, depth, func(info analysis.SearchInfo) {
+		lastDepth = info.Depth
+		send("info", map[string]interface{}{
+			"depth": info.Depth,
+			"cp":    info.CP,
+			"mate":  info.Mate,
+			"pv":    info.PV,
+		})
+	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			log.Debug("evaluation stream closed by client")
+			return
+		}
+		if !started {
+			handleError(w, r, err)
+			return
+		}
+		message := "evaluation failed"
+		if appErr, ok := err.(*errors.AppError); ok {
+			message = appErr.Message
+		}
+		send("error", map[string]interface{}{"error": message})
+		return
+	}
+
+	send("done", map[string]interface{}{
+		"depth":     lastDepth,
+		"cp":        result.CP,
+		"mate":      result.Mate,
+		"best_move": result.BestMove,
+		"pv":        result.PV,
+	})
+}
+
 func (s *Server) handleAnalysisStatus(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	profile := profileFromContext(r.Context())
@@ -65,7 +150,7 @@ func (s *Server) handleAnalysisStatus(w http.ResponseWriter, r *http.Request) {
 			baseFilter.PlayedAs != ""
 	}
 
-	var pending, processing, completed, failed int
+	var pending, processing, completed, failed, incomplete int
 	var err error
 
 	if hasFilters {
@@ -99,6 +184,13 @@ func (s *Server) handleAnalysisStatus(w http.ResponseWriter, r *http.Request) {
 			log.Warn("failed to count failed games: %v", err)
 			failed = 0
 		}
+
+		// For incomplete: status = 'incomplete'
+		incomplete, err = s.GameService.CountGamesByStatusWithFilter(ctx, profile.ID, "incomplete", baseFilter)
+		if err != nil {
+			log.Warn("failed to count incomplete games: %v", err)
+			incomplete = 0
+		}
 	} else {
 		// Use unfiltered counts (backward compatibility)
 		pending, err = s.GameService.CountGamesByStatus(ctx, profile.ID, "pending")
@@ -124,10 +216,21 @@ func (s *Server) handleAnalysisStatus(w http.ResponseWriter, r *http.Request) {
 			log.Warn("failed to count failed games: %v", err)
 			failed = 0
 		}
+
+		incomplete, err = s.GameService.CountGamesByStatus(ctx, profile.ID, "incomplete")
+		if err != nil {
+			log.Warn("failed to count incomplete games: %v", err)
+			incomplete = 0
+		}
 	}
 
 	// Get queue size
-	queueSize := s.AnalysisPool.QueueSize()
+	queueSize := 0
+	if counts, err := s.JobService.CountJobs(ctx, models.JobQueueAnalysis); err != nil {
+		log.Warn("failed to count queued analysis jobs: %v", err)
+	} else {
+		queueSize = counts[models.JobQueued]
+	}
 
 	// Get worker count
 	workerCount := s.AnalysisPool.WorkerCount()
@@ -158,10 +261,36 @@ func (s *Server) handleAnalysisStatus(w http.ResponseWriter, r *http.Request) {
 		"processing":       processing,
 		"completed":        completed,
 		"failed":           failed,
+		"incomplete":       incomplete,
 		"queue_size":       queueSize,
 		"is_running":        isRunning,
 		"estimated_seconds": estimatedSeconds,
 		"estimated_time":   formatDuration(estimatedSeconds),
+		"eval_cache":       s.AnalysisService.EvalCacheStats(),
+	}
+
+	// Report where a single game stands when one is asked for
+	if gameIDStr := r.URL.Query().Get("game_id"); gameIDStr != "" {
+		gameID, err := strconv.ParseInt(gameIDStr, 10, 64)
+		if err != nil {
+			handleError(w, r, errors.NewBadRequestError("invalid game ID"))
+			return
+		}
+		game, err := s.GameService.GetGame(ctx, gameID, profile.ID)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		position, err := s.JobService.AnalysisQueuePosition(ctx, gameID)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		status["game"] = map[string]interface{}{
+			"id":             gameID,
+			"status":         game.AnalysisStatus,
+			"queue_position": position,
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -209,6 +338,7 @@ func (s *Server) handleAnalysisQueuePage(w http.ResponseWriter, r *http.Request)
 		"time_classes": timeClasses,
 		"results":     results,
 		"colors":      colors,
+		"engines":     s.AnalysisService.Engines(),
 	})
 }
 
@@ -259,6 +389,10 @@ func (s *Server) handleQueueAnalysis(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filter := parseAnalysisFilter(r, profile.ID)
+	if filter.Engine != "" && !slices.Contains(s.AnalysisService.Engines(), filter.Engine) {
+		handleError(w, r, errors.NewValidationError("engine", "unknown engine "+filter.Engine))
+		return
+	}
 	
 	// Get count first (quick operation)
 	count, err := s.GameService.CountGamesForAnalysis(r.Context(), filter)
@@ -281,6 +415,9 @@ func (s *Server) handleQueueAnalysis(w http.ResponseWriter, r *http.Request) {
 			s.AnalysisPool.ClearQueue()
 			// Restart with a new background context
 			s.AnalysisPool.Restart(ctx)
+			if _, err := s.JobService.ClearQueued(ctx, models.JobQueueAnalysis); err != nil {
+				log.Warn("failed to clear queued analysis jobs: %v", err)
+			}
 		}
 		
 		// Queue games after clearing/restarting
@@ -413,6 +550,16 @@ func parseAnalysisFilter(r *http.Request, profileID int64) models.AnalysisFilter
 		filter.IncludeFailed = strings.ToLower(includeFailed) == "true" || includeFailed == "1"
 	}
 
+	// Only games analyzed part way
+	if incompleteOnly := r.URL.Query().Get("incomplete_only"); incompleteOnly != "" {
+		filter.IncompleteOnly = strings.ToLower(incompleteOnly) == "true" || incompleteOnly == "1"
+	} else if incompleteOnly := r.FormValue("incomplete_only"); incompleteOnly != "" {
+		filter.IncompleteOnly = strings.ToLower(incompleteOnly) == "true" || incompleteOnly == "1"
+	}
+
+	// Engine profile
+	filter.Engine = r.FormValue("engine")
+
 	return filter
 }
 
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+)
+
+func (s *Server) handleDecks(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	decks, err := s.DeckService.ListDecks(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	tagCounts, err := s.FlashcardService.TagCounts(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	s.render(w, r, "pages/flashcard_decks.html", pageData{
+		"decks":      decks,
+		"tag_counts": tagCounts,
+	})
+}
+
+// handleCreateDeck creates a deck from the name form value and the tags,
+// given as repeated tag values or separated by commas in tags.
+func (s *Server) handleCreateDeck(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	name := r.FormValue("name")
+	tags := append(r.Form["tag"], strings.Split(r.FormValue("tags"), ",")...)
+	deck, err := s.DeckService.CreateDeck(r.Context(), profile.ID, name, tags)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("deck %d created for profile %d", deck.ID, profile.ID)
+	http.Redirect(w, r, "/flashcards/decks", http.StatusSeeOther)
+}
+
+func (s *Server) handleDeleteDeck(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid deck ID: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid deck ID"))
+		return
+	}
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.DeckService.DeleteDeck(r.Context(), id, profile.ID); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("deck %d deleted for profile %d", id, profile.ID)
+	http.Redirect(w, r, "/flashcards/decks", http.StatusSeeOther)
+}
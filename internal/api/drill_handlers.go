@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+)
+
+func (s *Server) handleHangingDrillPage(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("rendering hanging drill page")
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	count, err := s.DrillService.CountHangingDrills(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	s.render(w, r, "pages/drill_hanging.html", pageData{
+		"count":   count,
+		"profile": profile,
+	})
+}
+
+func (s *Server) handleNextHangingDrill(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("getting next hanging drill")
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context")
+		handleError(w, r, errors.NewBadRequestError("no profile selected"))
+		return
+	}
+
+	var after int64
+	if v := r.URL.Query().Get("after"); v != "" {
+		var err error
+		if after, err = strconv.ParseInt(v, 10, 64); err != nil {
+			handleError(w, r, errors.NewBadRequestError("invalid after"))
+			return
+		}
+	}
+
+	drill, err := s.DrillService.NextHangingDrill(r.Context(), profile.ID, after)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"drill": drill}); err != nil {
+		log.Error("failed to encode response: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) handleCheckHangingDrill(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("checking hanging drill move")
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context")
+		handleError(w, r, errors.NewBadRequestError("no profile selected"))
+		return
+	}
+
+	positionID, err := strconv.ParseInt(r.FormValue("position_id"), 10, 64)
+	if err != nil {
+		handleError(w, r, errors.NewBadRequestError("invalid position_id"))
+		return
+	}
+	move := r.FormValue("move")
+	if move == "" {
+		handleError(w, r, errors.NewBadRequestError("move required"))
+		return
+	}
+
+	result, err := s.DrillService.CheckHangingDrill(r.Context(), profile.ID, positionID, move)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Error("failed to encode response: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
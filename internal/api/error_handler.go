@@ -29,7 +29,7 @@ func handleError(w http.ResponseWriter, r *http.Request, err error) {
 	}
 
 	// Check if this is a JSON endpoint (API routes)
-	if r.URL.Path == "/api/evaluate" || r.Header.Get("Accept") == "application/json" {
+	if r.URL.Path == "/api/evaluate" || r.URL.Path == "/api/evaluate/stream" || r.URL.Path == "/api/explorer" || r.Header.Get("Accept") == "application/json" {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appErr.Status)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/explorer"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// parseExplorerQuery reads the position and filters of an explorer request.
+// moves is a comma-separated list of UCI moves from the starting position.
+func parseExplorerQuery(r *http.Request, profileID int64) (models.ExplorerFilter, []string) {
+	q := r.URL.Query()
+	filter := models.ExplorerFilter{
+		ProfileID: profileID,
+		FEN:       strings.TrimSpace(q.Get("fen")),
+		Color:     q.Get("color"),
+		TimeClass: q.Get("time_class"),
+	}
+
+	var path []string
+	for _, m := range strings.Split(q.Get("moves"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			path = append(path, m)
+		}
+	}
+	return filter, path
+}
+
+func (s *Server) handleExplorer(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	filter, path := parseExplorerQuery(r, profile.ID)
+	position, err := s.ExplorerService.GetPosition(r.Context(), filter, path)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	// Each breadcrumb links back to the position after that move
+	type crumb struct {
+		SAN   string
+		Moves string
+	}
+	crumbs := make([]crumb, 0, len(position.PathSAN))
+	for i, san := range position.PathSAN {
+		crumbs = append(crumbs, crumb{SAN: san, Moves: strings.Join(position.Path[:i+1], ",")})
+	}
+
+	s.render(w, r, "pages/explorer.html", pageData{
+		"profile":      profile,
+		"position":     position,
+		"path":         strings.Join(position.Path, ","),
+		"crumbs":       crumbs,
+		"by_fen":       filter.FEN != "",
+		"color":        filter.Color,
+		"time_class":   filter.TimeClass,
+		"time_classes": []string{"bullet", "blitz", "rapid", "daily"},
+		"max_ply":      explorer.MaxPly,
+	})
+}
+
+func (s *Server) handleExplorerAPI(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		handleError(w, r, errors.NewBadRequestError("profile required"))
+		return
+	}
+
+	filter, path := parseExplorerQuery(r, profile.ID)
+	position, err := s.ExplorerService.GetPosition(r.Context(), filter, path)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(position); err != nil {
+		log.Error("failed to encode response: %v", err)
+	}
+}
@@ -1,12 +1,18 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/flashcard"
 	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
 )
 
 func (s *Server) handleFlashcards(w http.ResponseWriter, r *http.Request) {
@@ -108,10 +114,28 @@ func (s *Server) handleFlashcards(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Default behavior: show next flashcard for review
-	log.Debug("fetching next flashcard")
+	// Default behavior: show next flashcard for review, from the deck and
+	// tags of the session if one is given
+	session, err := s.parseReviewSession(r, profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
 
-	card, err := s.FlashcardService.GetNextFlashcard(r.Context(), profile.ID)
+	if session.complete() {
+		log.Debug("review session complete: %d cards reviewed", session.Reviewed)
+		s.render(w, r, "pages/flashcards.html", pageData{
+			"card":             nil,
+			"session":          session,
+			"session_complete": true,
+			"filtered_by_game": false,
+		})
+		return
+	}
+
+	log.Debug("fetching next flashcard: tags=%v", session.allTags())
+
+	card, err := s.FlashcardService.NextSessionFlashcard(r.Context(), profile.ID, session.allTags())
 	if err != nil {
 		handleError(w, r, err)
 		return
@@ -123,10 +147,93 @@ func (s *Server) handleFlashcards(w http.ResponseWriter, r *http.Request) {
 
 	s.render(w, r, "pages/flashcards.html", pageData{
 		"card":             card,
+		"session":          session,
 		"filtered_by_game": false,
 	})
 }
 
+// reviewSession is a review restricted to the due cards of a deck and/or
+// carrying some tags, optionally limited to a number of cards. It is carried
+// along in the query string, with the number of cards reviewed so far.
+type reviewSession struct {
+	Deck     *models.FlashcardDeck
+	Tags     []string // tags asked for besides the deck's
+	Limit    int
+	Reviewed int
+}
+
+// parseReviewSession reads the deck, tag (repeated), limit and reviewed
+// parameters of a review session.
+func (s *Server) parseReviewSession(r *http.Request, profileID int64) (reviewSession, error) {
+	session := reviewSession{}
+	if deckStr := r.FormValue("deck"); deckStr != "" {
+		deckID, err := strconv.ParseInt(deckStr, 10, 64)
+		if err != nil {
+			return session, errors.NewBadRequestError("invalid deck")
+		}
+		if session.Deck, err = s.DeckService.GetDeck(r.Context(), deckID, profileID); err != nil {
+			return session, err
+		}
+	}
+	for _, tag := range r.Form["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			session.Tags = append(session.Tags, tag)
+		}
+	}
+	if limitStr := r.FormValue("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return session, errors.NewBadRequestError("invalid limit")
+		}
+		session.Limit = limit
+	}
+	if reviewedStr := r.FormValue("reviewed"); reviewedStr != "" {
+		reviewed, err := strconv.Atoi(reviewedStr)
+		if err != nil || reviewed < 0 {
+			return session, errors.NewBadRequestError("invalid reviewed count")
+		}
+		session.Reviewed = reviewed
+	}
+	return session, nil
+}
+
+// Active reports whether the review is restricted in any way.
+func (rs reviewSession) Active() bool {
+	return rs.Deck != nil || len(rs.Tags) > 0 || rs.Limit > 0
+}
+
+func (rs reviewSession) complete() bool {
+	return rs.Limit > 0 && rs.Reviewed >= rs.Limit
+}
+
+// allTags returns the deck's tags followed by the other tags asked for.
+func (rs reviewSession) allTags() []string {
+	var tags []string
+	if rs.Deck != nil {
+		tags = append(tags, rs.Deck.Tags...)
+	}
+	return append(tags, rs.Tags...)
+}
+
+// nextURL is the review page for the session's next card.
+func (rs reviewSession) nextURL() string {
+	if !rs.Active() {
+		return "/flashcards"
+	}
+	q := url.Values{}
+	if rs.Deck != nil {
+		q.Set("deck", strconv.FormatInt(rs.Deck.ID, 10))
+	}
+	for _, tag := range rs.Tags {
+		q.Add("tag", tag)
+	}
+	if rs.Limit > 0 {
+		q.Set("limit", strconv.Itoa(rs.Limit))
+		q.Set("reviewed", strconv.Itoa(rs.Reviewed+1))
+	}
+	return "/flashcards?" + q.Encode()
+}
+
 func (s *Server) handleReviewFlashcard(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	idStr := chi.URLParam(r, "id")
@@ -137,23 +244,17 @@ func (s *Server) handleReviewFlashcard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	quality, err := strconv.Atoi(r.FormValue("quality"))
+	review, err := parseReview(r)
 	if err != nil {
-		log.Warn("invalid quality value: %s", r.FormValue("quality"))
-		handleError(w, r, errors.NewBadRequestError("invalid quality"))
+		log.Warn("invalid review: %v", err)
+		handleError(w, r, errors.NewBadRequestError(err.Error()))
 		return
 	}
 
-	// Get time_seconds from form (optional, defaults to 0)
-	timeSeconds, _ := strconv.ParseFloat(r.FormValue("time_seconds"), 64)
-	if timeSeconds < 0 {
-		timeSeconds = 0
-	}
-
 	log = log.WithFields(map[string]any{
 		"flashcard_id": id,
-		"quality":      quality,
-		"time_seconds": timeSeconds,
+		"attempts":     len(review.Attempts),
+		"revealed":     review.Revealed,
 	})
 	log.Debug("reviewing flashcard")
 
@@ -164,16 +265,25 @@ func (s *Server) handleReviewFlashcard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.FlashcardService.ReviewFlashcard(r.Context(), id, profile.ID, quality, timeSeconds); err != nil {
+	grade, err := s.FlashcardService.SubmitReview(r.Context(), id, profile.ID, review)
+	if err != nil {
 		handleError(w, r, err)
 		return
 	}
 
-	log.Info("flashcard reviewed successfully")
+	log.Info("flashcard reviewed successfully: quality=%d", grade.Quality)
 
 	// Preserve game_id and card_index if present, and advance to next card
 	redirectURL := "/flashcards"
 	gameIDStr := r.FormValue("game_id")
+	if gameIDStr == "" {
+		// Carry on with the review session, if any
+		if session, err := s.parseReviewSession(r, profile.ID); err == nil {
+			redirectURL = session.nextURL()
+		} else {
+			log.Warn("invalid review session: %v", err)
+		}
+	}
 	cardIndexStr := r.FormValue("card_index")
 	if gameIDStr != "" {
 		redirectURL += "?game_id=" + gameIDStr
@@ -205,5 +315,214 @@ func (s *Server) handleReviewFlashcard(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if r.Header.Get("Accept") == "application/json" {
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]any{
+			"grade": grade,
+			"next":  redirectURL,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("failed to encode response: %v", err)
+		}
+		return
+	}
+
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
+
+// handleFlashcardMove checks one move of a flashcard's solution line. The
+// form carries the moves already played (played, repeated, including the
+// replies) and the move to check; the response says whether it was right and
+// gives the opponent's reply when the line goes on.
+func (s *Server) handleFlashcardMove(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid flashcard ID: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid flashcard ID"))
+		return
+	}
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		handleError(w, r, errors.NewBadRequestError("no profile selected"))
+		return
+	}
+
+	move := r.FormValue("move")
+	step, err := s.FlashcardService.PlayLineMove(r.Context(), id, profile.ID, r.Form["played"], move)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(step); err != nil {
+		log.Error("failed to encode response: %v", err)
+	}
+}
+
+// handleReviewSession returns the due cards of a review session as JSON: the
+// deck and tag parameters select the cards, and limit (DefaultSessionSize by
+// default) bounds how many are returned.
+func (s *Server) handleReviewSession(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		handleError(w, r, errors.NewBadRequestError("profile required"))
+		return
+	}
+
+	session, err := s.parseReviewSession(r, profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	cards, err := s.FlashcardService.ReviewSession(r.Context(), profile.ID, session.allTags(), session.Limit)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cards); err != nil {
+		log.Error("failed to encode response: %v", err)
+	}
+}
+
+// handleAddFlashcardTag puts the tag form value on a flashcard and answers
+// with the tag as stored.
+func (s *Server) handleAddFlashcardTag(w http.ResponseWriter, r *http.Request) {
+	s.handleFlashcardTag(w, r, true)
+}
+
+func (s *Server) handleRemoveFlashcardTag(w http.ResponseWriter, r *http.Request) {
+	s.handleFlashcardTag(w, r, false)
+}
+
+func (s *Server) handleFlashcardTag(w http.ResponseWriter, r *http.Request, add bool) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid flashcard ID: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid flashcard ID"))
+		return
+	}
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		handleError(w, r, errors.NewBadRequestError("no profile selected"))
+		return
+	}
+
+	tag := r.FormValue("tag")
+	if add {
+		tag, err = s.FlashcardService.AddTag(r.Context(), id, profile.ID, tag)
+	} else {
+		err = s.FlashcardService.RemoveTag(r.Context(), id, profile.ID, tag)
+	}
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"tag": tag}); err != nil {
+		log.Error("failed to encode response: %v", err)
+	}
+}
+
+// parseReview reads the moves attempted during a flashcard review: repeated
+// move and move_seconds fields in the order they were played, revealed when
+// the answer was shown, and time_seconds for the whole review.
+func parseReview(r *http.Request) (flashcard.Review, error) {
+	review := flashcard.Review{}
+	review.Revealed, _ = strconv.ParseBool(r.FormValue("revealed"))
+	review.TimeSeconds, _ = strconv.ParseFloat(r.FormValue("time_seconds"), 64)
+
+	moves, seconds := r.Form["move"], r.Form["move_seconds"]
+	if len(moves) != len(seconds) {
+		return review, fmt.Errorf("got %d moves but %d move timings", len(moves), len(seconds))
+	}
+	for i, move := range moves {
+		secs, err := strconv.ParseFloat(seconds[i], 64)
+		if err != nil {
+			return review, fmt.Errorf("invalid timing for move %d", i+1)
+		}
+		review.Attempts = append(review.Attempts, flashcard.Attempt{Move: move, Seconds: secs})
+	}
+	return review, nil
+}
+
+func (s *Server) handleStartFSRSOptimization(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	run, err := s.FlashcardService.StartFSRSOptimization(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("fsrs optimization %d queued for profile %d", run.ID, profile.ID)
+	http.Redirect(w, r, "/flashcards/analytics#scheduler", http.StatusSeeOther)
+}
+
+func (s *Server) handleApplyFSRSOptimization(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid optimization ID: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid optimization ID"))
+		return
+	}
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.FlashcardService.ApplyFSRSOptimization(r.Context(), profile.ID, id); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("fsrs optimization %d applied to profile %d", id, profile.ID)
+	http.Redirect(w, r, "/flashcards/analytics#scheduler", http.StatusSeeOther)
+}
+
+func (s *Server) handleFSRSOptimizationStatus(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		handleError(w, r, errors.NewBadRequestError("profile required"))
+		return
+	}
+
+	run, err := s.FlashcardService.LatestFSRSOptimization(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"optimization": run,
+		"scheduler":    profile.Scheduler,
+		"custom":       len(profile.FSRSWeights) > 0,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("failed to encode response: %v", err)
+	}
+}
@@ -2,6 +2,9 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -31,6 +34,70 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/games", http.StatusSeeOther)
 }
 
+// maxPGNUploadSize bounds PGN uploads (10 MB is several thousand games).
+const maxPGNUploadSize = 10 << 20
+
+func (s *Server) handleImportPGNPage(w http.ResponseWriter, r *http.Request) {
+	if profileFromContext(r.Context()) == nil {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	s.render(w, r, "pages/import_pgn.html", pageData{})
+}
+
+func (s *Server) handleImportPGN(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context during PGN import")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPGNUploadSize)
+	if err := r.ParseMultipartForm(maxPGNUploadSize); err != nil && err != http.ErrNotMultipart {
+		log.Warn("failed to parse PGN upload: %v", err)
+		handleError(w, r, errors.NewBadRequestError("invalid upload or file too large"))
+		return
+	}
+
+	pgnText := r.FormValue("pgn_text")
+	if file, _, err := r.FormFile("pgn_file"); err == nil {
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			log.Warn("failed to read PGN file: %v", err)
+			handleError(w, r, errors.NewBadRequestError("failed to read PGN file"))
+			return
+		}
+		pgnText = string(data)
+	}
+	if strings.TrimSpace(pgnText) == "" {
+		handleError(w, r, errors.NewBadRequestError("PGN file or text required"))
+		return
+	}
+
+	playerName := r.FormValue("player_name")
+	result, err := s.ImportService.ImportPGN(r.Context(), *profile, pgnText, playerName)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Error("failed to encode response: %v", err)
+		}
+		return
+	}
+
+	s.render(w, r, "pages/import_pgn.html", pageData{
+		"result":      result,
+		"player_name": playerName,
+	})
+}
+
 func (s *Server) handleResumeAnalysis(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	profile := profileFromContext(r.Context())
@@ -126,16 +193,18 @@ func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
 	perPageParam := r.URL.Query().Get("per_page")
 	orderBy := r.URL.Query().Get("order_by")
 	orderDir := strings.ToUpper(r.URL.Query().Get("order_dir"))
+	failureReason := r.URL.Query().Get("failure_reason")
 
 	log = log.WithFields(map[string]any{
-		"result":     result,
-		"time_class": timeClass,
-		"opening":    opening,
-		"opponent":   opponent,
-		"page":       pageParam,
-		"per_page":   perPageParam,
-		"order_by":   orderBy,
-		"order_dir":  orderDir,
+		"result":         result,
+		"time_class":     timeClass,
+		"opening":        opening,
+		"opponent":       opponent,
+		"page":           pageParam,
+		"per_page":       perPageParam,
+		"order_by":       orderBy,
+		"order_dir":      orderDir,
+		"failure_reason": failureReason,
 	})
 	log.Debug("listing games with filters")
 
@@ -173,15 +242,16 @@ func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filter := models.GameFilter{
-		ProfileID:   profile.ID,
-		Result:      result,
-		TimeClass:   timeClass,
-		OpeningName: opening,
-		Opponent:    opponent,
-		Limit:       perPage,
-		Offset:      offset,
-		OrderBy:     orderBy,
-		OrderDir:    orderDir,
+		ProfileID:     profile.ID,
+		Result:        result,
+		TimeClass:     timeClass,
+		OpeningName:   opening,
+		Opponent:      opponent,
+		FailureReason: failureReason,
+		Limit:         perPage,
+		Offset:        offset,
+		OrderBy:       orderBy,
+		OrderDir:      orderDir,
 	}
 
 	games, totalCount, err := s.GameService.ListGames(r.Context(), filter)
@@ -190,6 +260,11 @@ func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	failureReasons, err := s.GameService.CountFailureReasons(r.Context(), profile.ID)
+	if err != nil {
+		log.Warn("failed to count failure reasons: %v", err)
+	}
+
 	totalPages := totalCount / perPage
 	if totalCount%perPage != 0 {
 		totalPages++
@@ -200,18 +275,38 @@ func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug("found %d games", len(games))
 	s.render(w, r, "pages/games.html", pageData{
-		"games":       games,
-		"filters":     r.URL.Query(),
-		"profile":     profile,
-		"page":        page,
-		"per_page":    perPage,
-		"total_pages": totalPages,
-		"total_count": totalCount,
-		"order_by":    orderBy,
-		"order_dir":   orderDir,
+		"games":           games,
+		"filters":         r.URL.Query(),
+		"profile":         profile,
+		"page":            page,
+		"per_page":        perPage,
+		"total_pages":     totalPages,
+		"total_count":     totalCount,
+		"order_by":        orderBy,
+		"order_dir":       orderDir,
+		"failure_reasons": failureReasons,
 	})
 }
 
+func (s *Server) handleRetryFailedGames(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context during retry of failed games")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	count, err := s.GameService.RetryFailedGames(r.Context(), profile.ID, r.FormValue("failure_reason"))
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("queued %d failed games for analysis", count)
+	http.Redirect(w, r, "/games", http.StatusSeeOther)
+}
+
 func (s *Server) handleGameDetail(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	idStr := chi.URLParam(r, "id")
@@ -253,9 +348,50 @@ func (s *Server) handleGameDetail(w http.ResponseWriter, r *http.Request) {
 		log.Debug("found %d flashcards for game", flashcardCount)
 	}
 
+	// The shallowest search bounds how reliable the game's verdicts are
+	analysisDepth := 0
+	for i, p := range positions {
+		if i == 0 || p.Depth < analysisDepth {
+			analysisDepth = p.Depth
+		}
+	}
+
 	s.render(w, r, "pages/game_detail.html", pageData{
 		"game":            game,
 		"positions":       positions,
 		"flashcard_count": flashcardCount,
+		"analysis_depth":  analysisDepth,
+		"deepen_depth":    min(max(analysisDepth, 14)+4, 30),
 	})
 }
+
+func (s *Server) handleDeepenGameAnalysis(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid game ID for deepen: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid game ID"))
+		return
+	}
+
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context, redirecting to /profiles")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	depth, err := strconv.Atoi(r.FormValue("depth"))
+	if err != nil {
+		handleError(w, r, errors.NewBadRequestError("invalid depth"))
+		return
+	}
+
+	if err := s.GameService.QueueDeepenAnalysis(r.Context(), id, profile.ID, depth); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/games/%d", id), http.StatusSeeOther)
+}
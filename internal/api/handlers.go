@@ -4,6 +4,7 @@ import (
 	"html/template"
 	"net/http"
 
+	"github.com/vytor/chessflash/internal/analysis"
 	"github.com/vytor/chessflash/internal/chesscom"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/services"
@@ -14,10 +15,16 @@ type Server struct {
 	ProfileService       services.ProfileService
 	GameService          services.GameService
 	FlashcardService     services.FlashcardService
+	DeckService          services.DeckService
 	PuzzleRushService    services.PuzzleRushService
+	DrillService         services.DrillService
 	StatsService         services.StatsService
+	RepertoireService    services.RepertoireService
+	ExplorerService      services.ExplorerService
 	ImportService        services.ImportService
 	AnalysisService      services.AnalysisService
+	JobService           services.JobService
+	EnginePool           *analysis.EnginePool
 	AnalysisPool         *worker.Pool
 	ImportPool           *worker.Pool
 	ChessClient          *chesscom.Client
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"net/http"
 
 	"github.com/vytor/chessflash/internal/logger"
@@ -16,7 +17,8 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleReady returns a readiness probe - checks if the service is ready to accept traffic.
-// Returns 200 if DB and engine pool are healthy, 503 otherwise.
+// Returns 200 if DB and engine pool are healthy, 503 otherwise. The body reports
+// the engine pool state, including how many crashed engines were restarted.
 func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.FromContext(ctx)
@@ -29,12 +31,25 @@ func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check engine pool availability (if we have access to it)
-	// Note: Engine pool check is optional since it's not always accessible from Server
-	// In a future refactor, this could be injected as a dependency
+	response := map[string]interface{}{"status": "ready"}
+	status := http.StatusOK
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Ready"))
+	// Check engine pool availability: not ready when no default engine could be restarted
+	if s.EnginePool != nil {
+		stats := s.EnginePool.Stats()
+		response["engine_pool"] = stats
+		if def := stats.Engines[s.EnginePool.DefaultEngine()]; def.Unavailable >= def.Size {
+			log.Warn("readiness check failed - no %s engine available (%d restarts)", s.EnginePool.DefaultEngine(), def.Restarts)
+			response["status"] = "engine pool unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("failed to encode response: %v", err)
+	}
 }
 
 // checkDatabase verifies database connectivity with a simple query.
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// jobListLimit bounds how many jobs of each state the admin page shows
+const jobListLimit = 100
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	ctx := r.Context()
+
+	data := pageData{}
+	for _, state := range []string{models.JobRunning, models.JobQueued, models.JobFailed} {
+		jobs, err := s.JobService.ListJobs(ctx, state, jobListLimit)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		data[state] = jobs
+	}
+
+	counts, err := s.JobService.CountJobs(ctx, "")
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	data["counts"] = counts
+
+	log.Debug("job counts: %v", counts)
+	s.render(w, r, "pages/admin_jobs.html", data)
+}
+
+func (s *Server) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid job ID for retry: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid job ID"))
+		return
+	}
+
+	if err := s.JobService.RetryJob(r.Context(), id); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("job %d queued for retry", id)
+	http.Redirect(w, r, "/admin/jobs", http.StatusSeeOther)
+}
@@ -35,7 +35,8 @@ func (s *Server) handleCreateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profile, err := s.ProfileService.CreateProfile(r.Context(), username)
+	platform := strings.ToLower(strings.TrimSpace(r.FormValue("platform")))
+	profile, err := s.ProfileService.CreateProfile(r.Context(), username, platform)
 	if err != nil {
 		handleError(w, r, err)
 		return
@@ -65,6 +66,31 @@ func (s *Server) handleSelectProfile(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+func (s *Server) handleSetScheduler(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Warn("invalid profile id for scheduler: %s", idStr)
+		handleError(w, r, errors.NewBadRequestError("invalid profile id"))
+		return
+	}
+
+	if _, err := s.ProfileService.GetProfile(r.Context(), id); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	scheduler := strings.ToLower(strings.TrimSpace(r.FormValue("scheduler")))
+	if err := s.FlashcardService.SetScheduler(r.Context(), id, scheduler); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("profile %d now uses %s scheduler", id, scheduler)
+	http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+}
+
 func (s *Server) handleDeleteProfile(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	idStr := chi.URLParam(r, "id")
@@ -125,19 +125,13 @@ func (s *Server) handlePuzzleRushAnswer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	qualityStr := r.FormValue("quality")
-	quality, err := strconv.Atoi(qualityStr)
+	review, err := parseReview(r)
 	if err != nil {
-		handleError(w, r, errors.NewBadRequestError("invalid quality"))
+		handleError(w, r, errors.NewBadRequestError(err.Error()))
 		return
 	}
 
-	timeSeconds, _ := strconv.ParseFloat(r.FormValue("time_seconds"), 64)
-	if timeSeconds < 0 {
-		timeSeconds = 0
-	}
-
-	session, err := s.PuzzleRushService.SubmitAnswer(r.Context(), sessionID, profile.ID, flashcardID, quality, timeSeconds)
+	session, err := s.PuzzleRushService.SubmitAnswer(r.Context(), sessionID, profile.ID, flashcardID, review)
 	if err != nil {
 		handleError(w, r, err)
 		return
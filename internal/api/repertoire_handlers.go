@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+)
+
+func (s *Server) handleImportRepertoire(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context during repertoire import")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPGNUploadSize)
+	if err := r.ParseMultipartForm(maxPGNUploadSize); err != nil && err != http.ErrNotMultipart {
+		log.Warn("failed to parse repertoire upload: %v", err)
+		handleError(w, r, errors.NewBadRequestError("invalid upload or file too large"))
+		return
+	}
+
+	pgnText := r.FormValue("pgn_text")
+	if file, _, err := r.FormFile("pgn_file"); err == nil {
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			log.Warn("failed to read repertoire file: %v", err)
+			handleError(w, r, errors.NewBadRequestError("failed to read PGN file"))
+			return
+		}
+		pgnText = string(data)
+	}
+
+	color := strings.ToLower(strings.TrimSpace(r.FormValue("color")))
+	added, err := s.RepertoireService.ImportPGN(r.Context(), profile.ID, color, pgnText)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	q := url.Values{}
+	q.Set("imported", fmt.Sprintf("%d", added))
+	q.Set("color", color)
+	http.Redirect(w, r, "/openings?"+q.Encode(), http.StatusSeeOther)
+}
+
+func (s *Server) handleClearRepertoire(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	profile := profileFromContext(r.Context())
+	if profile == nil {
+		log.Warn("no profile in context during repertoire clear")
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+
+	color := chi.URLParam(r, "color")
+	if err := s.RepertoireService.ClearRepertoire(r.Context(), profile.ID, color); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	log.Info("cleared %s repertoire for profile %d", color, profile.ID)
+	http.Redirect(w, r, "/openings", http.StatusSeeOther)
+}
@@ -15,32 +15,57 @@ func (s *Server) Routes() http.Handler {
 
 	r.Get("/", s.handleHome)
 	r.Post("/import", s.handleImport)
+	r.Get("/import/pgn", s.handleImportPGNPage)
+	r.Post("/import/pgn", s.handleImportPGN)
 	r.Post("/resume-analysis", s.handleResumeAnalysis)
 	r.Post("/stop-analysis", s.handleStopAnalysis)
 	r.Get("/analysis/queue", s.handleAnalysisQueuePage)
 	r.Post("/analysis/queue", s.handleQueueAnalysis)
 	r.Get("/api/analysis/queue/count", s.handleAnalysisQueueCount)
 	r.Get("/games", s.handleGames)
+	r.Post("/games/retry-failed", s.handleRetryFailedGames)
 	r.Get("/games/{id}", s.handleGameDetail)
 	r.Post("/games/{id}/queue-analysis", s.handleQueueGameAnalysis)
+	r.Post("/games/{id}/deepen-analysis", s.handleDeepenGameAnalysis)
 	r.Get("/flashcards", s.handleFlashcards)
 	r.Post("/flashcards/{id}/review", s.handleReviewFlashcard)
+	r.Post("/flashcards/{id}/move", s.handleFlashcardMove)
+	r.Post("/flashcards/{id}/tags", s.handleAddFlashcardTag)
+	r.Post("/flashcards/{id}/tags/remove", s.handleRemoveFlashcardTag)
+	r.Get("/flashcards/decks", s.handleDecks)
+	r.Post("/flashcards/decks", s.handleCreateDeck)
+	r.Post("/flashcards/decks/{id}/delete", s.handleDeleteDeck)
+	r.Get("/api/flashcards/session", s.handleReviewSession)
 	r.Get("/flashcards/analytics", s.handleFlashcardAnalytics)
+	r.Post("/flashcards/scheduler/optimize", s.handleStartFSRSOptimization)
+	r.Post("/flashcards/scheduler/optimizations/{id}/apply", s.handleApplyFSRSOptimization)
+	r.Get("/api/flashcards/scheduler/optimization", s.handleFSRSOptimizationStatus)
 	r.Get("/puzzle-rush", s.handlePuzzleRushPage)
 	r.Post("/puzzle-rush/start", s.handlePuzzleRushStart)
 	r.Post("/puzzle-rush/answer", s.handlePuzzleRushAnswer)
 	r.Get("/puzzle-rush/current", s.handlePuzzleRushCurrent)
 	r.Get("/puzzle-rush/stats", s.handlePuzzleRushStats)
+	r.Get("/drills/hanging", s.handleHangingDrillPage)
+	r.Get("/api/drills/hanging/next", s.handleNextHangingDrill)
+	r.Post("/api/drills/hanging/check", s.handleCheckHangingDrill)
 	r.Get("/api/evaluate", s.handleEvaluatePosition)
+	r.Get("/api/evaluate/stream", s.handleEvaluateStream)
 	r.Get("/api/analysis/status", s.handleAnalysisStatus)
 	r.Get("/analytics", s.handleAnalytics)
 	r.Post("/analytics/refresh", s.handleRefreshStats)
 	r.Get("/openings", s.handleOpenings)
+	r.Post("/openings/repertoire", s.handleImportRepertoire)
+	r.Post("/openings/repertoire/{color}/clear", s.handleClearRepertoire)
+	r.Get("/explorer", s.handleExplorer)
+	r.Get("/api/explorer", s.handleExplorerAPI)
 	r.Get("/opponents", s.handleOpponents)
 	r.Get("/stats", s.handleStats)
+	r.Get("/admin/jobs", s.handleJobs)
+	r.Post("/admin/jobs/{id}/retry", s.handleRetryJob)
 	r.Get("/profiles", s.handleProfiles)
 	r.Post("/profiles", s.handleCreateProfile)
 	r.Post("/profiles/{id}/select", s.handleSelectProfile)
+	r.Post("/profiles/{id}/scheduler", s.handleSetScheduler)
 	r.Post("/profiles/{id}/delete", s.handleDeleteProfile)
 
 	// Health check endpoints
@@ -88,14 +88,35 @@ func (s *Server) handleOpenings(w http.ResponseWriter, r *http.Request) {
 		totalPages = 1
 	}
 
+	repertoireSummary, err := s.RepertoireService.GetSummary(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	deviationStats, err := s.RepertoireService.GetDeviationStats(r.Context(), profile.ID, 10)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	deviations, err := s.RepertoireService.GetDeviations(r.Context(), profile.ID, 10)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
 	log.Debug("found %d opening stats (page %d of %d)", len(stats), page, totalPages)
 	s.render(w, r, "pages/openings.html", pageData{
-		"stats":       stats,
-		"profile":     profile,
-		"page":        page,
-		"per_page":    perPage,
-		"total_pages": totalPages,
-		"total_count": totalCount,
+		"stats":           stats,
+		"profile":         profile,
+		"page":            page,
+		"per_page":        perPage,
+		"total_pages":     totalPages,
+		"total_count":     totalCount,
+		"repertoire":      repertoireSummary,
+		"deviation_stats": deviationStats,
+		"deviations":      deviations,
+		"imported":        r.URL.Query().Get("imported"),
+		"imported_color":  r.URL.Query().Get("color"),
 	})
 }
 
@@ -217,6 +238,16 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		handleError(w, r, err)
 		return
 	}
+	accuracyTrend, err := s.StatsService.GetAccuracyOverTime(r.Context(), profile.ID, timeClass, dateCutoff)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	openingAccuracy, err := s.StatsService.GetAccuracyByOpening(r.Context(), profile.ID, timeClass, dateCutoff, 15)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
 	ratingStats, err := s.StatsService.GetRatingStats(r.Context(), profile.ID, timeClass, dateCutoff)
 	if err != nil {
 		handleError(w, r, err)
@@ -235,6 +266,8 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		"color_stats":           colorStats,
 		"monthly_stats":         monthlyStats,
 		"mistake_stats":         mistakeStats,
+		"accuracy_trend":        accuracyTrend,
+		"opening_accuracy":      openingAccuracy,
 		"rating_stats":          ratingStats,
 		"profile":               profile,
 		"time_class":            timeClass,
@@ -282,6 +315,12 @@ func (s *Server) handleFlashcardAnalytics(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	motifStats, err := s.StatsService.GetFlashcardMotifStats(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
 	phaseStats, err := s.StatsService.GetFlashcardPhaseStats(r.Context(), profile.ID)
 	if err != nil {
 		handleError(w, r, err)
@@ -300,9 +339,17 @@ func (s *Server) handleFlashcardAnalytics(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	optimization, err := s.FlashcardService.LatestFSRSOptimization(r.Context(), profile.ID)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
 	s.render(w, r, "pages/flashcard_analytics.html", pageData{
+		"fsrs_optimization":    optimization,
 		"overall_stats":        overallStats,
 		"classification_stats":  classificationStats,
+		"motif_stats":          motifStats,
 		"phase_stats":          phaseStats,
 		"opening_stats":        openingStats,
 		"time_stats":           timeStats,
@@ -2,9 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"net/url"
 	"path/filepath"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/worker"
 )
 
 func LoadTemplates() (*template.Template, error) {
@@ -27,6 +32,37 @@ func LoadTemplates() (*template.Template, error) {
 			}
 			return b
 		},
+		// percent converts a 0-1 ratio to a percentage
+		"percent": func(v float64) float64 { return v * 100 },
+		// classificationLabel turns a move classification such as "missed_mate" into display text
+		"classificationLabel": func(c string) string { return strings.ReplaceAll(c, "_", " ") },
+		// moveNumber formats a 1-based ply as "12." for white or "12..." for black
+		"moveNumber": func(ply int) string {
+			if ply%2 == 1 {
+				return fmt.Sprintf("%d.", (ply+1)/2)
+			}
+			return fmt.Sprintf("%d...", ply/2)
+		},
+		// sanMoves converts UCI moves that are each playable from fen to SAN, comma separated
+		"sanMoves": func(fen string, moves []string) string {
+			out := make([]string, 0, len(moves))
+			for _, m := range moves {
+				if san := analysis.UCIToSAN(fen, []string{m}); len(san) == 1 {
+					m = san[0]
+				}
+				out = append(out, m)
+			}
+			return strings.Join(out, ", ")
+		},
+		// jobPriority names the priority class stored on a job
+		"jobPriority": func(p int) string { return worker.Priority(p).String() },
+		// deref returns the value of an optional float, or 0 when unset
+		"deref": func(v *float64) float64 {
+			if v == nil {
+				return 0
+			}
+			return *v
+		},
 		// seq returns a sequence of integers from start to end inclusive.
 		"seq": func(start, end int) []int {
 			if end < start {
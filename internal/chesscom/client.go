@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
+	"github.com/vytor/chessflash/internal/gamesource"
 	"github.com/vytor/chessflash/internal/logger"
 )
 
@@ -58,22 +58,22 @@ func (c *Client) FetchArchives(ctx context.Context, username string) ([]string,
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		log.Error("failed to fetch archives: %v", err)
-		return nil, err
+		return nil, gamesource.RequestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	log.Debug("archives response received in %v, status=%d", time.Since(start), resp.StatusCode)
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		log.Error("archives request failed: status=%d, body=%s", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("archives status %d: %s", resp.StatusCode, string(body))
+		err := gamesource.ResponseError(resp, "archives")
+		log.Error("archives request failed: %v", err)
+		return nil, err
 	}
 
 	var out archivesResp
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		log.Error("failed to decode archives response: %v", err)
-		return nil, err
+		return nil, gamesource.RequestError(ctx, err)
 	}
 
 	log.Info("fetched %d archives for user %s", len(out.Archives), username)
@@ -95,16 +95,16 @@ func (c *Client) FetchMonthly(ctx context.Context, archiveURL string) ([]Monthly
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		log.Error("failed to fetch monthly games: %v", err)
-		return nil, err
+		return nil, gamesource.RequestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	log.Debug("monthly response received in %v, status=%d", time.Since(start), resp.StatusCode)
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		log.Error("monthly request failed: status=%d, body=%s", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("monthly status %d: %s", resp.StatusCode, string(body))
+		err := gamesource.ResponseError(resp, "monthly")
+		log.Error("monthly request failed: %v", err)
+		return nil, err
 	}
 
 	var payload struct {
@@ -112,7 +112,7 @@ func (c *Client) FetchMonthly(ctx context.Context, archiveURL string) ([]Monthly
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
 		log.Error("failed to decode monthly response: %v", err)
-		return nil, err
+		return nil, gamesource.RequestError(ctx, err)
 	}
 
 	log.Info("fetched %d games from archive", len(payload.Games))
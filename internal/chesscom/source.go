@@ -0,0 +1,195 @@
+package chesscom
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vytor/chessflash/internal/gamesource"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/pgn"
+	"github.com/vytor/chessflash/internal/retry"
+)
+
+const (
+	// monthlyAttempts bounds how often an archive is fetched when Chess.com
+	// rate limits or fails before the import gives up on it
+	monthlyAttempts = 4
+	// monthlyRetryBase and monthlyRetryLimit bound the backoff between tries
+	monthlyRetryBase  = 2 * time.Second
+	monthlyRetryLimit = 30 * time.Second
+)
+
+// Source adapts the Chess.com monthly archive API to gamesource.Source.
+type Source struct {
+	client        ClientInterface
+	archiveLimit  int
+	maxConcurrent int
+	retryBase     time.Duration
+	retryLimit    time.Duration
+}
+
+// NewSource creates a Chess.com game source. archiveLimit of 0 fetches all
+// archives; maxConcurrent bounds the number of archives fetched in parallel.
+func NewSource(client ClientInterface, archiveLimit, maxConcurrent int) *Source {
+	return &Source{
+		client:        client,
+		archiveLimit:  archiveLimit,
+		maxConcurrent: maxConcurrent,
+		retryBase:     monthlyRetryBase,
+		retryLimit:    monthlyRetryLimit,
+	}
+}
+
+// Ensure Source implements the interface
+var _ gamesource.Source = (*Source)(nil)
+
+func (s *Source) Platform() string { return gamesource.PlatformChessCom }
+
+// FetchGames fetches the user's monthly archives in parallel and passes every
+// game to fn. Archives are filtered by month, so games slightly older than
+// since may still be returned; callers dedupe by game id. Archives that fail
+// with a retryable error are fetched again with backoff; if one still fails,
+// the games of the others are passed on and its error is returned.
+func (s *Source) FetchGames(ctx context.Context, username string, since time.Time, fn func(gamesource.Game) error) error {
+	log := logger.FromContext(ctx).WithPrefix("chesscom").WithField("username", username)
+
+	archives, err := s.client.FetchArchives(ctx, username)
+	if err != nil {
+		log.Error("failed to fetch archives: %v", err)
+		return err
+	}
+
+	if !since.IsZero() {
+		archives = filterArchivesByDate(archives, since)
+		log.Info("filtered archives to %d based on last_sync_at", len(archives))
+	}
+
+	// ArchiveLimit of 0 means fetch all archives
+	if s.archiveLimit > 0 && len(archives) > s.archiveLimit {
+		archives = archives[len(archives)-s.archiveLimit:]
+		log.Debug("limiting to last %d archives", s.archiveLimit)
+	}
+	log.Info("fetching %d archives in parallel", len(archives))
+
+	maxConc := s.maxConcurrent
+	if maxConc <= 0 {
+		maxConc = 10
+	}
+	log.Debug("using %d concurrent workers for archive fetching", maxConc)
+
+	type archiveResult struct {
+		url   string
+		games []MonthlyGame
+		err   error
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan archiveResult, len(archives))
+	sem := make(chan struct{}, maxConc)
+
+	var wg sync.WaitGroup
+	for _, url := range archives {
+		wg.Add(1)
+		go func(archiveURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var monthly []MonthlyGame
+			err := retry.Do(fetchCtx, monthlyAttempts, s.retryBase, s.retryLimit, func() error {
+				var err error
+				monthly, err = s.client.FetchMonthly(fetchCtx, archiveURL)
+				return err
+			})
+			select {
+			case results <- archiveResult{url: archiveURL, games: monthly, err: err}:
+			case <-fetchCtx.Done():
+				return
+			}
+		}(url)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var fetchErr error
+	for res := range results {
+		if ctx.Err() != nil {
+			log.Warn("import cancelled: %v", ctx.Err())
+			return ctx.Err()
+		}
+		if res.err != nil {
+			log.Error("failed to fetch monthly games from %s: %v", res.url, res.err)
+			if fetchErr == nil {
+				fetchErr = fmt.Errorf("archive %s: %w", res.url, res.err)
+			}
+			continue
+		}
+		for _, mg := range res.games {
+			if err := fn(ToGame(mg)); err != nil {
+				return err
+			}
+		}
+	}
+	return fetchErr
+}
+
+// ToGame converts a Chess.com archive game to the platform-neutral format.
+func ToGame(mg MonthlyGame) gamesource.Game {
+	return gamesource.Game{
+		ID:        pgn.ExtractGameID(mg.URL),
+		URL:       mg.URL,
+		PGN:       mg.PGN,
+		TimeClass: mg.TimeClass,
+		EndTime:   time.Unix(mg.EndTime, 0),
+		White: gamesource.Player{
+			Username: mg.White.Username,
+			Rating:   mg.White.Rating,
+			Result:   NormalizeResult(mg.White.Result),
+		},
+		Black: gamesource.Player{
+			Username: mg.Black.Username,
+			Rating:   mg.Black.Rating,
+			Result:   NormalizeResult(mg.Black.Result),
+		},
+	}
+}
+
+// filterArchivesByDate keeps archives from the given month/year onwards.
+// Archive URLs look like: https://api.chess.com/pub/player/{username}/games/YYYY/MM
+func filterArchivesByDate(archives []string, since time.Time) []string {
+	if since.IsZero() {
+		return archives
+	}
+	sinceMonth := time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var filtered []string
+	for _, url := range archives {
+		parts := strings.Split(strings.TrimSuffix(url, "/"), "/")
+		if len(parts) < 2 {
+			continue
+		}
+		yearStr := parts[len(parts)-2]
+		monthStr := parts[len(parts)-1]
+
+		year, err1 := strconv.Atoi(yearStr)
+		monthInt, err2 := strconv.Atoi(monthStr)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		archiveMonth := time.Date(year, time.Month(monthInt), 1, 0, 0, 0, 0, time.UTC)
+		if archiveMonth.Before(sinceMonth) {
+			continue
+		}
+		filtered = append(filtered, url)
+	}
+	return filtered
+}
@@ -0,0 +1,97 @@
+package chesscom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/gamesource"
+)
+
+// fakeClient serves one game per archive and fails each archive with the
+// queued errors before succeeding.
+type fakeClient struct {
+	mu       sync.Mutex
+	archives []string
+	failures map[string][]error
+	calls    map[string]int
+}
+
+func (c *fakeClient) FetchArchives(ctx context.Context, username string) ([]string, error) {
+	return c.archives, nil
+}
+
+func (c *fakeClient) FetchMonthly(ctx context.Context, archiveURL string) ([]MonthlyGame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[archiveURL]++
+	if errs := c.failures[archiveURL]; len(errs) > 0 {
+		c.failures[archiveURL] = errs[1:]
+		return nil, errs[0]
+	}
+	return []MonthlyGame{{URL: "https://www.chess.com/game/live/" + archiveURL[len(archiveURL)-2:]}}, nil
+}
+
+func newTestSource(client *fakeClient) *Source {
+	s := NewSource(client, 0, 2)
+	s.retryBase = time.Millisecond
+	s.retryLimit = time.Millisecond
+	return s
+}
+
+func fetchAll(t *testing.T, s *Source) ([]gamesource.Game, error) {
+	t.Helper()
+	var games []gamesource.Game
+	err := s.FetchGames(context.Background(), "alice", time.Time{}, func(g gamesource.Game) error {
+		games = append(games, g)
+		return nil
+	})
+	return games, err
+}
+
+func TestFetchGames_RetriesRateLimitedArchive(t *testing.T) {
+	const jan, feb = "https://api.chess.com/pub/player/alice/games/2024/01", "https://api.chess.com/pub/player/alice/games/2024/02"
+	client := &fakeClient{
+		archives: []string{jan, feb},
+		failures: map[string][]error{
+			feb: {errors.NewRetryableError(errors.ReasonRateLimited, fmt.Errorf("status 429"))},
+		},
+		calls: map[string]int{},
+	}
+
+	games, err := fetchAll(t, newTestSource(client))
+	require.NoError(t, err)
+	assert.Len(t, games, 2)
+	assert.Equal(t, 1, client.calls[jan])
+	assert.Equal(t, 2, client.calls[feb])
+}
+
+func TestFetchGames_ReturnsErrorAfterOtherArchives(t *testing.T) {
+	const (
+		jan = "https://api.chess.com/pub/player/alice/games/2024/01"
+		feb = "https://api.chess.com/pub/player/alice/games/2024/02"
+		mar = "https://api.chess.com/pub/player/alice/games/2024/03"
+	)
+	unavailable := errors.NewRetryableError(errors.ReasonUpstreamError, fmt.Errorf("status 503"))
+	client := &fakeClient{
+		archives: []string{jan, feb, mar},
+		failures: map[string][]error{
+			jan: {unavailable, unavailable, unavailable, unavailable},
+			mar: {errors.NewPermanentError(errors.ReasonUpstreamError, fmt.Errorf("status 404"))},
+		},
+		calls: map[string]int{},
+	}
+
+	games, err := fetchAll(t, newTestSource(client))
+	require.Error(t, err)
+	assert.Equal(t, errors.ReasonUpstreamError, errors.FailureReason(err))
+	require.Len(t, games, 1, "games of the archive that loaded are still passed on")
+	assert.Equal(t, monthlyAttempts, client.calls[jan])
+	assert.Equal(t, 1, client.calls[feb])
+	assert.Equal(t, 1, client.calls[mar], "permanent errors are not retried")
+}
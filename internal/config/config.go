@@ -9,14 +9,34 @@ import (
 	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/vytor/chessflash/internal/analysis"
 )
 
+// DefaultEngine names the engine profile built from the STOCKFISH_* settings.
+const DefaultEngine = "stockfish"
+
+// EngineProfile configures a UCI engine analysis can run.
+type EngineProfile struct {
+	Name      string
+	Path      string
+	Options   map[string]string // UCI options applied with setoption, e.g. Threads, Hash, Skill Level
+	Depth     int
+	MaxTimeMs int // 0 = no limit
+	Instances int // engine processes kept running
+}
+
 type Config struct {
 	Addr                   string
 	DBPath                 string
 	StockfishPath          string
 	StockfishDepth         int
 	StockfishMaxTime       int // Max time in milliseconds per position (0 = no limit)
+	StockfishMultiPV       int // Number of candidate lines to analyze per position
+	StockfishOptions       map[string]string // UCI options for Stockfish, from STOCKFISH_OPTIONS
+	Engines                []EngineProfile // Extra engine profiles listed in ENGINES
+	AnalysisEngine         string // Profile used when a job does not pick one
+	MoveClassifier         string // How moves are classified: centipawn or win_probability
+	EvalCacheSize          int // Cached engine evaluations kept (0 = unbounded)
 	LogLevel               string
 	AnalysisWorkerCount    int
 	AnalysisQueueSize      int
@@ -32,12 +52,17 @@ func Load() Config {
 	// Ignore error so the app still starts when .env is absent in production.
 	_ = godotenv.Load()
 
-	return Config{
+	cfg := Config{
 		Addr:                   envOr("ADDR", ":8080"),
 		DBPath:                 envOr("DB_PATH", "file:chessflash.db"),
 		StockfishPath:          envOr("STOCKFISH_PATH", "stockfish"),
 		StockfishDepth:         envIntOr("STOCKFISH_DEPTH", 18),
 		StockfishMaxTime:       envIntOr("STOCKFISH_MAX_TIME", 0), // 0 = disabled, use depth only
+		StockfishMultiPV:       envIntOr("STOCKFISH_MULTIPV", 3),
+		StockfishOptions:       parseEngineOptions(os.Getenv("STOCKFISH_OPTIONS")),
+		AnalysisEngine:         envOr("ANALYSIS_ENGINE", DefaultEngine),
+		MoveClassifier:         envOr("MOVE_CLASSIFIER", analysis.ClassifierCentipawn),
+		EvalCacheSize:          envIntOr("EVAL_CACHE_SIZE", 200000),
 		LogLevel:               envOr("LOG_LEVEL", "INFO"),
 		AnalysisWorkerCount:    envIntOr("ANALYSIS_WORKER_COUNT", 2),
 		AnalysisQueueSize:      envIntOr("ANALYSIS_QUEUE_SIZE", 64),
@@ -46,6 +71,58 @@ func Load() Config {
 		ArchiveLimit:           envIntOr("ARCHIVE_LIMIT", 0),
 		MaxConcurrentArchive:   envIntOr("MAX_CONCURRENT_ARCHIVE", 10),
 	}
+	cfg.Engines = loadEngineProfiles(cfg)
+	return cfg
+}
+
+// loadEngineProfiles reads the profiles named in ENGINES (comma separated).
+// Each profile NAME is configured by ENGINE_NAME_PATH, ENGINE_NAME_OPTIONS,
+// ENGINE_NAME_DEPTH, ENGINE_NAME_MAX_TIME and ENGINE_NAME_INSTANCES, with
+// depth and time defaulting to the Stockfish settings.
+func loadEngineProfiles(cfg Config) []EngineProfile {
+	var profiles []EngineProfile
+	for _, name := range strings.Split(os.Getenv("ENGINES"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "ENGINE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		profiles = append(profiles, EngineProfile{
+			Name:      name,
+			Path:      os.Getenv(prefix + "PATH"),
+			Options:   parseEngineOptions(os.Getenv(prefix + "OPTIONS")),
+			Depth:     envIntOr(prefix+"DEPTH", cfg.StockfishDepth),
+			MaxTimeMs: envIntOr(prefix+"MAX_TIME", cfg.StockfishMaxTime),
+			Instances: envIntOr(prefix+"INSTANCES", 1),
+		})
+	}
+	return profiles
+}
+
+// parseEngineOptions parses "Threads=4,Hash=256,Skill Level=10" into UCI
+// option values. An entry without "=" is a button option.
+func parseEngineOptions(s string) map[string]string {
+	options := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		name, value, _ := strings.Cut(entry, "=")
+		if name = strings.TrimSpace(name); name != "" {
+			options[name] = strings.TrimSpace(value)
+		}
+	}
+	return options
+}
+
+// EngineProfiles returns every configured engine, the Stockfish one first.
+func (c Config) EngineProfiles() []EngineProfile {
+	profiles := []EngineProfile{{
+		Name:      DefaultEngine,
+		Path:      c.StockfishPath,
+		Options:   c.StockfishOptions,
+		Depth:     c.StockfishDepth,
+		MaxTimeMs: c.StockfishMaxTime,
+		Instances: c.AnalysisWorkerCount,
+	}}
+	return append(profiles, c.Engines...)
 }
 
 func envOr(key, def string) string {
@@ -92,6 +169,19 @@ func (c Config) Validate() error {
 		errs = append(errs, fmt.Sprintf("STOCKFISH_DEPTH must be 1-30, got %d", c.StockfishDepth))
 	}
 
+	if c.StockfishMultiPV < 0 || c.StockfishMultiPV > 10 {
+		errs = append(errs, fmt.Sprintf("STOCKFISH_MULTIPV must be 0-10, got %d", c.StockfishMultiPV))
+	}
+
+	// Empty falls back to the centipawn classifier
+	if c.MoveClassifier != "" && !analysis.IsValidClassifier(c.MoveClassifier) {
+		errs = append(errs, fmt.Sprintf("MOVE_CLASSIFIER must be %s, got %q", strings.Join(analysis.Classifiers, "/"), c.MoveClassifier))
+	}
+
+	if c.EvalCacheSize < 0 {
+		errs = append(errs, fmt.Sprintf("EVAL_CACHE_SIZE must be >= 0, got %d", c.EvalCacheSize))
+	}
+
 	if c.AnalysisWorkerCount < 1 {
 		errs = append(errs, fmt.Sprintf("ANALYSIS_WORKER_COUNT must be >= 1, got %d", c.AnalysisWorkerCount))
 	}
@@ -112,6 +202,38 @@ func (c Config) Validate() error {
 		errs = append(errs, fmt.Sprintf("MAX_CONCURRENT_ARCHIVE must be >= 1, got %d", c.MaxConcurrentArchive))
 	}
 
+	// Validate extra engine profiles; the Stockfish one is checked above
+	names := map[string]bool{DefaultEngine: true}
+	for _, e := range c.Engines {
+		switch {
+		case e.Name == "":
+			errs = append(errs, "ENGINES cannot contain an empty name")
+			continue
+		case names[e.Name]:
+			errs = append(errs, fmt.Sprintf("ENGINES lists %q more than once", e.Name))
+			continue
+		}
+		names[e.Name] = true
+
+		if e.Path == "" {
+			errs = append(errs, fmt.Sprintf("engine %q needs a path", e.Name))
+		} else if _, err := exec.LookPath(e.Path); err != nil {
+			errs = append(errs, fmt.Sprintf("engine %q path %q not found or not executable", e.Name, e.Path))
+		}
+		if e.Depth < 1 || e.Depth > 30 {
+			errs = append(errs, fmt.Sprintf("engine %q depth must be 1-30, got %d", e.Name, e.Depth))
+		}
+		if e.MaxTimeMs < 0 {
+			errs = append(errs, fmt.Sprintf("engine %q max time must be >= 0, got %d", e.Name, e.MaxTimeMs))
+		}
+		if e.Instances < 1 {
+			errs = append(errs, fmt.Sprintf("engine %q instances must be >= 1, got %d", e.Name, e.Instances))
+		}
+	}
+	if c.AnalysisEngine != "" && !names[c.AnalysisEngine] {
+		errs = append(errs, fmt.Sprintf("ANALYSIS_ENGINE %q is not a configured engine", c.AnalysisEngine))
+	}
+
 	// Validate log level
 	validLogLevels := map[string]bool{"DEBUG": true, "INFO": true, "WARN": true, "ERROR": true}
 	if !validLogLevels[strings.ToUpper(c.LogLevel)] {
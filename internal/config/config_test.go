@@ -329,6 +329,44 @@ func TestValidate_InvalidMaxConcurrentArchive(t *testing.T) {
 	assert.Contains(t, err.Error(), "MAX_CONCURRENT_ARCHIVE")
 }
 
+func TestValidate_MoveClassifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		classifier string
+		wantErr    bool
+	}{
+		{name: "centipawn", classifier: "centipawn"},
+		{name: "win probability", classifier: "win_probability"},
+		{name: "empty uses default", classifier: ""},
+		{name: "unknown classifier", classifier: "engine", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{
+				Addr:                 ":8080",
+				DBPath:               "test.db",
+				StockfishDepth:       18,
+				MoveClassifier:       tt.classifier,
+				LogLevel:             "INFO",
+				AnalysisWorkerCount:  2,
+				AnalysisQueueSize:    64,
+				ImportWorkerCount:    2,
+				ImportQueueSize:      32,
+				MaxConcurrentArchive: 10,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "MOVE_CLASSIFIER")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestValidate_MultipleErrors(t *testing.T) {
 	cfg := config.Config{
 		Addr:        "",
@@ -422,3 +460,63 @@ func TestLoad_EnvironmentVariables(t *testing.T) {
 	assert.Equal(t, ":9090", cfg.Addr)
 	assert.Equal(t, "custom.db", cfg.DBPath)
 }
+
+func TestLoad_EngineProfiles(t *testing.T) {
+	t.Setenv("STOCKFISH_DEPTH", "16")
+	t.Setenv("STOCKFISH_OPTIONS", "Threads=4, Hash=256")
+	t.Setenv("ENGINES", "lc0, weak-fish")
+	t.Setenv("ENGINE_LC0_PATH", "/usr/bin/lc0")
+	t.Setenv("ENGINE_LC0_INSTANCES", "2")
+	t.Setenv("ENGINE_WEAK_FISH_PATH", "stockfish")
+	t.Setenv("ENGINE_WEAK_FISH_OPTIONS", "Skill Level=3,Clear Hash")
+	t.Setenv("ENGINE_WEAK_FISH_DEPTH", "8")
+	t.Setenv("ANALYSIS_ENGINE", "weak-fish")
+
+	cfg := config.Load()
+
+	assert.Equal(t, "weak-fish", cfg.AnalysisEngine)
+	profiles := cfg.EngineProfiles()
+	require.Len(t, profiles, 3)
+	assert.Equal(t, config.DefaultEngine, profiles[0].Name)
+	assert.Equal(t, map[string]string{"Threads": "4", "Hash": "256"}, profiles[0].Options)
+	assert.Equal(t, cfg.AnalysisWorkerCount, profiles[0].Instances)
+
+	assert.Equal(t, config.EngineProfile{
+		Name: "lc0", Path: "/usr/bin/lc0", Options: map[string]string{}, Depth: 16, Instances: 2,
+	}, profiles[1])
+	assert.Equal(t, config.EngineProfile{
+		Name: "weak-fish", Path: "stockfish", Options: map[string]string{"Skill Level": "3", "Clear Hash": ""}, Depth: 8, Instances: 1,
+	}, profiles[2])
+}
+
+func TestValidate_EngineProfiles(t *testing.T) {
+	cfg := config.Config{
+		Addr:                 ":8080",
+		DBPath:               "test.db",
+		StockfishDepth:       18,
+		LogLevel:             "INFO",
+		AnalysisWorkerCount:  2,
+		AnalysisQueueSize:    64,
+		ImportWorkerCount:    2,
+		ImportQueueSize:      32,
+		MaxConcurrentArchive: 10,
+		Engines: []config.EngineProfile{
+			{Name: "weak", Path: "sh", Depth: 8, Instances: 1},
+		},
+		AnalysisEngine: "weak",
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AnalysisEngine = "lc0"
+	cfg.Engines = append(cfg.Engines,
+		config.EngineProfile{Name: "weak", Path: "sh", Depth: 8, Instances: 1},
+		config.EngineProfile{Name: "broken", Path: "nonexistent-engine-12345", Depth: 40, Instances: 0},
+	)
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `ANALYSIS_ENGINE "lc0"`)
+	assert.Contains(t, err.Error(), `"weak" more than once`)
+	assert.Contains(t, err.Error(), `engine "broken" path`)
+	assert.Contains(t, err.Error(), `engine "broken" depth`)
+	assert.Contains(t, err.Error(), `engine "broken" instances`)
+}
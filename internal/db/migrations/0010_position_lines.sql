@@ -0,0 +1,13 @@
+-- Alternative engine lines (MultiPV) for analyzed positions
+CREATE TABLE IF NOT EXISTS position_lines (
+    id INTEGER PRIMARY KEY,
+    position_id INTEGER NOT NULL REFERENCES positions(id) ON DELETE CASCADE,
+    rank INTEGER NOT NULL, -- 1 = best line
+    move TEXT NOT NULL, -- first move of the line in UCI notation
+    cp REAL, -- centipawns (+ white advantage), only when mate is NULL
+    mate INTEGER, -- mate in N (+ white mates)
+    pv TEXT, -- space-separated UCI moves
+    UNIQUE(position_id, rank)
+);
+
+CREATE INDEX IF NOT EXISTS idx_position_lines_position_id ON position_lines(position_id);
@@ -0,0 +1,2 @@
+-- Principal variation of the best line for analyzed positions
+ALTER TABLE positions ADD COLUMN pv TEXT; -- space-separated UCI moves, starting with best_move
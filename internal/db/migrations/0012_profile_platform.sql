@@ -0,0 +1,2 @@
+-- Platform the profile's username belongs to (chesscom, lichess)
+ALTER TABLE profiles ADD COLUMN platform TEXT NOT NULL DEFAULT 'chesscom';
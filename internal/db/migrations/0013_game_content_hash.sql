@@ -0,0 +1,5 @@
+-- Content hash for games uploaded as PGN, used for deduplication since they
+-- have no platform game id
+ALTER TABLE games ADD COLUMN content_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_games_profile_content_hash ON games(profile_id, content_hash) WHERE content_hash IS NOT NULL;
@@ -0,0 +1,28 @@
+-- Prepared opening moves per profile and color. fen is normalized (no move
+-- counters) so transpositions share entries; move is UCI.
+CREATE TABLE IF NOT EXISTS repertoire_moves (
+    id INTEGER PRIMARY KEY,
+    profile_id INTEGER NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    color TEXT NOT NULL, -- white, black
+    fen TEXT NOT NULL,
+    move TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(profile_id, color, fen, move)
+);
+
+-- First ply of an analyzed game that left the player's repertoire
+CREATE TABLE IF NOT EXISTS repertoire_deviations (
+    game_id INTEGER PRIMARY KEY REFERENCES games(id) ON DELETE CASCADE,
+    profile_id INTEGER NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    ply INTEGER NOT NULL,
+    deviated_by TEXT NOT NULL, -- player, opponent
+    fen TEXT NOT NULL,
+    move_played TEXT NOT NULL,
+    expected_moves TEXT NOT NULL, -- space separated UCI moves
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_repertoire_deviations_profile ON repertoire_deviations(profile_id, deviated_by);
+
+-- Repertoire drill cards are answered with the prepared moves instead of the engine's
+ALTER TABLE flashcards ADD COLUMN kind TEXT NOT NULL DEFAULT 'engine';
@@ -0,0 +1,15 @@
+-- Opening moves of every imported game, keyed by the normalized FEN before the
+-- move, for the opening explorer
+CREATE TABLE IF NOT EXISTS opening_tree_moves (
+    game_id INTEGER NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    ply INTEGER NOT NULL,
+    fen TEXT NOT NULL,
+    move TEXT NOT NULL, -- UCI
+    san TEXT NOT NULL,
+    PRIMARY KEY (game_id, ply)
+);
+
+CREATE INDEX IF NOT EXISTS idx_opening_tree_moves_fen ON opening_tree_moves(fen);
+
+-- Set once a game's moves are in the opening tree (even when its PGN had none)
+ALTER TABLE games ADD COLUMN opening_tree_indexed INTEGER NOT NULL DEFAULT 0;
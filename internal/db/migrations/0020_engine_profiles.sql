@@ -0,0 +1,13 @@
+-- Evaluations depend on the engine that produced them. Cached entries predate
+-- engine profiles and cannot be attributed, so the cache starts over.
+DROP TABLE IF EXISTS eval_cache;
+
+CREATE TABLE eval_cache (
+    engine TEXT NOT NULL,
+    fen TEXT NOT NULL,
+    depth INTEGER NOT NULL,
+    multipv INTEGER NOT NULL,
+    result TEXT NOT NULL, -- JSON encoded analysis.EvalResult
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (engine, fen, depth, multipv)
+);
@@ -0,0 +1,6 @@
+-- Search depth each position was evaluated at (0 when analyzed before it was recorded)
+ALTER TABLE positions ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+-- Re-analysis retires flashcards whose move turned out fine instead of
+-- deleting them, so their review history survives if the verdict flips back
+ALTER TABLE flashcards ADD COLUMN retired_at DATETIME;
@@ -0,0 +1,23 @@
+-- Durable background jobs, claimed by the worker pools under a lease
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    queue TEXT NOT NULL, -- worker pool that runs the job: analysis or import
+    kind TEXT NOT NULL,
+    payload TEXT NOT NULL, -- JSON encoded job arguments
+    state TEXT NOT NULL DEFAULT 'queued', -- queued, running, completed or failed
+    priority INTEGER NOT NULL DEFAULT 0, -- higher runs first
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    run_at DATETIME NOT NULL, -- not claimed before this time
+    lease_owner TEXT, -- token of the worker holding a running job
+    lease_expires_at DATETIME, -- a running job past this time is claimed again
+    last_error TEXT,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    completed_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_claim ON jobs(queue, state, priority DESC, run_at);
+
+-- The same job is queued at most once at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_active ON jobs(kind, payload) WHERE state IN ('queued', 'running');
@@ -0,0 +1,9 @@
+-- Tactical motifs (fork, pin, skewer, ...) detected in a flashcard's best move
+-- and engine line when the card is created, or when deepening changes them
+CREATE TABLE IF NOT EXISTS flashcard_motifs (
+    flashcard_id INTEGER NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    motif TEXT NOT NULL,
+    PRIMARY KEY (flashcard_id, motif)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flashcard_motifs_motif ON flashcard_motifs(motif);
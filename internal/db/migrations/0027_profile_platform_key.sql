@@ -0,0 +1,24 @@
+-- The same username on chess.com and Lichess can be two different players, so
+-- profiles are unique per (username, platform). SQLite cannot drop the UNIQUE
+-- on username in place, so the table is rebuilt with foreign keys off to keep
+-- dropping the old table from cascading into games and flashcards.
+PRAGMA foreign_keys = OFF;
+
+CREATE TABLE profiles_new (
+    id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_sync_at DATETIME,
+    platform TEXT NOT NULL DEFAULT 'chesscom',
+    scheduler TEXT NOT NULL DEFAULT 'sm2',
+    fsrs_weights TEXT,
+    UNIQUE (username, platform)
+);
+
+INSERT INTO profiles_new (id, username, created_at, last_sync_at, platform, scheduler, fsrs_weights)
+SELECT id, username, created_at, last_sync_at, platform, scheduler, fsrs_weights FROM profiles;
+
+DROP TABLE profiles;
+ALTER TABLE profiles_new RENAME TO profiles;
+
+PRAGMA foreign_keys = ON;
@@ -0,0 +1,6 @@
+-- Set once a position's hung material was computed (even when it is 0), so
+-- errors analyzed before material_hung existed are checked by a startup pass
+ALTER TABLE positions ADD COLUMN material_hung_checked INTEGER NOT NULL DEFAULT 0;
+
+UPDATE positions SET material_hung_checked = 1
+WHERE material_hung > 0 OR classification NOT IN ('inaccuracy', 'mistake', 'blunder', 'allowed_mate');
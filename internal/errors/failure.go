@@ -0,0 +1,72 @@
+package errors
+
+import (
+	stderrors "errors"
+	"time"
+)
+
+// Failure reasons recorded when background work cannot finish
+const (
+	ReasonRateLimited       = "rate_limited"
+	ReasonUpstreamError     = "upstream_error"
+	ReasonNetworkError      = "network_error"
+	ReasonEngineUnavailable = "engine_unavailable"
+	ReasonEngineError       = "engine_error"
+	ReasonInvalidPGN        = "invalid_pgn"
+	ReasonStorageError      = "storage_error"
+	ReasonUnknown           = "unknown"
+)
+
+// FailureError is a failure of background work, with a reason that is stored
+// and shown to the user. Retryable failures are worth trying again later, no
+// sooner than RetryAfter when the other side asked for a delay.
+type FailureError struct {
+	Reason     string
+	Retryable  bool
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *FailureError) Error() string {
+	return e.Reason + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error for error wrapping support
+func (e *FailureError) Unwrap() error {
+	return e.Err
+}
+
+// NewRetryableError creates a FailureError that may succeed when retried
+func NewRetryableError(reason string, err error) *FailureError {
+	return &FailureError{Reason: reason, Retryable: true, Err: err}
+}
+
+// NewPermanentError creates a FailureError that retrying will not fix
+func NewPermanentError(reason string, err error) *FailureError {
+	return &FailureError{Reason: reason, Err: err}
+}
+
+// IsRetryable reports whether err is, or wraps, a retryable FailureError.
+func IsRetryable(err error) bool {
+	var fe *FailureError
+	return stderrors.As(err, &fe) && fe.Retryable
+}
+
+// RetryAfter returns the delay a retryable error asked for, or 0.
+func RetryAfter(err error) time.Duration {
+	var fe *FailureError
+	if stderrors.As(err, &fe) {
+		return fe.RetryAfter
+	}
+	return 0
+}
+
+// FailureReason returns the reason of a FailureError, or ReasonUnknown for
+// any other error.
+func FailureReason(err error) string {
+	var fe *FailureError
+	if stderrors.As(err, &fe) {
+		return fe.Reason
+	}
+	return ReasonUnknown
+}
@@ -0,0 +1,65 @@
+package explorer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// MaxPly is how deep into each game the opening tree goes.
+const MaxPly = 30
+
+// StartFEN is the normalized starting position, the root of the tree.
+var StartFEN = analysis.NormalizeFEN(chess.StartingPosition().String())
+
+// ExtractMoves returns the first maxPly moves of the game's main line, each
+// keyed by the normalized FEN before it so transpositions meet in the tree.
+func ExtractMoves(gameID int64, pgnText string, maxPly int) ([]models.OpeningTreeMove, error) {
+	opt, err := chess.PGN(strings.NewReader(pgnText))
+	if err != nil {
+		return nil, fmt.Errorf("parse pgn: %w", err)
+	}
+	game := chess.NewGame(opt)
+
+	positions := game.Positions()
+	var moves []models.OpeningTreeMove
+	for i, move := range game.Moves() {
+		if i >= maxPly || i >= len(positions) {
+			break
+		}
+		pos := positions[i]
+		moves = append(moves, models.OpeningTreeMove{
+			GameID: gameID,
+			Ply:    i + 1,
+			FEN:    analysis.NormalizeFEN(pos.String()),
+			Move:   analysis.MoveToUCI(move),
+			SAN:    chess.AlgebraicNotation{}.Encode(pos, move),
+		})
+	}
+	return moves, nil
+}
+
+// Replay plays the UCI moves from the starting position and returns the
+// normalized FEN reached along with the moves in SAN.
+func Replay(moves []string) (string, []string, error) {
+	pos := chess.StartingPosition()
+	san := make([]string, 0, len(moves))
+	for i, uci := range moves {
+		var found *chess.Move
+		for _, m := range pos.ValidMoves() {
+			if analysis.MoveToUCI(&m) == uci {
+				found = &m
+				break
+			}
+		}
+		if found == nil {
+			return "", nil, fmt.Errorf("move %d (%s) is not legal", i+1, uci)
+		}
+		san = append(san, chess.AlgebraicNotation{}.Encode(pos, found))
+		pos = pos.Update(found)
+	}
+	return analysis.NormalizeFEN(pos.String()), san, nil
+}
@@ -0,0 +1,59 @@
+package explorer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/explorer"
+)
+
+const gamePGN = `[Event "Live Chess"]
+[White "me"]
+[Black "them"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 1-0
+`
+
+func TestExtractMoves(t *testing.T) {
+	moves, err := explorer.ExtractMoves(7, gamePGN, explorer.MaxPly)
+	require.NoError(t, err)
+	require.Len(t, moves, 6)
+
+	assert.Equal(t, int64(7), moves[0].GameID)
+	assert.Equal(t, 1, moves[0].Ply)
+	assert.Equal(t, explorer.StartFEN, moves[0].FEN)
+	assert.Equal(t, "e2e4", moves[0].Move)
+	assert.Equal(t, "e4", moves[0].SAN)
+
+	// The double push leaves no capturable en passant square, so it is dropped
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -", moves[1].FEN)
+	assert.Equal(t, "Nf3", moves[2].SAN)
+	assert.Equal(t, 6, moves[5].Ply)
+}
+
+func TestExtractMoves_MaxPly(t *testing.T) {
+	moves, err := explorer.ExtractMoves(1, gamePGN, 2)
+	require.NoError(t, err)
+	require.Len(t, moves, 2)
+	assert.Equal(t, "e7e5", moves[1].Move)
+}
+
+func TestReplay(t *testing.T) {
+	moves, err := explorer.ExtractMoves(1, gamePGN, explorer.MaxPly)
+	require.NoError(t, err)
+
+	fen, san, err := explorer.Replay([]string{"e2e4", "e7e5", "g1f3"})
+	require.NoError(t, err)
+	assert.Equal(t, moves[3].FEN, fen)
+	assert.Equal(t, []string{"e4", "e5", "Nf3"}, san)
+
+	fen, san, err = explorer.Replay(nil)
+	require.NoError(t, err)
+	assert.Equal(t, explorer.StartFEN, fen)
+	assert.Empty(t, san)
+
+	_, _, err = explorer.Replay([]string{"e2e4", "e2e4"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package flashcard
+
+import (
+	"strings"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// DefaultAlternativeToleranceCP is how far (in centipawns, from the mover's
+// perspective) an alternative line may fall behind the best line and still be
+// accepted as a correct answer.
+const DefaultAlternativeToleranceCP = 30
+
+// mateScore is the score assigned to a forced mate so it sorts above any
+// centipawn evaluation. Shorter mates score higher.
+const mateScore = 100000
+
+// AcceptableMoves returns the UCI moves that count as a correct answer for the
+// position: the best move plus every alternative line within toleranceCP of the
+// best line. The move actually played in the game is never accepted.
+func AcceptableMoves(fen, bestMove, movePlayed string, lines []models.PositionLine, toleranceCP float64) []string {
+	moves := []string{}
+	seen := map[string]bool{}
+	add := func(m string) {
+		if m == "" || seen[m] || m == movePlayed {
+			return
+		}
+		seen[m] = true
+		moves = append(moves, m)
+	}
+
+	add(bestMove)
+	if len(lines) == 0 {
+		return moves
+	}
+
+	whiteToMove := sideToMoveIsWhite(fen)
+	bestScore := moverScore(lines[0], whiteToMove)
+	for _, l := range lines {
+		score := moverScore(l, whiteToMove)
+		if bestScore >= mateScore-1000 {
+			// Winning a forced mate: any line that still mates is fine
+			if score >= mateScore-1000 {
+				add(l.Move)
+			}
+			continue
+		}
+		if bestScore-score <= toleranceCP {
+			add(l.Move)
+		}
+	}
+	return moves
+}
+
+// IsAcceptableMove reports whether move is one of the acceptable answers.
+func IsAcceptableMove(move string, acceptable []string) bool {
+	move = strings.ToLower(strings.TrimSpace(move))
+	if move == "" {
+		return false
+	}
+	for _, m := range acceptable {
+		if strings.ToLower(m) == move {
+			return true
+		}
+	}
+	return false
+}
+
+// moverScore converts a white-perspective line score to the side to move.
+func moverScore(l models.PositionLine, whiteToMove bool) float64 {
+	var score float64
+	if l.Mate != nil {
+		m := *l.Mate
+		if m > 0 {
+			score = float64(mateScore - m)
+		} else {
+			score = float64(-mateScore - m)
+		}
+	} else {
+		score = l.CP
+	}
+	if !whiteToMove {
+		score = -score
+	}
+	return score
+}
+
+func sideToMoveIsWhite(fen string) bool {
+	parts := strings.Fields(fen)
+	return len(parts) < 2 || parts[1] != "b"
+}
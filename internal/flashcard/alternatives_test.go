@@ -0,0 +1,98 @@
+package flashcard_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+const (
+	whiteToMoveFEN = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	blackToMoveFEN = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestAcceptableMoves(t *testing.T) {
+	tests := []struct {
+		name       string
+		fen        string
+		bestMove   string
+		movePlayed string
+		lines      []models.PositionLine
+		expected   []string
+	}{
+		{
+			name:       "no lines falls back to best move",
+			fen:        whiteToMoveFEN,
+			bestMove:   "f1b5",
+			movePlayed: "a2a3",
+			expected:   []string{"f1b5"},
+		},
+		{
+			name:       "white alternatives within tolerance",
+			fen:        whiteToMoveFEN,
+			bestMove:   "f1b5",
+			movePlayed: "a2a3",
+			lines: []models.PositionLine{
+				{Rank: 1, Move: "f1b5", CP: 40},
+				{Rank: 2, Move: "f1c4", CP: 25},
+				{Rank: 3, Move: "d2d4", CP: 5},
+			},
+			expected: []string{"f1b5", "f1c4"},
+		},
+		{
+			name:       "black alternatives use mover perspective",
+			fen:        blackToMoveFEN,
+			bestMove:   "c7c5",
+			movePlayed: "a7a6",
+			lines: []models.PositionLine{
+				{Rank: 1, Move: "c7c5", CP: 20},
+				{Rank: 2, Move: "e7e5", CP: 35},
+				{Rank: 3, Move: "g8f6", CP: 60},
+			},
+			expected: []string{"c7c5", "e7e5"},
+		},
+		{
+			name:       "move played is never accepted",
+			fen:        whiteToMoveFEN,
+			bestMove:   "f1b5",
+			movePlayed: "f1c4",
+			lines: []models.PositionLine{
+				{Rank: 1, Move: "f1b5", CP: 40},
+				{Rank: 2, Move: "f1c4", CP: 35},
+			},
+			expected: []string{"f1b5"},
+		},
+		{
+			name:       "any forced mate is accepted",
+			fen:        whiteToMoveFEN,
+			bestMove:   "d1h5",
+			movePlayed: "a2a3",
+			lines: []models.PositionLine{
+				{Rank: 1, Move: "d1h5", Mate: intPtr(2)},
+				{Rank: 2, Move: "f3g5", Mate: intPtr(4)},
+				{Rank: 3, Move: "f1c4", CP: 900},
+			},
+			expected: []string{"d1h5", "f3g5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := flashcard.AcceptableMoves(tt.fen, tt.bestMove, tt.movePlayed, tt.lines, flashcard.DefaultAlternativeToleranceCP)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestIsAcceptableMove(t *testing.T) {
+	acceptable := []string{"e2e4", "d2d4"}
+
+	assert.True(t, flashcard.IsAcceptableMove("e2e4", acceptable))
+	assert.True(t, flashcard.IsAcceptableMove(" D2D4 ", acceptable))
+	assert.False(t, flashcard.IsAcceptableMove("g1f3", acceptable))
+	assert.False(t, flashcard.IsAcceptableMove("", acceptable))
+}
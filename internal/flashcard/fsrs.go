@@ -0,0 +1,211 @@
+package flashcard
+
+import (
+	"math"
+	"time"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// FSRS-4.5 forgetting curve constants. With these, the interval equals the
+// stability when the desired retention is 90%.
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+)
+
+const (
+	minDifficulty = 1.0
+	maxDifficulty = 10.0
+	minStability  = 0.1
+)
+
+// defaultFSRSWeights are the published FSRS-4.5 default parameters.
+var defaultFSRSWeights = []float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// FSRSWeightCount is the number of weights the FSRS-4.5 model expects.
+const FSRSWeightCount = 17
+
+// FSRSParams configures the FSRS scheduler.
+type FSRSParams struct {
+	Weights             []float64
+	DesiredRetention    float64
+	MaximumIntervalDays int
+}
+
+// DefaultFSRSParams returns the default FSRS-4.5 weights with 90% desired retention.
+func DefaultFSRSParams() FSRSParams {
+	w := make([]float64, len(defaultFSRSWeights))
+	copy(w, defaultFSRSWeights)
+	return FSRSParams{
+		Weights:             w,
+		DesiredRetention:    0.9,
+		MaximumIntervalDays: 36500,
+	}
+}
+
+// FSRSParamsFromWeights returns the default parameters with weights replaced
+// when a full set is given, e.g. weights fitted to a profile's review log.
+func FSRSParamsFromWeights(weights []float64) FSRSParams {
+	params := DefaultFSRSParams()
+	if len(weights) == FSRSWeightCount {
+		params.Weights = append([]float64(nil), weights...)
+	}
+	return params
+}
+
+// FSRS schedules reviews with the Free Spaced Repetition Scheduler, tracking
+// stability (days until recall probability drops to 90%) and difficulty (1-10)
+// per card.
+type FSRS struct {
+	params FSRSParams
+}
+
+// NewFSRS creates a FSRS scheduler. Invalid parameters fall back to the defaults.
+func NewFSRS(params FSRSParams) *FSRS {
+	defaults := DefaultFSRSParams()
+	if len(params.Weights) != FSRSWeightCount {
+		params.Weights = defaults.Weights
+	}
+	if params.DesiredRetention <= 0 || params.DesiredRetention >= 1 {
+		params.DesiredRetention = defaults.DesiredRetention
+	}
+	if params.MaximumIntervalDays <= 0 {
+		params.MaximumIntervalDays = defaults.MaximumIntervalDays
+	}
+	return &FSRS{params: params}
+}
+
+// Name implements Scheduler.
+func (f *FSRS) Name() string { return SchedulerFSRS }
+
+// Schedule implements Scheduler.
+func (f *FSRS) Schedule(card models.Flashcard, quality int, now time.Time) models.Flashcard {
+	card = f.review(card, quality, now)
+
+	card.TimesReviewed++
+	if quality >= 2 {
+		card.TimesCorrect++
+	} else {
+		card.TimesCorrect = 0
+	}
+	return card
+}
+
+// Replay rebuilds the card's memory state from its review history, oldest
+// review first. Review counters are left untouched.
+func (f *FSRS) Replay(card models.Flashcard, history []models.ReviewHistory) models.Flashcard {
+	card.Stability = 0
+	card.Difficulty = 0
+	card.LastReviewedAt = nil
+	for _, h := range history {
+		card = f.review(card, h.Quality, h.ReviewedAt)
+	}
+	return card
+}
+
+// Retrievability returns the probability of recalling the card at now, or 0
+// when the card has no FSRS memory state yet.
+func Retrievability(card models.Flashcard, now time.Time) float64 {
+	if card.Stability <= 0 || card.LastReviewedAt == nil {
+		return 0
+	}
+	return forgettingCurve(elapsedDays(*card.LastReviewedAt, now), card.Stability)
+}
+
+func (f *FSRS) review(card models.Flashcard, quality int, now time.Time) models.Flashcard {
+	w := f.params.Weights
+	rating := ratingFromQuality(quality)
+
+	if card.Stability <= 0 || card.LastReviewedAt == nil {
+		card.Stability = math.Max(w[rating-1], minStability)
+		card.Difficulty = f.initDifficulty(rating)
+	} else {
+		r := forgettingCurve(elapsedDays(*card.LastReviewedAt, now), card.Stability)
+		if rating == 1 {
+			card.Stability = f.forgetStability(card.Difficulty, card.Stability, r)
+		} else {
+			card.Stability = f.recallStability(card.Difficulty, card.Stability, r, rating)
+		}
+		card.Difficulty = f.nextDifficulty(card.Difficulty, rating)
+	}
+
+	interval := f.nextInterval(card.Stability)
+	card.IntervalDays = interval
+	card.DueAt = now.Add(time.Duration(interval) * 24 * time.Hour)
+	card.LastReviewedAt = &now
+	return card
+}
+
+// ratingFromQuality maps review quality (0=Again .. 3=Easy) to FSRS ratings (1..4).
+func ratingFromQuality(quality int) int {
+	switch {
+	case quality <= 0:
+		return 1
+	case quality >= 3:
+		return 4
+	default:
+		return quality + 1
+	}
+}
+
+func (f *FSRS) initDifficulty(rating int) float64 {
+	w := f.params.Weights
+	return clampDifficulty(w[4] - float64(rating-3)*w[5])
+}
+
+func (f *FSRS) nextDifficulty(d float64, rating int) float64 {
+	w := f.params.Weights
+	next := d - w[6]*float64(rating-3)
+	// Mean reversion towards the initial difficulty of a "Good" rating
+	return clampDifficulty(w[7]*f.initDifficulty(3) + (1-w[7])*next)
+}
+
+func (f *FSRS) recallStability(d, s, r float64, rating int) float64 {
+	w := f.params.Weights
+	hardPenalty, easyBonus := 1.0, 1.0
+	if rating == 2 {
+		hardPenalty = w[15]
+	}
+	if rating == 4 {
+		easyBonus = w[16]
+	}
+	return s * (1 + math.Exp(w[8])*(11-d)*math.Pow(s, -w[9])*(math.Exp((1-r)*w[10])-1)*hardPenalty*easyBonus)
+}
+
+func (f *FSRS) forgetStability(d, s, r float64) float64 {
+	w := f.params.Weights
+	next := w[11] * math.Pow(d, -w[12]) * (math.Pow(s+1, w[13]) - 1) * math.Exp((1-r)*w[14])
+	return math.Max(math.Min(next, s), minStability)
+}
+
+func (f *FSRS) nextInterval(stability float64) int {
+	days := stability / fsrsFactor * (math.Pow(f.params.DesiredRetention, 1/fsrsDecay) - 1)
+	interval := int(math.Round(days))
+	if interval < 1 {
+		interval = 1
+	}
+	if interval > f.params.MaximumIntervalDays {
+		interval = f.params.MaximumIntervalDays
+	}
+	return interval
+}
+
+func forgettingCurve(elapsed, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsed/stability, fsrsDecay)
+}
+
+func elapsedDays(from, to time.Time) float64 {
+	days := to.Sub(from).Hours() / 24
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+func clampDifficulty(d float64) float64 {
+	return math.Min(math.Max(d, minDifficulty), maxDifficulty)
+}
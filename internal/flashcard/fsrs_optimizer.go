@@ -0,0 +1,243 @@
+package flashcard
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// MinOptimizationReviews is the number of scored reviews (reviews after a
+// card's first one, at least a day apart) needed before weights are fitted.
+const MinOptimizationReviews = 50
+
+// ErrNotEnoughReviews is returned when the review log is too small to fit.
+var ErrNotEnoughReviews = errors.New("not enough reviews to optimize scheduler weights")
+
+const (
+	optimizerIterations   = 150
+	optimizerLearningRate = 0.05
+	optimizerRegularize   = 0.01
+	minScoredElapsedDays  = 1.0
+)
+
+// retentionCurveDays are the days since review at which the retention curve is reported.
+var retentionCurveDays = []int{1, 2, 3, 5, 7, 10, 14, 21, 30, 45, 60, 90}
+
+// fsrsWeightBounds keeps fitted weights inside the ranges the FSRS-4.5 model is defined for.
+var fsrsWeightBounds = [FSRSWeightCount][2]float64{
+	{0.1, 100}, {0.1, 100}, {0.1, 100}, {0.1, 100},
+	{1, 10}, {0.1, 5}, {0.1, 5}, {0, 0.5},
+	{0, 3}, {0.1, 0.8}, {0.01, 2.5}, {0.5, 5},
+	{0.01, 0.2}, {0.01, 0.9}, {0.01, 2}, {0, 1},
+	{1, 6},
+}
+
+// FSRSFit is the outcome of fitting FSRS weights to a review log.
+type FSRSFit struct {
+	Weights            []float64
+	ReviewCount        int
+	LogLossBefore      float64
+	LogLossAfter       float64
+	ObservedRetention  float64
+	PredictedRetention float64
+	RetentionCurve     []models.RetentionPoint
+}
+
+// OptimizeFSRS fits FSRS weights to a review log by minimising the log loss
+// of the predicted recall probability against whether each review was
+// remembered (quality above Again). histories maps flashcard id to its reviews.
+// The fit starts from initial's weights and is pulled towards them, so small
+// logs only nudge the defaults.
+func OptimizeFSRS(ctx context.Context, histories map[int64][]models.ReviewHistory, initial FSRSParams) (*FSRSFit, error) {
+	start := NewFSRS(initial).params
+	sequences := reviewSequences(histories)
+
+	scored := 0
+	recalled := 0
+	for _, seq := range sequences {
+		for i := 1; i < len(seq); i++ {
+			if elapsedDays(seq[i-1].ReviewedAt, seq[i].ReviewedAt) >= minScoredElapsedDays {
+				scored++
+				if seq[i].Quality > 0 {
+					recalled++
+				}
+			}
+		}
+	}
+	if scored < MinOptimizationReviews {
+		return nil, ErrNotEnoughReviews
+	}
+
+	prior := append([]float64(nil), start.Weights...)
+	objective := func(w []float64) float64 {
+		return logLoss(sequences, w) + regularization(w, prior)
+	}
+
+	weights := append([]float64(nil), start.Weights...)
+	m := make([]float64, len(weights))
+	v := make([]float64, len(weights))
+	grad := make([]float64, len(weights))
+	const beta1, beta2, eps = 0.9, 0.999, 1e-8
+
+	for iter := 1; iter <= optimizerIterations; iter++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Central differences; the model is cheap enough that analytic
+		// gradients are not worth the complexity.
+		for i := range weights {
+			h := 1e-4 * math.Max(1, math.Abs(weights[i]))
+			orig := weights[i]
+			weights[i] = orig + h
+			up := objective(weights)
+			weights[i] = orig - h
+			down := objective(weights)
+			weights[i] = orig
+			grad[i] = (up - down) / (2 * h)
+		}
+
+		for i := range weights {
+			m[i] = beta1*m[i] + (1-beta1)*grad[i]
+			v[i] = beta2*v[i] + (1-beta2)*grad[i]*grad[i]
+			mHat := m[i] / (1 - math.Pow(beta1, float64(iter)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(iter)))
+			// Scale steps by the weight's magnitude so large stabilities move as fast as small factors
+			step := optimizerLearningRate * math.Max(0.1, math.Abs(prior[i]))
+			weights[i] -= step * mHat / (math.Sqrt(vHat) + eps)
+			weights[i] = math.Min(math.Max(weights[i], fsrsWeightBounds[i][0]), fsrsWeightBounds[i][1])
+		}
+	}
+
+	before := logLoss(sequences, start.Weights)
+	after := logLoss(sequences, weights)
+	if after >= before {
+		// Never report weights that predict the log worse than the ones we started from
+		weights = append([]float64(nil), start.Weights...)
+		after = before
+	}
+
+	fitted := start
+	fitted.Weights = weights
+	return &FSRSFit{
+		Weights:            weights,
+		ReviewCount:        scored,
+		LogLossBefore:      before,
+		LogLossAfter:       after,
+		ObservedRetention:  float64(recalled) / float64(scored),
+		PredictedRetention: meanPredictedRecall(sequences, weights),
+		RetentionCurve:     retentionCurve(sequences, start, fitted),
+	}, nil
+}
+
+// reviewSequences orders each card's reviews oldest first, dropping cards
+// that were only reviewed once since they carry nothing to fit.
+func reviewSequences(histories map[int64][]models.ReviewHistory) [][]models.ReviewHistory {
+	ids := make([]int64, 0, len(histories))
+	for id, h := range histories {
+		if len(h) > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	sequences := make([][]models.ReviewHistory, 0, len(ids))
+	for _, id := range ids {
+		seq := append([]models.ReviewHistory(nil), histories[id]...)
+		sort.SliceStable(seq, func(i, j int) bool { return seq[i].ReviewedAt.Before(seq[j].ReviewedAt) })
+		sequences = append(sequences, seq)
+	}
+	return sequences
+}
+
+// replayScored walks each sequence with the given weights and calls fn with
+// the predicted recall probability before every scored review.
+func replayScored(sequences [][]models.ReviewHistory, weights []float64, fn func(r float64, recalled bool)) {
+	f := &FSRS{params: FSRSParams{Weights: weights, DesiredRetention: 0.9, MaximumIntervalDays: 36500}}
+	for _, seq := range sequences {
+		var card models.Flashcard
+		for i, h := range seq {
+			if i > 0 {
+				elapsed := elapsedDays(*card.LastReviewedAt, h.ReviewedAt)
+				if elapsed >= minScoredElapsedDays {
+					fn(forgettingCurve(elapsed, card.Stability), h.Quality > 0)
+				}
+			}
+			card = f.review(card, h.Quality, h.ReviewedAt)
+		}
+	}
+}
+
+func logLoss(sequences [][]models.ReviewHistory, weights []float64) float64 {
+	const clip = 1e-6
+	var total float64
+	var n int
+	replayScored(sequences, weights, func(r float64, recalled bool) {
+		r = math.Min(math.Max(r, clip), 1-clip)
+		if recalled {
+			total -= math.Log(r)
+		} else {
+			total -= math.Log(1 - r)
+		}
+		n++
+	})
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
+
+func regularization(weights, prior []float64) float64 {
+	var sum float64
+	for i := range weights {
+		d := (weights[i] - prior[i]) / math.Max(0.1, math.Abs(prior[i]))
+		sum += d * d
+	}
+	return optimizerRegularize * sum / float64(len(weights))
+}
+
+func meanPredictedRecall(sequences [][]models.ReviewHistory, weights []float64) float64 {
+	var total float64
+	var n int
+	replayScored(sequences, weights, func(r float64, _ bool) {
+		total += r
+		n++
+	})
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
+
+// retentionCurve averages, over every reviewed card, the predicted recall
+// probability a given number of days after its last review.
+func retentionCurve(sequences [][]models.ReviewHistory, current, fitted FSRSParams) []models.RetentionPoint {
+	stabilities := func(params FSRSParams) []float64 {
+		f := &FSRS{params: params}
+		out := make([]float64, 0, len(sequences))
+		for _, seq := range sequences {
+			out = append(out, f.Replay(models.Flashcard{}, seq).Stability)
+		}
+		return out
+	}
+	currentS := stabilities(current)
+	fittedS := stabilities(fitted)
+
+	curve := make([]models.RetentionPoint, 0, len(retentionCurveDays))
+	for _, day := range retentionCurveDays {
+		point := models.RetentionPoint{Days: day}
+		for i := range currentS {
+			point.Current += forgettingCurve(float64(day), currentS[i])
+			point.Fitted += forgettingCurve(float64(day), fittedS[i])
+		}
+		if n := float64(len(currentS)); n > 0 {
+			point.Current /= n
+			point.Fitted /= n
+		}
+		curve = append(curve, point)
+	}
+	return curve
+}
@@ -0,0 +1,89 @@
+package flashcard_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// simulateReviews builds a review log for a learner who forgets faster than
+// the default weights predict: recall after t days follows the FSRS curve
+// with the stability shrunk by forgetFactor.
+func simulateReviews(cards, reviewsPerCard int, forgetFactor float64) map[int64][]models.ReviewHistory {
+	rng := rand.New(rand.NewSource(42))
+	fsrs := flashcard.NewFSRS(flashcard.DefaultFSRSParams())
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	history := make(map[int64][]models.ReviewHistory)
+	for id := int64(1); id <= int64(cards); id++ {
+		var card models.Flashcard
+		now := start
+		for i := 0; i < reviewsPerCard; i++ {
+			quality := 2
+			if card.LastReviewedAt != nil {
+				elapsed := now.Sub(*card.LastReviewedAt).Hours() / 24
+				recall := math.Pow(1+19.0/81.0*elapsed/(card.Stability*forgetFactor), -0.5)
+				if rng.Float64() > recall {
+					quality = 0
+				}
+			}
+			history[id] = append(history[id], models.ReviewHistory{FlashcardID: id, Quality: quality, ReviewedAt: now})
+			card = fsrs.Schedule(card, quality, now)
+			now = card.DueAt
+		}
+	}
+	return history
+}
+
+func TestOptimizeFSRS_FitsFasterForgetting(t *testing.T) {
+	history := simulateReviews(60, 6, 0.4)
+
+	fit, err := flashcard.OptimizeFSRS(context.Background(), history, flashcard.DefaultFSRSParams())
+	require.NoError(t, err)
+
+	assert.Len(t, fit.Weights, flashcard.FSRSWeightCount)
+	assert.Equal(t, 300, fit.ReviewCount)
+	assert.Less(t, fit.LogLossAfter, fit.LogLossBefore, "fitted weights should predict the log better")
+	assert.Less(t, fit.ObservedRetention, 0.9, "the simulated learner forgets more than the 90% target")
+	assert.InDelta(t, fit.ObservedRetention, fit.PredictedRetention, 0.1)
+
+	require.NotEmpty(t, fit.RetentionCurve)
+	assert.Equal(t, 1, fit.RetentionCurve[0].Days)
+	for i, p := range fit.RetentionCurve {
+		assert.Less(t, p.Fitted, p.Current, "fitted curve should sit below the default one at day %d", p.Days)
+		if i > 0 {
+			assert.LessOrEqual(t, p.Fitted, fit.RetentionCurve[i-1].Fitted, "retention should decay over time")
+		}
+	}
+}
+
+func TestOptimizeFSRS_NotEnoughReviews(t *testing.T) {
+	history := simulateReviews(5, 3, 1)
+
+	_, err := flashcard.OptimizeFSRS(context.Background(), history, flashcard.DefaultFSRSParams())
+	assert.ErrorIs(t, err, flashcard.ErrNotEnoughReviews)
+}
+
+func TestOptimizeFSRS_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := flashcard.OptimizeFSRS(ctx, simulateReviews(60, 6, 0.4), flashcard.DefaultFSRSParams())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFSRSParamsFromWeights(t *testing.T) {
+	assert.Equal(t, flashcard.DefaultFSRSParams(), flashcard.FSRSParamsFromWeights(nil))
+	assert.Equal(t, flashcard.DefaultFSRSParams(), flashcard.FSRSParamsFromWeights([]float64{1, 2}))
+
+	weights := flashcard.DefaultFSRSParams().Weights
+	weights[0] = 0.9
+	assert.Equal(t, 0.9, flashcard.FSRSParamsFromWeights(weights).Weights[0])
+}
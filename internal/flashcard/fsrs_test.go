@@ -0,0 +1,129 @@
+package flashcard_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+func TestFSRS_FirstReview(t *testing.T) {
+	fsrs := flashcard.NewFSRS(flashcard.DefaultFSRSParams())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		quality  int
+		interval int
+	}{
+		{name: "again", quality: 0, interval: 1},
+		{name: "hard", quality: 1, interval: 1},
+		{name: "good", quality: 2, interval: 4},
+		{name: "easy", quality: 3, interval: 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := fsrs.Schedule(models.Flashcard{EaseFactor: 2.5}, tt.quality, now)
+
+			assert.Equal(t, tt.interval, updated.IntervalDays)
+			assert.Greater(t, updated.Stability, 0.0)
+			assert.GreaterOrEqual(t, updated.Difficulty, 1.0)
+			assert.LessOrEqual(t, updated.Difficulty, 10.0)
+			assert.Equal(t, now.Add(time.Duration(tt.interval)*24*time.Hour), updated.DueAt)
+			require.NotNil(t, updated.LastReviewedAt)
+			assert.Equal(t, now, *updated.LastReviewedAt)
+			assert.Equal(t, 1, updated.TimesReviewed)
+			assert.Equal(t, 2.5, updated.EaseFactor, "FSRS should leave the SM-2 ease factor alone")
+		})
+	}
+}
+
+func TestFSRS_SuccessfulReviewsGrowStability(t *testing.T) {
+	fsrs := flashcard.NewFSRS(flashcard.DefaultFSRSParams())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	card := fsrs.Schedule(models.Flashcard{}, 2, now)
+	for i := 0; i < 4; i++ {
+		prev := card
+		now = card.DueAt
+		card = fsrs.Schedule(card, 2, now)
+		assert.Greater(t, card.Stability, prev.Stability, "stability should grow after a successful review")
+		assert.Greater(t, card.IntervalDays, prev.IntervalDays, "interval should grow after a successful review")
+	}
+	assert.Equal(t, 5, card.TimesCorrect)
+}
+
+func TestFSRS_LapseReducesStability(t *testing.T) {
+	fsrs := flashcard.NewFSRS(flashcard.DefaultFSRSParams())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	card := fsrs.Schedule(models.Flashcard{}, 3, now)
+	card = fsrs.Schedule(card, 2, card.DueAt)
+	lapsed := fsrs.Schedule(card, 0, card.DueAt)
+
+	assert.Less(t, lapsed.Stability, card.Stability)
+	assert.Greater(t, lapsed.Difficulty, card.Difficulty, "forgetting should make the card harder")
+	assert.Less(t, lapsed.IntervalDays, card.IntervalDays)
+	assert.Equal(t, 0, lapsed.TimesCorrect)
+}
+
+func TestFSRS_Replay(t *testing.T) {
+	fsrs := flashcard.NewFSRS(flashcard.DefaultFSRSParams())
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	history := []models.ReviewHistory{
+		{Quality: 1, ReviewedAt: start},
+		{Quality: 2, ReviewedAt: start.Add(24 * time.Hour)},
+		{Quality: 3, ReviewedAt: start.Add(5 * 24 * time.Hour)},
+	}
+
+	card := models.Flashcard{ID: 9, TimesReviewed: 3, TimesCorrect: 2}
+	replayed := fsrs.Replay(card, history)
+
+	expected := models.Flashcard{}
+	for _, h := range history {
+		expected = fsrs.Schedule(expected, h.Quality, h.ReviewedAt)
+	}
+
+	assert.Equal(t, int64(9), replayed.ID)
+	assert.InDelta(t, expected.Stability, replayed.Stability, 1e-9)
+	assert.InDelta(t, expected.Difficulty, replayed.Difficulty, 1e-9)
+	assert.Equal(t, expected.DueAt, replayed.DueAt)
+	assert.Equal(t, 3, replayed.TimesReviewed, "replay should not touch review counters")
+	assert.Equal(t, 2, replayed.TimesCorrect)
+}
+
+func TestRetrievability(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reviewed := now.Add(-10 * 24 * time.Hour)
+
+	assert.Equal(t, 0.0, flashcard.Retrievability(models.Flashcard{}, now))
+
+	card := models.Flashcard{Stability: 10, LastReviewedAt: &reviewed}
+	assert.InDelta(t, 0.9, flashcard.Retrievability(card, now), 1e-9, "recall probability is 90% after stability days")
+
+	card.LastReviewedAt = &now
+	assert.InDelta(t, 1.0, flashcard.Retrievability(card, now), 1e-9)
+}
+
+func TestNewScheduler(t *testing.T) {
+	assert.Equal(t, flashcard.SchedulerSM2, flashcard.NewScheduler("sm2", flashcard.DefaultFSRSParams()).Name())
+	assert.Equal(t, flashcard.SchedulerFSRS, flashcard.NewScheduler("fsrs", flashcard.DefaultFSRSParams()).Name())
+	assert.Equal(t, flashcard.SchedulerSM2, flashcard.NewScheduler("", flashcard.DefaultFSRSParams()).Name())
+	assert.True(t, flashcard.IsValidScheduler("fsrs"))
+	assert.False(t, flashcard.IsValidScheduler("leitner"))
+}
+
+func TestSM2Schedule_ClearsFSRSState(t *testing.T) {
+	now := time.Now()
+	card := models.Flashcard{EaseFactor: 2.5, IntervalDays: 1, Stability: 4, Difficulty: 5}
+
+	updated := flashcard.SM2{}.Schedule(card, 2, now)
+
+	assert.Equal(t, 6, updated.IntervalDays)
+	assert.Zero(t, updated.Stability)
+	assert.Zero(t, updated.Difficulty)
+}
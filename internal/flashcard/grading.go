@@ -0,0 +1,122 @@
+package flashcard
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxMisses is how many wrong moves fail a card during a review.
+const MaxMisses = 3
+
+// Review qualities passed to a Scheduler.
+const (
+	QualityAgain = 0
+	QualityHard  = 1
+	QualityGood  = 2
+	QualityEasy  = 3
+)
+
+// Attempt is a move tried during a review. Seconds is the time elapsed since
+// the card was shown when the move was made.
+type Attempt struct {
+	Move    string
+	Seconds float64
+}
+
+// Review is what the client reports about a flashcard review: the moves it
+// tried in order, whether the answer was revealed, and how long the card was
+// shown in total. Moves that follow the solution line are listed without the
+// replies the server answered them with.
+type Review struct {
+	Attempts    []Attempt
+	Revealed    bool
+	TimeSeconds float64
+	MaxMisses   int // wrong moves that fail the card, MaxMisses when 0
+}
+
+// Grade is the outcome of a review as decided on the server.
+type Grade struct {
+	Quality     int     `json:"quality"`
+	Correct     bool    `json:"correct"`
+	Attempts    int     `json:"attempts"`
+	Misses      int     `json:"misses"`
+	TimeSeconds float64 `json:"time_seconds"`
+}
+
+// GradeReview replays the attempted moves against the card's solution line
+// (see PlayStep) and derives the review quality for the line as a whole,
+// allowing a few more seconds for every move the line asks for:
+//
+//   - revealing the answer or missing MaxMisses times is Again
+//   - solving without a miss is Easy under 10s a move, Good under 30s, else Hard
+//   - solving after a miss is Good under 20s a move, else Hard
+//
+// It returns an error when the review could not have happened: an illegal
+// move, attempts out of time order, moves after the card was solved or
+// failed, or a review that ended without being solved, revealed or failed.
+func GradeReview(fen string, line, acceptable []string, review Review) (Grade, error) {
+	maxMisses := review.MaxMisses
+	if maxMisses <= 0 {
+		maxMisses = MaxMisses
+	}
+
+	grade := Grade{Attempts: len(review.Attempts)}
+	var played []string
+	last := 0.0
+	for i, a := range review.Attempts {
+		if grade.Correct || grade.Misses >= maxMisses {
+			return Grade{}, fmt.Errorf("attempt %d was made after the card was finished", i+1)
+		}
+		if a.Seconds < 0 || a.Seconds < last {
+			return Grade{}, fmt.Errorf("attempt %d is timed before the previous one", i+1)
+		}
+		last = a.Seconds
+
+		step, err := PlayStep(fen, line, acceptable, played, a.Move)
+		if err != nil {
+			return Grade{}, fmt.Errorf("attempt %d: %w", i+1, err)
+		}
+		switch {
+		case !step.Correct:
+			grade.Misses++
+		case step.Done:
+			grade.Correct = true
+			grade.TimeSeconds = a.Seconds
+		default:
+			played = append(played, strings.ToLower(strings.TrimSpace(a.Move)), step.Reply)
+		}
+	}
+
+	switch {
+	case grade.Correct && review.Revealed:
+		return Grade{}, fmt.Errorf("the answer was revealed after the card was solved")
+	case grade.Correct:
+		grade.Quality = solvedQuality(grade.Misses, LineMoves(line), grade.TimeSeconds)
+		return grade, nil
+	case !review.Revealed && grade.Misses < maxMisses:
+		return Grade{}, fmt.Errorf("the review ended before the card was solved or revealed")
+	}
+
+	grade.Quality = QualityAgain
+	grade.TimeSeconds = max(review.TimeSeconds, last)
+	return grade, nil
+}
+
+// solvedQuality grades a card whose line of moves was solved with the given
+// misses after seconds.
+func solvedQuality(misses, moves int, seconds float64) int {
+	perMove := seconds / float64(max(moves, 1))
+	if misses == 0 {
+		switch {
+		case perMove < 10:
+			return QualityEasy
+		case perMove < 30:
+			return QualityGood
+		}
+		return QualityHard
+	}
+	if perMove < 20 {
+		return QualityGood
+	}
+	return QualityHard
+}
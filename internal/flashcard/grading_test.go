@@ -0,0 +1,159 @@
+package flashcard_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/flashcard"
+)
+
+func TestGradeReview(t *testing.T) {
+	acceptable := []string{"f1b5", "f1c4"}
+	tests := []struct {
+		name     string
+		review   flashcard.Review
+		expected flashcard.Grade
+	}{
+		{
+			name:     "fast first try is easy",
+			review:   flashcard.Review{Attempts: []flashcard.Attempt{{Move: "f1b5", Seconds: 4}}, TimeSeconds: 6},
+			expected: flashcard.Grade{Quality: flashcard.QualityEasy, Correct: true, Attempts: 1, TimeSeconds: 4},
+		},
+		{
+			name:     "alternative move counts as correct",
+			review:   flashcard.Review{Attempts: []flashcard.Attempt{{Move: "F1C4", Seconds: 15}}},
+			expected: flashcard.Grade{Quality: flashcard.QualityGood, Correct: true, Attempts: 1, TimeSeconds: 15},
+		},
+		{
+			name:     "slow first try is hard",
+			review:   flashcard.Review{Attempts: []flashcard.Attempt{{Move: "f1b5", Seconds: 45}}},
+			expected: flashcard.Grade{Quality: flashcard.QualityHard, Correct: true, Attempts: 1, TimeSeconds: 45},
+		},
+		{
+			name: "second try is good when quick",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{
+				{Move: "a2a3", Seconds: 5}, {Move: "f1b5", Seconds: 12},
+			}},
+			expected: flashcard.Grade{Quality: flashcard.QualityGood, Correct: true, Attempts: 2, Misses: 1, TimeSeconds: 12},
+		},
+		{
+			name: "third try is hard when slow",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{
+				{Move: "a2a3", Seconds: 5}, {Move: "h2h3", Seconds: 12}, {Move: "f1b5", Seconds: 25},
+			}},
+			expected: flashcard.Grade{Quality: flashcard.QualityHard, Correct: true, Attempts: 3, Misses: 2, TimeSeconds: 25},
+		},
+		{
+			name: "missing every attempt is again",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{
+				{Move: "a2a3", Seconds: 5}, {Move: "h2h3", Seconds: 12}, {Move: "d2d3", Seconds: 20},
+			}, TimeSeconds: 30},
+			expected: flashcard.Grade{Quality: flashcard.QualityAgain, Attempts: 3, Misses: 3, TimeSeconds: 30},
+		},
+		{
+			name:     "revealing the answer is again",
+			review:   flashcard.Review{Attempts: []flashcard.Attempt{{Move: "a2a3", Seconds: 5}}, Revealed: true, TimeSeconds: 8},
+			expected: flashcard.Grade{Quality: flashcard.QualityAgain, Attempts: 1, Misses: 1, TimeSeconds: 8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grade, err := flashcard.GradeReview(whiteToMoveFEN, nil, acceptable, tt.review)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, grade)
+		})
+	}
+}
+
+func TestGradeReview_RejectsImpossibleReviews(t *testing.T) {
+	acceptable := []string{"f1b5"}
+	tests := []struct {
+		name   string
+		review flashcard.Review
+	}{
+		{
+			name:   "illegal move",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{{Move: "f1f5", Seconds: 3}}},
+		},
+		{
+			name:   "move for the wrong side",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{{Move: "e5e4", Seconds: 3}}},
+		},
+		{
+			name: "too many attempts",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{
+				{Move: "a2a3", Seconds: 1}, {Move: "h2h3", Seconds: 2}, {Move: "d2d3", Seconds: 3}, {Move: "f1b5", Seconds: 4},
+			}},
+		},
+		{
+			name:   "one try allowed",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{{Move: "a2a3", Seconds: 1}, {Move: "f1b5", Seconds: 2}}, MaxMisses: 1},
+		},
+		{
+			name: "attempt after solving",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{
+				{Move: "f1b5", Seconds: 1}, {Move: "a2a3", Seconds: 2},
+			}},
+		},
+		{
+			name: "attempts out of order",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{
+				{Move: "a2a3", Seconds: 9}, {Move: "f1b5", Seconds: 2},
+			}},
+		},
+		{
+			name:   "negative timing",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{{Move: "f1b5", Seconds: -1}}},
+		},
+		{
+			name:   "revealed after solving",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{{Move: "f1b5", Seconds: 3}}, Revealed: true},
+		},
+		{
+			name:   "unfinished review",
+			review: flashcard.Review{Attempts: []flashcard.Attempt{{Move: "a2a3", Seconds: 3}}},
+		},
+		{
+			name:   "nothing attempted",
+			review: flashcard.Review{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := flashcard.GradeReview(whiteToMoveFEN, nil, acceptable, tt.review)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGradeReview_Line(t *testing.T) {
+	line := []string{"f1c4", "g8f6", "f3g5", "d7d5", "e4d5"}
+	acceptable := []string{"f1c4", "f1b5"}
+
+	grade, err := flashcard.GradeReview(whiteToMoveFEN, line, acceptable, flashcard.Review{Attempts: []flashcard.Attempt{
+		{Move: "f1c4", Seconds: 3}, {Move: "f3g5", Seconds: 8}, {Move: "h2h3", Seconds: 10}, {Move: "e4d5", Seconds: 14},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, flashcard.Grade{Quality: flashcard.QualityGood, Correct: true, Attempts: 4, Misses: 1, TimeSeconds: 14}, grade)
+
+	grade, err = flashcard.GradeReview(whiteToMoveFEN, line, acceptable, flashcard.Review{Attempts: []flashcard.Attempt{
+		{Move: "f1b5", Seconds: 4},
+	}})
+	require.NoError(t, err)
+	assert.True(t, grade.Correct, "an alternative first move solves the card")
+
+	_, err = flashcard.GradeReview(whiteToMoveFEN, line, acceptable, flashcard.Review{Attempts: []flashcard.Attempt{
+		{Move: "f1c4", Seconds: 3}, {Move: "f3g5", Seconds: 8},
+	}})
+	assert.Error(t, err, "the line was left unfinished")
+
+	grade, err = flashcard.GradeReview(whiteToMoveFEN, line, acceptable, flashcard.Review{Attempts: []flashcard.Attempt{
+		{Move: "f1c4", Seconds: 3}, {Move: "h2h3", Seconds: 5},
+	}, MaxMisses: 1})
+	require.NoError(t, err)
+	assert.Equal(t, flashcard.QualityAgain, grade.Quality)
+	assert.False(t, grade.Correct)
+}
@@ -0,0 +1,124 @@
+package flashcard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/analysis"
+)
+
+const (
+	// MaxLineMoves bounds how many of the solver's moves a tactical card asks
+	// for, so long engine lines don't turn into memorization exercises
+	MaxLineMoves = 3
+	// MaxMateLineMoves bounds the forced mates that are played out in full
+	MaxMateLineMoves = 5
+)
+
+// lineClassifications are the mistakes whose refutation is worth playing out
+// as a sequence rather than answering with the first move only.
+var lineClassifications = map[string]bool{
+	"blunder":     true,
+	"miss":        true,
+	"missed_mate": true,
+}
+
+// SolutionLine returns the moves a card must be answered with, in UCI and
+// alternating between the solver and the forced replies: the best move alone
+// for most cards, and for tactical cards the start of the engine line, up to
+// MaxLineMoves of the solver's moves or a whole forced mate. The line always
+// ends on a solver move.
+func SolutionLine(fen, bestMove string, pv []string, classification string, mateBefore *int) []string {
+	if bestMove == "" {
+		return nil
+	}
+	line := []string{bestMove}
+	if !lineClassifications[classification] || len(pv) < 3 || pv[0] != bestMove {
+		return line
+	}
+
+	plies := 2*MaxLineMoves - 1
+	if mate := moverMate(fen, mateBefore); mate > 0 && mate <= MaxMateLineMoves {
+		plies = 2*mate - 1
+	}
+	// Keep the legal part of the line, cut back to end on a solver move
+	plies = min(plies, len(analysis.UCIToSAN(fen, pv)))
+	if plies%2 == 0 {
+		plies--
+	}
+	if plies <= 1 {
+		return line
+	}
+	return pv[:plies]
+}
+
+// LineMoves returns how many of the solver's moves a line holds.
+func LineMoves(line []string) int {
+	return (len(line) + 1) / 2
+}
+
+// Step is the outcome of one move played against a solution line.
+type Step struct {
+	Correct bool   `json:"correct"`
+	Reply   string `json:"reply,omitempty"` // the opponent's answer, in UCI
+	Done    bool   `json:"done"`
+}
+
+// PlayStep checks move against the solution line after the plies already
+// played, which must follow the line up to one of the solver's turns.
+//
+// The first move may be any of the acceptable ones; an alternative to the
+// line's first move solves the card at once since the line no longer
+// applies. Later moves must follow the line, except that any mate is
+// accepted.
+func PlayStep(fen string, line, acceptable, played []string, move string) (Step, error) {
+	if len(line) == 0 {
+		line = acceptable[:min(len(acceptable), 1)]
+	}
+	if len(played)%2 != 0 || len(played) >= len(line) {
+		return Step{}, fmt.Errorf("%d moves played is not a turn of the solution", len(played))
+	}
+	for i, m := range played {
+		if m != line[i] {
+			return Step{}, fmt.Errorf("move %d (%s) does not follow the solution", i+1, m)
+		}
+	}
+
+	pos, err := analysis.PlayMoves(fen, played)
+	if err != nil {
+		return Step{}, err
+	}
+	move = strings.ToLower(strings.TrimSpace(move))
+	if !analysis.IsLegalMove(pos, move) {
+		return Step{}, fmt.Errorf("%q is not a legal move", move)
+	}
+
+	ply := len(played)
+	switch {
+	case move == line[ply]:
+	case ply == 0 && IsAcceptableMove(move, acceptable):
+		return Step{Correct: true, Done: true}, nil
+	case ply > 0 && analysis.GivesCheckmate(pos, move):
+		return Step{Correct: true, Done: true}, nil
+	default:
+		return Step{}, nil
+	}
+
+	if ply+1 >= len(line) {
+		return Step{Correct: true, Done: true}, nil
+	}
+	return Step{Correct: true, Reply: line[ply+1]}, nil
+}
+
+// moverMate returns the length of the forced mate the side to move has, or 0.
+// mate is from White's perspective.
+func moverMate(fen string, mate *int) int {
+	if mate == nil {
+		return 0
+	}
+	m := *mate
+	if !sideToMoveIsWhite(fen) {
+		m = -m
+	}
+	return max(m, 0)
+}
@@ -0,0 +1,115 @@
+package flashcard_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/flashcard"
+)
+
+// friedLiver is a legal line from whiteToMoveFEN
+var friedLiver = []string{"f1c4", "g8f6", "f3g5", "d7d5", "e4d5", "f6d5", "g5f7"}
+
+func TestSolutionLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		bestMove       string
+		pv             []string
+		classification string
+		mateBefore     *int
+		expected       []string
+	}{
+		{
+			name:           "non-tactical cards ask for the best move",
+			bestMove:       "f1c4",
+			pv:             friedLiver,
+			classification: "mistake",
+			expected:       []string{"f1c4"},
+		},
+		{
+			name:           "tactical cards play out the line",
+			bestMove:       "f1c4",
+			pv:             friedLiver,
+			classification: "blunder",
+			expected:       friedLiver[:5],
+		},
+		{
+			name:           "line ends on a solver move",
+			bestMove:       "f1c4",
+			pv:             friedLiver[:4],
+			classification: "miss",
+			expected:       friedLiver[:3],
+		},
+		{
+			name:           "short mates are played out to the end",
+			bestMove:       "f1c4",
+			pv:             friedLiver,
+			classification: "missed_mate",
+			mateBefore:     intPtr(2),
+			expected:       friedLiver[:3],
+		},
+		{
+			name:           "pv that does not start with the best move",
+			bestMove:       "f1b5",
+			pv:             friedLiver,
+			classification: "blunder",
+			expected:       []string{"f1b5"},
+		},
+		{
+			name:           "illegal tail is dropped",
+			bestMove:       "f1c4",
+			pv:             []string{"f1c4", "g8f6", "f3g5", "d7d5", "e4e5"},
+			classification: "blunder",
+			expected:       friedLiver[:3],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := flashcard.SolutionLine(whiteToMoveFEN, tt.bestMove, tt.pv, tt.classification, tt.mateBefore)
+			assert.Equal(t, tt.expected, line)
+		})
+	}
+	assert.Equal(t, 3, flashcard.LineMoves(friedLiver[:5]))
+}
+
+func TestPlayStep(t *testing.T) {
+	line := friedLiver[:5]
+	acceptable := []string{"f1c4", "f1b5"}
+
+	step, err := flashcard.PlayStep(whiteToMoveFEN, line, acceptable, nil, "f1c4")
+	require.NoError(t, err)
+	assert.Equal(t, flashcard.Step{Correct: true, Reply: "g8f6"}, step)
+
+	step, err = flashcard.PlayStep(whiteToMoveFEN, line, acceptable, line[:2], "d2d3")
+	require.NoError(t, err)
+	assert.False(t, step.Correct)
+
+	step, err = flashcard.PlayStep(whiteToMoveFEN, line, acceptable, line[:4], "e4d5")
+	require.NoError(t, err)
+	assert.Equal(t, flashcard.Step{Correct: true, Done: true}, step)
+
+	step, err = flashcard.PlayStep(whiteToMoveFEN, line, acceptable, nil, "f1b5")
+	require.NoError(t, err)
+	assert.Equal(t, flashcard.Step{Correct: true, Done: true}, step)
+
+	_, err = flashcard.PlayStep(whiteToMoveFEN, line, acceptable, []string{"f1c4", "b8c6"}, "f3g5")
+	assert.Error(t, err, "played moves must follow the line")
+
+	_, err = flashcard.PlayStep(whiteToMoveFEN, line, acceptable, line[:1], "g8f6")
+	assert.Error(t, err, "only the solver's turns can be played")
+
+	_, err = flashcard.PlayStep(whiteToMoveFEN, line, acceptable, line[:2], "f3f7")
+	assert.Error(t, err, "illegal moves are rejected")
+}
+
+func TestPlayStep_AcceptsAnyMate(t *testing.T) {
+	// White mates with either rook once Black's king is boxed in
+	fen := "6k1/5ppp/8/8/8/8/5PPP/R2R2K1 w - - 0 1"
+	line := []string{"h2h3", "g8h8", "d1d8"}
+
+	step, err := flashcard.PlayStep(fen, line, []string{"h2h3"}, line[:2], "a1a8")
+	require.NoError(t, err)
+	assert.Equal(t, flashcard.Step{Correct: true, Done: true}, step)
+}
@@ -0,0 +1,35 @@
+package flashcard
+
+import (
+	"time"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// Scheduler names selectable per profile.
+const (
+	SchedulerSM2  = "sm2"
+	SchedulerFSRS = "fsrs"
+)
+
+// Scheduler decides when a flashcard is due again after a review.
+// quality: 0=Again, 1=Hard, 2=Good, 3=Easy (higher values are treated as Easy)
+type Scheduler interface {
+	Name() string
+	Schedule(card models.Flashcard, quality int, now time.Time) models.Flashcard
+}
+
+// IsValidScheduler reports whether name is a known scheduler.
+func IsValidScheduler(name string) bool {
+	return name == SchedulerSM2 || name == SchedulerFSRS
+}
+
+// NewScheduler returns the scheduler registered under name, falling back to
+// SM-2 for unknown names so older profiles keep their behaviour. params is
+// only used by FSRS.
+func NewScheduler(name string, params FSRSParams) Scheduler {
+	if name == SchedulerFSRS {
+		return NewFSRS(params)
+	}
+	return SM2{}
+}
@@ -6,9 +6,30 @@ import (
 	"github.com/vytor/chessflash/internal/models"
 )
 
+// SM2 schedules reviews with the SM-2 variant implemented by ApplyReview.
+type SM2 struct{}
+
+// Name implements Scheduler.
+func (SM2) Name() string { return SchedulerSM2 }
+
+// Schedule implements Scheduler. SM-2 does not maintain a FSRS memory state,
+// so any previous one is cleared and gets re-seeded from the review history
+// when the profile switches back to FSRS.
+func (SM2) Schedule(card models.Flashcard, quality int, now time.Time) models.Flashcard {
+	card = applySM2(card, quality, now)
+	card.Stability = 0
+	card.Difficulty = 0
+	card.LastReviewedAt = &now
+	return card
+}
+
 // ApplyReview updates flashcard scheduling using SM-2 variant.
 // quality: 0=Again, 1=Hard, 2=Good, 3=Easy
 func ApplyReview(card models.Flashcard, quality int) models.Flashcard {
+	return applySM2(card, quality, time.Now())
+}
+
+func applySM2(card models.Flashcard, quality int, now time.Time) models.Flashcard {
 	const minEase = 1.3
 	ef := card.EaseFactor
 	ef = ef + 0.1 - float64(3-quality)*(0.08+float64(3-quality)*0.02)
@@ -36,7 +57,7 @@ func ApplyReview(card models.Flashcard, quality int) models.Flashcard {
 	}
 	card.IntervalDays = interval
 	card.EaseFactor = ef
-	card.DueAt = time.Now().Add(time.Duration(interval) * 24 * time.Hour)
+	card.DueAt = now.Add(time.Duration(interval) * 24 * time.Hour)
 	return card
 }
 
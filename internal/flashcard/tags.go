@@ -0,0 +1,183 @@
+package flashcard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// Kinds of automatic tags, written "kind:value" (e.g. "phase:middlegame").
+// They are derived from a card's position and game rather than stored.
+const (
+	TagECO            = "eco"
+	TagOpening        = "opening"
+	TagPhase          = "phase"
+	TagClassification = "classification"
+	TagTimeClass      = "time_class"
+	TagMotif          = "motif"
+	TagHung           = "hung"
+)
+
+// Game phases by move number, matching the phase statistics
+const (
+	PhaseOpening    = "opening"
+	PhaseMiddlegame = "middlegame"
+	PhaseEndgame    = "endgame"
+)
+
+// How much material a move left en prise, roughly by the piece it is worth
+const (
+	HungPawn  = "pawn"
+	HungPiece = "piece"
+	HungRook  = "rook"
+	HungQueen = "queen"
+)
+
+// maxTagLength bounds user tags
+const maxTagLength = 40
+
+// Phase returns the game phase a position's move number falls in.
+func Phase(moveNumber int) string {
+	switch {
+	case moveNumber <= 15:
+		return PhaseOpening
+	case moveNumber <= 35:
+		return PhaseMiddlegame
+	}
+	return PhaseEndgame
+}
+
+// HungMaterial names how much material, in pawns, a move left en prise, or
+// returns "" when it hung none.
+func HungMaterial(pawns int) string {
+	switch {
+	case pawns >= 9:
+		return HungQueen
+	case pawns >= 5:
+		return HungRook
+	case pawns >= 3:
+		return HungPiece
+	case pawns > 0:
+		return HungPawn
+	}
+	return ""
+}
+
+// OpeningFamily returns the opening an opening name is a variation of, e.g.
+// "Sicilian Defense" for "Sicilian Defense: Najdorf Variation".
+func OpeningFamily(name string) string {
+	if i := strings.IndexAny(name, ":,"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
+
+// AutoTags returns the automatic tags of a card.
+func AutoTags(card models.FlashcardWithPosition) []string {
+	tags := []string{}
+	add := func(kind, value string) {
+		if value != "" {
+			tags = append(tags, kind+":"+value)
+		}
+	}
+	add(TagECO, card.ECOCode)
+	add(TagOpening, OpeningFamily(card.OpeningName))
+	add(TagPhase, Phase(card.MoveNumber))
+	add(TagClassification, card.Classification)
+	add(TagTimeClass, card.TimeClass)
+	add(TagHung, HungMaterial(card.MaterialHung))
+	for _, motif := range card.Motifs {
+		add(TagMotif, motif)
+	}
+	return tags
+}
+
+// NormalizeUserTag trims and lowercases a tag the user puts on a card. User
+// tags cannot contain ':' so they never clash with the automatic ones.
+func NormalizeUserTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	switch {
+	case tag == "":
+		return "", fmt.Errorf("tag cannot be empty")
+	case strings.Contains(tag, ":"):
+		return "", fmt.Errorf("tag cannot contain ':'")
+	case len(tag) > maxTagLength:
+		return "", fmt.Errorf("tag cannot be longer than %d characters", maxTagLength)
+	}
+	return tag, nil
+}
+
+// ParseFilter builds the filter for cards carrying all the given tags, both
+// automatic ("phase:middlegame") and user tags. Two different values for the
+// same kind of automatic tag can never match and are rejected, except for
+// motifs since a card can have several.
+func ParseFilter(tags []string, limit int) (models.FlashcardFilter, error) {
+	filter := models.FlashcardFilter{Limit: limit}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		kind, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			userTag, err := NormalizeUserTag(tag)
+			if err != nil {
+				return filter, err
+			}
+			filter.Tags = append(filter.Tags, userTag)
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if strings.EqualFold(kind, TagMotif) {
+			value = strings.ToLower(value)
+			if !slices.Contains(analysis.Motifs, value) {
+				return filter, fmt.Errorf("unknown motif %q", value)
+			}
+			if !slices.Contains(filter.Motifs, value) {
+				filter.Motifs = append(filter.Motifs, value)
+			}
+			continue
+		}
+
+		var field *string
+		switch strings.ToLower(kind) {
+		case TagECO:
+			field = &filter.ECOCode
+			value = strings.ToUpper(value)
+		case TagOpening:
+			field = &filter.Opening
+		case TagPhase:
+			field = &filter.Phase
+			value = strings.ToLower(value)
+			if value != PhaseOpening && value != PhaseMiddlegame && value != PhaseEndgame {
+				return filter, fmt.Errorf("unknown phase %q", value)
+			}
+		case TagClassification:
+			field = &filter.Classification
+			value = strings.ToLower(value)
+		case TagTimeClass:
+			field = &filter.TimeClass
+			value = strings.ToLower(value)
+		case TagHung:
+			field = &filter.Hung
+			value = strings.ToLower(value)
+			if value != HungPawn && value != HungPiece && value != HungRook && value != HungQueen {
+				return filter, fmt.Errorf("unknown hung material %q", value)
+			}
+		default:
+			return filter, fmt.Errorf("unknown tag kind %q", kind)
+		}
+		if value == "" {
+			return filter, fmt.Errorf("tag %q has no value", tag)
+		}
+		if *field != "" && !strings.EqualFold(*field, value) {
+			return filter, fmt.Errorf("a card cannot have both %s:%s and %s", kind, *field, tag)
+		}
+		*field = value
+	}
+	return filter, nil
+}
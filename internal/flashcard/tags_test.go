@@ -0,0 +1,113 @@
+package flashcard_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+func TestPhase(t *testing.T) {
+	assert.Equal(t, flashcard.PhaseOpening, flashcard.Phase(15))
+	assert.Equal(t, flashcard.PhaseMiddlegame, flashcard.Phase(16))
+	assert.Equal(t, flashcard.PhaseMiddlegame, flashcard.Phase(35))
+	assert.Equal(t, flashcard.PhaseEndgame, flashcard.Phase(36))
+}
+
+func TestOpeningFamily(t *testing.T) {
+	assert.Equal(t, "Sicilian Defense", flashcard.OpeningFamily("Sicilian Defense: Najdorf Variation"))
+	assert.Equal(t, "Queen's Gambit Declined", flashcard.OpeningFamily("Queen's Gambit Declined, Exchange Variation"))
+	assert.Equal(t, "Italian Game", flashcard.OpeningFamily("Italian Game"))
+	assert.Equal(t, "", flashcard.OpeningFamily(""))
+}
+
+func TestHungMaterial(t *testing.T) {
+	assert.Equal(t, "", flashcard.HungMaterial(0))
+	assert.Equal(t, flashcard.HungPawn, flashcard.HungMaterial(2))
+	assert.Equal(t, flashcard.HungPiece, flashcard.HungMaterial(3))
+	assert.Equal(t, flashcard.HungRook, flashcard.HungMaterial(5))
+	assert.Equal(t, flashcard.HungQueen, flashcard.HungMaterial(9))
+}
+
+func TestAutoTags(t *testing.T) {
+	card := models.FlashcardWithPosition{
+		ECOCode:      "B90",
+		OpeningName:  "Sicilian Defense: Najdorf Variation",
+		TimeClass:    "blitz",
+		MaterialHung: 3,
+	}
+	card.MoveNumber = 22
+	card.Classification = "blunder"
+	card.Motifs = []string{"fork", "pin"}
+
+	assert.Equal(t, []string{
+		"eco:B90",
+		"opening:Sicilian Defense",
+		"phase:middlegame",
+		"classification:blunder",
+		"time_class:blitz",
+		"hung:piece",
+		"motif:fork",
+		"motif:pin",
+	}, flashcard.AutoTags(card))
+
+	// Games without opening data still get the other tags
+	assert.Equal(t, []string{"phase:opening"}, flashcard.AutoTags(models.FlashcardWithPosition{}))
+}
+
+func TestNormalizeUserTag(t *testing.T) {
+	tag, err := flashcard.NormalizeUserTag("  Knight   Forks ")
+	require.NoError(t, err)
+	assert.Equal(t, "knight forks", tag)
+
+	for _, invalid := range []string{"", "   ", "phase:endgame", "a-very-long-tag-that-goes-on-and-on-and-on"} {
+		_, err := flashcard.NormalizeUserTag(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := flashcard.ParseFilter([]string{
+		"opening:Sicilian Defense",
+		"Phase:Middlegame",
+		"classification:blunder",
+		"eco:b90",
+		"time_class:Blitz",
+		"hung:Queen",
+		"motif:Fork",
+		"motif:pin",
+		"motif:fork",
+		"Forks",
+		"",
+	}, 20)
+	require.NoError(t, err)
+	assert.Equal(t, models.FlashcardFilter{
+		ECOCode:        "B90",
+		Opening:        "Sicilian Defense",
+		Phase:          "middlegame",
+		Classification: "blunder",
+		TimeClass:      "blitz",
+		Motifs:         []string{"fork", "pin"},
+		Hung:           "queen",
+		Tags:           []string{"forks"},
+		Limit:          20,
+	}, filter)
+
+	// Repeating a tag is fine, asking for two values of a kind is not
+	_, err = flashcard.ParseFilter([]string{"phase:endgame", "phase:endgame"}, 0)
+	assert.NoError(t, err)
+
+	for _, invalid := range [][]string{
+		{"phase:endgame", "phase:opening"},
+		{"phase:late"},
+		{"color:white"},
+		{"classification:"},
+		{"motif:zwischenzug"},
+		{"hung:king"},
+	} {
+		_, err := flashcard.ParseFilter(invalid, 0)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,52 @@
+package gamesource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vytor/chessflash/internal/errors"
+)
+
+// ResponseError converts an unsuccessful API response into a typed error:
+// rate limits and server errors are retryable, other statuses are not.
+func ResponseError(resp *http.Response, what string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err := fmt.Errorf("%s status %d: %s", what, resp.StatusCode, string(body))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		fe := errors.NewRetryableError(errors.ReasonRateLimited, err)
+		fe.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+		return fe
+	case resp.StatusCode >= 500:
+		return errors.NewRetryableError(errors.ReasonUpstreamError, err)
+	}
+	return errors.NewPermanentError(errors.ReasonUpstreamError, err)
+}
+
+// RequestError types an error from sending a request or reading its
+// response: network failures are retryable, cancellation is returned as is.
+func RequestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return errors.NewRetryableError(errors.ReasonNetworkError, err)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package gamesource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vytor/chessflash/internal/errors"
+)
+
+func response(status int, header http.Header) *http.Response {
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader("body"))}
+}
+
+func TestResponseError(t *testing.T) {
+	err := ResponseError(response(http.StatusTooManyRequests, http.Header{"Retry-After": {"12"}}), "archives")
+	assert.True(t, errors.IsRetryable(err))
+	assert.Equal(t, errors.ReasonRateLimited, errors.FailureReason(err))
+	assert.Equal(t, 12*time.Second, errors.RetryAfter(err))
+	assert.Contains(t, err.Error(), "archives status 429: body")
+
+	err = ResponseError(response(http.StatusBadGateway, http.Header{}), "monthly")
+	assert.True(t, errors.IsRetryable(err))
+	assert.Equal(t, errors.ReasonUpstreamError, errors.FailureReason(err))
+
+	err = ResponseError(response(http.StatusNotFound, http.Header{}), "monthly")
+	assert.False(t, errors.IsRetryable(err))
+	assert.Equal(t, errors.ReasonUpstreamError, errors.FailureReason(err))
+}
+
+func TestRequestError(t *testing.T) {
+	err := RequestError(context.Background(), fmt.Errorf("connection refused"))
+	assert.True(t, errors.IsRetryable(err))
+	assert.Equal(t, errors.ReasonNetworkError, errors.FailureReason(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = RequestError(ctx, context.Canceled)
+	assert.False(t, errors.IsRetryable(err))
+}
@@ -0,0 +1,64 @@
+// Package gamesource defines the common interface for online platforms that
+// games can be imported from, so the import job does not depend on any one API.
+package gamesource
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Supported platforms, stored on models.Profile.Platform.
+const (
+	PlatformChessCom = "chesscom"
+	PlatformLichess  = "lichess"
+)
+
+// Game is a finished game fetched from a platform, normalized to the fields the
+// importer needs.
+type Game struct {
+	ID        string // platform-specific game id, unique per platform
+	URL       string
+	PGN       string
+	TimeClass string // bullet, blitz, rapid, daily
+	EndTime   time.Time
+	White     Player
+	Black     Player
+}
+
+// Player is one side of a Game.
+type Player struct {
+	Username string
+	Rating   int
+	Result   string // win, draw, loss
+}
+
+// Source streams a user's games from an online platform.
+type Source interface {
+	// Platform returns the platform identifier (e.g. PlatformLichess).
+	Platform() string
+	// FetchGames calls fn for each game played by username, restricted to
+	// games played on or after since when since is not zero. Returning an
+	// error from fn stops the fetch and is returned to the caller. A source
+	// that fetches in parts may pass the games of the parts it got and then
+	// return the error of those it could not fetch, so the caller must not
+	// treat the fetch as complete.
+	FetchGames(ctx context.Context, username string, since time.Time, fn func(Game) error) error
+}
+
+// IsValidPlatform reports whether platform is a supported platform identifier.
+func IsValidPlatform(platform string) bool {
+	switch platform {
+	case PlatformChessCom, PlatformLichess:
+		return true
+	}
+	return false
+}
+
+// DeriveResult determines which color the user played, their opponent, and the result
+func DeriveResult(username string, g Game) (playedAs, opponent, result string) {
+	if strings.EqualFold(g.White.Username, username) {
+		return "white", g.Black.Username, g.White.Result
+	}
+	return "black", g.White.Username, g.Black.Result
+}
@@ -1,7 +1,24 @@
 package jobs
 
+import (
+	"errors"
+
+	"github.com/vytor/chessflash/internal/worker"
+)
+
+// ErrAlreadyQueued is returned by EnqueueAnalysis when the game is already
+// queued at that priority or higher, so the call changed nothing.
+var ErrAlreadyQueued = errors.New("analysis already queued")
+
 // JobQueue provides an abstraction for enqueueing background jobs
 type JobQueue interface {
-	EnqueueAnalysis(gameID int64) error
+	EnqueueAnalysis(gameID int64, engine string, priority worker.Priority) error // empty engine means the default
+	EnqueueDeepenAnalysis(gameID int64, depth int, engine string) error
 	EnqueueImport(profileID int64, username string) error
+
+	// EnqueueFSRSOptimization fits the profile's FSRS parameters in the
+	// background, recording progress and outcome on the optimization run
+	// optimizationID. It runs alongside imports, the other long-running
+	// per-profile work, so it never holds up game analysis.
+	EnqueueFSRSOptimization(profileID, optimizationID int64) error
 }
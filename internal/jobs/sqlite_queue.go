@@ -0,0 +1,407 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/gamesource"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/retry"
+	"github.com/vytor/chessflash/internal/worker"
+)
+
+// Job kinds stored in the jobs table, named after the worker jobs they run
+const (
+	kindAnalyzeGame    = "analyze_game"
+	kindDeepenAnalysis = "deepen_analysis"
+	kindImportGames    = "import_games"
+	kindOptimizeFSRS   = "optimize_fsrs"
+)
+
+const (
+	// leaseDuration is how long a claimed job is held without a heartbeat
+	// before another claim may take it over
+	leaseDuration = time.Minute
+	// pollInterval is how often the dispatchers look for due jobs when
+	// nothing was enqueued in between
+	pollInterval = 2 * time.Second
+	// retryBase and retryLimit bound the backoff before a job that failed
+	// with a retryable error runs again
+	retryBase  = 30 * time.Second
+	retryLimit = 30 * time.Minute
+	// fairClaimEvery makes every nth claim of a queue take its longest
+	// waiting job whatever the priority, so a steady stream of interactive
+	// work cannot starve the backfill
+	fairClaimEvery = 5
+)
+
+type analyzeGamePayload struct {
+	GameID int64  `json:"game_id"`
+	Engine string `json:"engine,omitempty"`
+}
+
+type deepenAnalysisPayload struct {
+	GameID int64  `json:"game_id"`
+	Depth  int    `json:"depth"`
+	Engine string `json:"engine,omitempty"`
+}
+
+type importGamesPayload struct {
+	ProfileID int64 `json:"profile_id"`
+}
+
+type optimizeFSRSPayload struct {
+	ProfileID      int64 `json:"profile_id"`
+	OptimizationID int64 `json:"optimization_id"`
+}
+
+// SQLiteQueue implements JobQueue over the jobs table, so queued work
+// survives restarts. A dispatcher per queue claims due jobs, highest priority
+// first, under a lease whenever a worker of the matching pool is idle, and
+// the lease is kept alive while the job runs. Jobs interrupted by a stopped
+// pool or a shutdown go back to the queue; jobs whose worker died are claimed
+// again once their lease expires. Jobs that fail with a retryable error run
+// again after an exponential backoff until they run out of attempts.
+type SQLiteQueue struct {
+	jobRepo         repository.JobRepository
+	pools           map[string]*worker.Pool
+	profileRepo     repository.ProfileRepository
+	gameRepo        repository.GameRepository
+	statsRepo       repository.StatsRepository
+	flashcardRepo   repository.FlashcardRepository
+	fsrsRepo        repository.FSRSOptimizationRepository
+	analysisService worker.AnalysisServiceInterface
+	explorer        worker.OpeningIndexer
+	sources         map[string]gamesource.Source
+	stockfishPath   string
+	stockfishDepth  int
+
+	owner  string       // identifies this process in lease tokens
+	leases atomic.Int64 // numbers the leases taken by this process
+	wake   map[string]chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewSQLiteQueue creates a new SQLiteQueue. Call Start to begin running jobs.
+func NewSQLiteQueue(
+	jobRepo repository.JobRepository,
+	analysisPool *worker.Pool,
+	importPool *worker.Pool,
+	profileRepo repository.ProfileRepository,
+	gameRepo repository.GameRepository,
+	statsRepo repository.StatsRepository,
+	flashcardRepo repository.FlashcardRepository,
+	fsrsRepo repository.FSRSOptimizationRepository,
+	analysisService worker.AnalysisServiceInterface,
+	explorer worker.OpeningIndexer,
+	sources []gamesource.Source,
+	stockfishPath string,
+	stockfishDepth int,
+) *SQLiteQueue {
+	byPlatform := make(map[string]gamesource.Source, len(sources))
+	for _, src := range sources {
+		byPlatform[src.Platform()] = src
+	}
+
+	pools := map[string]*worker.Pool{
+		models.JobQueueAnalysis: analysisPool,
+		models.JobQueueImport:   importPool,
+	}
+	wake := make(map[string]chan struct{}, len(pools))
+	for queue := range pools {
+		wake[queue] = make(chan struct{}, 1)
+	}
+
+	return &SQLiteQueue{
+		jobRepo:         jobRepo,
+		pools:           pools,
+		profileRepo:     profileRepo,
+		gameRepo:        gameRepo,
+		statsRepo:       statsRepo,
+		flashcardRepo:   flashcardRepo,
+		fsrsRepo:        fsrsRepo,
+		analysisService: analysisService,
+		explorer:        explorer,
+		sources:         byPlatform,
+		stockfishPath:   stockfishPath,
+		stockfishDepth:  stockfishDepth,
+		owner:           fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+		wake:            wake,
+	}
+}
+
+func (q *SQLiteQueue) EnqueueAnalysis(gameID int64, engine string, priority worker.Priority) error {
+	queued, err := q.enqueue(models.JobQueueAnalysis, kindAnalyzeGame, priority, analyzeGamePayload{GameID: gameID, Engine: engine})
+	if err == nil && !queued {
+		return ErrAlreadyQueued
+	}
+	return err
+}
+
+// EnqueueDeepenAnalysis is always interactive: deepening is requested from a
+// game's page.
+func (q *SQLiteQueue) EnqueueDeepenAnalysis(gameID int64, depth int, engine string) error {
+	_, err := q.enqueue(models.JobQueueAnalysis, kindDeepenAnalysis, worker.PriorityInteractive, deepenAnalysisPayload{GameID: gameID, Depth: depth, Engine: engine})
+	return err
+}
+
+func (q *SQLiteQueue) EnqueueImport(profileID int64, username string) error {
+	profile, _, err := importSource(context.Background(), q.profileRepo, q.sources, profileID, username)
+	if err != nil {
+		return err
+	}
+	_, err = q.enqueue(models.JobQueueImport, kindImportGames, worker.PriorityInteractive, importGamesPayload{ProfileID: profile.ID})
+	return err
+}
+
+func (q *SQLiteQueue) EnqueueFSRSOptimization(profileID, optimizationID int64) error {
+	_, err := q.enqueue(models.JobQueueImport, kindOptimizeFSRS, worker.PriorityInteractive, optimizeFSRSPayload{ProfileID: profileID, OptimizationID: optimizationID})
+	return err
+}
+
+// enqueue stores the job and wakes the queue's dispatcher. It reports false
+// when the job was already queued and nothing changed.
+func (q *SQLiteQueue) enqueue(queue, kind string, priority worker.Priority, payload any) (bool, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	id, err := q.jobRepo.Enqueue(context.Background(), models.Job{Queue: queue, Kind: kind, Payload: string(data), Priority: int(priority)})
+	if err != nil {
+		return false, err
+	}
+	if id == 0 {
+		return false, nil
+	}
+	select {
+	case q.wake[queue] <- struct{}{}:
+	default:
+	}
+	return true, nil
+}
+
+// Start runs a dispatcher per queue until ctx is cancelled.
+func (q *SQLiteQueue) Start(ctx context.Context) {
+	ctx = logger.NewContext(ctx, logger.Default().WithPrefix("job-queue"))
+	for queue, pool := range q.pools {
+		q.wg.Add(1)
+		go q.dispatch(ctx, queue, pool)
+	}
+	logger.FromContext(ctx).Info("job queue started")
+}
+
+// Wait blocks until the dispatchers have stopped.
+func (q *SQLiteQueue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *SQLiteQueue) dispatch(ctx context.Context, queue string, pool *worker.Pool) {
+	defer q.wg.Done()
+	log := logger.FromContext(ctx).WithField("queue", queue)
+	ctx = logger.NewContext(ctx, log)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	claims := 0
+	for {
+		q.fill(ctx, queue, pool, &claims)
+		select {
+		case <-ctx.Done():
+			log.Debug("dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-q.wake[queue]:
+		}
+	}
+}
+
+// fill claims due jobs for as long as the pool has idle workers to start
+// them, so a claimed job never sits in the pool long enough to lose its lease.
+// claims counts the claims made by the queue's dispatcher.
+func (q *SQLiteQueue) fill(ctx context.Context, queue string, pool *worker.Pool, claims *int) {
+	log := logger.FromContext(ctx)
+
+	for ctx.Err() == nil && pool.IsRunning() && pool.Idle() > 0 {
+		owner := fmt.Sprintf("%s/%d", q.owner, q.leases.Add(1))
+		*claims++
+		job, err := q.jobRepo.Claim(ctx, queue, owner, leaseDuration, *claims%fairClaimEvery == 0)
+		if err != nil {
+			log.Warn("failed to claim job: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		run, err := q.build(ctx, *job)
+		if err != nil {
+			log.Error("cannot run job %d (%s): %v", job.ID, job.Kind, err)
+			if err := q.jobRepo.Fail(ctx, job.ID, owner, err.Error()); err != nil {
+				log.Warn("failed to record job %d failure: %v", job.ID, err)
+			}
+			continue
+		}
+
+		if err := pool.Submit(&leasedJob{jobRepo: q.jobRepo, job: *job, run: run}); err != nil {
+			log.Warn("failed to submit job %d: %v", job.ID, err)
+			if err := q.jobRepo.Release(ctx, job.ID, owner); err != nil {
+				log.Warn("failed to release job %d: %v", job.ID, err)
+			}
+			return
+		}
+	}
+}
+
+// build decodes a stored job into the worker job that runs it
+func (q *SQLiteQueue) build(ctx context.Context, job models.Job) (worker.Job, error) {
+	switch job.Kind {
+	case kindAnalyzeGame:
+		var p analyzeGamePayload
+		if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+			return nil, err
+		}
+		return &worker.AnalyzeGameJob{
+			AnalysisService: q.analysisService,
+			GameID:          p.GameID,
+			Engine:          p.Engine,
+		}, nil
+
+	case kindDeepenAnalysis:
+		var p deepenAnalysisPayload
+		if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+			return nil, err
+		}
+		return &worker.DeepenAnalysisJob{
+			AnalysisService: q.analysisService,
+			GameID:          p.GameID,
+			Depth:           p.Depth,
+			Engine:          p.Engine,
+		}, nil
+
+	case kindImportGames:
+		var p importGamesPayload
+		if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+			return nil, err
+		}
+		// The profile is read when the job starts so the import continues
+		// from its latest sync time
+		profile, err := q.profileRepo.Get(ctx, p.ProfileID)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil {
+			return nil, fmt.Errorf("profile %d not found", p.ProfileID)
+		}
+		source, ok := q.sources[profile.Platform]
+		if !ok {
+			return nil, fmt.Errorf("no game source for platform %q", profile.Platform)
+		}
+		return &worker.ImportGamesJob{
+			GameRepo:       q.gameRepo,
+			ProfileRepo:    q.profileRepo,
+			StatsRepo:      q.statsRepo,
+			Explorer:       q.explorer,
+			Source:         source,
+			Profile:        *profile,
+			AnalysisPool:   q.pools[models.JobQueueAnalysis],
+			StockfishPath:  q.stockfishPath,
+			StockfishDepth: q.stockfishDepth,
+		}, nil
+
+	case kindOptimizeFSRS:
+		var p optimizeFSRSPayload
+		if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+			return nil, err
+		}
+		return &worker.OptimizeFSRSJob{
+			FlashcardRepo:    q.flashcardRepo,
+			ProfileRepo:      q.profileRepo,
+			OptimizationRepo: q.fsrsRepo,
+			ProfileID:        p.ProfileID,
+			OptimizationID:   p.OptimizationID,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown job kind %q", job.Kind)
+}
+
+// leasedJob runs a claimed job, extending its lease while it runs, and
+// records how it ended.
+type leasedJob struct {
+	jobRepo repository.JobRepository
+	job     models.Job
+	run     worker.Job
+}
+
+func (j *leasedJob) Name() string { return j.run.Name() }
+
+func (j *leasedJob) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("job_id", j.job.ID)
+	owner := j.job.LeaseOwner
+
+	// A job that waited in the pool past its lease may have been claimed again
+	if err := j.jobRepo.Heartbeat(ctx, j.job.ID, owner, leaseDuration); err != nil {
+		log.Warn("not running job: %v", err)
+		return nil
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var lost atomic.Bool
+	go func() {
+		ticker := time.NewTicker(leaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				err := j.jobRepo.Heartbeat(runCtx, j.job.ID, owner, leaseDuration)
+				if stderrors.Is(err, repository.ErrLeaseLost) {
+					log.Warn("lease lost, cancelling job")
+					lost.Store(true)
+					cancel()
+					return
+				}
+				if err != nil && runCtx.Err() == nil {
+					log.Warn("failed to extend job lease: %v", err)
+				}
+			}
+		}
+	}()
+
+	err := j.run.Run(runCtx)
+	cancel()
+
+	// The outcome is recorded even when the pool is shutting down
+	done := context.WithoutCancel(ctx)
+	var recordErr error
+	switch {
+	case lost.Load():
+		// Whoever holds the lease now owns the outcome
+	case err == nil:
+		recordErr = j.jobRepo.Complete(done, j.job.ID, owner)
+	case ctx.Err() != nil:
+		log.Info("job interrupted, returning it to the queue")
+		recordErr = j.jobRepo.Release(done, j.job.ID, owner)
+	case errors.IsRetryable(err) && j.job.Attempts < j.job.MaxAttempts:
+		delay := max(retry.Backoff(j.job.Attempts, retryBase, retryLimit), errors.RetryAfter(err))
+		log.Warn("job failed on attempt %d of %d, retrying in %v: %v", j.job.Attempts, j.job.MaxAttempts, delay.Round(time.Second), err)
+		recordErr = j.jobRepo.Reschedule(done, j.job.ID, owner, time.Now().Add(delay), err.Error())
+	default:
+		recordErr = j.jobRepo.Fail(done, j.job.ID, owner, err.Error())
+	}
+	if recordErr != nil {
+		log.Warn("failed to record job outcome: %v", recordErr)
+	}
+	return err
+}
@@ -2,10 +2,11 @@ package jobs
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
-	"github.com/vytor/chessflash/internal/chesscom"
+	"github.com/vytor/chessflash/internal/gamesource"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/repository"
@@ -19,12 +20,13 @@ type WorkerQueue struct {
 	profileRepo     repository.ProfileRepository
 	gameRepo        repository.GameRepository
 	statsRepo       repository.StatsRepository
+	flashcardRepo   repository.FlashcardRepository
+	fsrsRepo        repository.FSRSOptimizationRepository
 	analysisService worker.AnalysisServiceInterface
-	chessClient     *chesscom.Client
+	explorer        worker.OpeningIndexer
+	sources         map[string]gamesource.Source
 	stockfishPath   string
 	stockfishDepth  int
-	archiveLimit    int
-	maxConcurrent   int
 
 	// Backfill mechanism
 	backfillMu      sync.Mutex
@@ -41,32 +43,42 @@ func NewWorkerQueue(
 	profileRepo repository.ProfileRepository,
 	gameRepo repository.GameRepository,
 	statsRepo repository.StatsRepository,
+	flashcardRepo repository.FlashcardRepository,
+	fsrsRepo repository.FSRSOptimizationRepository,
 	analysisService worker.AnalysisServiceInterface,
-	chessClient *chesscom.Client,
+	explorer worker.OpeningIndexer,
+	sources []gamesource.Source,
 	stockfishPath string,
 	stockfishDepth int,
-	archiveLimit int,
-	maxConcurrent int,
 ) JobQueue {
+	byPlatform := make(map[string]gamesource.Source, len(sources))
+	for _, src := range sources {
+		byPlatform[src.Platform()] = src
+	}
+
 	return &WorkerQueue{
 		analysisPool:    analysisPool,
 		importPool:      importPool,
 		profileRepo:     profileRepo,
 		gameRepo:        gameRepo,
 		statsRepo:       statsRepo,
+		flashcardRepo:   flashcardRepo,
+		fsrsRepo:        fsrsRepo,
 		analysisService: analysisService,
-		chessClient:     chessClient,
+		explorer:        explorer,
+		sources:         byPlatform,
 		stockfishPath:   stockfishPath,
 		stockfishDepth:  stockfishDepth,
-		archiveLimit:    archiveLimit,
-		maxConcurrent:   maxConcurrent,
 	}
 }
 
-func (q *WorkerQueue) EnqueueAnalysis(gameID int64) error {
+// EnqueueAnalysis submits the game straight to the analysis pool, which runs
+// jobs in submission order whatever their priority.
+func (q *WorkerQueue) EnqueueAnalysis(gameID int64, engine string, priority worker.Priority) error {
 	err := q.analysisPool.Submit(&worker.AnalyzeGameJob{
 		AnalysisService: q.analysisService,
 		GameID:          gameID,
+		Engine:          engine,
 	})
 
 	// If queue is full, start backfill if not already running
@@ -77,33 +89,64 @@ func (q *WorkerQueue) EnqueueAnalysis(gameID int64) error {
 	return err
 }
 
+func (q *WorkerQueue) EnqueueDeepenAnalysis(gameID int64, depth int, engine string) error {
+	return q.analysisPool.Submit(&worker.DeepenAnalysisJob{
+		AnalysisService: q.analysisService,
+		GameID:          gameID,
+		Depth:           depth,
+		Engine:          engine,
+	})
+}
+
 func (q *WorkerQueue) EnqueueImport(profileID int64, username string) error {
-	// Get profile from repository
-	ctx := context.Background()
-	profile, err := q.profileRepo.Get(ctx, profileID)
-	if err != nil || profile == nil {
-		// If profile not found, try to upsert it
-		profile, err = q.profileRepo.Upsert(ctx, username)
-		if err != nil {
-			return err
-		}
+	profile, source, err := importSource(context.Background(), q.profileRepo, q.sources, profileID, username)
+	if err != nil {
+		return err
 	}
 
 	err = q.importPool.Submit(&worker.ImportGamesJob{
 		GameRepo:       q.gameRepo,
 		ProfileRepo:    q.profileRepo,
 		StatsRepo:      q.statsRepo,
-		ChessClient:    q.chessClient,
+		Explorer:       q.explorer,
+		Source:         source,
 		Profile:        *profile,
 		AnalysisPool:   q.analysisPool,
 		StockfishPath:  q.stockfishPath,
 		StockfishDepth: q.stockfishDepth,
-		ArchiveLimit:   q.archiveLimit,
-		MaxConcurrent:  q.maxConcurrent,
 	})
 	return err
 }
 
+// importSource looks up the profile to import for, creating it when missing,
+// and the game source of its platform.
+func importSource(ctx context.Context, profileRepo repository.ProfileRepository, sources map[string]gamesource.Source, profileID int64, username string) (*models.Profile, gamesource.Source, error) {
+	profile, err := profileRepo.Get(ctx, profileID)
+	if err != nil || profile == nil {
+		// If profile not found, try to upsert it
+		profile, err = profileRepo.Upsert(ctx, username, gamesource.PlatformChessCom)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	source, ok := sources[profile.Platform]
+	if !ok {
+		return nil, nil, fmt.Errorf("no game source for platform %q", profile.Platform)
+	}
+	return profile, source, nil
+}
+
+func (q *WorkerQueue) EnqueueFSRSOptimization(profileID, optimizationID int64) error {
+	return q.importPool.Submit(&worker.OptimizeFSRSJob{
+		FlashcardRepo:    q.flashcardRepo,
+		ProfileRepo:      q.profileRepo,
+		OptimizationRepo: q.fsrsRepo,
+		ProfileID:        profileID,
+		OptimizationID:   optimizationID,
+	})
+}
+
 // StartBackfill starts the automatic backfill process for the given filter
 func (q *WorkerQueue) StartBackfill(filter models.AnalysisFilter) {
 	q.backfillMu.Lock()
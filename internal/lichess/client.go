@@ -0,0 +1,161 @@
+package lichess
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vytor/chessflash/internal/gamesource"
+	"github.com/vytor/chessflash/internal/logger"
+)
+
+const defaultBaseURL = "https://lichess.org"
+
+// maxLineSize bounds a single NDJSON game record; long correspondence games
+// with clock and eval comments can exceed bufio's 64KB default.
+const maxLineSize = 1024 * 1024
+
+// standardPerfTypes restricts exports to standard chess; variants are skipped
+// because analysis assumes the standard starting position.
+const standardPerfTypes = "ultraBullet,bullet,blitz,rapid,classical,correspondence"
+
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	log        *logger.Logger
+}
+
+func New() *Client {
+	return NewWithBaseURL(defaultBaseURL)
+}
+
+// NewWithBaseURL creates a client against a different Lichess host, such as a
+// local stand-in during tests.
+func NewWithBaseURL(baseURL string) *Client {
+	return &Client{
+		// No overall timeout: exports are streamed and can take minutes for
+		// large histories. Cancellation is handled through the context.
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		log:        logger.Default().WithPrefix("lichess"),
+	}
+}
+
+// ExportedGame is a single game from the NDJSON export endpoint.
+type ExportedGame struct {
+	ID         string  `json:"id"`
+	Rated      bool    `json:"rated"`
+	Variant    string  `json:"variant"`
+	Speed      string  `json:"speed"`
+	Perf       string  `json:"perf"`
+	CreatedAt  int64   `json:"createdAt"`  // milliseconds since epoch
+	LastMoveAt int64   `json:"lastMoveAt"` // milliseconds since epoch
+	Status     string  `json:"status"`
+	Winner     string  `json:"winner"` // white, black, or empty for draws
+	Players    Players `json:"players"`
+	PGN        string  `json:"pgn"`
+}
+
+type Players struct {
+	White Player `json:"white"`
+	Black Player `json:"black"`
+}
+
+type Player struct {
+	User    *User `json:"user"`
+	Rating  int   `json:"rating"`
+	AILevel int   `json:"aiLevel"`
+}
+
+type User struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// Name returns the player's display name, naming engine opponents by level.
+func (p Player) Name() string {
+	if p.User != nil {
+		return p.User.Name
+	}
+	if p.AILevel > 0 {
+		return fmt.Sprintf("Stockfish level %d", p.AILevel)
+	}
+	return "Anonymous"
+}
+
+// StreamGames streams the user's games from /api/games/user/{username} and
+// calls fn for each one as it arrives. When since is not zero only games
+// created at or after it are requested. PGNs include clock and eval comments.
+func (c *Client) StreamGames(ctx context.Context, username string, since time.Time, fn func(ExportedGame) error) error {
+	log := logger.FromContext(ctx).WithPrefix("lichess").WithField("username", username)
+
+	query := url.Values{}
+	query.Set("pgnInJson", "true")
+	query.Set("clocks", "true")
+	query.Set("evals", "true")
+	query.Set("opening", "true")
+	query.Set("finished", "true")
+	query.Set("perfType", standardPerfTypes)
+	if !since.IsZero() {
+		query.Set("since", strconv.FormatInt(since.UnixMilli(), 10))
+	}
+	exportURL := fmt.Sprintf("%s/api/games/user/%s?%s", c.baseURL, url.PathEscape(username), query.Encode())
+
+	log.Debug("streaming games from: %s", exportURL)
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, exportURL, nil)
+	if err != nil {
+		log.Error("failed to create request: %v", err)
+		return err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Error("failed to stream games: %v", err)
+		return gamesource.RequestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	log.Debug("export response received in %v, status=%d", time.Since(start), resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		err := gamesource.ResponseError(resp, "export")
+		log.Error("export request failed: %v", err)
+		return err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var count int
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var game ExportedGame
+		if err := json.Unmarshal([]byte(line), &game); err != nil {
+			log.Error("failed to decode exported game: %v", err)
+			return err
+		}
+		count++
+		if err := fn(game); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error("failed to read export stream: %v", err)
+		return gamesource.RequestError(ctx, err)
+	}
+
+	log.Info("streamed %d games in %v", count, time.Since(start))
+	return nil
+}
@@ -0,0 +1,128 @@
+package lichess_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/gamesource"
+	"github.com/vytor/chessflash/internal/lichess"
+)
+
+const exportNDJSON = `{"id":"abcd1234","rated":true,"variant":"standard","speed":"blitz","perf":"blitz","createdAt":1700000000000,"lastMoveAt":1700000300000,"status":"mate","winner":"white","players":{"white":{"user":{"name":"Alice","id":"alice"},"rating":1850},"black":{"user":{"name":"Bob","id":"bob"},"rating":1790}},"pgn":"[Event \"Rated blitz game\"]\n[White \"Alice\"]\n[Black \"Bob\"]\n[Result \"1-0\"]\n[ECO \"C50\"]\n[Opening \"Italian Game\"]\n\n1. e4 { [%clk 0:03:00] } e5 { [%clk 0:03:00] } 1-0\n"}
+
+{"id":"efgh5678","rated":false,"variant":"standard","speed":"classical","perf":"classical","createdAt":1700100000000,"lastMoveAt":1700103000000,"status":"draw","players":{"white":{"user":{"name":"Bob","id":"bob"},"rating":1800},"black":{"user":{"name":"Alice","id":"alice"},"rating":1860}},"pgn":"[Event \"Casual classical game\"]\n\n1. d4 d5 1/2-1/2\n"}
+{"id":"ijkl9012","rated":false,"variant":"standard","speed":"bullet","perf":"bullet","createdAt":1700200000000,"lastMoveAt":1700200000000,"status":"aborted","players":{"white":{"user":{"name":"Alice","id":"alice"},"rating":1700},"black":{"aiLevel":3}},"pgn":"[Event \"Aborted\"]\n\n*\n"}
+`
+
+func newExportServer(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, exportNDJSON)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamGames(t *testing.T) {
+	since := time.UnixMilli(1699999999000)
+	server := newExportServer(t, func(r *http.Request) {
+		assert.Equal(t, "/api/games/user/alice", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Accept"))
+		assert.Equal(t, "1699999999000", r.URL.Query().Get("since"))
+		assert.Equal(t, "true", r.URL.Query().Get("pgnInJson"))
+		assert.Equal(t, "true", r.URL.Query().Get("clocks"))
+		assert.Equal(t, "true", r.URL.Query().Get("evals"))
+	})
+
+	client := lichess.NewWithBaseURL(server.URL)
+	var games []lichess.ExportedGame
+	err := client.StreamGames(context.Background(), "alice", since, func(g lichess.ExportedGame) error {
+		games = append(games, g)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, games, 3)
+	assert.Equal(t, "abcd1234", games[0].ID)
+	assert.Equal(t, "Alice", games[0].Players.White.Name())
+	assert.Equal(t, 1790, games[0].Players.Black.Rating)
+	assert.Contains(t, games[0].PGN, "[%clk 0:03:00]")
+	assert.Equal(t, "Stockfish level 3", games[2].Players.Black.Name())
+}
+
+func TestStreamGames_NoSinceParameter(t *testing.T) {
+	server := newExportServer(t, func(r *http.Request) {
+		assert.False(t, r.URL.Query().Has("since"))
+	})
+
+	client := lichess.NewWithBaseURL(server.URL)
+	err := client.StreamGames(context.Background(), "alice", time.Time{}, func(lichess.ExportedGame) error { return nil })
+	require.NoError(t, err)
+}
+
+func TestStreamGames_StopsOnCallbackError(t *testing.T) {
+	server := newExportServer(t, nil)
+	stop := errors.New("stop")
+
+	client := lichess.NewWithBaseURL(server.URL)
+	var count int
+	err := client.StreamGames(context.Background(), "alice", time.Time{}, func(lichess.ExportedGame) error {
+		count++
+		return stop
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, count)
+}
+
+func TestStreamGames_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := lichess.NewWithBaseURL(server.URL)
+	err := client.StreamGames(context.Background(), "alice", time.Time{}, func(lichess.ExportedGame) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
+
+func TestSourceFetchGames(t *testing.T) {
+	server := newExportServer(t, nil)
+	source := lichess.NewSource(lichess.NewWithBaseURL(server.URL))
+
+	var games []gamesource.Game
+	err := source.FetchGames(context.Background(), "alice", time.Time{}, func(g gamesource.Game) error {
+		games = append(games, g)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, gamesource.PlatformLichess, source.Platform())
+	require.Len(t, games, 2, "aborted games should be skipped")
+
+	assert.Equal(t, "abcd1234", games[0].ID)
+	assert.Equal(t, "blitz", games[0].TimeClass)
+	assert.Equal(t, "win", games[0].White.Result)
+	assert.Equal(t, "loss", games[0].Black.Result)
+	assert.Equal(t, time.UnixMilli(1700000300000), games[0].EndTime)
+
+	assert.Equal(t, "rapid", games[1].TimeClass)
+	assert.Equal(t, "draw", games[1].White.Result)
+
+	playedAs, opponent, result := gamesource.DeriveResult("alice", games[1])
+	assert.Equal(t, "black", playedAs)
+	assert.Equal(t, "Bob", opponent)
+	assert.Equal(t, "draw", result)
+}
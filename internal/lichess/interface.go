@@ -0,0 +1,15 @@
+package lichess
+
+import (
+	"context"
+	"time"
+)
+
+// ClientInterface defines the interface for Lichess API operations.
+// This interface enables testability by allowing mock implementations.
+type ClientInterface interface {
+	StreamGames(ctx context.Context, username string, since time.Time, fn func(ExportedGame) error) error
+}
+
+// Ensure Client implements the interface
+var _ ClientInterface = (*Client)(nil)
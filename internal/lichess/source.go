@@ -0,0 +1,94 @@
+package lichess
+
+import (
+	"context"
+	"time"
+
+	"github.com/vytor/chessflash/internal/gamesource"
+)
+
+// Source adapts the Lichess game export API to gamesource.Source.
+type Source struct {
+	client ClientInterface
+}
+
+// NewSource creates a Lichess game source.
+func NewSource(client ClientInterface) *Source {
+	return &Source{client: client}
+}
+
+// Ensure Source implements the interface
+var _ gamesource.Source = (*Source)(nil)
+
+func (s *Source) Platform() string { return gamesource.PlatformLichess }
+
+// FetchGames streams the user's finished standard games and passes each one to fn.
+func (s *Source) FetchGames(ctx context.Context, username string, since time.Time, fn func(gamesource.Game) error) error {
+	return s.client.StreamGames(ctx, username, since, func(eg ExportedGame) error {
+		game, ok := ToGame(eg)
+		if !ok {
+			return nil
+		}
+		return fn(game)
+	})
+}
+
+// ToGame converts an exported Lichess game to the platform-neutral format.
+// Games that never finished (aborted, not started) are rejected.
+func ToGame(eg ExportedGame) (gamesource.Game, bool) {
+	switch eg.Status {
+	case "created", "started", "aborted", "noStart", "unknownFinish":
+		return gamesource.Game{}, false
+	}
+	if eg.PGN == "" {
+		return gamesource.Game{}, false
+	}
+
+	whiteResult, blackResult := "draw", "draw"
+	switch eg.Winner {
+	case "white":
+		whiteResult, blackResult = "win", "loss"
+	case "black":
+		whiteResult, blackResult = "loss", "win"
+	}
+
+	endTime := eg.LastMoveAt
+	if endTime == 0 {
+		endTime = eg.CreatedAt
+	}
+
+	return gamesource.Game{
+		ID:        eg.ID,
+		URL:       "https://lichess.org/" + eg.ID,
+		PGN:       eg.PGN,
+		TimeClass: NormalizeTimeClass(eg.Speed),
+		EndTime:   time.UnixMilli(endTime),
+		White: gamesource.Player{
+			Username: eg.Players.White.Name(),
+			Rating:   eg.Players.White.Rating,
+			Result:   whiteResult,
+		},
+		Black: gamesource.Player{
+			Username: eg.Players.Black.Name(),
+			Rating:   eg.Players.Black.Rating,
+			Result:   blackResult,
+		},
+	}, true
+}
+
+// NormalizeTimeClass maps Lichess speeds onto the Chess.com time classes used
+// throughout the app (bullet, blitz, rapid, daily).
+func NormalizeTimeClass(speed string) string {
+	switch speed {
+	case "ultraBullet", "bullet":
+		return "bullet"
+	case "blitz":
+		return "blitz"
+	case "rapid", "classical":
+		return "rapid"
+	case "correspondence":
+		return "daily"
+	default:
+		return speed
+	}
+}
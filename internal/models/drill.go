@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// HangingDrill is one of the player's own moves that left material en
+// prise, drilled by finding any move that does not.
+type HangingDrill struct {
+	PositionID     int64     `json:"position_id"`
+	GameID         int64     `json:"game_id"`
+	MoveNumber     int       `json:"move_number"`
+	FEN            string    `json:"fen"`
+	MovePlayed     string    `json:"move_played"`
+	PrevMovePlayed string    `json:"prev_move_played"`
+	BestMove       string    `json:"best_move"`
+	Classification string    `json:"classification"`
+	MaterialHung   int       `json:"material_hung"`
+	WhitePlayer    string    `json:"white_player"`
+	BlackPlayer    string    `json:"black_player"`
+	TimeClass      string    `json:"time_class"`
+	PlayedAt       time.Time `json:"played_at"`
+}
+
+// HangingDrillResult is the verdict on a move tried in a hanging drill.
+type HangingDrillResult struct {
+	Move         string `json:"move"`
+	MoveSAN      string `json:"move_san"`
+	Safe         bool   `json:"safe"`
+	MaterialHung int    `json:"material_hung"` // what the tried move leaves en prise
+	MovePlayed   string `json:"move_played"`   // the game move, which hung material
+	BestMove     string `json:"best_move"`
+}
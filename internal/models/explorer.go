@@ -0,0 +1,52 @@
+package models
+
+// OpeningTreeMove is one opening move of a game, from the position before it.
+type OpeningTreeMove struct {
+	GameID int64  `json:"game_id"`
+	Ply    int    `json:"ply"` // 1-based
+	FEN    string `json:"fen"` // normalized
+	Move   string `json:"move"`
+	SAN    string `json:"san"`
+}
+
+// ExplorerFilter selects the games aggregated into the opening tree.
+type ExplorerFilter struct {
+	ProfileID int64
+	FEN       string // normalized
+	Color     string // color the player had; empty for both
+	TimeClass string
+}
+
+// ExplorerMove aggregates the games that continued with a move from a position.
+// Results are from the player's perspective.
+type ExplorerMove struct {
+	Move          string   `json:"move"`
+	SAN           string   `json:"san"`
+	Games         int      `json:"games"`
+	Wins          int      `json:"wins"`
+	Draws         int      `json:"draws"`
+	Losses        int      `json:"losses"`
+	AnalyzedGames int      `json:"analyzed_games"`
+	AvgAccuracy   *float64 `json:"avg_accuracy"` // player's accuracy, nil until a game is analyzed
+	BlunderRate   float64  `json:"blunder_rate"` // player blunders per analyzed game
+}
+
+// Score is the player's points per game (0-1), counting draws as half.
+func (m ExplorerMove) Score() float64 {
+	if m.Games == 0 {
+		return 0
+	}
+	return (float64(m.Wins) + 0.5*float64(m.Draws)) / float64(m.Games)
+}
+
+// ExplorerPosition is a node of the opening tree with the moves played from it.
+type ExplorerPosition struct {
+	FEN     string         `json:"fen"`
+	Path    []string       `json:"path"`     // UCI moves from the start position, when reached by moves
+	PathSAN []string       `json:"path_san"` // Path in SAN
+	Games   int            `json:"games"`
+	Wins    int            `json:"wins"`
+	Draws   int            `json:"draws"`
+	Losses  int            `json:"losses"`
+	Moves   []ExplorerMove `json:"moves"`
+}
@@ -2,6 +2,13 @@ package models
 
 import "time"
 
+// Flashcard kinds: engine cards are answered with the engine's best moves,
+// repertoire cards with the prepared moves of the player's repertoire
+const (
+	FlashcardKindEngine     = "engine"
+	FlashcardKindRepertoire = "repertoire"
+)
+
 type Flashcard struct {
 	ID            int64     `json:"id"`
 	PositionID    int64     `json:"position_id"`
@@ -10,7 +17,21 @@ type Flashcard struct {
 	EaseFactor    float64   `json:"ease_factor"`
 	TimesReviewed int       `json:"times_reviewed"`
 	TimesCorrect  int       `json:"times_correct"`
+	Kind          string    `json:"kind"`
 	CreatedAt     time.Time `json:"created_at"`
+
+	// Tactical motifs of the best move and its line, detected by analysis
+	Motifs []string `json:"motifs,omitempty"`
+
+	// Set when re-analysis found the move was not a mistake after all; retired
+	// cards are never due but keep their review history
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+
+	// FSRS memory state; zero when the card has not been scheduled by FSRS
+	Stability      float64    `json:"stability,omitempty"`
+	Difficulty     float64    `json:"difficulty,omitempty"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+	Retrievability float64    `json:"retrievability,omitempty"` // computed, not stored
 }
 
 type FlashcardWithPosition struct {
@@ -33,6 +54,56 @@ type FlashcardWithPosition struct {
 	OpponentRating int       `json:"opponent_rating"`
 	PlayedAt       time.Time `json:"played_at"`
 	TimeClass      string    `json:"time_class"`
+	ECOCode        string    `json:"eco_code,omitempty"`
+	OpeningName    string    `json:"opening_name,omitempty"`
+	MaterialHung   int       `json:"material_hung"`
+
+	PV              []string       `json:"pv,omitempty"`
+	PVSAN           []string       `json:"pv_san,omitempty"`
+	Lines           []PositionLine `json:"lines,omitempty"`
+	AcceptableMoves []string       `json:"acceptable_moves"`
+	RepertoireMoves []string       `json:"repertoire_moves,omitempty"` // prepared moves, for repertoire cards
+	Line            []string       `json:"-"`                          // solution moves, answered by the server step by step
+	LineMoves       int            `json:"line_moves"`                 // solver moves in Line
+	Tags            []string       `json:"tags"`                       // the user's tags
+	AutoTags        []string       `json:"auto_tags"`                  // tags derived from the position and game
+}
+
+// FlashcardFilter selects the due cards of a review session: cards must match
+// every field that is set and carry every user tag in Tags.
+type FlashcardFilter struct {
+	ECOCode        string
+	Opening        string // opening family, matching its variations too
+	Phase          string // opening, middlegame or endgame
+	Classification string
+	TimeClass      string
+	Motifs         []string // tactical motifs, all required
+	Hung           string   // how much material the move hung: pawn, piece, rook or queen
+	Tags           []string
+	Limit          int
+}
+
+// FlashcardDeck is a named set of tags reviewed together.
+type FlashcardDeck struct {
+	ID        int64     `json:"id"`
+	ProfileID int64     `json:"profile_id"`
+	Name      string    `json:"name"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	DueCount  int       `json:"due_count"` // computed, not stored
+}
+
+// ReviewSession is a batch of due cards matching a set of tags.
+type ReviewSession struct {
+	Tags  []string                `json:"tags"`
+	Due   int                     `json:"due"` // all due cards matching the tags
+	Cards []FlashcardWithPosition `json:"cards"`
+}
+
+// FlashcardTagCount is how many active cards carry a tag.
+type FlashcardTagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
 }
 
 type ReviewHistory struct {
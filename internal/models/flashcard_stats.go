@@ -21,6 +21,17 @@ type FlashcardClassificationStat struct {
 	AvgReviewsNeeded float64 `json:"avg_reviews_needed"`
 }
 
+// FlashcardMotifStat is the review performance of the cards sharing a
+// tactical motif. A card with several motifs counts towards each of them.
+type FlashcardMotifStat struct {
+	Motif            string  `json:"motif"`
+	TotalCards       int     `json:"total_cards"`
+	TotalReviews     int     `json:"total_reviews"`
+	AvgAccuracy      float64 `json:"avg_accuracy"`
+	AvgEaseFactor    float64 `json:"avg_ease_factor"`
+	AvgReviewsNeeded float64 `json:"avg_reviews_needed"`
+}
+
 type FlashcardPhaseStat struct {
 	Phase         string  `json:"phase"`
 	TotalCards    int     `json:"total_cards"`
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// FSRS optimization run statuses
+const (
+	FSRSOptimizationPending   = "pending"
+	FSRSOptimizationRunning   = "running"
+	FSRSOptimizationCompleted = "completed"
+	FSRSOptimizationFailed    = "failed"
+)
+
+// FSRSOptimization is a run of the scheduler weight optimizer over a profile's review log.
+type FSRSOptimization struct {
+	ID                 int64            `json:"id"`
+	ProfileID          int64            `json:"profile_id"`
+	Status             string           `json:"status"`
+	ReviewCount        int              `json:"review_count"`
+	LogLossBefore      float64          `json:"log_loss_before"`
+	LogLossAfter       float64          `json:"log_loss_after"`
+	ObservedRetention  float64          `json:"observed_retention"`
+	PredictedRetention float64          `json:"predicted_retention"`
+	Weights            []float64        `json:"weights,omitempty"`
+	RetentionCurve     []RetentionPoint `json:"retention_curve,omitempty"`
+	Error              string           `json:"error,omitempty"`
+	CreatedAt          time.Time        `json:"created_at"`
+	CompletedAt        *time.Time       `json:"completed_at,omitempty"`
+	AppliedAt          *time.Time       `json:"applied_at,omitempty"`
+}
+
+// RetentionPoint is the predicted average recall probability a number of days
+// after review, with the profile's current weights and with the fitted ones.
+type RetentionPoint struct {
+	Days    int     `json:"days"`
+	Current float64 `json:"current"`
+	Fitted  float64 `json:"fitted"`
+}
@@ -18,32 +18,96 @@ type Game struct {
 	OpeningName    string    `json:"opening_name"`
 	OpeningURL     string    `json:"opening_url"`
 	AnalysisStatus string    `json:"analysis_status"`
+	FailureReason  string    `json:"failure_reason,omitempty"` // why the last analysis failed (errors.Reason*)
+	ContentHash    string    `json:"content_hash,omitempty"`   // set for uploaded PGN games
+	WhiteACPL      *float64  `json:"white_acpl,omitempty"`
+	BlackACPL      *float64  `json:"black_acpl,omitempty"`
+	WhiteAccuracy  *float64  `json:"white_accuracy,omitempty"`
+	BlackAccuracy  *float64  `json:"black_accuracy,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
-type GameFilter struct {
-	ProfileID   int64
-	TimeClass   string
-	Result      string
-	OpeningName string
-	Opponent    string
-	Limit       int
-	Offset      int
-	OrderBy     string
-	OrderDir    string
+// PlayerAccuracy returns the profile owner's accuracy, or nil if not computed yet.
+func (g Game) PlayerAccuracy() *float64 {
+	if g.PlayedAs == "black" {
+		return g.BlackAccuracy
+	}
+	return g.WhiteAccuracy
 }
 
-type AnalysisFilter struct {
+// OpponentAccuracy returns the opponent's accuracy, or nil if not computed yet.
+func (g Game) OpponentAccuracy() *float64 {
+	if g.PlayedAs == "black" {
+		return g.WhiteAccuracy
+	}
+	return g.BlackAccuracy
+}
+
+// PlayerACPL returns the profile owner's average centipawn loss, or nil if not computed yet.
+func (g Game) PlayerACPL() *float64 {
+	if g.PlayedAs == "black" {
+		return g.BlackACPL
+	}
+	return g.WhiteACPL
+}
+
+// OpponentACPL returns the opponent's average centipawn loss, or nil if not computed yet.
+func (g Game) OpponentACPL() *float64 {
+	if g.PlayedAs == "black" {
+		return g.WhiteACPL
+	}
+	return g.BlackACPL
+}
+
+// GameAccuracy holds the per-side accuracy figures computed for an analyzed game.
+type GameAccuracy struct {
+	WhiteACPL     float64
+	BlackACPL     float64
+	WhiteAccuracy float64
+	BlackAccuracy float64
+}
+
+type GameFilter struct {
 	ProfileID     int64
-	TimeClasses   []string // Support multiple time classes
+	TimeClass     string
 	Result        string
-	Opponent      string
 	OpeningName   string
-	StartDate     *time.Time
-	EndDate       *time.Time
+	Opponent      string
+	FailureReason string // only failed games that failed for this reason
 	Limit         int
-	MinRating     int
-	MaxRating     int
-	PlayedAs      string
-	IncludeFailed bool
+	Offset        int
+	OrderBy       string
+	OrderDir      string
+}
+
+type AnalysisFilter struct {
+	ProfileID      int64
+	TimeClasses    []string // Support multiple time classes
+	Result         string
+	Opponent       string
+	OpeningName    string
+	StartDate      *time.Time
+	EndDate        *time.Time
+	Limit          int
+	MinRating      int
+	MaxRating      int
+	PlayedAs       string
+	IncludeFailed  bool
+	IncompleteOnly bool   // only games whose analysis stopped part way
+	FailureReason  string // only failed games that failed for this reason
+	Engine         string // engine profile to analyze with, empty for the default
+}
+
+// PGNImportResult summarizes a PGN file upload.
+type PGNImportResult struct {
+	Total      int              `json:"total"`
+	Imported   int              `json:"imported"`
+	Duplicates int              `json:"duplicates"`
+	Errors     []PGNImportError `json:"errors"`
+}
+
+// PGNImportError describes a game from an upload that could not be imported.
+type PGNImportError struct {
+	Index  int    `json:"index"` // 1-based position of the game in the file
+	Reason string `json:"reason"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Job queues, each run by its own worker pool
+const (
+	JobQueueAnalysis = "analysis"
+	JobQueueImport   = "import"
+)
+
+// Job is a background job stored in the database so that it survives restarts.
+type Job struct {
+	ID             int64      `json:"id"`
+	Queue          string     `json:"queue"`
+	Kind           string     `json:"kind"`
+	Payload        string     `json:"payload"` // JSON encoded arguments
+	State          string     `json:"state"`
+	Priority       int        `json:"priority"` // higher runs first
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts"`
+	RunAt          time.Time  `json:"run_at"`
+	LeaseOwner     string     `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
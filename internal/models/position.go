@@ -3,17 +3,33 @@ package models
 import "time"
 
 type Position struct {
-	ID             int64     `json:"id"`
-	GameID         int64     `json:"game_id"`
-	MoveNumber     int       `json:"move_number"`
-	FEN            string    `json:"fen"`
-	MovePlayed     string    `json:"move_played"`
-	BestMove       string    `json:"best_move"`
-	EvalBefore     float64   `json:"eval_before"`
-	EvalAfter      float64   `json:"eval_after"`
-	EvalDiff       float64   `json:"eval_diff"`
-	MateBefore     *int      `json:"mate_before"`
-	MateAfter      *int      `json:"mate_after"`
-	Classification string    `json:"classification"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID             int64          `json:"id"`
+	GameID         int64          `json:"game_id"`
+	MoveNumber     int            `json:"move_number"`
+	FEN            string         `json:"fen"`
+	MovePlayed     string         `json:"move_played"`
+	BestMove       string         `json:"best_move"`
+	EvalBefore     float64        `json:"eval_before"`
+	EvalAfter      float64        `json:"eval_after"`
+	EvalDiff       float64        `json:"eval_diff"`
+	MateBefore     *int           `json:"mate_before"`
+	MateAfter      *int           `json:"mate_after"`
+	Classification string         `json:"classification"`
+	PV             []string       `json:"pv,omitempty"`     // best line in UCI, starting with BestMove
+	PVSAN          []string       `json:"pv_san,omitempty"` // PV converted to SAN for display (not stored)
+	Lines          []PositionLine `json:"lines,omitempty"`
+	Depth          int            `json:"depth"`         // search depth of the evaluation, 0 when unknown
+	MaterialHung   int            `json:"material_hung"` // pawns the move left en prise, 0 unless the engine judged it an error
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// PositionLine is one of the top engine lines (MultiPV) for a position.
+// Scores are from white's perspective, like EvalBefore/MateBefore.
+type PositionLine struct {
+	PositionID int64    `json:"position_id"`
+	Rank       int      `json:"rank"`
+	Move       string   `json:"move"`
+	CP         float64  `json:"cp"`
+	Mate       *int     `json:"mate"`
+	PV         []string `json:"pv"`
 }
@@ -3,8 +3,12 @@ package models
 import "time"
 
 type Profile struct {
-	ID         int64      `json:"id"`
-	Username   string     `json:"username"`
-	CreatedAt  time.Time  `json:"created_at"`
-	LastSyncAt *time.Time `json:"last_sync_at"`
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Platform  string `json:"platform"`  // chesscom, lichess
+	Scheduler string `json:"scheduler"` // sm2, fsrs
+	// FSRSWeights are weights fitted to the profile's review log; nil uses the defaults
+	FSRSWeights []float64  `json:"fsrs_weights,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSyncAt  *time.Time `json:"last_sync_at"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Who left the repertoire first in a game
+const (
+	DeviatedByPlayer   = "player"
+	DeviatedByOpponent = "opponent"
+)
+
+// RepertoireMove is one prepared move: the move to play (or expect) in a
+// position, for the repertoire of the given color. FEN is normalized so
+// transpositions share the same entries.
+type RepertoireMove struct {
+	ID        int64     `json:"id"`
+	ProfileID int64     `json:"profile_id"`
+	Color     string    `json:"color"`
+	FEN       string    `json:"fen"`
+	Move      string    `json:"move"` // UCI
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RepertoireSummary counts the prepared positions and moves of one color.
+type RepertoireSummary struct {
+	Color     string `json:"color"`
+	Positions int    `json:"positions"`
+	Moves     int    `json:"moves"`
+}
+
+// RepertoireDeviation is the first ply of a game that left the player's
+// repertoire, either by the player or by the opponent.
+type RepertoireDeviation struct {
+	GameID        int64     `json:"game_id"`
+	ProfileID     int64     `json:"profile_id"`
+	Ply           int       `json:"ply"` // 1-based, like Position.MoveNumber
+	DeviatedBy    string    `json:"deviated_by"`
+	FEN           string    `json:"fen"`
+	MovePlayed    string    `json:"move_played"`
+	ExpectedMoves []string  `json:"expected_moves"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Game details, filled when listing deviations
+	Opponent    string    `json:"opponent,omitempty"`
+	PlayedAs    string    `json:"played_as,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	OpeningName string    `json:"opening_name,omitempty"`
+	PlayedAt    time.Time `json:"played_at,omitempty"`
+}
+
+// RepertoireDeviationStat groups deviations from the same position by the same move.
+type RepertoireDeviationStat struct {
+	DeviatedBy    string   `json:"deviated_by"`
+	FEN           string   `json:"fen"`
+	MovePlayed    string   `json:"move_played"`
+	ExpectedMoves []string `json:"expected_moves"`
+	Ply           int      `json:"ply"`
+	Games         int      `json:"games"`
+	Losses        int      `json:"losses"`
+}
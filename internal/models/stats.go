@@ -57,6 +57,24 @@ type MonthlyStat struct {
 	AvgRating     float64 `json:"avg_rating"`
 }
 
+// AccuracyTrendStat is the player's average accuracy for one month of analyzed games.
+type AccuracyTrendStat struct {
+	YearMonth           string  `json:"year_month"`
+	Games               int     `json:"games"`
+	AvgAccuracy         float64 `json:"avg_accuracy"`
+	AvgACPL             float64 `json:"avg_acpl"`
+	AvgOpponentAccuracy float64 `json:"avg_opponent_accuracy"`
+}
+
+// OpeningAccuracyStat is the player's average accuracy in one opening.
+type OpeningAccuracyStat struct {
+	OpeningName string  `json:"opening_name"`
+	ECOCode     string  `json:"eco_code"`
+	Games       int     `json:"games"`
+	AvgAccuracy float64 `json:"avg_accuracy"`
+	AvgACPL     float64 `json:"avg_acpl"`
+}
+
 type MistakePhaseStat struct {
 	Phase          string  `json:"phase"`
 	Classification string  `json:"classification"`
@@ -0,0 +1,82 @@
+package pgn
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SplitGames splits a multi-game PGN document into individual game texts.
+// A new game starts at the first header line that follows movetext, so games
+// separated only by blank lines or by comments are handled alike.
+func SplitGames(pgn string) []string {
+	pgn = strings.TrimPrefix(pgn, "\ufeff")
+	pgn = strings.ReplaceAll(pgn, "\r\n", "\n")
+
+	var games []string
+	var current []string
+	inMovetext := false
+
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(current, "\n"))
+		if text != "" {
+			games = append(games, text)
+		}
+		current = current[:0]
+		inMovetext = false
+	}
+
+	for _, line := range strings.Split(pgn, "\n") {
+		trimmed := strings.TrimSpace(line)
+		isHeader := strings.HasPrefix(trimmed, "[")
+		if isHeader && inMovetext {
+			flush()
+		}
+		if trimmed != "" && !isHeader {
+			inMovetext = true
+		}
+		current = append(current, line)
+	}
+	flush()
+	return games
+}
+
+// TimeClassFromTimeControl derives the Chess.com style time class (bullet,
+// blitz, rapid, daily) from a PGN TimeControl tag such as "600+5". The
+// estimated game duration is base + 40 * increment seconds. Unknown or missing
+// time controls return an empty string.
+func TimeClassFromTimeControl(tc string) string {
+	tc = strings.TrimSpace(tc)
+	if tc == "" || tc == "-" || tc == "?" {
+		return ""
+	}
+	// Multi-stage controls ("40/5400+30:1800+30") are classified by their
+	// first stage; "moves/seconds" stages use the seconds as the base time.
+	stage := strings.SplitN(tc, ":", 2)[0]
+	if i := strings.Index(stage, "/"); i >= 0 {
+		stage = stage[i+1:]
+	}
+	parts := strings.SplitN(stage, "+", 2)
+	base, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return ""
+	}
+	inc := 0
+	if len(parts) == 2 {
+		if inc, err = strconv.Atoi(parts[1]); err != nil {
+			return ""
+		}
+	}
+
+	estimated := base + 40*inc
+	switch {
+	case base >= 86400:
+		// Correspondence controls give at least a day per move, e.g. "1/259200"
+		return "daily"
+	case estimated < 180:
+		return "bullet"
+	case estimated < 600:
+		return "blitz"
+	default:
+		return "rapid"
+	}
+}
@@ -0,0 +1,54 @@
+package pgn_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/pgn"
+)
+
+func TestSplitGames_MultipleGames(t *testing.T) {
+	pgnText := "\ufeff[Event \"Club\"]\r\n[White \"Alice\"]\r\n[Black \"Bob\"]\r\n\r\n1. e4 e5 2. Nf3 1-0\r\n\r\n" +
+		"[Event \"Club\"]\n[White \"Bob\"]\n[Black \"Alice\"]\n\n1. d4 d5\n2. c4 { [%clk 0:10:00] } 0-1\n" +
+		"[Event \"Blitz\"]\n[White \"Carol\"]\n[Black \"Alice\"]\n\n1. c4 1/2-1/2"
+
+	games := pgn.SplitGames(pgnText)
+
+	require.Len(t, games, 3)
+	assert.Equal(t, "Alice", pgn.ParsePGNHeaders(games[0])["White"])
+	assert.Contains(t, games[0], "1. e4 e5 2. Nf3 1-0")
+	assert.Contains(t, games[1], "2. c4 { [%clk 0:10:00] } 0-1")
+	assert.Equal(t, "Carol", pgn.ParsePGNHeaders(games[2])["White"])
+}
+
+func TestSplitGames_Empty(t *testing.T) {
+	assert.Empty(t, pgn.SplitGames(""))
+	assert.Empty(t, pgn.SplitGames("\n\n  \n"))
+}
+
+func TestTimeClassFromTimeControl(t *testing.T) {
+	tests := []struct {
+		tc       string
+		expected string
+	}{
+		{"60", "bullet"},
+		{"120+1", "bullet"},
+		{"180+0", "blitz"},
+		{"300+2", "blitz"},
+		{"600", "rapid"},
+		{"900+10", "rapid"},
+		{"40/5400+30:1800+30", "rapid"},
+		{"1/86400", "daily"},
+		{"1/259200", "daily"},
+		{"-", ""},
+		{"", ""},
+		{"abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tc, func(t *testing.T) {
+			assert.Equal(t, tt.expected, pgn.TimeClassFromTimeControl(tt.tc))
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package repertoire
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/pgn"
+)
+
+// Colors a repertoire can be prepared for
+const (
+	White = "white"
+	Black = "black"
+)
+
+// IsValidColor reports whether color is white or black.
+func IsValidColor(color string) bool {
+	return color == White || color == Black
+}
+
+// ParsePGN extracts every prepared move from the main lines and variations of
+// the games in pgnText. Moves keep only FEN and Move; the same position and
+// move reached through several lines is returned once.
+func ParsePGN(pgnText string) ([]models.RepertoireMove, error) {
+	var moves []models.RepertoireMove
+	seen := map[string]bool{}
+
+	for i, gameText := range pgn.SplitGames(pgnText) {
+		opt, err := chess.PGN(strings.NewReader(gameText))
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %w", i+1, err)
+		}
+		game := chess.NewGame(opt)
+
+		var walk func(parent *chess.Move)
+		walk = func(parent *chess.Move) {
+			if parent.Position() == nil {
+				return
+			}
+			fen := analysis.NormalizeFEN(parent.Position().String())
+			for _, child := range parent.Children() {
+				uci := analysis.MoveToUCI(child)
+				if key := fen + "|" + uci; !seen[key] {
+					seen[key] = true
+					moves = append(moves, models.RepertoireMove{FEN: fen, Move: uci})
+				}
+				walk(child)
+			}
+		}
+		walk(game.GetRootMove())
+	}
+	return moves, nil
+}
+
+// Tree indexes prepared moves by normalized FEN.
+type Tree map[string][]string
+
+// NewTree builds a tree from stored repertoire moves.
+func NewTree(moves []models.RepertoireMove) Tree {
+	t := Tree{}
+	for _, m := range moves {
+		fen := analysis.NormalizeFEN(m.FEN)
+		t[fen] = append(t[fen], m.Move)
+	}
+	return t
+}
+
+// Expected returns the prepared moves for the position, or nil when the
+// repertoire does not cover it.
+func (t Tree) Expected(fen string) []string {
+	return t[analysis.NormalizeFEN(fen)]
+}
+
+// FindDeviation walks the game's positions in order and returns the first ply
+// whose position is covered by the repertoire of color but whose move is not
+// one of the prepared ones. The game leaving the covered positions without a
+// wrong move means the preparation simply ended, which is not a deviation.
+func FindDeviation(t Tree, color string, positions []models.Position) *models.RepertoireDeviation {
+	for _, p := range positions {
+		expected := t.Expected(p.FEN)
+		if len(expected) == 0 {
+			return nil
+		}
+		if containsMove(expected, p.MovePlayed) {
+			continue
+		}
+
+		deviatedBy := models.DeviatedByOpponent
+		if sideToMove(p.FEN) == color {
+			deviatedBy = models.DeviatedByPlayer
+		}
+		return &models.RepertoireDeviation{
+			Ply:           p.MoveNumber,
+			DeviatedBy:    deviatedBy,
+			FEN:           p.FEN,
+			MovePlayed:    p.MovePlayed,
+			ExpectedMoves: append([]string(nil), expected...),
+		}
+	}
+	return nil
+}
+
+func containsMove(moves []string, move string) bool {
+	for _, m := range moves {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
+
+// sideToMove returns white or black from the FEN's active color field.
+func sideToMove(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 1 && fields[1] == "b" {
+		return Black
+	}
+	return White
+}
@@ -0,0 +1,130 @@
+package repertoire_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repertoire"
+)
+
+const (
+	startFEN      = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	afterE4FEN    = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	afterE4E5FEN  = "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+	afterE4C5FEN  = "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"
+	italianFEN    = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	repertoirePGN = `[Event "White repertoire"]
+
+1. e4 e5 (1... c5 2. Nf3 (2. c3) d6) 2. Nf3 Nc6 3. Bc4 *
+
+[Event "Transposition"]
+
+1. Nf3 Nc6 2. e4 e5 3. Bc4 *
+`
+)
+
+func TestParsePGN(t *testing.T) {
+	moves, err := repertoire.ParsePGN(repertoirePGN)
+	require.NoError(t, err)
+
+	tree := repertoire.NewTree(moves)
+	assert.Equal(t, []string{"e2e4", "g1f3"}, tree.Expected(startFEN))
+	assert.ElementsMatch(t, []string{"e7e5", "c7c5"}, tree.Expected(afterE4FEN))
+	assert.ElementsMatch(t, []string{"g1f3", "c2c3"}, tree.Expected(afterE4C5FEN))
+	// Both games reach the Italian position; the move is only stored once
+	assert.Equal(t, []string{"f1c4"}, tree.Expected(italianFEN))
+
+	count := 0
+	for _, m := range moves {
+		if m.Move == "f1c4" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestParsePGN_InvalidMove(t *testing.T) {
+	_, err := repertoire.ParsePGN("[Event \"Broken\"]\n\n1. e4 e5 2. Ke3 *\n")
+	assert.Error(t, err)
+}
+
+func TestFindDeviation(t *testing.T) {
+	moves, err := repertoire.ParsePGN(repertoirePGN)
+	require.NoError(t, err)
+	tree := repertoire.NewTree(moves)
+
+	tests := []struct {
+		name      string
+		tree      repertoire.Tree
+		color     string
+		positions []models.Position
+		expected  *models.RepertoireDeviation
+	}{
+		{
+			name:  "player leaves the repertoire",
+			color: repertoire.White,
+			positions: []models.Position{
+				{MoveNumber: 1, FEN: startFEN, MovePlayed: "e2e4"},
+				{MoveNumber: 2, FEN: afterE4FEN, MovePlayed: "c7c5"},
+				{MoveNumber: 3, FEN: afterE4C5FEN, MovePlayed: "b1c3"},
+			},
+			expected: &models.RepertoireDeviation{
+				Ply: 3, DeviatedBy: models.DeviatedByPlayer, FEN: afterE4C5FEN,
+				MovePlayed: "b1c3", ExpectedMoves: []string{"g1f3", "c2c3"},
+			},
+		},
+		{
+			name:  "opponent leaves the repertoire",
+			color: repertoire.White,
+			positions: []models.Position{
+				{MoveNumber: 1, FEN: startFEN, MovePlayed: "e2e4"},
+				{MoveNumber: 2, FEN: afterE4FEN, MovePlayed: "e7e6"},
+			},
+			expected: &models.RepertoireDeviation{
+				Ply: 2, DeviatedBy: models.DeviatedByOpponent, FEN: afterE4FEN,
+				MovePlayed: "e7e6", ExpectedMoves: []string{"e7e5", "c7c5"},
+			},
+		},
+		{
+			name:  "preparation ends without a deviation",
+			color: repertoire.White,
+			positions: []models.Position{
+				{MoveNumber: 1, FEN: startFEN, MovePlayed: "e2e4"},
+				{MoveNumber: 2, FEN: afterE4FEN, MovePlayed: "e7e5"},
+				{MoveNumber: 3, FEN: afterE4E5FEN, MovePlayed: "g1f3"},
+				{MoveNumber: 4, FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2", MovePlayed: "b8c6"},
+				{MoveNumber: 5, FEN: italianFEN, MovePlayed: "f1c4"},
+				{MoveNumber: 6, FEN: "r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3", MovePlayed: "g8f6"},
+			},
+		},
+		{
+			name:  "black repertoire sees white's first move as the opponent's",
+			color: repertoire.Black,
+			positions: []models.Position{
+				{MoveNumber: 1, FEN: startFEN, MovePlayed: "d2d4"},
+			},
+			expected: &models.RepertoireDeviation{
+				Ply: 1, DeviatedBy: models.DeviatedByOpponent, FEN: startFEN,
+				MovePlayed: "d2d4", ExpectedMoves: []string{"e2e4", "g1f3"},
+			},
+		},
+		{
+			name:      "empty repertoire",
+			tree:      repertoire.NewTree(nil),
+			color:     repertoire.White,
+			positions: []models.Position{{MoveNumber: 1, FEN: startFEN, MovePlayed: "d2d4"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := tree
+			if tt.tree != nil {
+				tr = tt.tree
+			}
+			assert.Equal(t, tt.expected, repertoire.FindDeviation(tr, tt.color, tt.positions))
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// DeckRepository handles flashcard deck data access
+type DeckRepository interface {
+	Insert(ctx context.Context, deck models.FlashcardDeck) (int64, error)
+	Get(ctx context.Context, id int64, profileID int64) (*models.FlashcardDeck, error)
+	ListByProfile(ctx context.Context, profileID int64) ([]models.FlashcardDeck, error)
+	Delete(ctx context.Context, id int64, profileID int64) error
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+)
+
+// EvalCacheRepository handles cached engine evaluation data access
+type EvalCacheRepository interface {
+	Get(ctx context.Context, engine, fen string, depth, multiPV int) ([]byte, error)
+	Put(ctx context.Context, engine, fen string, depth, multiPV int, data []byte) error
+	Prune(ctx context.Context, maxEntries int) (int, error)
+}
@@ -10,9 +10,22 @@ import (
 type FlashcardRepository interface {
 	Insert(ctx context.Context, flashcard models.Flashcard) (int64, error)
 	Update(ctx context.Context, flashcard models.Flashcard) error
-	NextFlashcards(ctx context.Context, profileID int64, limit int) ([]models.Flashcard, error)
+	SetMotifs(ctx context.Context, id int64, motifs []string) error
+	UndetectedMotifCards(ctx context.Context, limit int) ([]models.FlashcardWithPosition, error)
+	NextFlashcards(ctx context.Context, profileID int64, filter models.FlashcardFilter) ([]models.Flashcard, error)
+	CountDue(ctx context.Context, profileID int64, filter models.FlashcardFilter) (int, error)
 	FlashcardWithPosition(ctx context.Context, id int64, profileID int64) (*models.FlashcardWithPosition, error)
+	FlashcardsWithPosition(ctx context.Context, ids []int64, profileID int64) ([]models.FlashcardWithPosition, error)
 	InsertReviewHistory(ctx context.Context, flashcardID int64, quality int, timeSeconds float64) error
+	ReviewHistoryByProfile(ctx context.Context, profileID int64) (map[int64][]models.ReviewHistory, error)
+	UnseededReviewHistory(ctx context.Context, profileID int64) (map[int64][]models.ReviewHistory, error)
+	UpdateMemoryState(ctx context.Context, flashcard models.Flashcard) error
 	CountByGameID(ctx context.Context, gameID int64, profileID int64) (int, error)
 	ListByGameID(ctx context.Context, gameID int64, profileID int64, limit int, offset int) ([]models.FlashcardWithPosition, error)
+	ListForGame(ctx context.Context, gameID int64) ([]models.Flashcard, error)
+	SetRetired(ctx context.Context, id int64, retired bool) error
+	AddTag(ctx context.Context, flashcardID int64, tag string) error
+	RemoveTag(ctx context.Context, flashcardID int64, tag string) error
+	ListTags(ctx context.Context, flashcardID int64) ([]string, error)
+	TagCounts(ctx context.Context, profileID int64) ([]models.FlashcardTagCount, error)
 }
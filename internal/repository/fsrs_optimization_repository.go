@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// FSRSOptimizationRepository handles FSRS optimizer run data access
+type FSRSOptimizationRepository interface {
+	Insert(ctx context.Context, profileID int64) (int64, error)
+	Update(ctx context.Context, o models.FSRSOptimization) error
+	Get(ctx context.Context, id int64) (*models.FSRSOptimization, error)
+	Latest(ctx context.Context, profileID int64) (*models.FSRSOptimization, error)
+	MarkApplied(ctx context.Context, id int64) error
+}
@@ -14,7 +14,10 @@ type GameRepository interface {
 	Insert(ctx context.Context, game models.Game) (int64, error)
 	InsertBatch(ctx context.Context, games []models.Game) ([]int64, error)
 	UpdateStatus(ctx context.Context, id int64, status string) error
+	UpdateFailure(ctx context.Context, id int64, status, reason string) error
+	CountFailureReasons(ctx context.Context, profileID int64) (map[string]int, error)
 	UpdateOpening(ctx context.Context, id int64, ecoCode, openingName string) error
+	UpdateAccuracy(ctx context.Context, id int64, acc models.GameAccuracy) error
 	ResetProcessingToPending(ctx context.Context, profileID int64) error
 	GamesNeedingAnalysis(ctx context.Context, profileID int64) ([]models.Game, error)
 	CountGamesNeedingAnalysis(ctx context.Context, profileID int64) (int, error)
@@ -22,6 +25,7 @@ type GameRepository interface {
 	CountGamesForAnalysis(ctx context.Context, filter models.AnalysisFilter) (int, error)
 	CountGamesByStatusWithFilter(ctx context.Context, profileID int64, status string, filter models.AnalysisFilter) (int, error)
 	GetExistingChessComIDs(ctx context.Context, profileID int64) (map[string]bool, error)
+	GetExistingContentHashes(ctx context.Context, profileID int64) (map[string]bool, error)
 	CountByStatus(ctx context.Context, profileID int64, status string) (int, error)
 	GetAverageAnalysisTime(ctx context.Context, profileID int64) (float64, error)
 }
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// ErrLeaseLost is returned when a job is no longer held under the given lease,
+// because it expired and the job was claimed again.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// JobRepository handles durable background job data access
+type JobRepository interface {
+	Enqueue(ctx context.Context, job models.Job) (int64, error)
+	Claim(ctx context.Context, queue, owner string, lease time.Duration, oldestFirst bool) (*models.Job, error)
+	Heartbeat(ctx context.Context, id int64, owner string, lease time.Duration) error
+	Complete(ctx context.Context, id int64, owner string) error
+	Fail(ctx context.Context, id int64, owner string, reason string) error
+	Reschedule(ctx context.Context, id int64, owner string, runAt time.Time, reason string) error
+	Release(ctx context.Context, id int64, owner string) error
+	Retry(ctx context.Context, id int64) error
+	List(ctx context.Context, states []string, limit int) ([]models.Job, error)
+	CountByState(ctx context.Context, queue string) (map[string]int, error)
+	DeleteQueued(ctx context.Context, queue string) (int, error)
+	QueuePosition(ctx context.Context, queue string, gameID int64) (int, error)
+}
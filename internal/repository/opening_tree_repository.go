@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// OpeningTreeRepository handles opening explorer data access
+type OpeningTreeRepository interface {
+	UnindexedGames(ctx context.Context, profileID int64, limit int) ([]models.Game, error)
+	IndexGame(ctx context.Context, gameID int64, moves []models.OpeningTreeMove) error
+	PositionMoves(ctx context.Context, filter models.ExplorerFilter) ([]models.ExplorerMove, error)
+}
@@ -11,4 +11,14 @@ type PositionRepository interface {
 	Insert(ctx context.Context, position models.Position) (int64, error)
 	InsertBatch(ctx context.Context, positions []models.Position) ([]int64, error)
 	PositionsForGame(ctx context.Context, gameID int64) ([]models.Position, error)
+	UpdateAnalysis(ctx context.Context, positions []models.Position) error
+
+	// Errors analyzed before hung material was recorded
+	UncheckedMaterialHung(ctx context.Context, limit int) ([]models.Position, error)
+	SetMaterialHung(ctx context.Context, id int64, pawns int) error
+
+	// The player's own moves that hung material, for the hanging drill
+	RandomHanging(ctx context.Context, profileID int64, excludeID int64) (*models.HangingDrill, error)
+	GetHanging(ctx context.Context, id int64, profileID int64) (*models.HangingDrill, error)
+	CountHanging(ctx context.Context, profileID int64) (int, error)
 }
@@ -11,7 +11,9 @@ import (
 type ProfileRepository interface {
 	Get(ctx context.Context, id int64) (*models.Profile, error)
 	List(ctx context.Context) ([]models.Profile, error)
-	Upsert(ctx context.Context, username string) (*models.Profile, error)
+	Upsert(ctx context.Context, username, platform string) (*models.Profile, error)
 	UpdateSync(ctx context.Context, id int64, t time.Time) error
+	UpdateScheduler(ctx context.Context, id int64, scheduler string) error
+	UpdateFSRSWeights(ctx context.Context, id int64, weights []float64) error
 	Delete(ctx context.Context, id int64) error
 }
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// RepertoireRepository handles opening repertoire data access
+type RepertoireRepository interface {
+	InsertMoves(ctx context.Context, profileID int64, color string, moves []models.RepertoireMove) (int, error)
+	ListMoves(ctx context.Context, profileID int64, color string) ([]models.RepertoireMove, error)
+	DeleteMoves(ctx context.Context, profileID int64, color string) error
+	Summary(ctx context.Context, profileID int64) ([]models.RepertoireSummary, error)
+	SaveDeviation(ctx context.Context, d models.RepertoireDeviation) error
+	ListDeviations(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviation, error)
+	DeviationStats(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviationStat, error)
+}
@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+type deckRepository struct {
+	db *sql.DB
+}
+
+// NewDeckRepository creates a new DeckRepository implementation
+func NewDeckRepository(db *sql.DB) repository.DeckRepository {
+	return &deckRepository{db: db}
+}
+
+const deckColumns = `id, profile_id, name, tags, created_at`
+
+func (r *deckRepository) Insert(ctx context.Context, deck models.FlashcardDeck) (int64, error) {
+	log := logger.FromContext(ctx).WithPrefix("deck_repo")
+	log.Debug("inserting deck: profile_id=%d, name=%s", deck.ProfileID, deck.Name)
+
+	tags := deck.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.db.ExecContext(ctx, `INSERT INTO flashcard_decks (profile_id, name, tags) VALUES (?, ?, ?)`,
+		deck.ProfileID, deck.Name, string(b))
+	if err != nil {
+		log.Error("failed to insert deck: %v", err)
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error("failed to get deck id: %v", err)
+		return 0, err
+	}
+	log.Debug("deck inserted: id=%d", id)
+	return id, nil
+}
+
+func (r *deckRepository) Get(ctx context.Context, id int64, profileID int64) (*models.FlashcardDeck, error) {
+	log := logger.FromContext(ctx).WithPrefix("deck_repo")
+	log.Debug("getting deck: id=%d, profile_id=%d", id, profileID)
+
+	deck, err := scanDeck(r.db.QueryRowContext(ctx, `SELECT `+deckColumns+` FROM flashcard_decks WHERE id = ? AND profile_id = ?`, id, profileID))
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Debug("deck not found: id=%d", id)
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("failed to get deck: %v", err)
+		return nil, err
+	}
+	return deck, nil
+}
+
+func (r *deckRepository) ListByProfile(ctx context.Context, profileID int64) ([]models.FlashcardDeck, error) {
+	log := logger.FromContext(ctx).WithPrefix("deck_repo")
+	log.Debug("listing decks: profile_id=%d", profileID)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+deckColumns+` FROM flashcard_decks WHERE profile_id = ? ORDER BY name`, profileID)
+	if err != nil {
+		log.Error("failed to query decks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var decks []models.FlashcardDeck
+	for rows.Next() {
+		deck, err := scanDeck(rows)
+		if err != nil {
+			log.Error("failed to scan deck row: %v", err)
+			return nil, err
+		}
+		decks = append(decks, *deck)
+	}
+	log.Debug("found %d decks", len(decks))
+	return decks, rows.Err()
+}
+
+func (r *deckRepository) Delete(ctx context.Context, id int64, profileID int64) error {
+	log := logger.FromContext(ctx).WithPrefix("deck_repo")
+	log.Debug("deleting deck: id=%d, profile_id=%d", id, profileID)
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM flashcard_decks WHERE id = ? AND profile_id = ?`, id, profileID)
+	if err != nil {
+		log.Error("failed to delete deck: %v", err)
+	}
+	return err
+}
+
+func scanDeck(row interface{ Scan(...any) error }) (*models.FlashcardDeck, error) {
+	var deck models.FlashcardDeck
+	var tags string
+	if err := row.Scan(&deck.ID, &deck.ProfileID, &deck.Name, &tags, &deck.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &deck.Tags); err != nil {
+		return nil, err
+	}
+	return &deck, nil
+}
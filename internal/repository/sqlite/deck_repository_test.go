@@ -0,0 +1,83 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+type DeckRepositorySuite struct {
+	suite.Suite
+	db          *sql.DB
+	repo        repository.DeckRepository
+	profileRepo repository.ProfileRepository
+}
+
+func (s *DeckRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewDeckRepository(s.db)
+	s.profileRepo = sqlite.NewProfileRepository(s.db)
+}
+
+func (s *DeckRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *DeckRepositorySuite) TestLifecycle() {
+	ctx := context.Background()
+	profile, err := s.profileRepo.Upsert(ctx, "testuser", "chesscom")
+	s.Require().NoError(err)
+	other, err := s.profileRepo.Upsert(ctx, "otheruser", "chesscom")
+	s.Require().NoError(err)
+
+	id, err := s.repo.Insert(ctx, models.FlashcardDeck{
+		ProfileID: profile.ID,
+		Name:      "Sicilian blunders",
+		Tags:      []string{"opening:Sicilian Defense", "classification:blunder"},
+	})
+	s.Require().NoError(err)
+	_, err = s.repo.Insert(ctx, models.FlashcardDeck{ProfileID: profile.ID, Name: "Endgames", Tags: []string{"phase:endgame"}})
+	s.Require().NoError(err)
+
+	// Names are unique per profile
+	_, err = s.repo.Insert(ctx, models.FlashcardDeck{ProfileID: profile.ID, Name: "Endgames", Tags: []string{"phase:endgame"}})
+	s.Assert().Error(err)
+	_, err = s.repo.Insert(ctx, models.FlashcardDeck{ProfileID: other.ID, Name: "Endgames", Tags: []string{"phase:endgame"}})
+	s.Assert().NoError(err)
+
+	deck, err := s.repo.Get(ctx, id, profile.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(deck)
+	s.Assert().Equal("Sicilian blunders", deck.Name)
+	s.Assert().Equal([]string{"opening:Sicilian Defense", "classification:blunder"}, deck.Tags)
+
+	deck, err = s.repo.Get(ctx, id, other.ID)
+	s.Require().NoError(err)
+	s.Assert().Nil(deck)
+
+	decks, err := s.repo.ListByProfile(ctx, profile.ID)
+	s.Require().NoError(err)
+	s.Require().Len(decks, 2)
+	s.Assert().Equal("Endgames", decks[0].Name)
+
+	// Other profiles cannot delete the deck
+	s.Require().NoError(s.repo.Delete(ctx, id, other.ID))
+	deck, err = s.repo.Get(ctx, id, profile.ID)
+	s.Require().NoError(err)
+	s.Assert().NotNil(deck)
+
+	s.Require().NoError(s.repo.Delete(ctx, id, profile.ID))
+	deck, err = s.repo.Get(ctx, id, profile.ID)
+	s.Require().NoError(err)
+	s.Assert().Nil(deck)
+}
+
+func TestDeckRepositorySuite(t *testing.T) {
+	suite.Run(t, new(DeckRepositorySuite))
+}
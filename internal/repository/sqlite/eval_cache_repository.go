@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+type evalCacheRepository struct {
+	db *sql.DB
+}
+
+// NewEvalCacheRepository creates a new EvalCacheRepository implementation
+func NewEvalCacheRepository(db *sql.DB) repository.EvalCacheRepository {
+	return &evalCacheRepository{db: db}
+}
+
+// Get returns the stored evaluation, or nil when the position is not cached.
+func (r *evalCacheRepository) Get(ctx context.Context, engine, fen string, depth, multiPV int) ([]byte, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `
+SELECT result FROM eval_cache WHERE engine = ? AND fen = ? AND depth = ? AND multipv = ?
+`, engine, fen, depth, multiPV).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.FromContext(ctx).WithPrefix("eval_cache_repo").Error("failed to get cached evaluation: %v", err)
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+func (r *evalCacheRepository) Put(ctx context.Context, engine, fen string, depth, multiPV int, data []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT OR REPLACE INTO eval_cache (engine, fen, depth, multipv, result)
+VALUES (?, ?, ?, ?, ?)
+`, engine, fen, depth, multiPV, string(data))
+	if err != nil {
+		logger.FromContext(ctx).WithPrefix("eval_cache_repo").Error("failed to cache evaluation: %v", err)
+	}
+	return err
+}
+
+// Prune deletes all but the maxEntries most recently stored evaluations.
+// Replacing an evaluation gives it a new rowid, so rowid order is store order.
+func (r *evalCacheRepository) Prune(ctx context.Context, maxEntries int) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+DELETE FROM eval_cache
+WHERE rowid IN (SELECT rowid FROM eval_cache ORDER BY rowid DESC LIMIT -1 OFFSET ?)
+`, maxEntries)
+	if err != nil {
+		logger.FromContext(ctx).WithPrefix("eval_cache_repo").Error("failed to prune evaluations: %v", err)
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,74 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+type EvalCacheRepositorySuite struct {
+	suite.Suite
+	db   *sql.DB
+	repo repository.EvalCacheRepository
+}
+
+func (s *EvalCacheRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewEvalCacheRepository(s.db)
+}
+
+func (s *EvalCacheRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *EvalCacheRepositorySuite) TestGetPut() {
+	ctx := context.Background()
+
+	data, err := s.repo.Get(ctx, "stockfish", explorerStartFEN, 18, 3)
+	s.Require().NoError(err)
+	s.Assert().Nil(data)
+
+	s.Require().NoError(s.repo.Put(ctx, "stockfish", explorerStartFEN, 18, 3, []byte(`{"BestMove":"e2e4"}`)))
+	s.Require().NoError(s.repo.Put(ctx, "stockfish", explorerStartFEN, 18, 3, []byte(`{"BestMove":"d2d4"}`)))
+	s.Require().NoError(s.repo.Put(ctx, "stockfish", explorerStartFEN, 12, 1, []byte(`{"BestMove":"c2c4"}`)))
+
+	data, err = s.repo.Get(ctx, "stockfish", explorerStartFEN, 18, 3)
+	s.Require().NoError(err)
+	s.Assert().JSONEq(`{"BestMove":"d2d4"}`, string(data))
+
+	data, err = s.repo.Get(ctx, "stockfish", explorerStartFEN, 12, 1)
+	s.Require().NoError(err)
+	s.Assert().JSONEq(`{"BestMove":"c2c4"}`, string(data))
+
+	data, err = s.repo.Get(ctx, "lc0", explorerStartFEN, 18, 3)
+	s.Require().NoError(err)
+	s.Assert().Nil(data)
+}
+
+func (s *EvalCacheRepositorySuite) TestPruneKeepsNewest() {
+	ctx := context.Background()
+	for depth := 1; depth <= 5; depth++ {
+		s.Require().NoError(s.repo.Put(ctx, "stockfish", explorerStartFEN, depth, 1, []byte(`{}`)))
+	}
+	// Storing an evaluation again makes it the newest
+	s.Require().NoError(s.repo.Put(ctx, "stockfish", explorerStartFEN, 1, 1, []byte(`{}`)))
+
+	pruned, err := s.repo.Prune(ctx, 2)
+	s.Require().NoError(err)
+	s.Equal(3, pruned)
+
+	for depth, kept := range map[int]bool{1: true, 2: false, 3: false, 4: false, 5: true} {
+		data, err := s.repo.Get(ctx, "stockfish", explorerStartFEN, depth, 1)
+		s.Require().NoError(err)
+		s.Equal(kept, data != nil, "depth %d", depth)
+	}
+}
+
+func TestEvalCacheRepositorySuite(t *testing.T) {
+	suite.Run(t, new(EvalCacheRepositorySuite))
+}
@@ -3,8 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"strings"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/repository"
@@ -21,56 +22,195 @@ func NewFlashcardRepository(db *sql.DB) repository.FlashcardRepository {
 
 func (r *flashcardRepository) Insert(ctx context.Context, c models.Flashcard) (int64, error) {
 	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
-	log.Debug("inserting flashcard: position_id=%d", c.PositionID)
+	log.Debug("inserting flashcard: position_id=%d, kind=%s", c.PositionID, c.Kind)
 
-	res, err := r.db.ExecContext(ctx, `
-INSERT INTO flashcards (position_id, due_at, interval_days, ease_factor, times_reviewed, times_correct)
-VALUES (?, ?, ?, ?, ?, ?)
-`, c.PositionID, c.DueAt, c.IntervalDays, c.EaseFactor, c.TimesReviewed, c.TimesCorrect)
-	if err != nil {
-		log.Error("failed to insert flashcard: %v", err)
-		return 0, err
+	kind := c.Kind
+	if kind == "" {
+		kind = models.FlashcardKindEngine
 	}
-	id, err := res.LastInsertId()
+	var id int64
+	err := tx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO flashcards (position_id, due_at, interval_days, ease_factor, times_reviewed, times_correct, kind, motifs_detected)
+VALUES (?, ?, ?, ?, ?, ?, ?, 1)
+`, c.PositionID, c.DueAt, c.IntervalDays, c.EaseFactor, c.TimesReviewed, c.TimesCorrect, kind)
+		if err != nil {
+			log.Error("failed to insert flashcard: %v", err)
+			return err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			log.Error("failed to get flashcard id: %v", err)
+			return err
+		}
+		return insertMotifs(ctx, tx, id, c.Motifs)
+	})
 	if err != nil {
-		log.Error("failed to get flashcard id: %v", err)
 		return 0, err
 	}
-	log.Debug("flashcard inserted: id=%d", id)
+	log.Debug("flashcard inserted: id=%d, motifs=%v", id, c.Motifs)
 	return id, nil
 }
 
+// SetMotifs replaces the tactical motifs of a flashcard and marks them as
+// detected.
+func (r *flashcardRepository) SetMotifs(ctx context.Context, id int64, motifs []string) error {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("setting flashcard motifs: id=%d, motifs=%v", id, motifs)
+
+	return tx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM flashcard_motifs WHERE flashcard_id = ?`, id); err != nil {
+			log.Error("failed to clear flashcard motifs: %v", err)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE flashcards SET motifs_detected = 1 WHERE id = ?`, id); err != nil {
+			log.Error("failed to mark flashcard motifs as detected: %v", err)
+			return err
+		}
+		return insertMotifs(ctx, tx, id, motifs)
+	})
+}
+
+// UndetectedMotifCards returns engine flashcards whose motifs were never
+// detected, with the position fields detection needs.
+func (r *flashcardRepository) UndetectedMotifCards(ctx context.Context, limit int) ([]models.FlashcardWithPosition, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("listing flashcards without detected motifs: limit=%d", limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT f.id, f.position_id, p.fen, p.best_move, COALESCE(p.pv, '')
+FROM flashcards f
+JOIN positions p ON p.id = f.position_id
+WHERE f.motifs_detected = 0 AND f.kind = ?
+ORDER BY f.id
+LIMIT ?
+`, models.FlashcardKindEngine, limit)
+	if err != nil {
+		log.Error("failed to query flashcards without detected motifs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.FlashcardWithPosition
+	for rows.Next() {
+		var c models.FlashcardWithPosition
+		var pv string
+		if err := rows.Scan(&c.ID, &c.PositionID, &c.FEN, &c.BestMove, &pv); err != nil {
+			log.Error("failed to scan flashcard without detected motifs: %v", err)
+			return nil, err
+		}
+		c.Kind = models.FlashcardKindEngine
+		c.PV = strings.Fields(pv)
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+func insertMotifs(ctx context.Context, tx *sql.Tx, flashcardID int64, motifs []string) error {
+	for _, motif := range motifs {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO flashcard_motifs (flashcard_id, motif) VALUES (?, ?)`, flashcardID, motif); err != nil {
+			logger.FromContext(ctx).WithPrefix("flashcard_repo").Error("failed to insert flashcard motif: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *flashcardRepository) Update(ctx context.Context, c models.Flashcard) error {
 	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
-	log.Debug("updating flashcard: id=%d, interval=%d, ease=%.2f", c.ID, c.IntervalDays, c.EaseFactor)
+	log.Debug("updating flashcard: id=%d, interval=%d, ease=%.2f, stability=%.2f", c.ID, c.IntervalDays, c.EaseFactor, c.Stability)
 
 	_, err := r.db.ExecContext(ctx, `
 UPDATE flashcards
-SET due_at = ?, interval_days = ?, ease_factor = ?, times_reviewed = ?, times_correct = ?
+SET due_at = ?, interval_days = ?, ease_factor = ?, times_reviewed = ?, times_correct = ?,
+    stability = ?, difficulty = ?, last_reviewed_at = ?
 WHERE id = ?
-`, c.DueAt, c.IntervalDays, c.EaseFactor, c.TimesReviewed, c.TimesCorrect, c.ID)
+`, c.DueAt, c.IntervalDays, c.EaseFactor, c.TimesReviewed, c.TimesCorrect,
+		nullFloat64(c.Stability), nullFloat64(c.Difficulty), c.LastReviewedAt, c.ID)
 	if err != nil {
 		log.Error("failed to update flashcard: %v", err)
 	}
 	return err
 }
 
-func (r *flashcardRepository) NextFlashcards(ctx context.Context, profileID int64, limit int) ([]models.Flashcard, error) {
+// flashcardPhase is the game phase of a card's position, by move number
+const flashcardPhase = `CASE WHEN p.move_number <= 15 THEN 'opening' WHEN p.move_number <= 35 THEN 'middlegame' ELSE 'endgame' END`
+
+// openingFamily cuts a game's opening name at its first ':' or ',', leaving
+// the opening its variation belongs to
+const openingFamily = `TRIM(CASE
+    WHEN instr(g.opening_name, ':') > 0 AND (instr(g.opening_name, ',') = 0 OR instr(g.opening_name, ':') < instr(g.opening_name, ','))
+        THEN substr(g.opening_name, 1, instr(g.opening_name, ':') - 1)
+    WHEN instr(g.opening_name, ',') > 0 THEN substr(g.opening_name, 1, instr(g.opening_name, ',') - 1)
+    ELSE g.opening_name
+END)`
+
+// hungMaterial names how much material a card's move left en prise, matching
+// flashcard.HungMaterial
+const hungMaterial = `CASE
+    WHEN p.material_hung >= 9 THEN 'queen'
+    WHEN p.material_hung >= 5 THEN 'rook'
+    WHEN p.material_hung >= 3 THEN 'piece'
+    WHEN p.material_hung > 0 THEN 'pawn'
+    ELSE ''
+END`
+
+// applyFlashcardFilter selects the profile's due cards matching the filter,
+// joining their positions and games.
+func applyFlashcardFilter(query squirrel.SelectBuilder, profileID int64, filter models.FlashcardFilter) squirrel.SelectBuilder {
+	query = query.
+		From("flashcards f").
+		Join("positions p ON p.id = f.position_id").
+		Join("games g ON g.id = p.game_id").
+		Where(squirrel.Eq{"g.profile_id": profileID}).
+		Where("f.due_at <= CURRENT_TIMESTAMP AND f.retired_at IS NULL")
+
+	if filter.ECOCode != "" {
+		query = query.Where("g.eco_code = ? COLLATE NOCASE", filter.ECOCode)
+	}
+	if filter.Opening != "" {
+		query = query.Where(openingFamily+" = ? COLLATE NOCASE", filter.Opening)
+	}
+	if filter.Phase != "" {
+		query = query.Where(flashcardPhase+" = ?", filter.Phase)
+	}
+	if filter.Classification != "" {
+		query = query.Where(squirrel.Eq{"p.classification": filter.Classification})
+	}
+	if filter.TimeClass != "" {
+		query = query.Where(squirrel.Eq{"g.time_class": filter.TimeClass})
+	}
+	if filter.Hung != "" {
+		query = query.Where(hungMaterial+" = ?", filter.Hung)
+	}
+	for _, motif := range filter.Motifs {
+		query = query.Where("EXISTS (SELECT 1 FROM flashcard_motifs m WHERE m.flashcard_id = f.id AND m.motif = ?)", motif)
+	}
+	for _, tag := range filter.Tags {
+		query = query.Where("EXISTS (SELECT 1 FROM flashcard_tags t WHERE t.flashcard_id = f.id AND t.tag = ?)", tag)
+	}
+	return query
+}
+
+// NextFlashcards returns due cards matching the filter in random order, at
+// most filter.Limit of them when it is set.
+func (r *flashcardRepository) NextFlashcards(ctx context.Context, profileID int64, filter models.FlashcardFilter) ([]models.Flashcard, error) {
 	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
-	log.Debug("fetching next flashcards: profile_id=%d, limit=%d", profileID, limit)
+	log.Debug("fetching next flashcards: profile_id=%d, filter=%+v", profileID, filter)
 
-	rows, err := r.db.QueryContext(ctx, `
-SELECT id, position_id, due_at, interval_days, ease_factor, times_reviewed, times_correct, created_at
-FROM flashcards
-WHERE due_at <= CURRENT_TIMESTAMP
-AND position_id IN (
-    SELECT p.id FROM positions p
-    JOIN games g ON g.id = p.game_id
-    WHERE g.profile_id = ?
-)
-ORDER BY RANDOM()
-LIMIT ?
-`, profileID, limit)
+	query := applyFlashcardFilter(sqlBuilder.Select(
+		"f.id", "f.position_id", "f.due_at", "f.interval_days", "f.ease_factor", "f.times_reviewed", "f.times_correct", "f.kind", "f.created_at",
+		"f.stability", "f.difficulty", "f.last_reviewed_at",
+	), profileID, filter).OrderBy("RANDOM()")
+	if filter.Limit > 0 {
+		query = query.Limit(uint64(filter.Limit))
+	}
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		log.Error("failed to build flashcards query: %v", err)
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
 	if err != nil {
 		log.Error("failed to query flashcards: %v", err)
 		return nil, err
@@ -79,60 +219,236 @@ LIMIT ?
 	var cards []models.Flashcard
 	for rows.Next() {
 		var c models.Flashcard
-		if err := rows.Scan(&c.ID, &c.PositionID, &c.DueAt, &c.IntervalDays, &c.EaseFactor, &c.TimesReviewed, &c.TimesCorrect, &c.CreatedAt); err != nil {
+		var stability, difficulty sql.NullFloat64
+		if err := rows.Scan(&c.ID, &c.PositionID, &c.DueAt, &c.IntervalDays, &c.EaseFactor, &c.TimesReviewed, &c.TimesCorrect, &c.Kind, &c.CreatedAt,
+			&stability, &difficulty, &c.LastReviewedAt); err != nil {
 			log.Error("failed to scan flashcard row: %v", err)
 			return nil, err
 		}
+		c.Stability, c.Difficulty = stability.Float64, difficulty.Float64
 		cards = append(cards, c)
 	}
 	log.Debug("found %d due flashcards", len(cards))
 	return cards, rows.Err()
 }
 
-func (r *flashcardRepository) FlashcardWithPosition(ctx context.Context, id int64, profileID int64) (*models.FlashcardWithPosition, error) {
+// CountDue counts the due cards matching the filter, ignoring its limit.
+func (r *flashcardRepository) CountDue(ctx context.Context, profileID int64, filter models.FlashcardFilter) (int, error) {
 	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
-	log.Debug("fetching flashcard with position: id=%d, profile_id=%d", id, profileID)
+	log.Debug("counting due flashcards: profile_id=%d, filter=%+v", profileID, filter)
 
-	var fp models.FlashcardWithPosition
-	var prevMovePlayed sql.NullString
-	var playerRating, opponentRating sql.NullInt64
-	err := r.db.QueryRowContext(ctx, `
+	sqlStr, args, err := applyFlashcardFilter(sqlBuilder.Select("COUNT(*)"), profileID, filter).ToSql()
+	if err != nil {
+		log.Error("failed to build count query: %v", err)
+		return 0, err
+	}
+	var count int
+	if err := r.db.QueryRowContext(ctx, sqlStr, args...).Scan(&count); err != nil {
+		log.Error("failed to count due flashcards: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// flashcardWithPositionQuery selects flashcards with their position and game;
+// callers append the WHERE clause.
+const flashcardWithPositionQuery = `
 SELECT 
-    f.id, f.position_id, f.due_at, f.interval_days, f.ease_factor, f.times_reviewed, f.times_correct, f.created_at,
-    p.game_id, p.move_number, p.fen, p.move_played, p.best_move, p.eval_before, p.eval_after, p.eval_diff, p.mate_before, p.mate_after, p.classification,
+    f.id, f.position_id, f.due_at, f.interval_days, f.ease_factor, f.times_reviewed, f.times_correct, f.kind, f.created_at,
+    f.stability, f.difficulty, f.last_reviewed_at,
+    p.game_id, p.move_number, p.fen, p.move_played, p.best_move, p.eval_before, p.eval_after, p.eval_diff, p.mate_before, p.mate_after, p.classification, COALESCE(p.pv, ''), p.material_hung,
     CASE WHEN g.played_as = 'white' THEN pr.username ELSE g.opponent END AS white_player,
     CASE WHEN g.played_as = 'black' THEN pr.username ELSE g.opponent END AS black_player,
     prev_p.move_played AS prev_move_played,
-    g.player_rating, g.opponent_rating, g.played_at, g.time_class
+    g.player_rating, g.opponent_rating, g.played_at, g.time_class, COALESCE(g.eco_code, ''), COALESCE(g.opening_name, ''),
+    COALESCE(rd.expected_moves, '')
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
 JOIN games g ON g.id = p.game_id
 JOIN profiles pr ON pr.id = g.profile_id
 LEFT JOIN positions prev_p ON prev_p.game_id = p.game_id AND prev_p.move_number = p.move_number - 1
-WHERE f.id = ? AND g.profile_id = ?
-`, id, profileID).Scan(&fp.ID, &fp.PositionID, &fp.DueAt, &fp.IntervalDays, &fp.EaseFactor, &fp.TimesReviewed, &fp.TimesCorrect, &fp.CreatedAt,
-		&fp.GameID, &fp.MoveNumber, &fp.FEN, &fp.MovePlayed, &fp.BestMove, &fp.EvalBefore, &fp.EvalAfter, &fp.EvalDiff, &fp.MateBefore, &fp.MateAfter, &fp.Classification,
-		&fp.WhitePlayer, &fp.BlackPlayer, &prevMovePlayed,
-		&playerRating, &opponentRating, &fp.PlayedAt, &fp.TimeClass)
-	if errors.Is(err, sql.ErrNoRows) {
+LEFT JOIN repertoire_deviations rd ON rd.game_id = p.game_id AND rd.ply = p.move_number
+`
+
+func (r *flashcardRepository) FlashcardWithPosition(ctx context.Context, id int64, profileID int64) (*models.FlashcardWithPosition, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("fetching flashcard with position: id=%d, profile_id=%d", id, profileID)
+
+	cards, err := r.queryFlashcardsWithPosition(ctx, `WHERE f.id = ? AND g.profile_id = ?`, id, profileID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
 		log.Debug("flashcard not found: id=%d", id)
 		return nil, nil
 	}
+	log.Debug("flashcard found: position_id=%d, classification=%s", cards[0].PositionID, cards[0].Classification)
+	return &cards[0], nil
+}
+
+// FlashcardsWithPosition loads the profile's flashcards with the given ids,
+// in the order of ids. Ids of missing cards are skipped.
+func (r *flashcardRepository) FlashcardsWithPosition(ctx context.Context, ids []int64, profileID int64) ([]models.FlashcardWithPosition, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("fetching flashcards with positions: count=%d, profile_id=%d", len(ids), profileID)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, profileID)
+	found, err := r.queryFlashcardsWithPosition(ctx, `WHERE f.id IN (`+squirrel.Placeholders(len(ids))+`) AND g.profile_id = ?`, args...)
 	if err != nil {
-		log.Error("failed to get flashcard with position: %v", err)
 		return nil, err
 	}
-	if prevMovePlayed.Valid {
-		fp.PrevMovePlayed = prevMovePlayed.String
+
+	byID := make(map[int64]models.FlashcardWithPosition, len(found))
+	for _, c := range found {
+		byID[c.ID] = c
+	}
+	cards := make([]models.FlashcardWithPosition, 0, len(found))
+	for _, id := range ids {
+		if c, ok := byID[id]; ok {
+			cards = append(cards, c)
+		}
+	}
+	return cards, nil
+}
+
+// queryFlashcardsWithPosition runs flashcardWithPositionQuery with the given
+// WHERE clause, then loads the lines, tags and motifs of every card found
+// with one query each.
+func (r *flashcardRepository) queryFlashcardsWithPosition(ctx context.Context, where string, args ...any) ([]models.FlashcardWithPosition, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+
+	rows, err := r.db.QueryContext(ctx, flashcardWithPositionQuery+where, args...)
+	if err != nil {
+		log.Error("failed to query flashcards with positions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.FlashcardWithPosition
+	for rows.Next() {
+		var fp models.FlashcardWithPosition
+		var prevMovePlayed sql.NullString
+		var stability, difficulty sql.NullFloat64
+		var pv, repertoireMoves string
+		var playerRating, opponentRating sql.NullInt64
+		if err := rows.Scan(&fp.ID, &fp.PositionID, &fp.DueAt, &fp.IntervalDays, &fp.EaseFactor, &fp.TimesReviewed, &fp.TimesCorrect, &fp.Kind, &fp.CreatedAt,
+			&stability, &difficulty, &fp.LastReviewedAt,
+			&fp.GameID, &fp.MoveNumber, &fp.FEN, &fp.MovePlayed, &fp.BestMove, &fp.EvalBefore, &fp.EvalAfter, &fp.EvalDiff, &fp.MateBefore, &fp.MateAfter, &fp.Classification, &pv, &fp.MaterialHung,
+			&fp.WhitePlayer, &fp.BlackPlayer, &prevMovePlayed,
+			&playerRating, &opponentRating, &fp.PlayedAt, &fp.TimeClass, &fp.ECOCode, &fp.OpeningName, &repertoireMoves); err != nil {
+			log.Error("failed to scan flashcard row: %v", err)
+			return nil, err
+		}
+		if prevMovePlayed.Valid {
+			fp.PrevMovePlayed = prevMovePlayed.String
+		}
+		fp.Stability, fp.Difficulty = stability.Float64, difficulty.Float64
+		fp.PV = strings.Fields(pv)
+		fp.RepertoireMoves = strings.Fields(repertoireMoves)
+		if playerRating.Valid {
+			fp.PlayerRating = int(playerRating.Int64)
+		}
+		if opponentRating.Valid {
+			fp.OpponentRating = int(opponentRating.Int64)
+		}
+		fp.Tags = []string{}
+		cards = append(cards, fp)
 	}
-	if playerRating.Valid {
-		fp.PlayerRating = int(playerRating.Int64)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	if opponentRating.Valid {
-		fp.OpponentRating = int(opponentRating.Int64)
+	rows.Close()
+
+	if err := r.attachFlashcardDetails(ctx, cards); err != nil {
+		return nil, err
 	}
-	log.Debug("flashcard found: position_id=%d, classification=%s", fp.PositionID, fp.Classification)
-	return &fp, nil
+	return cards, nil
+}
+
+// attachFlashcardDetails fills in the position lines, user tags and motifs
+// of the cards.
+func (r *flashcardRepository) attachFlashcardDetails(ctx context.Context, cards []models.FlashcardWithPosition) error {
+	if len(cards) == 0 {
+		return nil
+	}
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+
+	byID := make(map[int64]int, len(cards))
+	byPosition := make(map[int64]int, len(cards))
+	cardIDs := make([]any, len(cards))
+	positionIDs := make([]any, len(cards))
+	for i, c := range cards {
+		byID[c.ID] = i
+		byPosition[c.PositionID] = i
+		cardIDs[i] = c.ID
+		positionIDs[i] = c.PositionID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT position_id, rank, move, COALESCE(cp, 0), mate, COALESCE(pv, '')
+FROM position_lines
+WHERE position_id IN (`+squirrel.Placeholders(len(positionIDs))+`)
+ORDER BY position_id, rank ASC
+`, positionIDs...)
+	if err != nil {
+		log.Error("failed to load position lines: %v", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l, err := scanPositionLine(rows)
+		if err != nil {
+			log.Error("failed to scan position line: %v", err)
+			return err
+		}
+		cards[byPosition[l.PositionID]].Lines = append(cards[byPosition[l.PositionID]].Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	placeholders := squirrel.Placeholders(len(cardIDs))
+	err = r.eachFlashcardLabel(ctx, `SELECT flashcard_id, tag FROM flashcard_tags WHERE flashcard_id IN (`+placeholders+`) ORDER BY flashcard_id, tag`, cardIDs, func(id int64, tag string) {
+		cards[byID[id]].Tags = append(cards[byID[id]].Tags, tag)
+	})
+	if err != nil {
+		log.Error("failed to load flashcard tags: %v", err)
+		return err
+	}
+	err = r.eachFlashcardLabel(ctx, `SELECT flashcard_id, motif FROM flashcard_motifs WHERE flashcard_id IN (`+placeholders+`) ORDER BY flashcard_id, motif`, cardIDs, func(id int64, motif string) {
+		cards[byID[id]].Motifs = append(cards[byID[id]].Motifs, motif)
+	})
+	if err != nil {
+		log.Error("failed to load flashcard motifs: %v", err)
+		return err
+	}
+	return nil
+}
+
+// eachFlashcardLabel calls fn with every (flashcard id, text) row of query.
+func (r *flashcardRepository) eachFlashcardLabel(ctx context.Context, query string, args []any, fn func(int64, string)) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return err
+		}
+		fn(id, label)
+	}
+	return rows.Err()
 }
 
 func (r *flashcardRepository) InsertReviewHistory(ctx context.Context, flashcardID int64, quality int, timeSeconds float64) error {
@@ -149,6 +465,69 @@ func (r *flashcardRepository) InsertReviewHistory(ctx context.Context, flashcard
 	return err
 }
 
+func (r *flashcardRepository) ReviewHistoryByProfile(ctx context.Context, profileID int64) (map[int64][]models.ReviewHistory, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("fetching review history: profile_id=%d", profileID)
+	return r.reviewHistory(ctx, profileID, false)
+}
+
+func (r *flashcardRepository) UnseededReviewHistory(ctx context.Context, profileID int64) (map[int64][]models.ReviewHistory, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("fetching review history of cards without FSRS state: profile_id=%d", profileID)
+	return r.reviewHistory(ctx, profileID, true)
+}
+
+// reviewHistory groups a profile's reviews by flashcard, oldest first.
+func (r *flashcardRepository) reviewHistory(ctx context.Context, profileID int64, unseededOnly bool) (map[int64][]models.ReviewHistory, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+
+	where := "g.profile_id = ?"
+	if unseededOnly {
+		where += " AND f.stability IS NULL"
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT rh.id, rh.flashcard_id, rh.quality, rh.time_seconds, rh.reviewed_at
+FROM review_history rh
+JOIN flashcards f ON f.id = rh.flashcard_id
+JOIN positions p ON p.id = f.position_id
+JOIN games g ON g.id = p.game_id
+WHERE `+where+`
+ORDER BY rh.flashcard_id, rh.reviewed_at, rh.id
+`, profileID)
+	if err != nil {
+		log.Error("failed to query review history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make(map[int64][]models.ReviewHistory)
+	for rows.Next() {
+		var h models.ReviewHistory
+		if err := rows.Scan(&h.ID, &h.FlashcardID, &h.Quality, &h.TimeSeconds, &h.ReviewedAt); err != nil {
+			log.Error("failed to scan review history row: %v", err)
+			return nil, err
+		}
+		history[h.FlashcardID] = append(history[h.FlashcardID], h)
+	}
+	log.Debug("found review history for %d cards", len(history))
+	return history, rows.Err()
+}
+
+func (r *flashcardRepository) UpdateMemoryState(ctx context.Context, c models.Flashcard) error {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("updating flashcard memory state: id=%d, stability=%.2f, difficulty=%.2f", c.ID, c.Stability, c.Difficulty)
+
+	_, err := r.db.ExecContext(ctx, `
+UPDATE flashcards
+SET stability = ?, difficulty = ?, last_reviewed_at = ?, interval_days = ?, due_at = ?
+WHERE id = ?
+`, nullFloat64(c.Stability), nullFloat64(c.Difficulty), c.LastReviewedAt, c.IntervalDays, c.DueAt, c.ID)
+	if err != nil {
+		log.Error("failed to update flashcard memory state: %v", err)
+	}
+	return err
+}
+
 func (r *flashcardRepository) CountByGameID(ctx context.Context, gameID int64, profileID int64) (int, error) {
 	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
 	log.Debug("counting flashcards by game: game_id=%d, profile_id=%d", gameID, profileID)
@@ -159,7 +538,7 @@ SELECT COUNT(*)
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
 JOIN games g ON g.id = p.game_id
-WHERE p.game_id = ? AND g.profile_id = ?
+WHERE p.game_id = ? AND g.profile_id = ? AND f.retired_at IS NULL
 `, gameID, profileID).Scan(&count)
 	if err != nil {
 		log.Error("failed to count flashcards by game: %v", err)
@@ -173,52 +552,156 @@ func (r *flashcardRepository) ListByGameID(ctx context.Context, gameID int64, pr
 	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
 	log.Debug("listing flashcards by game: game_id=%d, profile_id=%d, limit=%d, offset=%d", gameID, profileID, limit, offset)
 
+	cards, err := r.queryFlashcardsWithPosition(ctx, `
+WHERE p.game_id = ? AND g.profile_id = ? AND f.retired_at IS NULL
+ORDER BY p.move_number ASC
+LIMIT ? OFFSET ?
+`, gameID, profileID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("found %d flashcards for game", len(cards))
+	return cards, nil
+}
+
+// ListForGame returns every flashcard of the game's positions, retired ones included.
+func (r *flashcardRepository) ListForGame(ctx context.Context, gameID int64) ([]models.Flashcard, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("listing all flashcards for game: game_id=%d", gameID)
+
 	rows, err := r.db.QueryContext(ctx, `
-SELECT 
-    f.id, f.position_id, f.due_at, f.interval_days, f.ease_factor, f.times_reviewed, f.times_correct, f.created_at,
-    p.game_id, p.move_number, p.fen, p.move_played, p.best_move, p.eval_before, p.eval_after, p.eval_diff, p.mate_before, p.mate_after, p.classification,
-    CASE WHEN g.played_as = 'white' THEN pr.username ELSE g.opponent END AS white_player,
-    CASE WHEN g.played_as = 'black' THEN pr.username ELSE g.opponent END AS black_player,
-    prev_p.move_played AS prev_move_played,
-    g.player_rating, g.opponent_rating, g.played_at, g.time_class
+SELECT f.id, f.position_id, f.due_at, f.interval_days, f.ease_factor, f.times_reviewed, f.times_correct, f.kind, f.created_at, f.retired_at
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
-JOIN games g ON g.id = p.game_id
-JOIN profiles pr ON pr.id = g.profile_id
-LEFT JOIN positions prev_p ON prev_p.game_id = p.game_id AND prev_p.move_number = p.move_number - 1
-WHERE p.game_id = ? AND g.profile_id = ?
+WHERE p.game_id = ?
 ORDER BY p.move_number ASC
-LIMIT ? OFFSET ?
-`, gameID, profileID, limit, offset)
+`, gameID)
 	if err != nil {
-		log.Error("failed to query flashcards by game: %v", err)
+		log.Error("failed to query flashcards for game: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var cards []models.FlashcardWithPosition
+	var cards []models.Flashcard
 	for rows.Next() {
-		var fp models.FlashcardWithPosition
-		var prevMovePlayed sql.NullString
-		var playerRating, opponentRating sql.NullInt64
-		if err := rows.Scan(&fp.ID, &fp.PositionID, &fp.DueAt, &fp.IntervalDays, &fp.EaseFactor, &fp.TimesReviewed, &fp.TimesCorrect, &fp.CreatedAt,
-			&fp.GameID, &fp.MoveNumber, &fp.FEN, &fp.MovePlayed, &fp.BestMove, &fp.EvalBefore, &fp.EvalAfter, &fp.EvalDiff, &fp.MateBefore, &fp.MateAfter, &fp.Classification,
-			&fp.WhitePlayer, &fp.BlackPlayer, &prevMovePlayed,
-			&playerRating, &opponentRating, &fp.PlayedAt, &fp.TimeClass); err != nil {
+		var c models.Flashcard
+		if err := rows.Scan(&c.ID, &c.PositionID, &c.DueAt, &c.IntervalDays, &c.EaseFactor, &c.TimesReviewed, &c.TimesCorrect, &c.Kind, &c.CreatedAt, &c.RetiredAt); err != nil {
 			log.Error("failed to scan flashcard row: %v", err)
 			return nil, err
 		}
-		if prevMovePlayed.Valid {
-			fp.PrevMovePlayed = prevMovePlayed.String
-		}
-		if playerRating.Valid {
-			fp.PlayerRating = int(playerRating.Int64)
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+// SetRetired retires a flashcard, hiding it from reviews while keeping its
+// history, or brings a retired one back.
+func (r *flashcardRepository) SetRetired(ctx context.Context, id int64, retired bool) error {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("setting flashcard retired: id=%d, retired=%t", id, retired)
+
+	query := `UPDATE flashcards SET retired_at = NULL WHERE id = ?`
+	if retired {
+		query = `UPDATE flashcards SET retired_at = COALESCE(retired_at, CURRENT_TIMESTAMP) WHERE id = ?`
+	}
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		log.Error("failed to update flashcard retirement: %v", err)
+		return err
+	}
+	return nil
+}
+
+// AddTag puts a user tag on a flashcard; tagging a card twice is a no-op.
+func (r *flashcardRepository) AddTag(ctx context.Context, flashcardID int64, tag string) error {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("adding flashcard tag: id=%d, tag=%s", flashcardID, tag)
+
+	_, err := r.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO flashcard_tags (flashcard_id, tag)
+VALUES (?, ?)
+`, flashcardID, tag)
+	if err != nil {
+		log.Error("failed to add flashcard tag: %v", err)
+	}
+	return err
+}
+
+func (r *flashcardRepository) RemoveTag(ctx context.Context, flashcardID int64, tag string) error {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("removing flashcard tag: id=%d, tag=%s", flashcardID, tag)
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM flashcard_tags WHERE flashcard_id = ? AND tag = ?`, flashcardID, tag)
+	if err != nil {
+		log.Error("failed to remove flashcard tag: %v", err)
+	}
+	return err
+}
+
+// ListTags returns a flashcard's user tags in alphabetical order.
+func (r *flashcardRepository) ListTags(ctx context.Context, flashcardID int64) ([]string, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("listing flashcard tags: id=%d", flashcardID)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT tag FROM flashcard_tags WHERE flashcard_id = ? ORDER BY tag`, flashcardID)
+	if err != nil {
+		log.Error("failed to query flashcard tags: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			log.Error("failed to scan flashcard tag: %v", err)
+			return nil, err
 		}
-		if opponentRating.Valid {
-			fp.OpponentRating = int(opponentRating.Int64)
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// TagCounts counts the profile's active cards by tag, for both the user tags
+// and the automatic ones derived from positions and games.
+func (r *flashcardRepository) TagCounts(ctx context.Context, profileID int64) ([]models.FlashcardTagCount, error) {
+	log := logger.FromContext(ctx).WithPrefix("flashcard_repo")
+	log.Debug("counting flashcard tags: profile_id=%d", profileID)
+
+	rows, err := r.db.QueryContext(ctx, `
+WITH cards AS (
+    SELECT f.id, COALESCE(g.eco_code, '') AS eco_code, COALESCE(`+openingFamily+`, '') AS opening,
+        `+flashcardPhase+` AS phase, COALESCE(p.classification, '') AS classification, COALESCE(g.time_class, '') AS time_class,
+        `+hungMaterial+` AS hung
+    FROM flashcards f
+    JOIN positions p ON p.id = f.position_id
+    JOIN games g ON g.id = p.game_id
+    WHERE g.profile_id = ? AND f.retired_at IS NULL
+), tags AS (
+    SELECT 'eco:' || eco_code AS tag FROM cards WHERE eco_code != ''
+    UNION ALL SELECT 'opening:' || opening FROM cards WHERE opening != ''
+    UNION ALL SELECT 'phase:' || phase FROM cards
+    UNION ALL SELECT 'classification:' || classification FROM cards WHERE classification != ''
+    UNION ALL SELECT 'time_class:' || time_class FROM cards WHERE time_class != ''
+    UNION ALL SELECT 'hung:' || hung FROM cards WHERE hung != ''
+    UNION ALL SELECT 'motif:' || m.motif FROM flashcard_motifs m JOIN cards c ON c.id = m.flashcard_id
+    UNION ALL SELECT t.tag FROM flashcard_tags t JOIN cards c ON c.id = t.flashcard_id
+)
+SELECT tag, COUNT(*) FROM tags
+GROUP BY tag
+ORDER BY tag
+`, profileID)
+	if err != nil {
+		log.Error("failed to query flashcard tag counts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var counts []models.FlashcardTagCount
+	for rows.Next() {
+		var c models.FlashcardTagCount
+		if err := rows.Scan(&c.Tag, &c.Count); err != nil {
+			log.Error("failed to scan flashcard tag count: %v", err)
+			return nil, err
 		}
-		cards = append(cards, fp)
+		counts = append(counts, c)
 	}
-	log.Debug("found %d flashcards for game", len(cards))
-	return cards, rows.Err()
+	return counts, rows.Err()
 }
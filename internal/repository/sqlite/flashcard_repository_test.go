@@ -139,7 +139,7 @@ func (s *FlashcardRepositorySuite) TestNextFlashcards() {
 	`, positionID2, time.Now().Add(24*time.Hour), 1, 2.5, 0, 0)
 	s.Require().NoError(err)
 
-	cards, err := s.repo.NextFlashcards(ctx, profileID, 10)
+	cards, err := s.repo.NextFlashcards(ctx, profileID, models.FlashcardFilter{Limit: 10})
 	s.Require().NoError(err)
 	s.Assert().Len(cards, 1) // Only the due one
 	s.Assert().Equal(positionID1, cards[0].PositionID)
@@ -217,6 +217,341 @@ func (s *FlashcardRepositorySuite) TestInsertReviewHistory() {
 	s.Assert().Equal(5.5, timeSeconds)
 }
 
+func (s *FlashcardRepositorySuite) TestUnseededReviewHistoryAndMemoryState() {
+	ctx := context.Background()
+	profileID, gameID := s.setupProfileAndGame()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, 1, "fen1", "e2e4", "d2d4", 0.0, -50.0, -50.0, "mistake")
+	s.Require().NoError(err)
+
+	var positionID int64
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM positions WHERE game_id = ?`, gameID).Scan(&positionID)
+	s.Require().NoError(err)
+
+	flashcardID, err := s.repo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now(), EaseFactor: 2.5})
+	s.Require().NoError(err)
+
+	first := time.Now().Add(-72 * time.Hour).UTC()
+	second := time.Now().Add(-24 * time.Hour).UTC()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO review_history (flashcard_id, quality, time_seconds, reviewed_at)
+		VALUES (?, 3, 4.0, ?), (?, 0, 12.0, ?)
+	`, flashcardID, second, flashcardID, first)
+	s.Require().NoError(err)
+
+	history, err := s.repo.UnseededReviewHistory(ctx, profileID)
+	s.Require().NoError(err)
+	s.Require().Len(history[flashcardID], 2)
+	s.Assert().Equal(0, history[flashcardID][0].Quality, "history should be ordered oldest first")
+	s.Assert().Equal(3, history[flashcardID][1].Quality)
+
+	reviewedAt := second
+	err = s.repo.UpdateMemoryState(ctx, models.Flashcard{
+		ID:             flashcardID,
+		DueAt:          time.Now().Add(48 * time.Hour),
+		IntervalDays:   2,
+		Stability:      2.4,
+		Difficulty:     6.1,
+		LastReviewedAt: &reviewedAt,
+	})
+	s.Require().NoError(err)
+
+	card, err := s.repo.FlashcardWithPosition(ctx, flashcardID, profileID)
+	s.Require().NoError(err)
+	s.Assert().Equal(2.4, card.Stability)
+	s.Assert().Equal(6.1, card.Difficulty)
+	s.Assert().Equal(2, card.IntervalDays)
+	s.Require().NotNil(card.LastReviewedAt)
+
+	// Seeded cards are no longer returned
+	history, err = s.repo.UnseededReviewHistory(ctx, profileID)
+	s.Require().NoError(err)
+	s.Assert().Empty(history)
+}
+
+func (s *FlashcardRepositorySuite) TestRetire() {
+	ctx := context.Background()
+	profileID, gameID := s.setupProfileAndGame()
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, 1, "fen1", "e2e4", "d2d4", 0.0, -50.0, -50.0, "mistake")
+	s.Require().NoError(err)
+	positionID, err := res.LastInsertId()
+	s.Require().NoError(err)
+
+	id, err := s.repo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now().Add(-time.Hour), EaseFactor: 2.5})
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.InsertReviewHistory(ctx, id, 2, 4.5))
+
+	s.Require().NoError(s.repo.SetRetired(ctx, id, true))
+
+	cards, err := s.repo.NextFlashcards(ctx, profileID, models.FlashcardFilter{Limit: 10})
+	s.Require().NoError(err)
+	s.Assert().Empty(cards)
+	count, err := s.repo.CountByGameID(ctx, gameID, profileID)
+	s.Require().NoError(err)
+	s.Assert().Zero(count)
+
+	all, err := s.repo.ListForGame(ctx, gameID)
+	s.Require().NoError(err)
+	s.Require().Len(all, 1)
+	s.Assert().NotNil(all[0].RetiredAt)
+	s.Assert().Equal(models.FlashcardKindEngine, all[0].Kind)
+
+	history, err := s.repo.ReviewHistoryByProfile(ctx, profileID)
+	s.Require().NoError(err)
+	s.Assert().Len(history[id], 1)
+
+	s.Require().NoError(s.repo.SetRetired(ctx, id, false))
+	cards, err = s.repo.NextFlashcards(ctx, profileID, models.FlashcardFilter{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(cards, 1)
+	s.Assert().Equal(id, cards[0].ID)
+}
+
+func (s *FlashcardRepositorySuite) TestFilterAndTags() {
+	ctx := context.Background()
+	profileID, gameID := s.setupProfileAndGame()
+	_, err := s.db.ExecContext(ctx, `UPDATE games SET eco_code = ?, opening_name = ? WHERE id = ?`,
+		"B90", "Sicilian Defense: Najdorf Variation", gameID)
+	s.Require().NoError(err)
+
+	insertCard := func(moveNumber int, classification string) int64 {
+		res, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, moveNumber, "fen", "e2e4", "d2d4", 0.0, -200.0, -200.0, classification)
+		s.Require().NoError(err)
+		positionID, err := res.LastInsertId()
+		s.Require().NoError(err)
+		id, err := s.repo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now().Add(-time.Hour), EaseFactor: 2.5})
+		s.Require().NoError(err)
+		return id
+	}
+	openingBlunder := insertCard(10, "blunder")
+	middlegameBlunder := insertCard(20, "blunder")
+	insertCard(25, "mistake")
+	insertCard(40, "blunder")
+
+	ids := func(filter models.FlashcardFilter) []int64 {
+		cards, err := s.repo.NextFlashcards(ctx, profileID, filter)
+		s.Require().NoError(err)
+		var ids []int64
+		for _, c := range cards {
+			ids = append(ids, c.ID)
+		}
+		// The count ignores the limit
+		count, err := s.repo.CountDue(ctx, profileID, filter)
+		s.Require().NoError(err)
+		if filter.Limit == 0 {
+			s.Assert().Equal(len(ids), count)
+		} else {
+			s.Assert().GreaterOrEqual(count, len(ids))
+		}
+		return ids
+	}
+
+	s.Assert().Len(ids(models.FlashcardFilter{}), 4)
+	s.Assert().Len(ids(models.FlashcardFilter{Limit: 2}), 2)
+	s.Assert().Equal([]int64{middlegameBlunder}, ids(models.FlashcardFilter{
+		Opening: "sicilian defense", Phase: "middlegame", Classification: "blunder",
+	}))
+	s.Assert().Len(ids(models.FlashcardFilter{ECOCode: "b90", TimeClass: "blitz"}), 4)
+	s.Assert().Empty(ids(models.FlashcardFilter{Opening: "Sicilian"}))
+	s.Assert().Empty(ids(models.FlashcardFilter{TimeClass: "rapid"}))
+
+	// Moves that hung material
+	_, err = s.db.ExecContext(ctx, `UPDATE positions SET material_hung = 3 WHERE id = (SELECT position_id FROM flashcards WHERE id = ?)`, middlegameBlunder)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int64{middlegameBlunder}, ids(models.FlashcardFilter{Hung: "piece"}))
+	s.Assert().Empty(ids(models.FlashcardFilter{Hung: "queen"}))
+
+	// User tags
+	s.Require().NoError(s.repo.AddTag(ctx, openingBlunder, "forks"))
+	s.Require().NoError(s.repo.AddTag(ctx, openingBlunder, "forks"))
+	s.Require().NoError(s.repo.AddTag(ctx, openingBlunder, "pins"))
+	s.Require().NoError(s.repo.AddTag(ctx, middlegameBlunder, "forks"))
+	s.Assert().ElementsMatch([]int64{openingBlunder, middlegameBlunder}, ids(models.FlashcardFilter{Tags: []string{"Forks"}}))
+	s.Assert().Equal([]int64{openingBlunder}, ids(models.FlashcardFilter{Tags: []string{"forks", "pins"}}))
+
+	card, err := s.repo.FlashcardWithPosition(ctx, openingBlunder, profileID)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"forks", "pins"}, card.Tags)
+	s.Assert().Equal("B90", card.ECOCode)
+	s.Assert().Equal("Sicilian Defense: Najdorf Variation", card.OpeningName)
+
+	s.Require().NoError(s.repo.RemoveTag(ctx, openingBlunder, "pins"))
+	tags, err := s.repo.ListTags(ctx, openingBlunder)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"forks"}, tags)
+
+	counts, err := s.repo.TagCounts(ctx, profileID)
+	s.Require().NoError(err)
+	byTag := map[string]int{}
+	for _, c := range counts {
+		byTag[c.Tag] = c.Count
+	}
+	s.Assert().Equal(map[string]int{
+		"eco:B90":                  4,
+		"opening:Sicilian Defense": 4,
+		"phase:opening":            1,
+		"phase:middlegame":         2,
+		"phase:endgame":            1,
+		"classification:blunder":   3,
+		"classification:mistake":   1,
+		"time_class:blitz":         4,
+		"hung:piece":               1,
+		"forks":                    2,
+	}, byTag)
+}
+
+func (s *FlashcardRepositorySuite) TestMotifs() {
+	ctx := context.Background()
+	profileID, gameID := s.setupProfileAndGame()
+
+	insertCard := func(moveNumber int, motifs []string) int64 {
+		res, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, moveNumber, "fen", "e2e4", "d2d4", 0.0, -200.0, -200.0, "blunder")
+		s.Require().NoError(err)
+		positionID, err := res.LastInsertId()
+		s.Require().NoError(err)
+		id, err := s.repo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now().Add(-time.Hour), EaseFactor: 2.5, Motifs: motifs})
+		s.Require().NoError(err)
+		return id
+	}
+	fork := insertCard(10, []string{"fork"})
+	forkAndPin := insertCard(20, []string{"fork", "pin"})
+	insertCard(30, nil)
+
+	card, err := s.repo.FlashcardWithPosition(ctx, forkAndPin, profileID)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"fork", "pin"}, card.Motifs)
+
+	ids := func(motifs ...string) []int64 {
+		cards, err := s.repo.NextFlashcards(ctx, profileID, models.FlashcardFilter{Motifs: motifs})
+		s.Require().NoError(err)
+		var ids []int64
+		for _, c := range cards {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	s.Assert().ElementsMatch([]int64{fork, forkAndPin}, ids("fork"))
+	s.Assert().Equal([]int64{forkAndPin}, ids("fork", "pin"))
+	s.Assert().Empty(ids("skewer"))
+
+	// Re-analysis replaces the motifs
+	s.Require().NoError(s.repo.SetMotifs(ctx, fork, []string{"skewer"}))
+	s.Assert().Equal([]int64{fork}, ids("skewer"))
+	s.Assert().Equal([]int64{forkAndPin}, ids("fork"))
+
+	counts, err := s.repo.TagCounts(ctx, profileID)
+	s.Require().NoError(err)
+	byTag := map[string]int{}
+	for _, c := range counts {
+		byTag[c.Tag] = c.Count
+	}
+	s.Assert().Equal(1, byTag["motif:fork"])
+	s.Assert().Equal(1, byTag["motif:pin"])
+	s.Assert().Equal(1, byTag["motif:skewer"])
+
+	stats, err := sqlite.NewStatsRepository(s.db).FlashcardMotifStats(ctx, profileID)
+	s.Require().NoError(err)
+	s.Require().Len(stats, 3)
+	for _, stat := range stats {
+		s.Assert().Equal(1, stat.TotalCards)
+	}
+}
+
+func (s *FlashcardRepositorySuite) TestFlashcardsWithPosition() {
+	ctx := context.Background()
+	profileID, gameID := s.setupProfileAndGame()
+
+	insertCard := func(moveNumber int, motifs []string) int64 {
+		res, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, moveNumber, "fen", "e2e4", "d2d4", 0.0, -200.0, -200.0, "blunder")
+		s.Require().NoError(err)
+		positionID, err := res.LastInsertId()
+		s.Require().NoError(err)
+		_, err = s.db.ExecContext(ctx, `
+		INSERT INTO position_lines (position_id, rank, move, cp, pv) VALUES (?, 1, 'd2d4', 30, 'd2d4 d7d5'), (?, 2, 'c2c4', 10, 'c2c4')
+	`, positionID, positionID)
+		s.Require().NoError(err)
+		id, err := s.repo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now(), EaseFactor: 2.5, Motifs: motifs})
+		s.Require().NoError(err)
+		return id
+	}
+	first := insertCard(10, []string{"pin", "fork"})
+	second := insertCard(20, nil)
+	s.Require().NoError(s.repo.AddTag(ctx, second, "endgame-drill"))
+
+	// Cards come back in the order asked for, unknown ids are skipped
+	cards, err := s.repo.FlashcardsWithPosition(ctx, []int64{second, 999, first}, profileID)
+	s.Require().NoError(err)
+	s.Require().Len(cards, 2)
+	s.Assert().Equal(second, cards[0].ID)
+	s.Assert().Equal(first, cards[1].ID)
+
+	s.Assert().Equal([]string{"endgame-drill"}, cards[0].Tags)
+	s.Assert().Empty(cards[0].Motifs)
+	s.Assert().Equal([]string{}, cards[1].Tags)
+	s.Assert().Equal([]string{"fork", "pin"}, cards[1].Motifs)
+	for _, c := range cards {
+		s.Require().Len(c.Lines, 2)
+		s.Assert().Equal("d2d4", c.Lines[0].Move)
+		s.Assert().Equal("c2c4", c.Lines[1].Move)
+	}
+
+	// Cards of another profile are not returned
+	cards, err = s.repo.FlashcardsWithPosition(ctx, []int64{first}, profileID+1)
+	s.Require().NoError(err)
+	s.Assert().Empty(cards)
+}
+
+func (s *FlashcardRepositorySuite) TestUndetectedMotifCards() {
+	ctx := context.Background()
+	_, gameID := s.setupProfileAndGame()
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification, pv)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, 10, "fen", "e2e4", "d2d4", 0.0, -200.0, -200.0, "blunder", "d2d4 d7d5")
+	s.Require().NoError(err)
+	positionID, err := res.LastInsertId()
+	s.Require().NoError(err)
+
+	// Cards inserted by analysis already carry their motifs
+	_, err = s.repo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now(), EaseFactor: 2.5})
+	s.Require().NoError(err)
+	cards, err := s.repo.UndetectedMotifCards(ctx, 10)
+	s.Require().NoError(err)
+	s.Assert().Empty(cards)
+
+	// A card from before motif detection is listed until its motifs are set
+	_, err = s.db.ExecContext(ctx, `UPDATE flashcards SET motifs_detected = 0`)
+	s.Require().NoError(err)
+	cards, err = s.repo.UndetectedMotifCards(ctx, 10)
+	s.Require().NoError(err)
+	s.Require().Len(cards, 1)
+	s.Assert().Equal("fen", cards[0].FEN)
+	s.Assert().Equal("d2d4", cards[0].BestMove)
+	s.Assert().Equal([]string{"d2d4", "d7d5"}, cards[0].PV)
+
+	s.Require().NoError(s.repo.SetMotifs(ctx, cards[0].ID, nil))
+	cards, err = s.repo.UndetectedMotifCards(ctx, 10)
+	s.Require().NoError(err)
+	s.Assert().Empty(cards)
+}
+
 func TestFlashcardRepositorySuite(t *testing.T) {
 	suite.Run(t, new(FlashcardRepositorySuite))
 }
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+type fsrsOptimizationRepository struct {
+	db *sql.DB
+}
+
+// NewFSRSOptimizationRepository creates a new FSRSOptimizationRepository implementation
+func NewFSRSOptimizationRepository(db *sql.DB) repository.FSRSOptimizationRepository {
+	return &fsrsOptimizationRepository{db: db}
+}
+
+const fsrsOptimizationColumns = `id, profile_id, status, review_count, log_loss_before, log_loss_after,
+    observed_retention, predicted_retention, weights, retention_curve, error, created_at, completed_at, applied_at`
+
+func (r *fsrsOptimizationRepository) Insert(ctx context.Context, profileID int64) (int64, error) {
+	log := logger.FromContext(ctx).WithPrefix("fsrs_repo")
+	log.Debug("inserting fsrs optimization: profile_id=%d", profileID)
+
+	res, err := r.db.ExecContext(ctx, `INSERT INTO fsrs_optimizations (profile_id, status) VALUES (?, ?)`,
+		profileID, models.FSRSOptimizationPending)
+	if err != nil {
+		log.Error("failed to insert fsrs optimization: %v", err)
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		log.Error("failed to get fsrs optimization id: %v", err)
+		return 0, err
+	}
+	log.Debug("fsrs optimization inserted: id=%d", id)
+	return id, nil
+}
+
+func (r *fsrsOptimizationRepository) Update(ctx context.Context, o models.FSRSOptimization) error {
+	log := logger.FromContext(ctx).WithPrefix("fsrs_repo")
+	log.Debug("updating fsrs optimization: id=%d, status=%s", o.ID, o.Status)
+
+	weights, err := marshalNullJSON(o.Weights, len(o.Weights) == 0)
+	if err != nil {
+		return err
+	}
+	curve, err := marshalNullJSON(o.RetentionCurve, len(o.RetentionCurve) == 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+UPDATE fsrs_optimizations
+SET status = ?, review_count = ?, log_loss_before = ?, log_loss_after = ?,
+    observed_retention = ?, predicted_retention = ?, weights = ?, retention_curve = ?,
+    error = ?, completed_at = ?
+WHERE id = ?
+`, o.Status, o.ReviewCount, o.LogLossBefore, o.LogLossAfter,
+		o.ObservedRetention, o.PredictedRetention, weights, curve,
+		nullString(o.Error), o.CompletedAt, o.ID)
+	if err != nil {
+		log.Error("failed to update fsrs optimization: %v", err)
+	}
+	return err
+}
+
+func (r *fsrsOptimizationRepository) Get(ctx context.Context, id int64) (*models.FSRSOptimization, error) {
+	log := logger.FromContext(ctx).WithPrefix("fsrs_repo")
+	log.Debug("getting fsrs optimization: id=%d", id)
+
+	o, err := scanFSRSOptimization(r.db.QueryRowContext(ctx, `SELECT `+fsrsOptimizationColumns+` FROM fsrs_optimizations WHERE id = ?`, id))
+	if err != nil {
+		log.Error("failed to get fsrs optimization: %v", err)
+	}
+	return o, err
+}
+
+func (r *fsrsOptimizationRepository) Latest(ctx context.Context, profileID int64) (*models.FSRSOptimization, error) {
+	log := logger.FromContext(ctx).WithPrefix("fsrs_repo")
+	log.Debug("getting latest fsrs optimization: profile_id=%d", profileID)
+
+	o, err := scanFSRSOptimization(r.db.QueryRowContext(ctx, `
+SELECT `+fsrsOptimizationColumns+`
+FROM fsrs_optimizations
+WHERE profile_id = ?
+ORDER BY created_at DESC, id DESC
+LIMIT 1
+`, profileID))
+	if err != nil {
+		log.Error("failed to get latest fsrs optimization: %v", err)
+	}
+	return o, err
+}
+
+func (r *fsrsOptimizationRepository) MarkApplied(ctx context.Context, id int64) error {
+	log := logger.FromContext(ctx).WithPrefix("fsrs_repo")
+	log.Debug("marking fsrs optimization applied: id=%d", id)
+
+	_, err := r.db.ExecContext(ctx, `UPDATE fsrs_optimizations SET applied_at = CURRENT_TIMESTAMP WHERE id = ?`, id)
+	if err != nil {
+		log.Error("failed to mark fsrs optimization applied: %v", err)
+	}
+	return err
+}
+
+// scanFSRSOptimization returns nil without error when no row matched.
+func scanFSRSOptimization(row *sql.Row) (*models.FSRSOptimization, error) {
+	var o models.FSRSOptimization
+	var weights, curve, errMsg sql.NullString
+	err := row.Scan(&o.ID, &o.ProfileID, &o.Status, &o.ReviewCount, &o.LogLossBefore, &o.LogLossAfter,
+		&o.ObservedRetention, &o.PredictedRetention, &weights, &curve, &errMsg, &o.CreatedAt, &o.CompletedAt, &o.AppliedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.Error = errMsg.String
+	if weights.Valid {
+		if err := json.Unmarshal([]byte(weights.String), &o.Weights); err != nil {
+			return nil, err
+		}
+	}
+	if curve.Valid {
+		if err := json.Unmarshal([]byte(curve.String), &o.RetentionCurve); err != nil {
+			return nil, err
+		}
+	}
+	return &o, nil
+}
@@ -0,0 +1,99 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+type FSRSOptimizationRepositorySuite struct {
+	suite.Suite
+	db          *sql.DB
+	repo        repository.FSRSOptimizationRepository
+	profileRepo repository.ProfileRepository
+}
+
+func (s *FSRSOptimizationRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewFSRSOptimizationRepository(s.db)
+	s.profileRepo = sqlite.NewProfileRepository(s.db)
+}
+
+func (s *FSRSOptimizationRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *FSRSOptimizationRepositorySuite) TestLifecycle() {
+	ctx := context.Background()
+	profile, err := s.profileRepo.Upsert(ctx, "testuser", "chesscom")
+	s.Require().NoError(err)
+
+	latest, err := s.repo.Latest(ctx, profile.ID)
+	s.Require().NoError(err)
+	s.Assert().Nil(latest)
+
+	id, err := s.repo.Insert(ctx, profile.ID)
+	s.Require().NoError(err)
+
+	run, err := s.repo.Get(ctx, id)
+	s.Require().NoError(err)
+	s.Require().NotNil(run)
+	s.Assert().Equal(models.FSRSOptimizationPending, run.Status)
+	s.Assert().Nil(run.Weights)
+
+	completed := time.Now()
+	run.Status = models.FSRSOptimizationCompleted
+	run.ReviewCount = 120
+	run.LogLossBefore = 0.41
+	run.LogLossAfter = 0.35
+	run.Weights = []float64{0.5, 1.2, 3.1, 12}
+	run.RetentionCurve = []models.RetentionPoint{{Days: 1, Current: 0.97, Fitted: 0.95}}
+	run.CompletedAt = &completed
+	s.Require().NoError(s.repo.Update(ctx, *run))
+
+	latest, err = s.repo.Latest(ctx, profile.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(latest)
+	s.Assert().Equal(id, latest.ID)
+	s.Assert().Equal(120, latest.ReviewCount)
+	s.Assert().Equal([]float64{0.5, 1.2, 3.1, 12}, latest.Weights)
+	s.Assert().Equal(run.RetentionCurve, latest.RetentionCurve)
+	s.Assert().NotNil(latest.CompletedAt)
+	s.Assert().Nil(latest.AppliedAt)
+
+	s.Require().NoError(s.repo.MarkApplied(ctx, id))
+	applied, err := s.repo.Get(ctx, id)
+	s.Require().NoError(err)
+	s.Assert().NotNil(applied.AppliedAt)
+}
+
+func (s *FSRSOptimizationRepositorySuite) TestProfileWeights() {
+	ctx := context.Background()
+	profile, err := s.profileRepo.Upsert(ctx, "testuser", "chesscom")
+	s.Require().NoError(err)
+	s.Assert().Nil(profile.FSRSWeights)
+	s.Assert().Equal("sm2", profile.Scheduler)
+
+	weights := []float64{0.4, 1.4, 3.7, 13.8}
+	s.Require().NoError(s.profileRepo.UpdateFSRSWeights(ctx, profile.ID, weights))
+
+	loaded, err := s.profileRepo.Get(ctx, profile.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(weights, loaded.FSRSWeights)
+
+	s.Require().NoError(s.profileRepo.UpdateFSRSWeights(ctx, profile.ID, nil))
+	loaded, err = s.profileRepo.Get(ctx, profile.ID)
+	s.Require().NoError(err)
+	s.Assert().Nil(loaded.FSRSWeights)
+}
+
+func TestFSRSOptimizationRepositorySuite(t *testing.T) {
+	suite.Run(t, new(FSRSOptimizationRepositorySuite))
+}
@@ -25,10 +25,16 @@ func applyAnalysisFilter(query squirrel.SelectBuilder, filter models.AnalysisFil
 
 	// Analysis status filter (only if not already filtered by specific status)
 	if includeStatusFilter {
-		statusFilter := []string{"pending", "failed"}
+		statusFilter := []string{"pending", "failed", "incomplete"}
 		if filter.IncludeFailed {
 			statusFilter = append(statusFilter, "processing")
 		}
+		if filter.IncompleteOnly {
+			statusFilter = []string{"incomplete"}
+		}
+		if filter.FailureReason != "" {
+			statusFilter = []string{"failed"}
+		}
 		placeholders := make([]interface{}, len(statusFilter))
 		for i, status := range statusFilter {
 			placeholders[i] = status
@@ -55,6 +61,11 @@ func applyAnalysisFilter(query squirrel.SelectBuilder, filter models.AnalysisFil
 		query = query.Where(squirrel.Eq{"opponent": filter.Opponent})
 	}
 
+	// Failure reason filter (the status filter is restricted to failed games)
+	if filter.FailureReason != "" {
+		query = query.Where(squirrel.Eq{"failure_reason": filter.FailureReason})
+	}
+
 	// Opening filter
 	if filter.OpeningName != "" {
 		query = query.Where(squirrel.Eq{"opening_name": filter.OpeningName})
@@ -96,10 +107,12 @@ func (r *gameRepository) Get(ctx context.Context, id int64) (*models.Game, error
 	var g models.Game
 	err := r.db.QueryRowContext(ctx, `
 SELECT id, profile_id, chess_com_id, pgn, time_class, result, played_as, opponent, player_rating, opponent_rating, played_at,
-       eco_code, opening_name, opening_url, analysis_status, created_at
+       eco_code, opening_name, opening_url, analysis_status, COALESCE(failure_reason, ''),
+       white_acpl, black_acpl, white_accuracy, black_accuracy, created_at
 FROM games
 WHERE id = ?
-`, id).Scan(&g.ID, &g.ProfileID, &g.ChessComID, &g.PGN, &g.TimeClass, &g.Result, &g.PlayedAs, &g.Opponent, &g.PlayerRating, &g.OpponentRating, &g.PlayedAt, &g.ECOCode, &g.OpeningName, &g.OpeningURL, &g.AnalysisStatus, &g.CreatedAt)
+`, id).Scan(&g.ID, &g.ProfileID, &g.ChessComID, &g.PGN, &g.TimeClass, &g.Result, &g.PlayedAs, &g.Opponent, &g.PlayerRating, &g.OpponentRating, &g.PlayedAt, &g.ECOCode, &g.OpeningName, &g.OpeningURL, &g.AnalysisStatus, &g.FailureReason,
+		&g.WhiteACPL, &g.BlackACPL, &g.WhiteAccuracy, &g.BlackAccuracy, &g.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Debug("game not found: id=%d", id)
@@ -120,7 +133,8 @@ func (r *gameRepository) List(ctx context.Context, filter models.GameFilter) ([]
 	query := sqlBuilder.Select(
 		"id", "profile_id", "chess_com_id", "pgn", "time_class", "result", "played_as",
 		"opponent", "player_rating", "opponent_rating", "played_at", "eco_code",
-		"opening_name", "opening_url", "analysis_status", "created_at",
+		"opening_name", "opening_url", "analysis_status", "COALESCE(failure_reason, '')",
+		"white_acpl", "black_acpl", "white_accuracy", "black_accuracy", "created_at",
 	).From("games")
 
 	// Dynamic WHERE clauses
@@ -139,6 +153,9 @@ func (r *gameRepository) List(ctx context.Context, filter models.GameFilter) ([]
 	if filter.Opponent != "" {
 		query = query.Where(squirrel.Eq{"opponent": filter.Opponent})
 	}
+	if filter.FailureReason != "" {
+		query = query.Where(squirrel.Eq{"analysis_status": "failed", "failure_reason": filter.FailureReason})
+	}
 
 	// Safe ORDER BY with validation
 	orderBy := "played_at"
@@ -179,7 +196,8 @@ func (r *gameRepository) List(ctx context.Context, filter models.GameFilter) ([]
 	var games []models.Game
 	for rows.Next() {
 		var g models.Game
-		if err := rows.Scan(&g.ID, &g.ProfileID, &g.ChessComID, &g.PGN, &g.TimeClass, &g.Result, &g.PlayedAs, &g.Opponent, &g.PlayerRating, &g.OpponentRating, &g.PlayedAt, &g.ECOCode, &g.OpeningName, &g.OpeningURL, &g.AnalysisStatus, &g.CreatedAt); err != nil {
+		if err := rows.Scan(&g.ID, &g.ProfileID, &g.ChessComID, &g.PGN, &g.TimeClass, &g.Result, &g.PlayedAs, &g.Opponent, &g.PlayerRating, &g.OpponentRating, &g.PlayedAt, &g.ECOCode, &g.OpeningName, &g.OpeningURL, &g.AnalysisStatus, &g.FailureReason,
+			&g.WhiteACPL, &g.BlackACPL, &g.WhiteAccuracy, &g.BlackAccuracy, &g.CreatedAt); err != nil {
 			log.Error("failed to scan game row: %v", err)
 			return nil, err
 		}
@@ -212,6 +230,9 @@ func (r *gameRepository) Count(ctx context.Context, filter models.GameFilter) (i
 	if filter.Opponent != "" {
 		query = query.Where(squirrel.Eq{"opponent": filter.Opponent})
 	}
+	if filter.FailureReason != "" {
+		query = query.Where(squirrel.Eq{"analysis_status": "failed", "failure_reason": filter.FailureReason})
+	}
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -235,8 +256,8 @@ func (r *gameRepository) Insert(ctx context.Context, g models.Game) (int64, erro
 	res, err := r.db.ExecContext(ctx, `
 INSERT INTO games (
     profile_id, chess_com_id, pgn, time_class, result, played_as,
-    opponent, player_rating, opponent_rating, played_at, eco_code, opening_name, opening_url, analysis_status
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    opponent, player_rating, opponent_rating, played_at, eco_code, opening_name, opening_url, analysis_status, content_hash
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(chess_com_id) DO UPDATE SET
     time_class = excluded.time_class,
     result = excluded.result,
@@ -248,7 +269,7 @@ ON CONFLICT(chess_com_id) DO UPDATE SET
     eco_code = excluded.eco_code,
     opening_name = excluded.opening_name,
     opening_url = excluded.opening_url
-`, g.ProfileID, g.ChessComID, g.PGN, g.TimeClass, g.Result, g.PlayedAs, g.Opponent, g.PlayerRating, g.OpponentRating, g.PlayedAt, g.ECOCode, g.OpeningName, g.OpeningURL, g.AnalysisStatus)
+`, g.ProfileID, g.ChessComID, g.PGN, g.TimeClass, g.Result, g.PlayedAs, g.Opponent, g.PlayerRating, g.OpponentRating, g.PlayedAt, g.ECOCode, g.OpeningName, g.OpeningURL, g.AnalysisStatus, nullString(g.ContentHash))
 	if err != nil {
 		log.Error("failed to insert game: %v", err)
 		return 0, err
@@ -280,9 +301,9 @@ func (r *gameRepository) InsertBatch(ctx context.Context, games []models.Game) (
 		stmt, err := tx.PrepareContext(ctx, `
 INSERT INTO games (
     profile_id, chess_com_id, pgn, time_class, result, played_as,
-    opponent, player_rating, opponent_rating, played_at, eco_code, opening_name, opening_url, analysis_status
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-ON CONFLICT(chess_com_id) DO NOTHING
+    opponent, player_rating, opponent_rating, played_at, eco_code, opening_name, opening_url, analysis_status, content_hash
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT DO NOTHING
 `)
 		if err != nil {
 			log.Error("failed to prepare batch insert: %v", err)
@@ -291,7 +312,7 @@ ON CONFLICT(chess_com_id) DO NOTHING
 		defer stmt.Close()
 
 		for _, g := range games {
-			res, err := stmt.ExecContext(ctx, g.ProfileID, g.ChessComID, g.PGN, g.TimeClass, g.Result, g.PlayedAs, g.Opponent, g.PlayerRating, g.OpponentRating, g.PlayedAt, g.ECOCode, g.OpeningName, g.OpeningURL, g.AnalysisStatus)
+			res, err := stmt.ExecContext(ctx, g.ProfileID, g.ChessComID, g.PGN, g.TimeClass, g.Result, g.PlayedAs, g.Opponent, g.PlayerRating, g.OpponentRating, g.PlayedAt, g.ECOCode, g.OpeningName, g.OpeningURL, g.AnalysisStatus, nullString(g.ContentHash))
 			if err != nil {
 				log.Error("failed to insert game chess_com_id=%s: %v", g.ChessComID, err)
 				return err
@@ -310,17 +331,61 @@ ON CONFLICT(chess_com_id) DO NOTHING
 	return insertedIDs, nil
 }
 
+// UpdateStatus sets the analysis status and clears any failure reason.
 func (r *gameRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
 	log := logger.FromContext(ctx).WithPrefix("game_repo")
 	log.Debug("updating game status: game_id=%d, status=%s", id, status)
 
-	_, err := r.db.ExecContext(ctx, `UPDATE games SET analysis_status = ? WHERE id = ?`, status, id)
+	_, err := r.db.ExecContext(ctx, `UPDATE games SET analysis_status = ?, failure_reason = NULL WHERE id = ?`, status, id)
 	if err != nil {
 		log.Error("failed to update game status: %v", err)
 	}
 	return err
 }
 
+// UpdateFailure sets the analysis status of a game whose analysis failed,
+// with the reason it failed.
+func (r *gameRepository) UpdateFailure(ctx context.Context, id int64, status, reason string) error {
+	log := logger.FromContext(ctx).WithPrefix("game_repo")
+	log.Debug("recording game failure: game_id=%d, status=%s, reason=%s", id, status, reason)
+
+	_, err := r.db.ExecContext(ctx, `UPDATE games SET analysis_status = ?, failure_reason = ? WHERE id = ?`, status, reason, id)
+	if err != nil {
+		log.Error("failed to record game failure: %v", err)
+	}
+	return err
+}
+
+// CountFailureReasons counts the profile's failed games by failure reason.
+func (r *gameRepository) CountFailureReasons(ctx context.Context, profileID int64) (map[string]int, error) {
+	log := logger.FromContext(ctx).WithPrefix("game_repo")
+	log.Debug("counting failure reasons: profile_id=%d", profileID)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT COALESCE(failure_reason, 'unknown'), COUNT(*)
+FROM games
+WHERE profile_id = ? AND analysis_status = 'failed'
+GROUP BY 1
+`, profileID)
+	if err != nil {
+		log.Error("failed to count failure reasons: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var reason string
+		var count int
+		if err := rows.Scan(&reason, &count); err != nil {
+			log.Error("failed to scan failure reason count: %v", err)
+			return nil, err
+		}
+		counts[reason] = count
+	}
+	return counts, rows.Err()
+}
+
 func (r *gameRepository) UpdateOpening(ctx context.Context, id int64, ecoCode, openingName string) error {
 	log := logger.FromContext(ctx).WithPrefix("game_repo")
 	log.Debug("updating game opening: game_id=%d, eco=%s, opening=%s", id, ecoCode, openingName)
@@ -336,13 +401,33 @@ WHERE id = ?
 	return err
 }
 
+func (r *gameRepository) UpdateAccuracy(ctx context.Context, id int64, acc models.GameAccuracy) error {
+	log := logger.FromContext(ctx).WithPrefix("game_repo")
+	log.Debug("updating game accuracy: game_id=%d, white=%.1f, black=%.1f", id, acc.WhiteAccuracy, acc.BlackAccuracy)
+
+	_, err := r.db.ExecContext(ctx, `
+UPDATE games
+SET white_acpl = ?, black_acpl = ?, white_accuracy = ?, black_accuracy = ?
+WHERE id = ?
+`, acc.WhiteACPL, acc.BlackACPL, acc.WhiteAccuracy, acc.BlackAccuracy, id)
+	if err != nil {
+		log.Error("failed to update game accuracy: %v", err)
+	}
+	return err
+}
+
+// ResetProcessingToPending requeues games whose analysis was interrupted:
+// back to pending, or to incomplete when some positions were already stored.
 func (r *gameRepository) ResetProcessingToPending(ctx context.Context, profileID int64) error {
 	log := logger.FromContext(ctx).WithPrefix("game_repo")
 	log.Debug("resetting processing games to pending: profile_id=%d", profileID)
 
 	_, err := r.db.ExecContext(ctx, `
 UPDATE games
-SET analysis_status = 'pending'
+SET analysis_status = CASE
+    WHEN EXISTS (SELECT 1 FROM positions p WHERE p.game_id = games.id) THEN 'incomplete'
+    ELSE 'pending'
+END
 WHERE profile_id = ? AND analysis_status = 'processing'
 `, profileID)
 	if err != nil {
@@ -359,7 +444,7 @@ func (r *gameRepository) GamesNeedingAnalysis(ctx context.Context, profileID int
 SELECT id, profile_id, chess_com_id, pgn, time_class, result, played_as, opponent, player_rating, opponent_rating, played_at,
        eco_code, opening_name, opening_url, analysis_status, created_at
 FROM games
-WHERE profile_id = ? AND analysis_status IN ('pending','processing','failed')
+WHERE profile_id = ? AND analysis_status IN ('pending','processing','failed','incomplete')
 ORDER BY played_at DESC
 `, profileID)
 	if err != nil {
@@ -390,7 +475,7 @@ func (r *gameRepository) CountGamesNeedingAnalysis(ctx context.Context, profileI
 	err := r.db.QueryRowContext(ctx, `
 SELECT COUNT(*)
 FROM games
-WHERE profile_id = ? AND analysis_status IN ('pending','processing','failed')
+WHERE profile_id = ? AND analysis_status IN ('pending','processing','failed','incomplete')
 `, profileID).Scan(&count)
 	if err != nil {
 		log.Error("failed to count games needing analysis: %v", err)
@@ -422,6 +507,29 @@ func (r *gameRepository) GetExistingChessComIDs(ctx context.Context, profileID i
 	return out, rows.Err()
 }
 
+func (r *gameRepository) GetExistingContentHashes(ctx context.Context, profileID int64) (map[string]bool, error) {
+	log := logger.FromContext(ctx).WithPrefix("game_repo")
+	log.Debug("loading existing content hashes for profile_id=%d", profileID)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT content_hash FROM games WHERE profile_id = ? AND content_hash IS NOT NULL`, profileID)
+	if err != nil {
+		log.Error("failed to list content hashes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			log.Error("failed to scan content hash: %v", err)
+			return nil, err
+		}
+		out[hash] = true
+	}
+	return out, rows.Err()
+}
+
 func (r *gameRepository) CountByStatus(ctx context.Context, profileID int64, status string) (int, error) {
 	log := logger.FromContext(ctx).WithPrefix("game_repo")
 	log.Debug("counting games by status: profile_id=%d, status=%s", profileID, status)
@@ -219,6 +219,59 @@ func (s *GameRepositorySuite) TestUpdateStatus() {
 	s.Assert().Equal("completed", updated.AnalysisStatus)
 }
 
+func (s *GameRepositorySuite) TestUpdateAccuracy() {
+	ctx := context.Background()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO profiles (username) VALUES (?)`, "testuser")
+	s.Require().NoError(err)
+
+	var profileID int64
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM profiles WHERE username = ?`, "testuser").Scan(&profileID)
+	s.Require().NoError(err)
+
+	id, err := s.repo.Insert(ctx, models.Game{
+		ProfileID:      profileID,
+		ChessComID:     "test123",
+		PGN:            "test",
+		TimeClass:      "blitz",
+		Result:         "win",
+		PlayedAs:       "black",
+		Opponent:       "opp1",
+		PlayedAt:       time.Now(),
+		OpeningName:    "Sicilian Defense",
+		AnalysisStatus: "completed",
+	})
+	s.Require().NoError(err)
+
+	fresh, err := s.repo.Get(ctx, id)
+	s.Require().NoError(err)
+	s.Assert().Nil(fresh.PlayerAccuracy(), "accuracy is unset until analysis completes")
+
+	err = s.repo.UpdateAccuracy(ctx, id, models.GameAccuracy{WhiteACPL: 80, BlackACPL: 25, WhiteAccuracy: 71.5, BlackAccuracy: 92.25})
+	s.Require().NoError(err)
+
+	updated, err := s.repo.Get(ctx, id)
+	s.Require().NoError(err)
+	s.Require().NotNil(updated.PlayerAccuracy())
+	s.Assert().Equal(92.25, *updated.PlayerAccuracy())
+	s.Assert().Equal(25.0, *updated.PlayerACPL())
+	s.Assert().Equal(71.5, *updated.OpponentAccuracy())
+
+	stats := sqlite.NewStatsRepository(s.db)
+	trend, err := stats.AccuracyOverTime(ctx, profileID, "", nil)
+	s.Require().NoError(err)
+	s.Require().Len(trend, 1)
+	s.Assert().Equal(1, trend[0].Games)
+	s.Assert().InDelta(92.3, trend[0].AvgAccuracy, 0.01)
+	s.Assert().InDelta(71.5, trend[0].AvgOpponentAccuracy, 0.01)
+
+	byOpening, err := stats.AccuracyByOpening(ctx, profileID, "blitz", nil, 10)
+	s.Require().NoError(err)
+	s.Require().Len(byOpening, 1)
+	s.Assert().Equal("Sicilian Defense", byOpening[0].OpeningName)
+	s.Assert().InDelta(25.0, byOpening[0].AvgACPL, 0.01)
+}
+
 func (s *GameRepositorySuite) TestGamesNeedingAnalysis() {
 	ctx := context.Background()
 
@@ -275,6 +328,118 @@ func (s *GameRepositorySuite) TestGamesNeedingAnalysis() {
 	s.Assert().Len(needing, 2) // pending and failed, not completed
 }
 
+func (s *GameRepositorySuite) TestIncompleteAnalysis() {
+	ctx := context.Background()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO profiles (username) VALUES (?)`, "testuser")
+	s.Require().NoError(err)
+	var profileID int64
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM profiles WHERE username = ?`, "testuser").Scan(&profileID)
+	s.Require().NoError(err)
+
+	// Two games were being analyzed when the server stopped; one had stored
+	// its first move already
+	var ids []int64
+	for _, chessComID := range []string{"started", "untouched", "done"} {
+		status := "processing"
+		if chessComID == "done" {
+			status = "completed"
+		}
+		id, err := s.repo.Insert(ctx, models.Game{
+			ProfileID:      profileID,
+			ChessComID:     chessComID,
+			PGN:            "test",
+			TimeClass:      "blitz",
+			Result:         "win",
+			PlayedAs:       "white",
+			Opponent:       "opp",
+			PlayedAt:       time.Now(),
+			AnalysisStatus: status,
+		})
+		s.Require().NoError(err)
+		ids = append(ids, id)
+	}
+	_, err = sqlite.NewPositionRepository(s.db).Insert(ctx, models.Position{
+		GameID: ids[0], MoveNumber: 1, FEN: "fen", MovePlayed: "e2e4", BestMove: "e2e4", Classification: "best", CreatedAt: time.Now(),
+	})
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.repo.ResetProcessingToPending(ctx, profileID))
+	started, err := s.repo.Get(ctx, ids[0])
+	s.Require().NoError(err)
+	s.Assert().Equal("incomplete", started.AnalysisStatus)
+	untouched, err := s.repo.Get(ctx, ids[1])
+	s.Require().NoError(err)
+	s.Assert().Equal("pending", untouched.AnalysisStatus)
+
+	games, err := s.repo.GamesForAnalysis(ctx, models.AnalysisFilter{ProfileID: profileID})
+	s.Require().NoError(err)
+	s.Assert().Len(games, 2)
+
+	games, err = s.repo.GamesForAnalysis(ctx, models.AnalysisFilter{ProfileID: profileID, IncompleteOnly: true})
+	s.Require().NoError(err)
+	s.Require().Len(games, 1)
+	s.Assert().Equal(ids[0], games[0].ID)
+
+	count, err := s.repo.CountGamesNeedingAnalysis(ctx, profileID)
+	s.Require().NoError(err)
+	s.Assert().Equal(2, count)
+}
+
+func (s *GameRepositorySuite) TestFailureReasons() {
+	ctx := context.Background()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO profiles (username) VALUES (?)`, "testuser")
+	s.Require().NoError(err)
+	var profileID int64
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM profiles WHERE username = ?`, "testuser").Scan(&profileID)
+	s.Require().NoError(err)
+
+	var ids []int64
+	for _, chessComID := range []string{"limited1", "limited2", "badpgn", "ok"} {
+		id, err := s.repo.Insert(ctx, models.Game{
+			ProfileID:      profileID,
+			ChessComID:     chessComID,
+			PGN:            "test",
+			TimeClass:      "blitz",
+			Result:         "win",
+			PlayedAs:       "white",
+			Opponent:       "opp",
+			PlayedAt:       time.Now(),
+			AnalysisStatus: "pending",
+		})
+		s.Require().NoError(err)
+		ids = append(ids, id)
+	}
+	s.Require().NoError(s.repo.UpdateFailure(ctx, ids[0], "failed", "rate_limited"))
+	s.Require().NoError(s.repo.UpdateFailure(ctx, ids[1], "failed", "rate_limited"))
+	s.Require().NoError(s.repo.UpdateFailure(ctx, ids[2], "failed", "invalid_pgn"))
+
+	counts, err := s.repo.CountFailureReasons(ctx, profileID)
+	s.Require().NoError(err)
+	s.Assert().Equal(map[string]int{"rate_limited": 2, "invalid_pgn": 1}, counts)
+
+	games, err := s.repo.List(ctx, models.GameFilter{ProfileID: profileID, FailureReason: "rate_limited", Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(games, 2)
+	s.Assert().Equal("rate_limited", games[0].FailureReason)
+	total, err := s.repo.Count(ctx, models.GameFilter{ProfileID: profileID, FailureReason: "rate_limited"})
+	s.Require().NoError(err)
+	s.Assert().Equal(2, total)
+
+	toRetry, err := s.repo.GamesForAnalysis(ctx, models.AnalysisFilter{ProfileID: profileID, FailureReason: "invalid_pgn"})
+	s.Require().NoError(err)
+	s.Require().Len(toRetry, 1)
+	s.Assert().Equal(ids[2], toRetry[0].ID)
+
+	// A new analysis run clears the reason
+	s.Require().NoError(s.repo.UpdateStatus(ctx, ids[0], "processing"))
+	game, err := s.repo.Get(ctx, ids[0])
+	s.Require().NoError(err)
+	s.Assert().Equal("processing", game.AnalysisStatus)
+	s.Assert().Empty(game.FailureReason)
+}
+
 func TestGameRepositorySuite(t *testing.T) {
 	suite.Run(t, new(GameRepositorySuite))
 }
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"strings"
 
 	"github.com/vytor/chessflash/internal/logger"
@@ -36,3 +37,25 @@ func tx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
 	log.Debug("transaction committed")
 	return nil
 }
+
+// nullString stores empty strings as NULL so optional unique columns do not collide.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullFloat64 stores zero as NULL for optional numeric state such as FSRS stability.
+func nullFloat64(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: v != 0}
+}
+
+// marshalNullJSON encodes v as JSON, or NULL when empty is true.
+func marshalNullJSON(v any, empty bool) (sql.NullString, error) {
+	if empty {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
@@ -0,0 +1,338 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+type jobRepository struct {
+	db *sql.DB
+}
+
+// NewJobRepository creates a new JobRepository implementation
+func NewJobRepository(db *sql.DB) repository.JobRepository {
+	return &jobRepository{db: db}
+}
+
+const jobColumns = `id, queue, kind, payload, state, priority, attempts, max_attempts, run_at,
+    lease_owner, lease_expires_at, last_error, created_at, updated_at, completed_at`
+
+// Enqueue stores a queued job. When the same job is already queued or
+// running it is raised to the new job's priority and moved up to its run_at
+// instead; 0 is returned when that changes nothing.
+func (r *jobRepository) Enqueue(ctx context.Context, job models.Job) (int64, error) {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("enqueueing job: queue=%s, kind=%s, payload=%s", job.Queue, job.Kind, job.Payload)
+
+	now := time.Now().UTC()
+	runAt := job.RunAt
+	if runAt.IsZero() {
+		runAt = now
+	}
+	maxAttempts := job.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+INSERT INTO jobs (queue, kind, payload, state, priority, max_attempts, run_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(kind, payload) WHERE state IN ('queued', 'running') DO UPDATE
+SET priority = MAX(priority, excluded.priority), run_at = MIN(run_at, excluded.run_at), updated_at = excluded.updated_at
+WHERE excluded.priority > priority OR excluded.run_at < run_at
+RETURNING id
+`, job.Queue, job.Kind, job.Payload, models.JobQueued, job.Priority, maxAttempts, runAt.UTC(), now, now).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Debug("job already queued: kind=%s, payload=%s", job.Kind, job.Payload)
+		return 0, nil
+	}
+	if err != nil {
+		log.Error("failed to enqueue job: %v", err)
+		return 0, err
+	}
+	log.Debug("job enqueued: id=%d", id)
+	return id, nil
+}
+
+// Claim leases the next due job of the queue to owner: the highest priority
+// queued job, or a running job whose lease expired. With oldestFirst the job
+// that has waited longest is taken instead, whatever its priority. Running
+// jobs that expired on their last attempt are failed instead. Returns nil
+// when nothing is due.
+func (r *jobRepository) Claim(ctx context.Context, queue, owner string, lease time.Duration, oldestFirst bool) (*models.Job, error) {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+
+	order := "priority DESC, run_at, id"
+	if oldestFirst {
+		order = "run_at, id"
+	}
+
+	now := time.Now().UTC()
+	var job *models.Job
+	err := tx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+UPDATE jobs
+SET state = ?, last_error = 'lease expired after ' || attempts || ' attempts',
+    lease_owner = NULL, lease_expires_at = NULL, updated_at = ?
+WHERE queue = ? AND state = ? AND lease_expires_at < ? AND attempts >= max_attempts
+`, models.JobFailed, now, queue, models.JobRunning, now); err != nil {
+			log.Error("failed to fail expired jobs: %v", err)
+			return err
+		}
+
+		var err error
+		job, err = scanJob(tx.QueryRowContext(ctx, `
+UPDATE jobs
+SET state = ?, attempts = attempts + 1, lease_owner = ?, lease_expires_at = ?, updated_at = ?
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE queue = ? AND ((state = ? AND run_at <= ?) OR (state = ? AND lease_expires_at < ?))
+    ORDER BY `+order+`
+    LIMIT 1
+)
+RETURNING `+jobColumns, models.JobRunning, owner, now.Add(lease), now,
+			queue, models.JobQueued, now, models.JobRunning, now))
+		return err
+	})
+	if err != nil {
+		log.Error("failed to claim job: queue=%s: %v", queue, err)
+		return nil, err
+	}
+	if job != nil {
+		log.Debug("job claimed: id=%d, kind=%s, attempt=%d", job.ID, job.Kind, job.Attempts)
+	}
+	return job, nil
+}
+
+func (r *jobRepository) Heartbeat(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("extending job lease: id=%d", id)
+
+	now := time.Now().UTC()
+	return r.updateLeased(ctx, id, owner, `lease_expires_at = ?, updated_at = ?`, now.Add(lease), now)
+}
+
+func (r *jobRepository) Complete(ctx context.Context, id int64, owner string) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("completing job: id=%d", id)
+
+	now := time.Now().UTC()
+	return r.updateLeased(ctx, id, owner,
+		`state = ?, lease_owner = NULL, lease_expires_at = NULL, updated_at = ?, completed_at = ?`,
+		models.JobCompleted, now, now)
+}
+
+func (r *jobRepository) Fail(ctx context.Context, id int64, owner string, reason string) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("failing job: id=%d, reason=%s", id, reason)
+
+	return r.updateLeased(ctx, id, owner,
+		`state = ?, last_error = ?, lease_owner = NULL, lease_expires_at = NULL, updated_at = ?`,
+		models.JobFailed, reason, time.Now().UTC())
+}
+
+// Reschedule queues a job that failed to run again at runAt, keeping the
+// failed attempt on its count.
+func (r *jobRepository) Reschedule(ctx context.Context, id int64, owner string, runAt time.Time, reason string) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("rescheduling job: id=%d, run_at=%v, reason=%s", id, runAt, reason)
+
+	return r.updateLeased(ctx, id, owner,
+		`state = ?, run_at = ?, last_error = ?, lease_owner = NULL, lease_expires_at = NULL, updated_at = ?`,
+		models.JobQueued, runAt.UTC(), reason, time.Now().UTC())
+}
+
+// Release puts an interrupted job back in the queue without counting the
+// attempt against it.
+func (r *jobRepository) Release(ctx context.Context, id int64, owner string) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("releasing job: id=%d", id)
+
+	return r.updateLeased(ctx, id, owner,
+		`state = ?, attempts = MAX(attempts - 1, 0), lease_owner = NULL, lease_expires_at = NULL, updated_at = ?`,
+		models.JobQueued, time.Now().UTC())
+}
+
+// updateLeased applies set to a running job held by owner, returning
+// repository.ErrLeaseLost when the lease is no longer theirs.
+func (r *jobRepository) updateLeased(ctx context.Context, id int64, owner string, set string, args ...any) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+
+	args = append(args, id, owner, models.JobRunning)
+	res, err := r.db.ExecContext(ctx, `UPDATE jobs SET `+set+` WHERE id = ? AND lease_owner = ? AND state = ?`, args...)
+	if err != nil {
+		log.Error("failed to update job %d: %v", id, err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		log.Warn("lease lost for job %d", id)
+		return repository.ErrLeaseLost
+	}
+	return nil
+}
+
+// Retry queues a failed job again with a fresh set of attempts. Returns
+// sql.ErrNoRows when no failed job has the id.
+func (r *jobRepository) Retry(ctx context.Context, id int64) error {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("retrying job: id=%d", id)
+
+	now := time.Now().UTC()
+	res, err := r.db.ExecContext(ctx, `
+UPDATE jobs
+SET state = ?, attempts = 0, run_at = ?, updated_at = ?
+WHERE id = ? AND state = ?
+`, models.JobQueued, now, now, id, models.JobFailed)
+	if err != nil {
+		log.Error("failed to retry job: %v", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// List returns jobs in the given states: queued jobs in the order they will
+// run, the others most recently updated first.
+func (r *jobRepository) List(ctx context.Context, states []string, limit int) ([]models.Job, error) {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("listing jobs: states=%v, limit=%d", states, limit)
+
+	if len(states) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	args := make([]any, 0, len(states)+3)
+	for _, s := range states {
+		args = append(args, s)
+	}
+	args = append(args, models.JobQueued, models.JobQueued, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+jobColumns+`
+FROM jobs
+WHERE state IN (`+strings.TrimSuffix(strings.Repeat("?, ", len(states)), ", ")+`)
+ORDER BY CASE WHEN state = ? THEN priority END DESC,
+         CASE WHEN state = ? THEN run_at END,
+         updated_at DESC, id DESC
+LIMIT ?
+`, args...)
+	if err != nil {
+		log.Error("failed to list jobs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			log.Error("failed to scan job row: %v", err)
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountByState counts the jobs of a queue (all queues when empty) by state.
+func (r *jobRepository) CountByState(ctx context.Context, queue string) (map[string]int, error) {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("counting jobs by state: queue=%s", queue)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT state, COUNT(*)
+FROM jobs
+WHERE ? = '' OR queue = ?
+GROUP BY state
+`, queue, queue)
+	if err != nil {
+		log.Error("failed to count jobs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var state string
+		var count int
+		if err := rows.Scan(&state, &count); err != nil {
+			log.Error("failed to scan job count: %v", err)
+			return nil, err
+		}
+		counts[state] = count
+	}
+	return counts, rows.Err()
+}
+
+// DeleteQueued drops the jobs of a queue that have not started yet.
+func (r *jobRepository) DeleteQueued(ctx context.Context, queue string) (int, error) {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("deleting queued jobs: queue=%s", queue)
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE queue = ? AND state = ?`, queue, models.JobQueued)
+	if err != nil {
+		log.Error("failed to delete queued jobs: %v", err)
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// QueuePosition returns the 1-based place of the game's queued job in the
+// queue's claim order, or 0 when no job for the game is queued.
+func (r *jobRepository) QueuePosition(ctx context.Context, queue string, gameID int64) (int, error) {
+	log := logger.FromContext(ctx).WithPrefix("job_repo")
+	log.Debug("getting queue position: queue=%s, game_id=%d", queue, gameID)
+
+	var position int
+	err := r.db.QueryRowContext(ctx, `
+WITH target AS (
+    SELECT priority, run_at, id FROM jobs
+    WHERE queue = ? AND state = ? AND json_extract(payload, '$.game_id') = ?
+    ORDER BY priority DESC, run_at, id
+    LIMIT 1
+)
+SELECT COUNT(*)
+FROM jobs j, target t
+WHERE j.queue = ? AND j.state = ?
+  AND (j.priority > t.priority
+       OR (j.priority = t.priority AND (j.run_at < t.run_at OR (j.run_at = t.run_at AND j.id <= t.id))))
+`, queue, models.JobQueued, gameID, queue, models.JobQueued).Scan(&position)
+	if err != nil {
+		log.Error("failed to get queue position: %v", err)
+		return 0, err
+	}
+	return position, nil
+}
+
+// scanJob returns nil without error when no row matched.
+func scanJob(row interface{ Scan(...any) error }) (*models.Job, error) {
+	var job models.Job
+	var owner, lastError sql.NullString
+	err := row.Scan(&job.ID, &job.Queue, &job.Kind, &job.Payload, &job.State, &job.Priority, &job.Attempts, &job.MaxAttempts, &job.RunAt,
+		&owner, &job.LeaseExpiresAt, &lastError, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.LeaseOwner = owner.String
+	job.LastError = lastError.String
+	return &job, nil
+}
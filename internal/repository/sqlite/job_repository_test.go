@@ -0,0 +1,294 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+type JobRepositorySuite struct {
+	suite.Suite
+	db   *sql.DB
+	repo repository.JobRepository
+}
+
+func (s *JobRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewJobRepository(s.db)
+}
+
+func (s *JobRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *JobRepositorySuite) enqueue(payload string, priority int) int64 {
+	id, err := s.repo.Enqueue(context.Background(), models.Job{
+		Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: payload, Priority: priority,
+	})
+	s.Require().NoError(err)
+	s.Require().NotZero(id)
+	return id
+}
+
+func (s *JobRepositorySuite) TestEnqueueDeduplicatesActiveJobs() {
+	ctx := context.Background()
+	first := s.enqueue(`{"game_id":1}`, 0)
+
+	id, err := s.repo.Enqueue(ctx, models.Job{Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: `{"game_id":1}`})
+	s.Require().NoError(err)
+	s.Equal(int64(0), id)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Require().NoError(s.repo.Complete(ctx, job.ID, "w1"))
+
+	// Once finished, the same job may be queued again
+	second := s.enqueue(`{"game_id":1}`, 0)
+	s.NotEqual(first, second)
+}
+
+func (s *JobRepositorySuite) TestEnqueueRaisesPriorityOfActiveJob() {
+	ctx := context.Background()
+	backfill := s.enqueue(`{"game_id":1}`, 0)
+	s.enqueue(`{"game_id":2}`, 1)
+
+	// Asking for the game interactively moves the backfill job ahead
+	id, err := s.repo.Enqueue(ctx, models.Job{Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: `{"game_id":1}`, Priority: 2})
+	s.Require().NoError(err)
+	s.Equal(backfill, id)
+
+	// A lower priority never demotes it
+	id, err = s.repo.Enqueue(ctx, models.Job{Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: `{"game_id":1}`})
+	s.Require().NoError(err)
+	s.Equal(int64(0), id)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(backfill, job.ID)
+	s.Equal(2, job.Priority)
+}
+
+func (s *JobRepositorySuite) TestClaimOrder() {
+	ctx := context.Background()
+	low := s.enqueue(`{"game_id":1}`, 0)
+	high := s.enqueue(`{"game_id":2}`, 10)
+	_, err := s.repo.Enqueue(ctx, models.Job{
+		Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: `{"game_id":3}`, Priority: 20,
+		RunAt: time.Now().Add(time.Hour),
+	})
+	s.Require().NoError(err)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(high, job.ID)
+	s.Equal(models.JobRunning, job.State)
+	s.Equal(1, job.Attempts)
+	s.Equal("w1", job.LeaseOwner)
+	s.Require().NotNil(job.LeaseExpiresAt)
+
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w2", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(low, job.ID)
+
+	// The remaining job is not due yet and other queues are separate
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w3", time.Minute, false)
+	s.Require().NoError(err)
+	s.Nil(job)
+	job, err = s.repo.Claim(ctx, models.JobQueueImport, "w3", time.Minute, false)
+	s.Require().NoError(err)
+	s.Nil(job)
+}
+
+func (s *JobRepositorySuite) TestClaimOldestFirst() {
+	ctx := context.Background()
+	oldest := s.enqueue(`{"game_id":1}`, 0)
+	s.enqueue(`{"game_id":2}`, 2)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, true)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(oldest, job.ID)
+}
+
+func (s *JobRepositorySuite) TestQueuePosition() {
+	ctx := context.Background()
+	s.enqueue(`{"game_id":1}`, 0)
+	s.enqueue(`{"game_id":2}`, 0)
+	s.enqueue(`{"game_id":3}`, 2)
+	s.enqueue(`{"game_id":4}`, 1)
+
+	for gameID, want := range map[int64]int{1: 3, 2: 4, 3: 1, 4: 2, 5: 0} {
+		position, err := s.repo.QueuePosition(ctx, models.JobQueueAnalysis, gameID)
+		s.Require().NoError(err)
+		s.Equal(want, position, "game %d", gameID)
+	}
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+
+	// A running job is no longer in the queue
+	position, err := s.repo.QueuePosition(ctx, models.JobQueueAnalysis, 3)
+	s.Require().NoError(err)
+	s.Equal(0, position)
+	position, err = s.repo.QueuePosition(ctx, models.JobQueueAnalysis, 1)
+	s.Require().NoError(err)
+	s.Equal(2, position)
+}
+
+func (s *JobRepositorySuite) TestLeaseOwnership() {
+	ctx := context.Background()
+	s.enqueue(`{"game_id":1}`, 0)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+
+	s.NoError(s.repo.Heartbeat(ctx, job.ID, "w1", time.Minute))
+	s.ErrorIs(s.repo.Heartbeat(ctx, job.ID, "w2", time.Minute), repository.ErrLeaseLost)
+	s.ErrorIs(s.repo.Complete(ctx, job.ID, "w2"), repository.ErrLeaseLost)
+
+	s.Require().NoError(s.repo.Complete(ctx, job.ID, "w1"))
+	s.ErrorIs(s.repo.Heartbeat(ctx, job.ID, "w1", time.Minute), repository.ErrLeaseLost)
+
+	counts, err := s.repo.CountByState(ctx, models.JobQueueAnalysis)
+	s.Require().NoError(err)
+	s.Equal(map[string]int{models.JobCompleted: 1}, counts)
+}
+
+func (s *JobRepositorySuite) TestExpiredLeaseIsReclaimed() {
+	ctx := context.Background()
+	id, err := s.repo.Enqueue(ctx, models.Job{
+		Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: `{"game_id":1}`, MaxAttempts: 2,
+	})
+	s.Require().NoError(err)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", -time.Second, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w2", -time.Second, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(id, job.ID)
+	s.Equal(2, job.Attempts)
+	s.ErrorIs(s.repo.Complete(ctx, id, "w1"), repository.ErrLeaseLost)
+
+	// The second lease expired on the last attempt
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w3", time.Minute, false)
+	s.Require().NoError(err)
+	s.Nil(job)
+
+	failed, err := s.repo.List(ctx, []string{models.JobFailed}, 10)
+	s.Require().NoError(err)
+	s.Require().Len(failed, 1)
+	s.Equal(id, failed[0].ID)
+	s.Contains(failed[0].LastError, "lease expired")
+}
+
+func (s *JobRepositorySuite) TestFailAndRetry() {
+	ctx := context.Background()
+	id := s.enqueue(`{"game_id":1}`, 0)
+
+	s.ErrorIs(s.repo.Retry(ctx, id), sql.ErrNoRows)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Require().NoError(s.repo.Fail(ctx, id, "w1", "engine crashed"))
+
+	failed, err := s.repo.List(ctx, []string{models.JobFailed}, 10)
+	s.Require().NoError(err)
+	s.Require().Len(failed, 1)
+	s.Equal("engine crashed", failed[0].LastError)
+	s.Equal(1, failed[0].Attempts)
+	s.Empty(failed[0].LeaseOwner)
+
+	s.Require().NoError(s.repo.Retry(ctx, id))
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w2", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(id, job.ID)
+	s.Equal(1, job.Attempts)
+}
+
+func (s *JobRepositorySuite) TestReschedule() {
+	ctx := context.Background()
+	id := s.enqueue(`{"game_id":1}`, 0)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Require().NoError(s.repo.Reschedule(ctx, id, "w1", time.Now().Add(time.Hour), "rate limited"))
+	s.ErrorIs(s.repo.Reschedule(ctx, id, "w1", time.Now(), "rate limited"), repository.ErrLeaseLost)
+
+	queued, err := s.repo.List(ctx, []string{models.JobQueued}, 10)
+	s.Require().NoError(err)
+	s.Require().Len(queued, 1)
+	s.Equal("rate limited", queued[0].LastError)
+	s.Equal(1, queued[0].Attempts)
+
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w2", time.Minute, false)
+	s.Require().NoError(err)
+	s.Nil(job, "rescheduled jobs wait for their run time")
+
+	// Enqueueing it again, as a retry does, makes it due now
+	again, err := s.repo.Enqueue(ctx, models.Job{Queue: models.JobQueueAnalysis, Kind: "analyze_game", Payload: `{"game_id":1}`})
+	s.Require().NoError(err)
+	s.Equal(id, again)
+	job, err = s.repo.Claim(ctx, models.JobQueueAnalysis, "w2", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Equal(id, job.ID)
+}
+
+func (s *JobRepositorySuite) TestReleaseDoesNotCountAttempt() {
+	ctx := context.Background()
+	id := s.enqueue(`{"game_id":1}`, 0)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+	s.Require().NoError(s.repo.Release(ctx, id, "w1"))
+
+	queued, err := s.repo.List(ctx, []string{models.JobQueued}, 10)
+	s.Require().NoError(err)
+	s.Require().Len(queued, 1)
+	s.Equal(0, queued[0].Attempts)
+	s.Nil(queued[0].LeaseExpiresAt)
+}
+
+func (s *JobRepositorySuite) TestDeleteQueued() {
+	ctx := context.Background()
+	s.enqueue(`{"game_id":1}`, 0)
+	s.enqueue(`{"game_id":2}`, 0)
+	_, err := s.repo.Enqueue(ctx, models.Job{Queue: models.JobQueueImport, Kind: "import_games", Payload: `{"profile_id":1}`})
+	s.Require().NoError(err)
+
+	job, err := s.repo.Claim(ctx, models.JobQueueAnalysis, "w1", time.Minute, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(job)
+
+	n, err := s.repo.DeleteQueued(ctx, models.JobQueueAnalysis)
+	s.Require().NoError(err)
+	s.Equal(1, n)
+
+	counts, err := s.repo.CountByState(ctx, "")
+	s.Require().NoError(err)
+	s.Equal(map[string]int{models.JobRunning: 1, models.JobQueued: 1}, counts)
+}
+
+func TestJobRepositorySuite(t *testing.T) {
+	suite.Run(t, new(JobRepositorySuite))
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+type openingTreeRepository struct {
+	db *sql.DB
+}
+
+// NewOpeningTreeRepository creates a new OpeningTreeRepository implementation
+func NewOpeningTreeRepository(db *sql.DB) repository.OpeningTreeRepository {
+	return &openingTreeRepository{db: db}
+}
+
+// UnindexedGames returns games whose moves are not in the opening tree yet,
+// with only ID and PGN set.
+func (r *openingTreeRepository) UnindexedGames(ctx context.Context, profileID int64, limit int) ([]models.Game, error) {
+	log := logger.FromContext(ctx).WithPrefix("opening_tree_repo")
+	log.Debug("listing unindexed games: profile_id=%d, limit=%d", profileID, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, pgn
+FROM games
+WHERE profile_id = ? AND opening_tree_indexed = 0
+ORDER BY id
+LIMIT ?
+`, profileID, limit)
+	if err != nil {
+		log.Error("failed to query unindexed games: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []models.Game
+	for rows.Next() {
+		var g models.Game
+		if err := rows.Scan(&g.ID, &g.PGN); err != nil {
+			log.Error("failed to scan unindexed game: %v", err)
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+// IndexGame replaces the game's opening moves and marks it as indexed.
+func (r *openingTreeRepository) IndexGame(ctx context.Context, gameID int64, moves []models.OpeningTreeMove) error {
+	log := logger.FromContext(ctx).WithPrefix("opening_tree_repo")
+	log.Debug("indexing game into opening tree: game_id=%d, moves=%d", gameID, len(moves))
+
+	return tx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM opening_tree_moves WHERE game_id = ?`, gameID); err != nil {
+			log.Error("failed to clear opening tree moves: %v", err)
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO opening_tree_moves (game_id, ply, fen, move, san)
+VALUES (?, ?, ?, ?, ?)
+`)
+		if err != nil {
+			log.Error("failed to prepare opening tree insert: %v", err)
+			return err
+		}
+		defer stmt.Close()
+
+		for _, m := range moves {
+			if _, err := stmt.ExecContext(ctx, gameID, m.Ply, m.FEN, m.Move, m.SAN); err != nil {
+				log.Error("failed to insert opening tree move %s: %v", m.Move, err)
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE games SET opening_tree_indexed = 1 WHERE id = ?`, gameID); err != nil {
+			log.Error("failed to mark game as indexed: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// PositionMoves aggregates the games that reached the position by the move
+// played from it, most played first. A game passing through the position
+// twice counts once per move.
+func (r *openingTreeRepository) PositionMoves(ctx context.Context, filter models.ExplorerFilter) ([]models.ExplorerMove, error) {
+	log := logger.FromContext(ctx).WithPrefix("opening_tree_repo")
+	log.Debug("fetching explorer moves: profile_id=%d, color=%s, time_class=%s", filter.ProfileID, filter.Color, filter.TimeClass)
+
+	query := `
+SELECT t.move, MAX(t.san),
+       COUNT(*) AS games,
+       SUM(CASE WHEN g.result = 'win' THEN 1 ELSE 0 END) AS wins,
+       SUM(CASE WHEN g.result = 'draw' THEN 1 ELSE 0 END) AS draws,
+       SUM(CASE WHEN g.result = 'loss' THEN 1 ELSE 0 END) AS losses,
+       SUM(CASE WHEN g.analysis_status = 'completed' THEN 1 ELSE 0 END) AS analyzed_games,
+       AVG(CASE WHEN g.played_as = 'white' THEN g.white_accuracy ELSE g.black_accuracy END) AS avg_accuracy,
+       COALESCE(SUM(CASE WHEN g.analysis_status = 'completed' THEN COALESCE(b.blunder_count, 0) END), 0) AS blunders
+FROM (
+    SELECT DISTINCT game_id, move, san
+    FROM opening_tree_moves
+    WHERE fen = ?
+) t
+JOIN games g ON g.id = t.game_id
+LEFT JOIN (
+    SELECT p.game_id, COUNT(*) AS blunder_count
+    FROM positions p
+    JOIN games pg ON pg.id = p.game_id
+    WHERE p.classification IN ('blunder', 'missed_mate', 'allowed_mate')
+      AND (p.move_number % 2 = 1) = (pg.played_as = 'white')
+    GROUP BY p.game_id
+) b ON b.game_id = g.id
+WHERE g.profile_id = ?`
+	args := []any{filter.FEN, filter.ProfileID}
+
+	if filter.Color != "" {
+		query += " AND g.played_as = ?"
+		args = append(args, filter.Color)
+	}
+	if filter.TimeClass != "" {
+		query += " AND g.time_class = ?"
+		args = append(args, filter.TimeClass)
+	}
+
+	query += " GROUP BY t.move ORDER BY games DESC, t.move"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Error("failed to query explorer moves: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []models.ExplorerMove
+	for rows.Next() {
+		var m models.ExplorerMove
+		var avgAccuracy sql.NullFloat64
+		var blunders int
+		if err := rows.Scan(&m.Move, &m.SAN, &m.Games, &m.Wins, &m.Draws, &m.Losses, &m.AnalyzedGames, &avgAccuracy, &blunders); err != nil {
+			log.Error("failed to scan explorer move: %v", err)
+			return nil, err
+		}
+		if avgAccuracy.Valid {
+			m.AvgAccuracy = &avgAccuracy.Float64
+		}
+		if m.AnalyzedGames > 0 {
+			m.BlunderRate = float64(blunders) / float64(m.AnalyzedGames)
+		}
+		moves = append(moves, m)
+	}
+	log.Debug("found %d explorer moves", len(moves))
+	return moves, rows.Err()
+}
@@ -0,0 +1,126 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+const explorerStartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+
+type OpeningTreeRepositorySuite struct {
+	suite.Suite
+	db        *sql.DB
+	repo      repository.OpeningTreeRepository
+	profileID int64
+}
+
+func (s *OpeningTreeRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewOpeningTreeRepository(s.db)
+
+	profile, err := sqlite.NewProfileRepository(s.db).Upsert(context.Background(), "testuser", "chesscom")
+	s.Require().NoError(err)
+	s.profileID = profile.ID
+}
+
+func (s *OpeningTreeRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *OpeningTreeRepositorySuite) insertGame(chessComID, result, playedAs, status string, accuracy *float64) int64 {
+	res, err := s.db.ExecContext(context.Background(), `
+		INSERT INTO games (profile_id, chess_com_id, pgn, time_class, result, played_as, opponent, played_at, analysis_status, white_accuracy, black_accuracy)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.profileID, chessComID, "test pgn", "blitz", result, playedAs, "opponent1", time.Now(), status, accuracy, accuracy)
+	s.Require().NoError(err)
+	id, err := res.LastInsertId()
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *OpeningTreeRepositorySuite) TestIndexGame() {
+	ctx := context.Background()
+	gameID := s.insertGame("game1", "win", "white", "pending", nil)
+
+	pending, err := s.repo.UnindexedGames(ctx, s.profileID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(pending, 1)
+	s.Assert().Equal("test pgn", pending[0].PGN)
+
+	moves := []models.OpeningTreeMove{{Ply: 1, FEN: explorerStartFEN, Move: "e2e4", SAN: "e4"}}
+	s.Require().NoError(s.repo.IndexGame(ctx, gameID, moves))
+	// Indexing again replaces the moves instead of duplicating them
+	s.Require().NoError(s.repo.IndexGame(ctx, gameID, moves))
+
+	pending, err = s.repo.UnindexedGames(ctx, s.profileID, 10)
+	s.Require().NoError(err)
+	s.Assert().Empty(pending)
+
+	stats, err := s.repo.PositionMoves(ctx, models.ExplorerFilter{ProfileID: s.profileID, FEN: explorerStartFEN})
+	s.Require().NoError(err)
+	s.Require().Len(stats, 1)
+	s.Assert().Equal(1, stats[0].Games)
+}
+
+func (s *OpeningTreeRepositorySuite) TestPositionMoves() {
+	ctx := context.Background()
+	acc80, acc60 := 80.0, 60.0
+
+	win := s.insertGame("game1", "win", "white", "completed", &acc80)
+	loss := s.insertGame("game2", "loss", "white", "completed", &acc60)
+	draw := s.insertGame("game3", "draw", "white", "pending", nil)
+	black := s.insertGame("game4", "loss", "black", "pending", nil)
+
+	for _, id := range []int64{win, loss} {
+		s.Require().NoError(s.repo.IndexGame(ctx, id, []models.OpeningTreeMove{{Ply: 1, FEN: explorerStartFEN, Move: "e2e4", SAN: "e4"}}))
+	}
+	s.Require().NoError(s.repo.IndexGame(ctx, draw, []models.OpeningTreeMove{{Ply: 1, FEN: explorerStartFEN, Move: "d2d4", SAN: "d4"}}))
+	s.Require().NoError(s.repo.IndexGame(ctx, black, []models.OpeningTreeMove{{Ply: 1, FEN: explorerStartFEN, Move: "e2e4", SAN: "e4"}}))
+
+	// Two blunders by the player (odd plies as white) and one by the opponent in the loss
+	for i, ply := range []int{11, 15, 16} {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, loss, ply, explorerStartFEN, "a2a3", "e2e4", 0.0, -300.0, -300.0, []string{"blunder", "missed_mate", "blunder"}[i])
+		s.Require().NoError(err)
+	}
+
+	moves, err := s.repo.PositionMoves(ctx, models.ExplorerFilter{ProfileID: s.profileID, FEN: explorerStartFEN, Color: "white"})
+	s.Require().NoError(err)
+	s.Require().Len(moves, 2)
+
+	e4 := moves[0]
+	s.Assert().Equal("e2e4", e4.Move)
+	s.Assert().Equal("e4", e4.SAN)
+	s.Assert().Equal(2, e4.Games)
+	s.Assert().Equal(1, e4.Wins)
+	s.Assert().Equal(1, e4.Losses)
+	s.Assert().Equal(2, e4.AnalyzedGames)
+	s.Require().NotNil(e4.AvgAccuracy)
+	s.Assert().InDelta(70.0, *e4.AvgAccuracy, 0.001)
+	s.Assert().InDelta(1.0, e4.BlunderRate, 0.001)
+	s.Assert().InDelta(0.5, e4.Score(), 0.001)
+
+	d4 := moves[1]
+	s.Assert().Equal(1, d4.Draws)
+	s.Assert().Nil(d4.AvgAccuracy)
+	s.Assert().Zero(d4.BlunderRate)
+
+	all, err := s.repo.PositionMoves(ctx, models.ExplorerFilter{ProfileID: s.profileID, FEN: explorerStartFEN, TimeClass: "blitz"})
+	s.Require().NoError(err)
+	s.Require().Len(all, 2)
+	s.Assert().Equal(3, all[0].Games)
+}
+
+func TestOpeningTreeRepositorySuite(t *testing.T) {
+	suite.Run(t, new(OpeningTreeRepositorySuite))
+}
@@ -3,6 +3,8 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
@@ -23,17 +25,30 @@ func (r *positionRepository) Insert(ctx context.Context, p models.Position) (int
 	log.Debug("inserting position: game_id=%d, move_number=%d, classification=%s",
 		p.GameID, p.MoveNumber, p.Classification)
 
-	res, err := r.db.ExecContext(ctx, `
-INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, mate_before, mate_after, classification, created_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-`, p.GameID, p.MoveNumber, p.FEN, p.MovePlayed, p.BestMove, p.EvalBefore, p.EvalAfter, p.EvalDiff, p.MateBefore, p.MateAfter, p.Classification, p.CreatedAt)
-	if err != nil {
-		log.Error("failed to insert position: %v", err)
-		return 0, err
-	}
-	id, err := res.LastInsertId()
+	// The position and its lines are stored together so that an analysis
+	// interrupted here never leaves a position without its candidate lines
+	var id int64
+	err := tx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, mate_before, mate_after, classification, pv, depth, material_hung, material_hung_checked, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
+`, p.GameID, p.MoveNumber, p.FEN, p.MovePlayed, p.BestMove, p.EvalBefore, p.EvalAfter, p.EvalDiff, p.MateBefore, p.MateAfter, p.Classification, strings.Join(p.PV, " "), p.Depth, p.MaterialHung, p.CreatedAt)
+		if err != nil {
+			log.Error("failed to insert position: %v", err)
+			return err
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			log.Error("failed to get position id: %v", err)
+			return err
+		}
+		if err := insertPositionLines(ctx, tx, id, p.Lines); err != nil {
+			log.Error("failed to insert position lines: %v", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Error("failed to get position id: %v", err)
 		return 0, err
 	}
 	log.Debug("position inserted: id=%d", id)
@@ -51,8 +66,8 @@ func (r *positionRepository) InsertBatch(ctx context.Context, positions []models
 	var insertedIDs []int64
 	err := tx(ctx, r.db, func(tx *sql.Tx) error {
 		stmt, err := tx.PrepareContext(ctx, `
-INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, mate_before, mate_after, classification, created_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, mate_before, mate_after, classification, pv, depth, material_hung, material_hung_checked, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
 `)
 		if err != nil {
 			log.Error("failed to prepare batch insert: %v", err)
@@ -61,13 +76,17 @@ VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		defer stmt.Close()
 
 		for _, p := range positions {
-			res, err := stmt.ExecContext(ctx, p.GameID, p.MoveNumber, p.FEN, p.MovePlayed, p.BestMove, p.EvalBefore, p.EvalAfter, p.EvalDiff, p.MateBefore, p.MateAfter, p.Classification, p.CreatedAt)
+			res, err := stmt.ExecContext(ctx, p.GameID, p.MoveNumber, p.FEN, p.MovePlayed, p.BestMove, p.EvalBefore, p.EvalAfter, p.EvalDiff, p.MateBefore, p.MateAfter, p.Classification, strings.Join(p.PV, " "), p.Depth, p.MaterialHung, p.CreatedAt)
 			if err != nil {
 				log.Error("failed to insert position game_id=%d move_number=%d: %v", p.GameID, p.MoveNumber, err)
 				return err
 			}
 			if id, err := res.LastInsertId(); err == nil && id != 0 {
 				insertedIDs = append(insertedIDs, id)
+				if err := insertPositionLines(ctx, tx, id, p.Lines); err != nil {
+					log.Error("failed to insert lines for position game_id=%d move_number=%d: %v", p.GameID, p.MoveNumber, err)
+					return err
+				}
 			}
 		}
 		return nil
@@ -85,7 +104,7 @@ func (r *positionRepository) PositionsForGame(ctx context.Context, gameID int64)
 	log.Debug("fetching positions for game: game_id=%d", gameID)
 
 	rows, err := r.db.QueryContext(ctx, `
-SELECT id, game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, mate_before, mate_after, classification, created_at
+SELECT id, game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, mate_before, mate_after, classification, COALESCE(pv, ''), depth, material_hung, created_at
 FROM positions
 WHERE game_id = ?
 ORDER BY move_number ASC
@@ -98,12 +117,254 @@ ORDER BY move_number ASC
 	var positions []models.Position
 	for rows.Next() {
 		var p models.Position
-		if err := rows.Scan(&p.ID, &p.GameID, &p.MoveNumber, &p.FEN, &p.MovePlayed, &p.BestMove, &p.EvalBefore, &p.EvalAfter, &p.EvalDiff, &p.MateBefore, &p.MateAfter, &p.Classification, &p.CreatedAt); err != nil {
+		var pv string
+		if err := rows.Scan(&p.ID, &p.GameID, &p.MoveNumber, &p.FEN, &p.MovePlayed, &p.BestMove, &p.EvalBefore, &p.EvalAfter, &p.EvalDiff, &p.MateBefore, &p.MateAfter, &p.Classification, &pv, &p.Depth, &p.MaterialHung, &p.CreatedAt); err != nil {
 			log.Error("failed to scan position row: %v", err)
 			return nil, err
 		}
+		p.PV = strings.Fields(pv)
 		positions = append(positions, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := attachPositionLines(ctx, r.db, positions); err != nil {
+		log.Error("failed to load position lines: %v", err)
+		return nil, err
+	}
 	log.Debug("found %d positions", len(positions))
+	return positions, nil
+}
+
+// UpdateAnalysis overwrites the evaluation, classification and lines of
+// already stored positions, matched by ID.
+func (r *positionRepository) UpdateAnalysis(ctx context.Context, positions []models.Position) error {
+	log := logger.FromContext(ctx).WithPrefix("position_repo")
+	log.Debug("updating analysis of %d positions", len(positions))
+
+	if len(positions) == 0 {
+		return nil
+	}
+
+	return tx(ctx, r.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+UPDATE positions
+SET best_move = ?, eval_before = ?, eval_after = ?, eval_diff = ?, mate_before = ?, mate_after = ?,
+    classification = ?, pv = ?, depth = ?, material_hung = ?, material_hung_checked = 1
+WHERE id = ?
+`)
+		if err != nil {
+			log.Error("failed to prepare position update: %v", err)
+			return err
+		}
+		defer stmt.Close()
+
+		for _, p := range positions {
+			if _, err := stmt.ExecContext(ctx, p.BestMove, p.EvalBefore, p.EvalAfter, p.EvalDiff, p.MateBefore, p.MateAfter,
+				p.Classification, strings.Join(p.PV, " "), p.Depth, p.MaterialHung, p.ID); err != nil {
+				log.Error("failed to update position id=%d: %v", p.ID, err)
+				return err
+			}
+			// A new search may report fewer lines, so old ones are replaced rather than merged
+			if _, err := tx.ExecContext(ctx, `DELETE FROM position_lines WHERE position_id = ?`, p.ID); err != nil {
+				log.Error("failed to clear lines of position id=%d: %v", p.ID, err)
+				return err
+			}
+			if err := insertPositionLines(ctx, tx, p.ID, p.Lines); err != nil {
+				log.Error("failed to insert lines of position id=%d: %v", p.ID, err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UncheckedMaterialHung returns positions whose hung material was never
+// computed, with the fields the static exchange evaluation needs.
+func (r *positionRepository) UncheckedMaterialHung(ctx context.Context, limit int) ([]models.Position, error) {
+	log := logger.FromContext(ctx).WithPrefix("position_repo")
+	log.Debug("listing positions without checked hung material: limit=%d", limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, game_id, move_number, fen, move_played, classification
+FROM positions
+WHERE material_hung_checked = 0
+ORDER BY id
+LIMIT ?
+`, limit)
+	if err != nil {
+		log.Error("failed to query positions without checked hung material: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []models.Position
+	for rows.Next() {
+		var p models.Position
+		if err := rows.Scan(&p.ID, &p.GameID, &p.MoveNumber, &p.FEN, &p.MovePlayed, &p.Classification); err != nil {
+			log.Error("failed to scan position without checked hung material: %v", err)
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
 	return positions, rows.Err()
 }
+
+// SetMaterialHung records the hung material of a position and marks it checked.
+func (r *positionRepository) SetMaterialHung(ctx context.Context, id int64, pawns int) error {
+	log := logger.FromContext(ctx).WithPrefix("position_repo")
+	log.Debug("setting hung material: id=%d, pawns=%d", id, pawns)
+
+	if _, err := r.db.ExecContext(ctx, `UPDATE positions SET material_hung = ?, material_hung_checked = 1 WHERE id = ?`, pawns, id); err != nil {
+		log.Error("failed to set hung material of position id=%d: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// hangingDrills selects the profile's own moves that hung material
+const hangingDrills = `
+SELECT
+    p.id, p.game_id, p.move_number, p.fen, p.move_played, p.best_move, p.classification, p.material_hung,
+    CASE WHEN g.played_as = 'white' THEN pr.username ELSE g.opponent END AS white_player,
+    CASE WHEN g.played_as = 'black' THEN pr.username ELSE g.opponent END AS black_player,
+    COALESCE(prev_p.move_played, ''), g.time_class, g.played_at
+FROM positions p
+JOIN games g ON g.id = p.game_id
+JOIN profiles pr ON pr.id = g.profile_id
+LEFT JOIN positions prev_p ON prev_p.game_id = p.game_id AND prev_p.move_number = p.move_number - 1
+WHERE g.profile_id = ? AND p.material_hung > 0
+  AND (p.move_number % 2 = 1) = (g.played_as = 'white')`
+
+// RandomHanging returns a random hanging drill other than excludeID, or nil
+// when there is none.
+func (r *positionRepository) RandomHanging(ctx context.Context, profileID int64, excludeID int64) (*models.HangingDrill, error) {
+	log := logger.FromContext(ctx).WithPrefix("position_repo")
+	log.Debug("fetching random hanging drill: profile_id=%d, exclude_id=%d", profileID, excludeID)
+
+	drill, err := scanHangingDrill(r.db.QueryRowContext(ctx, hangingDrills+`
+  AND p.id != ?
+ORDER BY RANDOM()
+LIMIT 1`, profileID, excludeID))
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Debug("no hanging drills found")
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("failed to get hanging drill: %v", err)
+		return nil, err
+	}
+	return drill, nil
+}
+
+func (r *positionRepository) GetHanging(ctx context.Context, id int64, profileID int64) (*models.HangingDrill, error) {
+	log := logger.FromContext(ctx).WithPrefix("position_repo")
+	log.Debug("getting hanging drill: id=%d, profile_id=%d", id, profileID)
+
+	drill, err := scanHangingDrill(r.db.QueryRowContext(ctx, hangingDrills+`
+  AND p.id = ?`, profileID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Debug("hanging drill not found: id=%d", id)
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("failed to get hanging drill: %v", err)
+		return nil, err
+	}
+	return drill, nil
+}
+
+func (r *positionRepository) CountHanging(ctx context.Context, profileID int64) (int, error) {
+	log := logger.FromContext(ctx).WithPrefix("position_repo")
+	log.Debug("counting hanging drills: profile_id=%d", profileID)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+hangingDrills+`)`, profileID).Scan(&count); err != nil {
+		log.Error("failed to count hanging drills: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func scanHangingDrill(row interface{ Scan(...any) error }) (*models.HangingDrill, error) {
+	var d models.HangingDrill
+	if err := row.Scan(&d.PositionID, &d.GameID, &d.MoveNumber, &d.FEN, &d.MovePlayed, &d.BestMove, &d.Classification, &d.MaterialHung,
+		&d.WhitePlayer, &d.BlackPlayer, &d.PrevMovePlayed, &d.TimeClass, &d.PlayedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// insertPositionLines stores the MultiPV lines for a position.
+func insertPositionLines(ctx context.Context, db execer, positionID int64, lines []models.PositionLine) error {
+	for _, l := range lines {
+		if _, err := db.ExecContext(ctx, `
+INSERT INTO position_lines (position_id, rank, move, cp, mate, pv)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(position_id, rank) DO UPDATE SET
+    move = excluded.move,
+    cp = excluded.cp,
+    mate = excluded.mate,
+    pv = excluded.pv
+`, positionID, l.Rank, l.Move, l.CP, l.Mate, strings.Join(l.PV, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachPositionLines loads lines for every position of a game in one query.
+func attachPositionLines(ctx context.Context, db queryer, positions []models.Position) error {
+	if len(positions) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(positions))
+	for i, p := range positions {
+		index[p.ID] = i
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT l.position_id, l.rank, l.move, COALESCE(l.cp, 0), l.mate, COALESCE(l.pv, '')
+FROM position_lines l
+JOIN positions p ON p.id = l.position_id
+WHERE p.game_id = ?
+ORDER BY l.position_id, l.rank ASC
+`, positions[0].GameID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l, err := scanPositionLine(rows)
+		if err != nil {
+			return err
+		}
+		if i, ok := index[l.PositionID]; ok {
+			positions[i].Lines = append(positions[i].Lines, l)
+		}
+	}
+	return rows.Err()
+}
+
+func scanPositionLine(rows *sql.Rows) (models.PositionLine, error) {
+	var l models.PositionLine
+	var pv string
+	if err := rows.Scan(&l.PositionID, &l.Rank, &l.Move, &l.CP, &l.Mate, &pv); err != nil {
+		return l, err
+	}
+	l.PV = strings.Fields(pv)
+	return l, nil
+}
@@ -0,0 +1,162 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+type PositionRepositorySuite struct {
+	suite.Suite
+	db     *sql.DB
+	repo   repository.PositionRepository
+	gameID int64
+}
+
+func (s *PositionRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewPositionRepository(s.db)
+
+	ctx := context.Background()
+	profile, err := sqlite.NewProfileRepository(s.db).Upsert(ctx, "testuser", "chesscom")
+	s.Require().NoError(err)
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO games (profile_id, chess_com_id, pgn, time_class, result, played_as, opponent, played_at, analysis_status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, profile.ID, "game1", "test pgn", "blitz", "win", "white", "opponent1", time.Now(), "completed")
+	s.Require().NoError(err)
+	s.gameID, err = res.LastInsertId()
+	s.Require().NoError(err)
+}
+
+func (s *PositionRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *PositionRepositorySuite) TestUpdateAnalysis() {
+	ctx := context.Background()
+	ids, err := s.repo.InsertBatch(ctx, []models.Position{{
+		GameID: s.gameID, MoveNumber: 1, FEN: explorerStartFEN, MovePlayed: "g2g4", BestMove: "e2e4",
+		EvalBefore: 30, EvalAfter: -80, EvalDiff: -110, Classification: "mistake", PV: []string{"e2e4"},
+		Lines: []models.PositionLine{{Rank: 1, Move: "e2e4", CP: 30}, {Rank: 2, Move: "d2d4", CP: 25}},
+		Depth: 12, CreatedAt: time.Now(),
+	}})
+	s.Require().NoError(err)
+	s.Require().Len(ids, 1)
+
+	mate := 7
+	s.Require().NoError(s.repo.UpdateAnalysis(ctx, []models.Position{{
+		ID: ids[0], BestMove: "d2d4", EvalBefore: 20, EvalAfter: -40, EvalDiff: -60, MateAfter: &mate,
+		Classification: "inaccuracy", PV: []string{"d2d4", "d7d5"},
+		Lines: []models.PositionLine{{Rank: 1, Move: "d2d4", CP: 20}},
+		Depth: 20, MaterialHung: 3,
+	}}))
+
+	positions, err := s.repo.PositionsForGame(ctx, s.gameID)
+	s.Require().NoError(err)
+	s.Require().Len(positions, 1)
+	p := positions[0]
+	s.Assert().Equal("g2g4", p.MovePlayed) // the game itself does not change
+	s.Assert().Equal("d2d4", p.BestMove)
+	s.Assert().Equal("inaccuracy", p.Classification)
+	s.Assert().Equal(20, p.Depth)
+	s.Assert().Equal(3, p.MaterialHung)
+	s.Assert().Equal(&mate, p.MateAfter)
+	s.Assert().Equal([]string{"d2d4", "d7d5"}, p.PV)
+	s.Require().Len(p.Lines, 1)
+	s.Assert().Equal("d2d4", p.Lines[0].Move)
+}
+
+func (s *PositionRepositorySuite) TestHangingDrills() {
+	ctx := context.Background()
+	profiles := sqlite.NewProfileRepository(s.db)
+	profile, err := profiles.Upsert(ctx, "testuser", "chesscom")
+	s.Require().NoError(err)
+
+	// The player is white: odd plies are theirs
+	ids, err := s.repo.InsertBatch(ctx, []models.Position{
+		{GameID: s.gameID, MoveNumber: 1, FEN: explorerStartFEN, MovePlayed: "e2e4", Classification: "good", CreatedAt: time.Now()},
+		{GameID: s.gameID, MoveNumber: 2, FEN: "fen2", MovePlayed: "e7e5", Classification: "blunder", MaterialHung: 3, CreatedAt: time.Now()},
+		{GameID: s.gameID, MoveNumber: 3, FEN: "fen3", MovePlayed: "d1h5", BestMove: "g1f3", Classification: "blunder", MaterialHung: 9, CreatedAt: time.Now()},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(ids, 3)
+
+	count, err := s.repo.CountHanging(ctx, profile.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(1, count)
+
+	drill, err := s.repo.RandomHanging(ctx, profile.ID, 0)
+	s.Require().NoError(err)
+	s.Require().NotNil(drill)
+	s.Assert().Equal(ids[2], drill.PositionID)
+	s.Assert().Equal("d1h5", drill.MovePlayed)
+	s.Assert().Equal("e7e5", drill.PrevMovePlayed)
+	s.Assert().Equal(9, drill.MaterialHung)
+	s.Assert().Equal("testuser", drill.WhitePlayer)
+	s.Assert().Equal("opponent1", drill.BlackPlayer)
+
+	drill, err = s.repo.RandomHanging(ctx, profile.ID, ids[2])
+	s.Require().NoError(err)
+	s.Assert().Nil(drill)
+
+	drill, err = s.repo.GetHanging(ctx, ids[2], profile.ID)
+	s.Require().NoError(err)
+	s.Assert().NotNil(drill)
+
+	// The opponent's blunder is not the player's to drill
+	drill, err = s.repo.GetHanging(ctx, ids[1], profile.ID)
+	s.Require().NoError(err)
+	s.Assert().Nil(drill)
+
+	drill, err = s.repo.GetHanging(ctx, ids[2], profile.ID+1)
+	s.Require().NoError(err)
+	s.Assert().Nil(drill)
+}
+
+func (s *PositionRepositorySuite) TestUncheckedMaterialHung() {
+	ctx := context.Background()
+
+	// Positions stored by analysis already carry their hung material
+	ids, err := s.repo.InsertBatch(ctx, []models.Position{
+		{GameID: s.gameID, MoveNumber: 1, FEN: explorerStartFEN, MovePlayed: "f2f3", Classification: "mistake", CreatedAt: time.Now()},
+		{GameID: s.gameID, MoveNumber: 2, FEN: "fen2", MovePlayed: "e7e5", Classification: "good", CreatedAt: time.Now()},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(ids, 2)
+	positions, err := s.repo.UncheckedMaterialHung(ctx, 10)
+	s.Require().NoError(err)
+	s.Assert().Empty(positions)
+
+	// A position from before hung material was recorded is listed until it is set
+	_, err = s.db.ExecContext(ctx, `UPDATE positions SET material_hung_checked = 0 WHERE id = ?`, ids[0])
+	s.Require().NoError(err)
+	positions, err = s.repo.UncheckedMaterialHung(ctx, 10)
+	s.Require().NoError(err)
+	s.Require().Len(positions, 1)
+	s.Assert().Equal(ids[0], positions[0].ID)
+	s.Assert().Equal(explorerStartFEN, positions[0].FEN)
+	s.Assert().Equal("f2f3", positions[0].MovePlayed)
+	s.Assert().Equal("mistake", positions[0].Classification)
+
+	s.Require().NoError(s.repo.SetMaterialHung(ctx, ids[0], 3))
+	positions, err = s.repo.UncheckedMaterialHung(ctx, 10)
+	s.Require().NoError(err)
+	s.Assert().Empty(positions)
+
+	positions, err = s.repo.PositionsForGame(ctx, s.gameID)
+	s.Require().NoError(err)
+	s.Require().Len(positions, 2)
+	s.Assert().Equal(3, positions[0].MaterialHung)
+}
+
+func TestPositionRepositorySuite(t *testing.T) {
+	suite.Run(t, new(PositionRepositorySuite))
+}
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -20,21 +21,26 @@ func NewProfileRepository(db *sql.DB) repository.ProfileRepository {
 	return &profileRepository{db: db}
 }
 
-func (r *profileRepository) Upsert(ctx context.Context, username string) (*models.Profile, error) {
+func (r *profileRepository) Upsert(ctx context.Context, username, platform string) (*models.Profile, error) {
 	log := logger.FromContext(ctx).WithPrefix("profile_repo")
-	log.Debug("upserting profile for username: %s", username)
+	log.Debug("upserting profile for username: %s, platform=%s", username, platform)
 
 	var p models.Profile
+	var weights sql.NullString
 	err := r.db.QueryRowContext(ctx, `
-INSERT INTO profiles (username)
-VALUES (?)
-ON CONFLICT(username) DO UPDATE SET username = excluded.username
-RETURNING id, username, created_at, last_sync_at
-`, username).Scan(&p.ID, &p.Username, &p.CreatedAt, &p.LastSyncAt)
+INSERT INTO profiles (username, platform)
+VALUES (?, ?)
+ON CONFLICT(username, platform) DO UPDATE SET username = excluded.username
+RETURNING id, username, platform, scheduler, fsrs_weights, created_at, last_sync_at
+`, username, platform).Scan(&p.ID, &p.Username, &p.Platform, &p.Scheduler, &weights, &p.CreatedAt, &p.LastSyncAt)
 	if err != nil {
 		log.Error("failed to upsert profile: %v", err)
 		return nil, err
 	}
+	if p.FSRSWeights, err = decodeWeights(weights); err != nil {
+		log.Error("failed to decode fsrs weights: %v", err)
+		return nil, err
+	}
 	log.Debug("profile upserted: id=%d", p.ID)
 	return &p, nil
 }
@@ -50,12 +56,50 @@ func (r *profileRepository) UpdateSync(ctx context.Context, id int64, t time.Tim
 	return err
 }
 
+func (r *profileRepository) UpdateScheduler(ctx context.Context, id int64, scheduler string) error {
+	log := logger.FromContext(ctx).WithPrefix("profile_repo")
+	log.Debug("updating profile scheduler: profile_id=%d, scheduler=%s", id, scheduler)
+
+	_, err := r.db.ExecContext(ctx, `UPDATE profiles SET scheduler = ? WHERE id = ?`, scheduler, id)
+	if err != nil {
+		log.Error("failed to update profile scheduler: %v", err)
+	}
+	return err
+}
+
+func (r *profileRepository) UpdateFSRSWeights(ctx context.Context, id int64, weights []float64) error {
+	log := logger.FromContext(ctx).WithPrefix("profile_repo")
+	log.Debug("updating profile fsrs weights: profile_id=%d", id)
+
+	encoded, err := marshalNullJSON(weights, len(weights) == 0)
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE profiles SET fsrs_weights = ? WHERE id = ?`, encoded, id); err != nil {
+		log.Error("failed to update profile fsrs weights: %v", err)
+		return err
+	}
+	return nil
+}
+
+// decodeWeights parses the JSON-encoded fsrs_weights column; NULL means defaults.
+func decodeWeights(ns sql.NullString) ([]float64, error) {
+	if !ns.Valid || ns.String == "" {
+		return nil, nil
+	}
+	var weights []float64
+	if err := json.Unmarshal([]byte(ns.String), &weights); err != nil {
+		return nil, err
+	}
+	return weights, nil
+}
+
 func (r *profileRepository) List(ctx context.Context) ([]models.Profile, error) {
 	log := logger.FromContext(ctx).WithPrefix("profile_repo")
 	log.Debug("listing profiles")
 
 	rows, err := r.db.QueryContext(ctx, `
-SELECT id, username, created_at, last_sync_at
+SELECT id, username, platform, scheduler, fsrs_weights, created_at, last_sync_at
 FROM profiles
 ORDER BY created_at ASC
 `)
@@ -68,10 +112,15 @@ ORDER BY created_at ASC
 	var profiles []models.Profile
 	for rows.Next() {
 		var p models.Profile
-		if err := rows.Scan(&p.ID, &p.Username, &p.CreatedAt, &p.LastSyncAt); err != nil {
+		var weights sql.NullString
+		if err := rows.Scan(&p.ID, &p.Username, &p.Platform, &p.Scheduler, &weights, &p.CreatedAt, &p.LastSyncAt); err != nil {
 			log.Error("failed to scan profile row: %v", err)
 			return nil, err
 		}
+		if p.FSRSWeights, err = decodeWeights(weights); err != nil {
+			log.Error("failed to decode fsrs weights: %v", err)
+			return nil, err
+		}
 		profiles = append(profiles, p)
 	}
 
@@ -84,11 +133,12 @@ func (r *profileRepository) Get(ctx context.Context, id int64) (*models.Profile,
 	log.Debug("getting profile: id=%d", id)
 
 	var p models.Profile
+	var weights sql.NullString
 	err := r.db.QueryRowContext(ctx, `
-SELECT id, username, created_at, last_sync_at
+SELECT id, username, platform, scheduler, fsrs_weights, created_at, last_sync_at
 FROM profiles
 WHERE id = ?
-`, id).Scan(&p.ID, &p.Username, &p.CreatedAt, &p.LastSyncAt)
+`, id).Scan(&p.ID, &p.Username, &p.Platform, &p.Scheduler, &weights, &p.CreatedAt, &p.LastSyncAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		log.Debug("profile not found: id=%d", id)
 		return nil, nil
@@ -97,6 +147,10 @@ WHERE id = ?
 		log.Error("failed to get profile: %v", err)
 		return nil, err
 	}
+	if p.FSRSWeights, err = decodeWeights(weights); err != nil {
+		log.Error("failed to decode fsrs weights: %v", err)
+		return nil, err
+	}
 	return &p, nil
 }
 
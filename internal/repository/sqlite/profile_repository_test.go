@@ -0,0 +1,52 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+type ProfileRepositorySuite struct {
+	suite.Suite
+	db   *sql.DB
+	repo repository.ProfileRepository
+}
+
+func (s *ProfileRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewProfileRepository(s.db)
+}
+
+func (s *ProfileRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *ProfileRepositorySuite) TestUpsertKeyedByUsernameAndPlatform() {
+	ctx := context.Background()
+	chesscom, err := s.repo.Upsert(ctx, "magnus", "chesscom")
+	s.Require().NoError(err)
+	s.Equal("chesscom", chesscom.Platform)
+
+	again, err := s.repo.Upsert(ctx, "magnus", "chesscom")
+	s.Require().NoError(err)
+	s.Equal(chesscom.ID, again.ID)
+
+	// The same handle on another platform is another player
+	lichess, err := s.repo.Upsert(ctx, "magnus", "lichess")
+	s.Require().NoError(err)
+	s.NotEqual(chesscom.ID, lichess.ID)
+	s.Equal("lichess", lichess.Platform)
+
+	profiles, err := s.repo.List(ctx)
+	s.Require().NoError(err)
+	s.Len(profiles, 2)
+}
+
+func TestProfileRepositorySuite(t *testing.T) {
+	suite.Run(t, new(ProfileRepositorySuite))
+}
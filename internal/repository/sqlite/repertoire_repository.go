@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+type repertoireRepository struct {
+	db *sql.DB
+}
+
+// NewRepertoireRepository creates a new RepertoireRepository implementation
+func NewRepertoireRepository(db *sql.DB) repository.RepertoireRepository {
+	return &repertoireRepository{db: db}
+}
+
+// InsertMoves stores the moves, skipping ones already in the repertoire, and
+// returns how many were added.
+func (r *repertoireRepository) InsertMoves(ctx context.Context, profileID int64, color string, moves []models.RepertoireMove) (int, error) {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("inserting %d repertoire moves: profile_id=%d, color=%s", len(moves), profileID, color)
+
+	if len(moves) == 0 {
+		return 0, nil
+	}
+
+	added := 0
+	err := tx(ctx, r.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+INSERT OR IGNORE INTO repertoire_moves (profile_id, color, fen, move)
+VALUES (?, ?, ?, ?)
+`)
+		if err != nil {
+			log.Error("failed to prepare repertoire insert: %v", err)
+			return err
+		}
+		defer stmt.Close()
+
+		for _, m := range moves {
+			res, err := stmt.ExecContext(ctx, profileID, color, m.FEN, m.Move)
+			if err != nil {
+				log.Error("failed to insert repertoire move %s: %v", m.Move, err)
+				return err
+			}
+			if n, err := res.RowsAffected(); err == nil {
+				added += int(n)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Debug("inserted %d new repertoire moves", added)
+	return added, nil
+}
+
+func (r *repertoireRepository) ListMoves(ctx context.Context, profileID int64, color string) ([]models.RepertoireMove, error) {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("listing repertoire moves: profile_id=%d, color=%s", profileID, color)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, profile_id, color, fen, move, created_at
+FROM repertoire_moves
+WHERE profile_id = ? AND color = ?
+ORDER BY id
+`, profileID, color)
+	if err != nil {
+		log.Error("failed to query repertoire moves: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []models.RepertoireMove
+	for rows.Next() {
+		var m models.RepertoireMove
+		if err := rows.Scan(&m.ID, &m.ProfileID, &m.Color, &m.FEN, &m.Move, &m.CreatedAt); err != nil {
+			log.Error("failed to scan repertoire move: %v", err)
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+	log.Debug("found %d repertoire moves", len(moves))
+	return moves, rows.Err()
+}
+
+func (r *repertoireRepository) DeleteMoves(ctx context.Context, profileID int64, color string) error {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("deleting repertoire: profile_id=%d, color=%s", profileID, color)
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM repertoire_moves WHERE profile_id = ? AND color = ?`, profileID, color)
+	if err != nil {
+		log.Error("failed to delete repertoire moves: %v", err)
+	}
+	return err
+}
+
+func (r *repertoireRepository) Summary(ctx context.Context, profileID int64) ([]models.RepertoireSummary, error) {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("summarizing repertoire: profile_id=%d", profileID)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT color, COUNT(DISTINCT fen), COUNT(*)
+FROM repertoire_moves
+WHERE profile_id = ?
+GROUP BY color
+ORDER BY color DESC
+`, profileID)
+	if err != nil {
+		log.Error("failed to query repertoire summary: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []models.RepertoireSummary
+	for rows.Next() {
+		var s models.RepertoireSummary
+		if err := rows.Scan(&s.Color, &s.Positions, &s.Moves); err != nil {
+			log.Error("failed to scan repertoire summary: %v", err)
+			return nil, err
+		}
+		summary = append(summary, s)
+	}
+	return summary, rows.Err()
+}
+
+// SaveDeviation stores the game's deviation, replacing an earlier one.
+func (r *repertoireRepository) SaveDeviation(ctx context.Context, d models.RepertoireDeviation) error {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("saving repertoire deviation: game_id=%d, ply=%d, deviated_by=%s", d.GameID, d.Ply, d.DeviatedBy)
+
+	_, err := r.db.ExecContext(ctx, `
+INSERT OR REPLACE INTO repertoire_deviations (game_id, profile_id, ply, deviated_by, fen, move_played, expected_moves)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, d.GameID, d.ProfileID, d.Ply, d.DeviatedBy, d.FEN, d.MovePlayed, strings.Join(d.ExpectedMoves, " "))
+	if err != nil {
+		log.Error("failed to save repertoire deviation: %v", err)
+	}
+	return err
+}
+
+func (r *repertoireRepository) ListDeviations(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviation, error) {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("listing repertoire deviations: profile_id=%d, limit=%d", profileID, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT rd.game_id, rd.profile_id, rd.ply, rd.deviated_by, rd.fen, rd.move_played, rd.expected_moves, rd.created_at,
+    g.opponent, g.played_as, g.result, COALESCE(g.opening_name, ''), g.played_at
+FROM repertoire_deviations rd
+JOIN games g ON g.id = rd.game_id
+WHERE rd.profile_id = ?
+ORDER BY g.played_at DESC
+LIMIT ?
+`, profileID, limit)
+	if err != nil {
+		log.Error("failed to query repertoire deviations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deviations []models.RepertoireDeviation
+	for rows.Next() {
+		var d models.RepertoireDeviation
+		var expected string
+		if err := rows.Scan(&d.GameID, &d.ProfileID, &d.Ply, &d.DeviatedBy, &d.FEN, &d.MovePlayed, &expected, &d.CreatedAt,
+			&d.Opponent, &d.PlayedAs, &d.Result, &d.OpeningName, &d.PlayedAt); err != nil {
+			log.Error("failed to scan repertoire deviation: %v", err)
+			return nil, err
+		}
+		d.ExpectedMoves = strings.Fields(expected)
+		deviations = append(deviations, d)
+	}
+	log.Debug("found %d repertoire deviations", len(deviations))
+	return deviations, rows.Err()
+}
+
+// DeviationStats groups deviations by position and move, most frequent first.
+func (r *repertoireRepository) DeviationStats(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviationStat, error) {
+	log := logger.FromContext(ctx).WithPrefix("repertoire_repo")
+	log.Debug("fetching repertoire deviation stats: profile_id=%d, limit=%d", profileID, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT rd.deviated_by, rd.fen, rd.move_played, MAX(rd.expected_moves), MIN(rd.ply),
+    COUNT(*) AS games,
+    SUM(CASE WHEN g.result = 'loss' THEN 1 ELSE 0 END) AS losses
+FROM repertoire_deviations rd
+JOIN games g ON g.id = rd.game_id
+WHERE rd.profile_id = ?
+GROUP BY rd.deviated_by, rd.fen, rd.move_played
+ORDER BY games DESC, MAX(g.played_at) DESC
+LIMIT ?
+`, profileID, limit)
+	if err != nil {
+		log.Error("failed to query repertoire deviation stats: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.RepertoireDeviationStat
+	for rows.Next() {
+		var s models.RepertoireDeviationStat
+		var expected string
+		if err := rows.Scan(&s.DeviatedBy, &s.FEN, &s.MovePlayed, &expected, &s.Ply, &s.Games, &s.Losses); err != nil {
+			log.Error("failed to scan repertoire deviation stat: %v", err)
+			return nil, err
+		}
+		s.ExpectedMoves = strings.Fields(expected)
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
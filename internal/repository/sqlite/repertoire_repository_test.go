@@ -0,0 +1,144 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+const sicilianFEN = "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"
+
+type RepertoireRepositorySuite struct {
+	suite.Suite
+	db        *sql.DB
+	repo      repository.RepertoireRepository
+	profileID int64
+}
+
+func (s *RepertoireRepositorySuite) SetupTest() {
+	s.db = testutil.NewTestDB(s.T())
+	s.repo = sqlite.NewRepertoireRepository(s.db)
+
+	profile, err := sqlite.NewProfileRepository(s.db).Upsert(context.Background(), "testuser", "chesscom")
+	s.Require().NoError(err)
+	s.profileID = profile.ID
+}
+
+func (s *RepertoireRepositorySuite) TearDownTest() {
+	testutil.MustClose(s.T(), s.db)
+}
+
+func (s *RepertoireRepositorySuite) insertGame(chessComID, result string) int64 {
+	res, err := s.db.ExecContext(context.Background(), `
+		INSERT INTO games (profile_id, chess_com_id, pgn, time_class, result, played_as, opponent, played_at, analysis_status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.profileID, chessComID, "test pgn", "blitz", result, "white", "opponent1", time.Now(), "completed")
+	s.Require().NoError(err)
+	id, err := res.LastInsertId()
+	s.Require().NoError(err)
+	return id
+}
+
+func (s *RepertoireRepositorySuite) TestMoves() {
+	ctx := context.Background()
+	moves := []models.RepertoireMove{
+		{FEN: sicilianFEN, Move: "g1f3"},
+		{FEN: sicilianFEN, Move: "c2c3"},
+	}
+
+	added, err := s.repo.InsertMoves(ctx, s.profileID, "white", moves)
+	s.Require().NoError(err)
+	s.Assert().Equal(2, added)
+
+	// Importing the same lines again only adds what is new
+	added, err = s.repo.InsertMoves(ctx, s.profileID, "white", append(moves, models.RepertoireMove{FEN: sicilianFEN, Move: "b1c3"}))
+	s.Require().NoError(err)
+	s.Assert().Equal(1, added)
+
+	stored, err := s.repo.ListMoves(ctx, s.profileID, "white")
+	s.Require().NoError(err)
+	s.Require().Len(stored, 3)
+	s.Assert().Equal("g1f3", stored[0].Move)
+
+	black, err := s.repo.ListMoves(ctx, s.profileID, "black")
+	s.Require().NoError(err)
+	s.Assert().Empty(black)
+
+	summary, err := s.repo.Summary(ctx, s.profileID)
+	s.Require().NoError(err)
+	s.Require().Len(summary, 1)
+	s.Assert().Equal(models.RepertoireSummary{Color: "white", Positions: 1, Moves: 3}, summary[0])
+
+	s.Require().NoError(s.repo.DeleteMoves(ctx, s.profileID, "white"))
+	stored, err = s.repo.ListMoves(ctx, s.profileID, "white")
+	s.Require().NoError(err)
+	s.Assert().Empty(stored)
+}
+
+func (s *RepertoireRepositorySuite) TestDeviations() {
+	ctx := context.Background()
+	for i, result := range []string{"loss", "win"} {
+		gameID := s.insertGame([]string{"game1", "game2"}[i], result)
+		s.Require().NoError(s.repo.SaveDeviation(ctx, models.RepertoireDeviation{
+			GameID:        gameID,
+			ProfileID:     s.profileID,
+			Ply:           3,
+			DeviatedBy:    models.DeviatedByPlayer,
+			FEN:           sicilianFEN,
+			MovePlayed:    "b1c3",
+			ExpectedMoves: []string{"g1f3", "c2c3"},
+		}))
+	}
+
+	deviations, err := s.repo.ListDeviations(ctx, s.profileID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deviations, 2)
+	s.Assert().Equal([]string{"g1f3", "c2c3"}, deviations[0].ExpectedMoves)
+	s.Assert().Equal("opponent1", deviations[0].Opponent)
+
+	stats, err := s.repo.DeviationStats(ctx, s.profileID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(stats, 1)
+	s.Assert().Equal(2, stats[0].Games)
+	s.Assert().Equal(1, stats[0].Losses)
+	s.Assert().Equal(models.DeviatedByPlayer, stats[0].DeviatedBy)
+}
+
+func (s *RepertoireRepositorySuite) TestRepertoireFlashcardMoves() {
+	ctx := context.Background()
+	gameID := s.insertGame("game1", "loss")
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO positions (game_id, move_number, fen, move_played, best_move, eval_before, eval_after, eval_diff, classification)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, gameID, 3, sicilianFEN, "b1c3", "g1f3", 30.0, 20.0, -10.0, "good")
+	s.Require().NoError(err)
+	positionID, err := res.LastInsertId()
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.repo.SaveDeviation(ctx, models.RepertoireDeviation{
+		GameID: gameID, ProfileID: s.profileID, Ply: 3, DeviatedBy: models.DeviatedByPlayer,
+		FEN: sicilianFEN, MovePlayed: "b1c3", ExpectedMoves: []string{"c2c3"},
+	}))
+
+	flashcardRepo := sqlite.NewFlashcardRepository(s.db)
+	id, err := flashcardRepo.Insert(ctx, models.Flashcard{PositionID: positionID, DueAt: time.Now(), EaseFactor: 2.5, Kind: models.FlashcardKindRepertoire})
+	s.Require().NoError(err)
+
+	card, err := flashcardRepo.FlashcardWithPosition(ctx, id, s.profileID)
+	s.Require().NoError(err)
+	s.Require().NotNil(card)
+	s.Assert().Equal(models.FlashcardKindRepertoire, card.Kind)
+	s.Assert().Equal([]string{"c2c3"}, card.RepertoireMoves)
+}
+
+func TestRepertoireRepositorySuite(t *testing.T) {
+	suite.Run(t, new(RepertoireRepositorySuite))
+}
@@ -140,7 +140,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 LEFT JOIN (
@@ -200,7 +200,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 WHERE g.profile_id = ?`
@@ -268,7 +268,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 WHERE g.profile_id = ?`
@@ -313,6 +313,99 @@ ORDER BY year_month DESC
 	return stats, rows.Err()
 }
 
+// Player-side accuracy columns, picked by the color the profile owner played
+const (
+	playerAccuracyExpr   = "CASE WHEN g.played_as = 'black' THEN g.black_accuracy ELSE g.white_accuracy END"
+	playerACPLExpr       = "CASE WHEN g.played_as = 'black' THEN g.black_acpl ELSE g.white_acpl END"
+	opponentAccuracyExpr = "CASE WHEN g.played_as = 'black' THEN g.white_accuracy ELSE g.black_accuracy END"
+)
+
+func (r *statsRepository) AccuracyOverTime(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.AccuracyTrendStat, error) {
+	log := logger.FromContext(ctx).WithPrefix("stats_repo")
+	log.Debug("fetching accuracy over time: profile_id=%d, time_class=%s, date_cutoff=%v", profileID, timeClass, dateCutoff)
+
+	query := `
+SELECT strftime('%Y-%m', g.played_at) AS year_month,
+       COUNT(*) AS games,
+       ROUND(AVG(` + playerAccuracyExpr + `), 1) AS avg_accuracy,
+       ROUND(AVG(` + playerACPLExpr + `), 1) AS avg_acpl,
+       ROUND(AVG(` + opponentAccuracyExpr + `), 1) AS avg_opponent_accuracy
+FROM games g
+WHERE g.profile_id = ? AND g.white_accuracy IS NOT NULL`
+	args := []any{profileID}
+
+	if timeClass != "" {
+		query += " AND g.time_class = ?"
+		args = append(args, timeClass)
+	}
+	if dateCutoff != nil {
+		query += " AND g.played_at >= ?"
+		args = append(args, dateCutoff)
+	}
+	query += " GROUP BY year_month ORDER BY year_month DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Error("failed to query accuracy over time: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []models.AccuracyTrendStat
+	for rows.Next() {
+		var s models.AccuracyTrendStat
+		if err := rows.Scan(&s.YearMonth, &s.Games, &s.AvgAccuracy, &s.AvgACPL, &s.AvgOpponentAccuracy); err != nil {
+			log.Error("failed to scan accuracy trend row: %v", err)
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+func (r *statsRepository) AccuracyByOpening(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time, limit int) ([]models.OpeningAccuracyStat, error) {
+	log := logger.FromContext(ctx).WithPrefix("stats_repo")
+	log.Debug("fetching accuracy by opening: profile_id=%d, time_class=%s, date_cutoff=%v, limit=%d", profileID, timeClass, dateCutoff, limit)
+
+	query := `
+SELECT COALESCE(g.opening_name, '') AS opening_name,
+       COALESCE(MAX(g.eco_code), '') AS eco_code,
+       COUNT(*) AS games,
+       ROUND(AVG(` + playerAccuracyExpr + `), 1) AS avg_accuracy,
+       ROUND(AVG(` + playerACPLExpr + `), 1) AS avg_acpl
+FROM games g
+WHERE g.profile_id = ? AND g.white_accuracy IS NOT NULL
+  AND g.opening_name IS NOT NULL AND g.opening_name != ''`
+	args := []any{profileID}
+
+	if timeClass != "" {
+		query += " AND g.time_class = ?"
+		args = append(args, timeClass)
+	}
+	if dateCutoff != nil {
+		query += " AND g.played_at >= ?"
+		args = append(args, dateCutoff)
+	}
+	query += " GROUP BY g.opening_name ORDER BY games DESC, avg_accuracy ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Error("failed to query accuracy by opening: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []models.OpeningAccuracyStat
+	for rows.Next() {
+		var s models.OpeningAccuracyStat
+		if err := rows.Scan(&s.OpeningName, &s.ECOCode, &s.Games, &s.AvgAccuracy, &s.AvgACPL); err != nil {
+			log.Error("failed to scan opening accuracy row: %v", err)
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
 func (r *statsRepository) MistakePhaseStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MistakePhaseStat, error) {
 	log := logger.FromContext(ctx).WithPrefix("stats_repo")
 	log.Debug("fetching mistake phase stats: profile_id=%d, time_class=%s, date_cutoff=%v", profileID, timeClass, dateCutoff)
@@ -513,7 +606,7 @@ SELECT
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
 JOIN games g ON g.id = p.game_id
-WHERE g.profile_id = ?
+WHERE g.profile_id = ? AND f.retired_at IS NULL
 `, profileID).Scan(
 		&stat.TotalCards,
 		&stat.TotalReviews,
@@ -551,7 +644,7 @@ SELECT
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
 JOIN games g ON g.id = p.game_id
-WHERE g.profile_id = ?
+WHERE g.profile_id = ? AND f.retired_at IS NULL
 GROUP BY p.classification
 ORDER BY total_cards DESC
 `, profileID)
@@ -573,6 +666,48 @@ ORDER BY total_cards DESC
 	return stats, rows.Err()
 }
 
+func (r *statsRepository) FlashcardMotifStats(ctx context.Context, profileID int64) ([]models.FlashcardMotifStat, error) {
+	log := logger.FromContext(ctx).WithPrefix("stats_repo")
+	log.Debug("fetching flashcard motif stats: profile_id=%d", profileID)
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT
+    m.motif,
+    COUNT(DISTINCT f.id) AS total_cards,
+    COALESCE(SUM(f.times_reviewed), 0) AS total_reviews,
+    CASE
+        WHEN SUM(f.times_reviewed) > 0
+        THEN ROUND(100.0 * SUM(f.times_correct) / SUM(f.times_reviewed), 1)
+        ELSE 0
+    END AS avg_accuracy,
+    COALESCE(AVG(f.ease_factor), 0) AS avg_ease_factor,
+    COALESCE(AVG(f.times_reviewed), 0) AS avg_reviews_needed
+FROM flashcard_motifs m
+JOIN flashcards f ON f.id = m.flashcard_id
+JOIN positions p ON p.id = f.position_id
+JOIN games g ON g.id = p.game_id
+WHERE g.profile_id = ? AND f.retired_at IS NULL
+GROUP BY m.motif
+ORDER BY total_cards DESC, m.motif
+`, profileID)
+	if err != nil {
+		log.Error("failed to query motif stats: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.FlashcardMotifStat
+	for rows.Next() {
+		var s models.FlashcardMotifStat
+		if err := rows.Scan(&s.Motif, &s.TotalCards, &s.TotalReviews, &s.AvgAccuracy, &s.AvgEaseFactor, &s.AvgReviewsNeeded); err != nil {
+			log.Error("failed to scan motif stat: %v", err)
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
 func (r *statsRepository) FlashcardPhaseStats(ctx context.Context, profileID int64) ([]models.FlashcardPhaseStat, error) {
 	log := logger.FromContext(ctx).WithPrefix("stats_repo")
 	log.Debug("fetching flashcard phase stats: profile_id=%d", profileID)
@@ -595,7 +730,7 @@ SELECT
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
 JOIN games g ON g.id = p.game_id
-WHERE g.profile_id = ?
+WHERE g.profile_id = ? AND f.retired_at IS NULL
 GROUP BY phase
 ORDER BY phase
 `, profileID)
@@ -640,7 +775,7 @@ SELECT
 FROM flashcards f
 JOIN positions p ON p.id = f.position_id
 JOIN games g ON g.id = p.game_id
-WHERE g.profile_id = ? AND g.opening_name IS NOT NULL AND g.opening_name != ''
+WHERE g.profile_id = ? AND f.retired_at IS NULL AND g.opening_name IS NOT NULL AND g.opening_name != ''
 GROUP BY g.opening_name, g.eco_code
 ORDER BY total_cards DESC
 LIMIT ?
@@ -810,7 +945,7 @@ WHERE profile_id = ? AND player_rating IS NOT NULL`
 SELECT COALESCE(COUNT(*), 0)
 FROM positions p
 JOIN games g ON g.id = p.game_id
-WHERE g.profile_id = ? AND p.classification = 'blunder'`
+WHERE g.profile_id = ? AND p.classification IN ('blunder', 'missed_mate', 'allowed_mate')`
 		blunderArgs := []any{profileID}
 		if timeClass != "" {
 			blunderQuery += " AND g.time_class = ?"
@@ -923,7 +1058,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 WHERE g.profile_id = ? AND g.opening_name IS NOT NULL AND g.opening_name != ''
@@ -977,7 +1112,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 LEFT JOIN (
@@ -1011,7 +1146,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 WHERE g.profile_id = ?
@@ -1042,7 +1177,7 @@ FROM games g
 LEFT JOIN (
     SELECT game_id, COUNT(*) AS blunder_count
     FROM positions
-    WHERE classification = 'blunder'
+    WHERE classification IN ('blunder', 'missed_mate', 'allowed_mate')
     GROUP BY game_id
 ) b ON b.game_id = g.id
 WHERE g.profile_id = ?
@@ -16,10 +16,13 @@ type StatsRepository interface {
 	TimeClassStats(ctx context.Context, profileID int64, dateCutoff *time.Time) ([]models.TimeClassStat, error)
 	ColorStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.ColorStat, error)
 	MonthlyStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MonthlyStat, error)
+	AccuracyOverTime(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.AccuracyTrendStat, error)
+	AccuracyByOpening(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time, limit int) ([]models.OpeningAccuracyStat, error)
 	MistakePhaseStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MistakePhaseStat, error)
 	RatingStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.RatingStat, error)
 	FlashcardStats(ctx context.Context, profileID int64) (*models.FlashcardStat, error)
 	FlashcardClassificationStats(ctx context.Context, profileID int64) ([]models.FlashcardClassificationStat, error)
+	FlashcardMotifStats(ctx context.Context, profileID int64) ([]models.FlashcardMotifStat, error)
 	FlashcardPhaseStats(ctx context.Context, profileID int64) ([]models.FlashcardPhaseStat, error)
 	FlashcardOpeningStats(ctx context.Context, profileID int64, limit int) ([]models.FlashcardOpeningStat, error)
 	FlashcardTimeStats(ctx context.Context, profileID int64) (*models.FlashcardTimeStat, error)
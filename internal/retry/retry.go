@@ -0,0 +1,51 @@
+// Package retry retries work that failed with a retryable error, backing off
+// exponentially between attempts.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/vytor/chessflash/internal/errors"
+)
+
+// Backoff returns the delay before the given retry (1 for the first): base
+// doubled for every earlier retry, capped at limit. The delay is jittered over
+// its upper half so that work failing together does not retry together.
+func Backoff(retry int, base, limit time.Duration) time.Duration {
+	d := limit
+	if retry < 1 {
+		retry = 1
+	}
+	if retry < 32 && base<<(retry-1) > 0 && base<<(retry-1) < limit {
+		d = base << (retry - 1)
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Do calls fn up to attempts times for as long as it fails with a retryable
+// error, waiting Backoff between attempts, or longer when the error asked
+// for it. It returns the last error.
+func Do(ctx context.Context, attempts int, base, limit time.Duration, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil || !errors.IsRetryable(err) || attempt == attempts {
+			return err
+		}
+
+		wait := Backoff(attempt, base, limit)
+		if after := errors.RetryAfter(err); after > wait {
+			wait = after
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package retry_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/retry"
+)
+
+func TestBackoff(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		d := retry.Backoff(1, time.Second, time.Minute)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+
+		d = retry.Backoff(3, time.Second, time.Minute)
+		assert.GreaterOrEqual(t, d, 2*time.Second)
+		assert.LessOrEqual(t, d, 4*time.Second)
+
+		d = retry.Backoff(40, time.Second, time.Minute)
+		assert.GreaterOrEqual(t, d, 30*time.Second)
+		assert.LessOrEqual(t, d, time.Minute)
+	}
+}
+
+func TestDo_RetriesRetryableErrors(t *testing.T) {
+	calls := 0
+	err := retry.Do(context.Background(), 3, time.Millisecond, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return errors.NewRetryableError(errors.ReasonRateLimited, fmt.Errorf("status 429"))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDo_GivesUpAfterAttempts(t *testing.T) {
+	calls := 0
+	err := retry.Do(context.Background(), 2, time.Millisecond, time.Millisecond, func() error {
+		calls++
+		return errors.NewRetryableError(errors.ReasonUpstreamError, fmt.Errorf("status 503"))
+	})
+	assert.Equal(t, errors.ReasonUpstreamError, errors.FailureReason(err))
+	assert.Equal(t, 2, calls)
+}
+
+func TestDo_StopsOnPermanentError(t *testing.T) {
+	calls := 0
+	err := retry.Do(context.Background(), 5, time.Millisecond, time.Millisecond, func() error {
+		calls++
+		return errors.NewPermanentError(errors.ReasonUpstreamError, fmt.Errorf("status 404"))
+	})
+	assert.Error(t, err)
+	assert.False(t, errors.IsRetryable(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestDo_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := retry.Do(ctx, 5, time.Hour, time.Hour, func() error {
+		calls++
+		cancel()
+		return errors.NewRetryableError(errors.ReasonNetworkError, fmt.Errorf("connection reset"))
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
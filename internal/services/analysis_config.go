@@ -5,4 +5,6 @@ type AnalysisConfig struct {
 	StockfishPath   string
 	StockfishDepth  int
 	StockfishMaxTime int // milliseconds, 0 = no limit
+	StockfishMultiPV int // candidate lines per position, 0 or 1 = best line only
+	Classifier       string // analysis.ClassifierCentipawn (default) or analysis.ClassifierWinProbability
 }
@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	stderrors "errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,22 +16,31 @@ import (
 	"github.com/vytor/chessflash/internal/errors"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repertoire"
 	"github.com/vytor/chessflash/internal/repository"
 )
 
 // AnalysisService handles position analysis business logic
 type AnalysisService interface {
 	EvaluatePosition(ctx context.Context, fen string) (analysis.EvalResult, error)
-	AnalyzeGame(ctx context.Context, gameID int64) error
+	StreamEvaluation(ctx context.Context, fen string, depth int, onInfo func(analysis.SearchInfo)) (analysis.EvalResult, error)
+	AnalyzeGame(ctx context.Context, gameID int64, engine string) error
+	DeepenAnalysis(ctx context.Context, gameID int64, depth int, engine string) error
+	DetectMissingMotifs(ctx context.Context) (int, error)
+	DetectMissingMaterialHung(ctx context.Context) (int, error)
+	EvalCacheStats() analysis.EvalCacheStats
+	Engines() []string
 }
 
 type analysisService struct {
-	gameRepo      repository.GameRepository
-	positionRepo  repository.PositionRepository
-	flashcardRepo repository.FlashcardRepository
-	statsRepo     repository.StatsRepository
-	config        AnalysisConfig
-	pool          *analysis.EnginePool
+	gameRepo       repository.GameRepository
+	positionRepo   repository.PositionRepository
+	flashcardRepo  repository.FlashcardRepository
+	statsRepo      repository.StatsRepository
+	repertoireRepo repository.RepertoireRepository
+	config         AnalysisConfig
+	pool           *analysis.EnginePool
+	classifier     analysis.Classifier
 }
 
 // NewAnalysisService creates a new AnalysisService
@@ -36,16 +49,19 @@ func NewAnalysisService(
 	positionRepo repository.PositionRepository,
 	flashcardRepo repository.FlashcardRepository,
 	statsRepo repository.StatsRepository,
+	repertoireRepo repository.RepertoireRepository,
 	config AnalysisConfig,
 	pool *analysis.EnginePool,
 ) AnalysisService {
 	return &analysisService{
-		gameRepo:      gameRepo,
-		positionRepo:  positionRepo,
-		flashcardRepo: flashcardRepo,
-		statsRepo:     statsRepo,
-		config:        config,
-		pool:          pool,
+		gameRepo:       gameRepo,
+		positionRepo:   positionRepo,
+		flashcardRepo:  flashcardRepo,
+		statsRepo:      statsRepo,
+		repertoireRepo: repertoireRepo,
+		config:         config,
+		pool:           pool,
+		classifier:     analysis.NewClassifier(config.Classifier),
 	}
 }
 
@@ -53,8 +69,8 @@ func (s *analysisService) EvaluatePosition(ctx context.Context, fen string) (ana
 	log := logger.FromContext(ctx)
 	log.Debug("evaluating position: fen=%s", fen)
 
-	if fen == "" {
-		return analysis.EvalResult{}, errors.NewValidationError("fen", "cannot be empty")
+	if err := validateFEN(fen); err != nil {
+		return analysis.EvalResult{}, err
 	}
 
 	// Use a lighter depth for real-time evaluation (faster response)
@@ -82,18 +98,87 @@ func (s *analysisService) EvaluatePosition(ctx context.Context, fen string) (ana
 	return result, nil
 }
 
-func (s *analysisService) AnalyzeGame(ctx context.Context, gameID int64) error {
+// validateFEN rejects an empty or malformed FEN. The FEN is passed to the
+// engine as a UCI command, so anything but a valid position is rejected
+// before it gets there.
+func validateFEN(fen string) error {
+	if fen == "" {
+		return errors.NewValidationError("fen", "cannot be empty")
+	}
+	if _, err := chess.FEN(fen); err != nil {
+		return errors.NewValidationError("fen", "is not a valid FEN")
+	}
+	return nil
+}
+
+// maxStreamTimeMs bounds a streamed search so an abandoned client cannot hold
+// an engine for long.
+const maxStreamTimeMs = 10000
+
+// StreamEvaluation searches fen to depth (the analysis depth when 0), passing
+// each deeper result to onInfo. Cancelling ctx stops the search.
+func (s *analysisService) StreamEvaluation(ctx context.Context, fen string, depth int, onInfo func(analysis.SearchInfo)) (analysis.EvalResult, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("streaming evaluation: fen=%s, depth=%d", fen, depth)
+
+	if err := validateFEN(fen); err != nil {
+		return analysis.EvalResult{}, err
+	}
+	if depth < 0 || depth > 30 {
+		return analysis.EvalResult{}, errors.NewValidationError("depth", "must be between 1 and 30")
+	}
+	if depth == 0 {
+		depth = s.config.StockfishDepth
+	}
+	if depth <= 0 {
+		depth = 18
+	}
+
+	maxTimeMs := maxStreamTimeMs
+	if s.config.StockfishMaxTime > 0 && s.config.StockfishMaxTime < maxTimeMs {
+		maxTimeMs = s.config.StockfishMaxTime
+	}
+
+	result, err := s.pool.EvaluateStream(ctx, fen, depth, maxTimeMs, onInfo)
+	if err != nil {
+		if ctx.Err() != nil {
+			return analysis.EvalResult{}, ctx.Err()
+		}
+		log.Error("failed to stream evaluation: %v", err)
+		return analysis.EvalResult{}, errors.NewInternalError(err)
+	}
+	return result, nil
+}
+
+// EvalCacheStats reports the engine evaluation cache hit rate since startup.
+func (s *analysisService) EvalCacheStats() analysis.EvalCacheStats {
+	return s.pool.CacheStats()
+}
+
+// Engines lists the configured engine profiles, the default first.
+func (s *analysisService) Engines() []string {
+	names := []string{s.pool.DefaultEngine()}
+	for _, name := range s.pool.Engines() {
+		if name != names[0] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// AnalyzeGame analyzes every position of the game with the named engine
+// profile (the default when empty).
+func (s *analysisService) AnalyzeGame(ctx context.Context, gameID int64, engineName string) error {
 	log := logger.FromContext(ctx).WithField("game_id", gameID)
 	log.Info("starting game analysis")
 
 	game, err := s.gameRepo.Get(ctx, gameID)
+	if stderrors.Is(err, sql.ErrNoRows) || (err == nil && game == nil) {
+		return errors.NewNotFoundError("game", gameID)
+	}
 	if err != nil {
 		log.Error("failed to get game: %v", err)
-		return err
-	}
-
-	if game == nil {
-		return errors.NewNotFoundError("game", gameID)
+		return errors.NewRetryableError(errors.ReasonStorageError, err)
 	}
 
 	if game.AnalysisStatus == "completed" {
@@ -105,6 +190,7 @@ func (s *analysisService) AnalyzeGame(ctx context.Context, gameID int64) error {
 		"opponent":   game.Opponent,
 		"time_class": game.TimeClass,
 		"result":     game.Result,
+		"engine":     engineName,
 	})
 
 	// Parse PGN early to determine expected move count for position validation
@@ -114,25 +200,49 @@ func (s *analysisService) AnalyzeGame(ctx context.Context, gameID int64) error {
 	}
 
 	moves := chessGame.Moves()
-	expectedPositionCount := len(moves)
 
-	// Check if positions already exist (analysis may have completed but status wasn't updated)
-	if shouldSkip, err := s.checkAndHandleExistingPositions(ctx, gameID, expectedPositionCount, log); err != nil {
-		log.Warn("failed to check existing positions: %v, continuing with analysis", err)
-	} else if shouldSkip {
+	// Positions are saved as soon as they are analyzed, so an interrupted
+	// analysis continues from the plies it already stored
+	analyzed, err := s.analyzedPlies(ctx, gameID)
+	if err != nil {
+		log.Error("failed to get existing positions: %v", err)
+		return errors.NewRetryableError(errors.ReasonStorageError, err)
+	}
+	if len(analyzed) >= len(moves) {
+		// The analysis stopped after storing the last ply but before it was
+		// finished, so only the finishing steps are left
+		log.Info("game already has all %d positions, finishing analysis", len(moves))
+		result := &analysisResult{}
+		for _, ply := range slices.Sorted(maps.Keys(analyzed)) {
+			result.add(analyzed[ply])
+		}
+		s.addStoredFlashcards(ctx, gameID, result, analyzed, log)
+		result.deviation = s.findRepertoireDeviation(ctx, game, result.positions, log)
+		result.flashcardsCreated = s.saveRepertoireDeviation(ctx, result, log)
+		s.finalizeAnalysis(ctx, gameID, game.ProfileID, len(moves), result, log)
 		return nil
 	}
+	if len(analyzed) > 0 {
+		log.Info("resuming analysis: %d of %d moves already analyzed", len(analyzed), len(moves))
+	}
 
 	if err := s.gameRepo.UpdateStatus(ctx, gameID, "processing"); err != nil {
 		log.Error("failed to update game status: %v", err)
-		return err
+		return errors.NewRetryableError(errors.ReasonStorageError, err)
 	}
 
-	engine, depth, maxTimeMs, err := s.acquireEngineAndConfig(ctx, gameID, log)
+	engine, depth, maxTimeMs, err := s.acquireEngineAndConfig(ctx, engineName, log)
 	if err != nil {
+		if ctx.Err() != nil {
+			s.markIncomplete(context.WithoutCancel(ctx), gameID, len(analyzed), nil, log)
+			return err
+		}
+		err = s.engineError(engineName, err)
+		s.markIncomplete(ctx, gameID, len(analyzed), err, log)
 		return err
 	}
-	defer s.pool.Release(engine)
+	// The analysis replaces an engine that dies part way through
+	defer func() { s.pool.Release(engine) }()
 
 	s.detectOpeningIfMissing(ctx, game, chessGame, log)
 
@@ -143,46 +253,382 @@ func (s *analysisService) AnalyzeGame(ctx context.Context, gameID int64) error {
 		log.Warn("unexpected positions length: got %d positions for %d moves", len(positions), len(moves))
 	}
 
-	analysisResult := s.analyzePositions(ctx, engine, positions, moves, game, depth, maxTimeMs, log)
+	flashcardsCreated := 0
+	save := func(p *models.Position, flashcard bool) error {
+		id, err := s.positionRepo.Insert(ctx, *p)
+		if err != nil {
+			return err
+		}
+		p.ID = id
+		if flashcard {
+			card := engineFlashcard(*p)
+			if _, err := s.flashcardRepo.Insert(ctx, card); err != nil {
+				log.Warn("failed to insert flashcard for position %d: %v", id, err)
+			} else {
+				flashcardsCreated++
+				log.Debug("flashcard created: %+v", card)
+			}
+		}
+		return nil
+	}
+
+	analysisResult := s.analyzePositions(ctx, engine, positions, moves, game, depth, maxTimeMs, analyzed, save, log)
+	engine = analysisResult.engine
+	analysisResult.flashcardsCreated = flashcardsCreated
+
+	// What was analyzed is already stored; record how far the analysis got
+	// even when it was cancelled
+	cancelErr := ctx.Err()
+	ctx = context.WithoutCancel(ctx)
 
-	if err := s.saveAnalysisResults(ctx, gameID, analysisResult, log); err != nil {
+	if analysisResult.err != nil {
+		log.Error("failed to save position: %v", analysisResult.err)
+		err := errors.NewRetryableError(errors.ReasonStorageError, analysisResult.err)
+		s.markIncomplete(ctx, gameID, len(analysisResult.positions), err, log)
+		return err
+	}
+	if len(analysisResult.positions) < len(moves) {
+		log.Warn("analysis stopped after %d of %d moves", len(analysisResult.positions), len(moves))
+		if cancelErr != nil {
+			s.markIncomplete(ctx, gameID, len(analysisResult.positions), nil, log)
+			return cancelErr
+		}
+		// Moves the engine failed on are left out, so they are searched
+		// again when the analysis is retried
+		err := errors.NewRetryableError(errors.ReasonEngineError,
+			fmt.Errorf("engine failed on %d of %d moves", len(moves)-len(analysisResult.positions), len(moves)))
+		s.markIncomplete(ctx, gameID, len(analysisResult.positions), err, log)
 		return err
 	}
 
+	s.addStoredFlashcards(ctx, gameID, analysisResult, analyzed, log)
+	analysisResult.deviation = s.findRepertoireDeviation(ctx, game, analysisResult.positions, log)
+	analysisResult.flashcardsCreated += s.saveRepertoireDeviation(ctx, analysisResult, log)
+
 	s.finalizeAnalysis(ctx, gameID, game.ProfileID, len(moves), analysisResult, log)
 	return nil
 }
 
-// checkAndHandleExistingPositions checks if positions already exist for a game.
-// Returns true if analysis should be skipped (positions exist and game marked as completed),
-// false if analysis should proceed, and an error if the check failed.
-func (s *analysisService) checkAndHandleExistingPositions(ctx context.Context, gameID int64, expectedPositionCount int, log *logger.Logger) (bool, error) {
-	existingPositions, err := s.positionRepo.PositionsForGame(ctx, gameID)
+// analyzedPlies returns the game's stored positions keyed by ply.
+func (s *analysisService) analyzedPlies(ctx context.Context, gameID int64) (map[int]models.Position, error) {
+	positions, err := s.positionRepo.PositionsForGame(ctx, gameID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	byPly := make(map[int]models.Position, len(positions))
+	for _, p := range positions {
+		byPly[p.MoveNumber] = p
+	}
+	return byPly, nil
+}
 
-	if len(existingPositions) == 0 {
-		return false, nil
+// addStoredFlashcards records which positions kept from an earlier run
+// already have a flashcard, so finishing the analysis does not try to create
+// a second one for them.
+func (s *analysisService) addStoredFlashcards(ctx context.Context, gameID int64, result *analysisResult, analyzed map[int]models.Position, log *logger.Logger) {
+	if len(analyzed) == 0 {
+		return
 	}
+	cards, err := s.flashcardRepo.ListForGame(ctx, gameID)
+	if err != nil {
+		log.Warn("failed to list flashcards of stored positions: %v", err)
+		return
+	}
+	hasCard := make(map[int64]bool, len(cards))
+	for _, c := range cards {
+		hasCard[c.PositionID] = true
+	}
+	for i, p := range result.positions {
+		if _, stored := analyzed[p.MoveNumber]; stored && hasCard[p.ID] {
+			result.flashcardIndices = append(result.flashcardIndices, i)
+		}
+	}
+	slices.Sort(result.flashcardIndices)
+}
 
-	if len(existingPositions) == expectedPositionCount {
-		log.Info("game already has %d positions (matches expected %d moves), marking as completed", len(existingPositions), expectedPositionCount)
-		if err := s.gameRepo.UpdateStatus(ctx, gameID, "completed"); err != nil {
-			log.Error("failed to update game status to completed: %v", err)
-			return false, err
+// markIncomplete flags a game whose analysis stopped part way with stored
+// positions, so that it is queued again and resumed instead of passing for
+// analyzed. cause records why the analysis failed; without it the analysis
+// was interrupted, and a game with nothing stored goes back to pending
+// rather than failed.
+func (s *analysisService) markIncomplete(ctx context.Context, gameID int64, stored int, cause error, log *logger.Logger) {
+	status := "incomplete"
+	if stored == 0 {
+		status = "failed"
+		if cause == nil {
+			status = "pending"
 		}
-		return true, nil
 	}
 
-	// Positions exist but count doesn't match - mark as completed to prevent re-queuing
-	// Note: Re-analyzing would fail due to unique constraint on (game_id, move_number)
-	log.Warn("game has %d positions but expected %d moves - analysis may be incomplete, marking as completed anyway", len(existingPositions), expectedPositionCount)
-	if err := s.gameRepo.UpdateStatus(ctx, gameID, "completed"); err != nil {
-		log.Error("failed to update game status to completed: %v", err)
-		return false, err
+	var err error
+	if cause != nil {
+		err = s.gameRepo.UpdateFailure(ctx, gameID, status, errors.FailureReason(cause))
+	} else {
+		err = s.gameRepo.UpdateStatus(ctx, gameID, status)
+	}
+	if err != nil {
+		log.Error("failed to update game status to %s: %v", status, err)
+	}
+}
+
+// DeepenAnalysis re-evaluates an analyzed game at a higher depth and updates
+// its positions in place. Flashcards follow the new verdicts: cards are added
+// for moves that are now mistakes, and engine cards for moves that turned out
+// fine are retired (keeping their review history) or restored if they come
+// back. Games already searched at depth or deeper are left alone.
+func (s *analysisService) DeepenAnalysis(ctx context.Context, gameID int64, depth int, engineName string) error {
+	log := logger.FromContext(ctx).WithFields(map[string]any{
+		"game_id": gameID,
+		"depth":   depth,
+		"engine":  engineName,
+	})
+	log.Info("deepening game analysis")
+
+	if depth < 1 || depth > 30 {
+		return errors.NewValidationError("depth", "must be between 1 and 30")
+	}
+
+	game, err := s.gameRepo.Get(ctx, gameID)
+	if err != nil {
+		log.Error("failed to get game: %v", err)
+		return err
+	}
+	if game == nil {
+		return errors.NewNotFoundError("game", gameID)
+	}
+
+	existing, err := s.positionRepo.PositionsForGame(ctx, gameID)
+	if err != nil {
+		log.Error("failed to get positions: %v", err)
+		return err
+	}
+	if game.AnalysisStatus != "completed" || len(existing) == 0 {
+		return errors.NewValidationError("game", "must be analyzed before it can be deepened")
+	}
+
+	byPly := make(map[int]models.Position, len(existing))
+	shallow := 0
+	for _, p := range existing {
+		byPly[p.MoveNumber] = p
+		if p.Depth < depth {
+			shallow++
+		}
+	}
+	if shallow == 0 {
+		log.Debug("all positions already searched at depth %d or deeper, skipping", depth)
+		return nil
+	}
+
+	pgnOpt, err := chess.PGN(strings.NewReader(game.PGN))
+	if err != nil {
+		log.Error("failed to parse PGN: %v", err)
+		return err
+	}
+	chessGame := chess.NewGame(pgnOpt)
+
+	engine, err := s.pool.AcquireEngine(ctx, engineName)
+	if err != nil {
+		log.Error("failed to acquire engine from pool: %v", err)
+		return s.engineError(engineName, err)
+	}
+	defer func() { s.pool.Release(engine) }()
+	_, maxTimeMs := s.searchLimits(engineName)
+
+	// The whole game is searched again so each evaluation can build on the
+	// previous one, as in a first analysis
+	result := s.analyzePositions(ctx, engine, chessGame.Positions(), chessGame.Moves(), game, depth, maxTimeMs, nil, nil, log)
+	engine = result.engine
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	updated := make([]models.Position, 0, len(result.positions))
+	wanted := make(map[int64]bool, len(result.flashcardIndices))
+	for i, p := range result.positions {
+		old, ok := byPly[p.MoveNumber]
+		if !ok {
+			continue
+		}
+		p.ID = old.ID
+		updated = append(updated, p)
+		if slices.Contains(result.flashcardIndices, i) {
+			wanted[p.ID] = true
+		}
+	}
+	if err := s.positionRepo.UpdateAnalysis(ctx, updated); err != nil {
+		log.Error("failed to update positions: %v", err)
+		return err
+	}
+
+	// Positions the engine failed on keep their previous verdicts
+	deepened := make(map[int64]models.Position, len(updated))
+	for _, p := range updated {
+		deepened[p.ID] = p
+	}
+	result.positions = existing
+	for i, p := range existing {
+		if d, ok := deepened[p.ID]; ok {
+			result.positions[i] = d
+		}
+	}
+
+	created, retired, restored := s.syncFlashcards(ctx, gameID, updated, wanted, log)
+	result.flashcardsCreated = created
+	log.Info("deepened %d positions (%d were shallower): %d flashcards created, %d retired, %d restored",
+		len(updated), shallow, created, retired, restored)
+
+	s.finalizeAnalysis(ctx, gameID, game.ProfileID, len(chessGame.Moves()), result, log)
+	return nil
+}
+
+// syncFlashcards makes the game's engine flashcards match the re-analyzed
+// verdicts in wanted (keyed by position ID). Repertoire cards are untouched.
+// Returns how many cards were created, retired and restored.
+func (s *analysisService) syncFlashcards(
+	ctx context.Context,
+	gameID int64,
+	positions []models.Position,
+	wanted map[int64]bool,
+	log *logger.Logger,
+) (created, retired, restored int) {
+	cards, err := s.flashcardRepo.ListForGame(ctx, gameID)
+	if err != nil {
+		log.Warn("failed to list flashcards: %v", err)
+		return 0, 0, 0
+	}
+	byPosition := make(map[int64]models.Flashcard, len(cards))
+	for _, c := range cards {
+		byPosition[c.PositionID] = c
 	}
-	return true, nil
+
+	for _, p := range positions {
+		card, exists := byPosition[p.ID]
+		switch {
+		case wanted[p.ID] && !exists:
+			if _, err := s.flashcardRepo.Insert(ctx, engineFlashcard(p)); err != nil {
+				log.Warn("failed to insert flashcard for position %d: %v", p.ID, err)
+				continue
+			}
+			created++
+		case wanted[p.ID] && card.RetiredAt != nil:
+			if err := s.flashcardRepo.SetRetired(ctx, card.ID, false); err != nil {
+				log.Warn("failed to restore flashcard %d: %v", card.ID, err)
+				continue
+			}
+			restored++
+		case !wanted[p.ID] && exists && card.RetiredAt == nil && card.Kind == models.FlashcardKindEngine:
+			if err := s.flashcardRepo.SetRetired(ctx, card.ID, true); err != nil {
+				log.Warn("failed to retire flashcard %d: %v", card.ID, err)
+				continue
+			}
+			retired++
+		}
+		// The deeper line may change the tactic behind a card
+		if wanted[p.ID] && exists && card.Kind == models.FlashcardKindEngine {
+			if err := s.flashcardRepo.SetMotifs(ctx, card.ID, analysis.DetectMotifs(p.FEN, p.BestMove, p.PV)); err != nil {
+				log.Warn("failed to update motifs of flashcard %d: %v", card.ID, err)
+			}
+		}
+	}
+	return created, retired, restored
+}
+
+// engineFlashcard returns a new flashcard for a position the engine flagged,
+// tagged with the tactical motifs of its best move.
+func engineFlashcard(p models.Position) models.Flashcard {
+	return models.Flashcard{
+		PositionID: p.ID,
+		DueAt:      time.Now(),
+		EaseFactor: 2.5,
+		Motifs:     analysis.DetectMotifs(p.FEN, p.BestMove, p.PV),
+	}
+}
+
+// motifBatch is how many flashcards DetectMissingMotifs tags per query
+const motifBatch = 200
+
+// DetectMissingMotifs tags engine flashcards created before motif detection
+// with the tactical motifs of their position and returns how many it tagged.
+func (s *analysisService) DetectMissingMotifs(ctx context.Context) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("detecting motifs of untagged flashcards")
+
+	tagged := 0
+	for {
+		cards, err := s.flashcardRepo.UndetectedMotifCards(ctx, motifBatch)
+		if err != nil {
+			log.Error("failed to list flashcards without motifs: %v", err)
+			return tagged, errors.NewInternalError(err)
+		}
+
+		for _, c := range cards {
+			if err := s.flashcardRepo.SetMotifs(ctx, c.ID, analysis.DetectMotifs(c.FEN, c.BestMove, c.PV)); err != nil {
+				log.Error("failed to set motifs of flashcard %d: %v", c.ID, err)
+				return tagged, errors.NewInternalError(err)
+			}
+			tagged++
+		}
+
+		if len(cards) < motifBatch {
+			break
+		}
+	}
+
+	if tagged > 0 {
+		log.Info("detected motifs of %d flashcards", tagged)
+	}
+	return tagged, nil
+}
+
+// materialHungBy returns the pawns a move classified as classification left
+// en prise. Leaving material en prise is told apart from deeper tactical
+// errors by a static exchange evaluation; sound sacrifices are not errors.
+func materialHungBy(classification, fen, moveUCI string) int {
+	switch classification {
+	case "inaccuracy", "mistake", "blunder", "allowed_mate":
+		return analysis.MaterialHung(fen, moveUCI)
+	}
+	return 0
+}
+
+// materialHungBatch is how many positions DetectMissingMaterialHung checks per query
+const materialHungBatch = 200
+
+// DetectMissingMaterialHung computes the hung material of positions analyzed
+// before it was recorded and returns how many it checked.
+func (s *analysisService) DetectMissingMaterialHung(ctx context.Context) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("detecting hung material of unchecked positions")
+
+	checked, hung := 0, 0
+	for {
+		positions, err := s.positionRepo.UncheckedMaterialHung(ctx, materialHungBatch)
+		if err != nil {
+			log.Error("failed to list positions without checked hung material: %v", err)
+			return checked, errors.NewInternalError(err)
+		}
+
+		for _, p := range positions {
+			pawns := materialHungBy(p.Classification, p.FEN, p.MovePlayed)
+			if err := s.positionRepo.SetMaterialHung(ctx, p.ID, pawns); err != nil {
+				log.Error("failed to set hung material of position %d: %v", p.ID, err)
+				return checked, errors.NewInternalError(err)
+			}
+			checked++
+			if pawns > 0 {
+				hung++
+			}
+		}
+
+		if len(positions) < materialHungBatch {
+			break
+		}
+	}
+
+	if checked > 0 {
+		log.Info("checked hung material of %d positions, %d hung material", checked, hung)
+	}
+	return checked, nil
 }
 
 // analysisResult holds the results of analyzing a game
@@ -191,25 +637,40 @@ type analysisResult struct {
 	flashcardIndices  []int
 	blunders          int
 	mistakes          int
+	misses            int
 	inaccuracies      int
 	flashcardsCreated int
+	deviation         *models.RepertoireDeviation // first ply out of the player's repertoire
+	err               error                       // why saving a position failed, stopping the analysis
+	engine            *analysis.Engine            // engine to release, nil when no replacement could be acquired
+}
+
+// add records an analyzed position and counts its classification
+func (r *analysisResult) add(p models.Position) {
+	r.positions = append(r.positions, p)
+	switch p.Classification {
+	case "blunder", "missed_mate", "allowed_mate":
+		r.blunders++
+	case "mistake":
+		r.mistakes++
+	case "miss":
+		r.misses++
+	case "inaccuracy":
+		r.inaccuracies++
+	}
 }
 
-// acquireEngineAndConfig acquires an engine and returns configuration
-func (s *analysisService) acquireEngineAndConfig(ctx context.Context, gameID int64, log *logger.Logger) (*analysis.Engine, int, int, error) {
-	log.Debug("acquiring stockfish engine from pool")
-	engine, err := s.pool.Acquire(ctx)
+// acquireEngineAndConfig acquires an engine of the named profile and returns
+// its search limits, falling back to the Stockfish settings
+func (s *analysisService) acquireEngineAndConfig(ctx context.Context, name string, log *logger.Logger) (*analysis.Engine, int, int, error) {
+	log.Debug("acquiring engine from pool: engine=%s", name)
+	engine, err := s.pool.AcquireEngine(ctx, name)
 	if err != nil {
 		log.Error("failed to acquire engine from pool: %v", err)
-		_ = s.gameRepo.UpdateStatus(ctx, gameID, "failed")
 		return nil, 0, 0, err
 	}
 
-	depth := s.config.StockfishDepth
-	if depth <= 0 {
-		depth = 18
-	}
-	maxTimeMs := s.config.StockfishMaxTime
+	depth, maxTimeMs := s.searchLimits(name)
 	log = log.WithFields(map[string]any{
 		"depth":       depth,
 		"max_time_ms": maxTimeMs,
@@ -218,13 +679,43 @@ func (s *analysisService) acquireEngineAndConfig(ctx context.Context, gameID int
 	return engine, depth, maxTimeMs, nil
 }
 
+// engineError types a failure to acquire an engine: an unknown engine profile
+// will not appear by retrying, while a busy or crashed engine may recover.
+func (s *analysisService) engineError(name string, err error) error {
+	if _, ok := s.pool.Config(name); !ok {
+		return errors.NewPermanentError(errors.ReasonEngineUnavailable, err)
+	}
+	return errors.NewRetryableError(errors.ReasonEngineUnavailable, err)
+}
+
+// searchLimits returns the depth and time limit of the named engine profile,
+// falling back to the Stockfish settings
+func (s *analysisService) searchLimits(name string) (int, int) {
+	profile, _ := s.pool.Config(name)
+	depth := profile.Depth
+	if depth <= 0 {
+		depth = s.config.StockfishDepth
+	}
+	if depth <= 0 {
+		depth = 18
+	}
+	maxTimeMs := profile.MaxTimeMs
+	if maxTimeMs <= 0 {
+		maxTimeMs = s.config.StockfishMaxTime
+	}
+	return depth, maxTimeMs
+}
+
 // parseGamePGN parses the game PGN and creates a chess game
 func (s *analysisService) parseGamePGN(ctx context.Context, game *models.Game, log *logger.Logger, gameID int64) (*chess.Game, error) {
 	log.Debug("parsing PGN")
 	pgnOpt, err := chess.PGN(strings.NewReader(game.PGN))
 	if err != nil {
 		log.Error("failed to parse PGN: %v", err)
-		_ = s.gameRepo.UpdateStatus(ctx, gameID, "failed")
+		err = errors.NewPermanentError(errors.ReasonInvalidPGN, err)
+		if updateErr := s.gameRepo.UpdateFailure(ctx, gameID, "failed", errors.ReasonInvalidPGN); updateErr != nil {
+			log.Error("failed to update game status to failed: %v", updateErr)
+		}
 		return nil, err
 	}
 	return chess.NewGame(pgnOpt), nil
@@ -247,7 +738,12 @@ func (s *analysisService) detectOpeningIfMissing(ctx context.Context, game *mode
 	}
 }
 
-// analyzePositions analyzes all positions in the game
+// analyzePositions analyzes the game's moves in order. Plies found in analyzed
+// were stored by an earlier run and are kept as they are. When save is set,
+// each new position is passed to it as soon as it is analyzed, along with
+// whether it deserves a flashcard; the analysis stops at the first save error.
+// An engine that dies on a move is swapped for a fresh one from the pool, and
+// the analysis stops when none can be had.
 func (s *analysisService) analyzePositions(
 	ctx context.Context,
 	engine *analysis.Engine,
@@ -255,15 +751,20 @@ func (s *analysisService) analyzePositions(
 	moves []*chess.Move,
 	game *models.Game,
 	depth, maxTimeMs int,
+	analyzed map[int]models.Position,
+	save func(p *models.Position, flashcard bool) error,
 	log *logger.Logger,
 ) *analysisResult {
 	userIsWhite := game.PlayedAs == "white"
 	result := &analysisResult{
 		positions:        make([]models.Position, 0, len(moves)),
 		flashcardIndices: make([]int, 0),
+		engine:           engine,
 	}
 
 	var prevEval *analysis.EvalResult
+	var prevMoveEval *analysis.EvalResult // evaluation before the opponent's last move
+	retried := false                      // the current move already failed once
 
 	for i := 0; i < len(moves); i++ {
 		if i >= len(positions)-1 {
@@ -275,29 +776,55 @@ func (s *analysisService) analyzePositions(
 			break
 		}
 
-		isWhiteMove := i%2 == 0
+		if stored, ok := analyzed[i+1]; ok {
+			result.add(stored)
+			// The stored evaluation lacks the next position's best line, so
+			// the next move is searched from scratch
+			prevEval = nil
+			prevMoveEval = storedEvalBefore(stored)
+			continue
+		}
+
 		posBefore := positions[i]
+		isWhiteMove := posBefore.Turn() == chess.White
 		posAfter := positions[i+1]
 
-		position, _, evalAfter, shouldCreateFlashcard := s.analyzePosition(
+		position, evalBefore, evalAfter, shouldCreateFlashcard := s.analyzePosition(
 			ctx, engine, posBefore, posAfter, moves[i], i+1,
-			isWhiteMove, userIsWhite, prevEval, depth, maxTimeMs, game.ID, log,
+			isWhiteMove, userIsWhite, prevEval, prevMoveEval, depth, maxTimeMs, game.ID, log,
 		)
+		if position == nil && ctx.Err() == nil && !engine.Healthy() {
+			log.Warn("engine failed on move %d, replacing it", i+1)
+			name := engine.Name()
+			s.pool.Release(engine)
+			replacement, err := s.pool.AcquireEngine(ctx, name)
+			result.engine = replacement
+			if err != nil {
+				log.Error("failed to acquire replacement engine: %v", err)
+				break
+			}
+			engine = replacement
+			if !retried {
+				// The move is searched once more on the new engine
+				retried = true
+				i--
+				continue
+			}
+		}
+		retried = false
+		prevMoveEval = evalBefore
 
 		if position != nil {
-			result.positions = append(result.positions, *position)
+			if save != nil {
+				if err := save(position, shouldCreateFlashcard); err != nil {
+					result.err = err
+					break
+				}
+			}
+			result.add(*position)
 			if shouldCreateFlashcard {
 				result.flashcardIndices = append(result.flashcardIndices, len(result.positions)-1)
 			}
-
-			switch position.Classification {
-			case "blunder":
-				result.blunders++
-			case "mistake":
-				result.mistakes++
-			case "inaccuracy":
-				result.inaccuracies++
-			}
 		}
 
 		if evalAfter != nil {
@@ -316,7 +843,7 @@ func (s *analysisService) analyzePosition(
 	move *chess.Move,
 	moveNumber int,
 	isWhiteMove, userIsWhite bool,
-	prevEval *analysis.EvalResult,
+	prevEval, prevMoveEval *analysis.EvalResult,
 	depth, maxTimeMs int,
 	gameID int64,
 	log *logger.Logger,
@@ -333,7 +860,7 @@ func (s *analysisService) analyzePosition(
 		evalBefore = *prevEval
 	} else {
 		var err error
-		evalBefore, err = engine.EvaluateFEN(ctx, fenBefore, depth, maxTimeMs)
+		evalBefore, err = s.pool.EvaluateWith(ctx, engine, fenBefore, depth, maxTimeMs, s.config.StockfishMultiPV)
 		if err != nil {
 			log.Warn("eval before move %d failed: %v", moveNumber, err)
 			return nil, nil, nil, false
@@ -341,7 +868,7 @@ func (s *analysisService) analyzePosition(
 	}
 
 	// Get evaluation after move
-	evalAfter, err := engine.EvaluateFEN(ctx, fenAfter, depth, maxTimeMs)
+	evalAfter, err := s.pool.EvaluateWith(ctx, engine, fenAfter, depth, maxTimeMs, s.config.StockfishMultiPV)
 	if err != nil {
 		log.Warn("eval after move %d failed: %v", moveNumber, err)
 		return nil, nil, nil, false
@@ -354,9 +881,11 @@ func (s *analysisService) analyzePosition(
 	movePlayedUCI := analysis.MoveToUCI(move)
 	bestMoveUCI := evalBefore.BestMove
 
-	classification := analysis.ClassifyMove(evalBeforeCP, evalAfterCP, isWhiteMove, movePlayedUCI, bestMoveUCI)
+	classification := s.classifier.Classify(analysis.NewMoveInput(fenBefore, movePlayedUCI, isWhiteMove, evalBefore, evalAfter, prevMoveEval))
 	log.Debug("classification: %s (movePlayed: %s, bestMove: %s)", classification, movePlayedUCI, bestMoveUCI)
 
+	materialHung := materialHungBy(classification, fenBefore, movePlayedUCI)
+
 	position := &models.Position{
 		GameID:         gameID,
 		MoveNumber:     moveNumber,
@@ -369,6 +898,10 @@ func (s *analysisService) analyzePosition(
 		MateBefore:     mateBefore,
 		MateAfter:      mateAfter,
 		Classification: classification,
+		PV:             evalBefore.PV,
+		Lines:          positionLines(evalBefore.Lines),
+		Depth:          depth,
+		MaterialHung:   materialHung,
 		CreatedAt:      time.Now(),
 	}
 
@@ -387,6 +920,44 @@ func (s *analysisService) analyzePosition(
 	return position, &evalBefore, evalAfterPtr, shouldCreateFlashcard
 }
 
+// storedEvalBefore rebuilds the evaluation before a stored position's move
+func storedEvalBefore(p models.Position) *analysis.EvalResult {
+	eval := &analysis.EvalResult{
+		BestMove: p.BestMove,
+		CP:       p.EvalBefore,
+		Mate:     p.MateBefore,
+		PV:       p.PV,
+	}
+	for _, line := range p.Lines {
+		eval.Lines = append(eval.Lines, analysis.Line{
+			Rank: line.Rank,
+			Move: line.Move,
+			CP:   line.CP,
+			Mate: line.Mate,
+			PV:   line.PV,
+		})
+	}
+	return eval
+}
+
+// positionLines converts engine candidate lines into position lines for storage
+func positionLines(lines []analysis.Line) []models.PositionLine {
+	if len(lines) == 0 {
+		return nil
+	}
+	result := make([]models.PositionLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, models.PositionLine{
+			Rank: line.Rank,
+			Move: line.Move,
+			CP:   line.CP,
+			Mate: line.Mate,
+			PV:   line.PV,
+		})
+	}
+	return result
+}
+
 // normalizeEvaluation extracts CP and mate values from evaluation result
 func normalizeEvaluation(eval analysis.EvalResult) (float64, *int) {
 	if eval.Mate != nil {
@@ -412,8 +983,9 @@ func (s *analysisService) shouldCreateFlashcardForPosition(
 		return false
 	}
 
-	// Create flashcard for blunders/mistakes
-	if classification == "blunder" || classification == "mistake" {
+	// Create flashcard for blunders/mistakes, mate errors and missed chances to punish the opponent
+	switch classification {
+	case "blunder", "mistake", "miss", "missed_mate", "allowed_mate":
 		return true
 	}
 
@@ -446,7 +1018,7 @@ func (s *analysisService) evaluateBestMoveImprovement(
 	}
 
 	fenAfterBestMove := posAfterBestMove.String()
-	evalAfterBestMove, err := engine.EvaluateFEN(ctx, fenAfterBestMove, depth, maxTimeMs)
+	evalAfterBestMove, err := s.pool.EvaluateWith(ctx, engine, fenAfterBestMove, depth, maxTimeMs, 1)
 	if err != nil {
 		log.Warn("failed to evaluate position after best move: %v", err)
 		return false
@@ -511,53 +1083,86 @@ func (s *analysisService) compareMateEvaluations(
 	return false
 }
 
-// saveAnalysisResults saves positions and creates flashcards
-func (s *analysisService) saveAnalysisResults(
+// findRepertoireDeviation checks the game's opening against the player's
+// repertoire for the color they played
+func (s *analysisService) findRepertoireDeviation(
 	ctx context.Context,
-	gameID int64,
-	result *analysisResult,
+	game *models.Game,
+	positions []models.Position,
 	log *logger.Logger,
-) error {
-	positionIDs, err := s.positionRepo.InsertBatch(ctx, result.positions)
+) *models.RepertoireDeviation {
+	moves, err := s.repertoireRepo.ListMoves(ctx, game.ProfileID, game.PlayedAs)
 	if err != nil {
-		log.Error("failed to batch insert positions: %v", err)
-		_ = s.gameRepo.UpdateStatus(ctx, gameID, "failed")
-		return err
+		log.Warn("failed to load repertoire: %v", err)
+		return nil
+	}
+	if len(moves) == 0 {
+		return nil
 	}
 
-	flashcardsCreated := s.createFlashcards(ctx, positionIDs, result.flashcardIndices, log)
-	result.flashcardsCreated = flashcardsCreated // Store for logging
-
-	return nil
+	deviation := repertoire.FindDeviation(repertoire.NewTree(moves), game.PlayedAs, positions)
+	if deviation == nil {
+		log.Debug("game stayed within the %s repertoire", game.PlayedAs)
+		return nil
+	}
+	deviation.GameID = game.ID
+	deviation.ProfileID = game.ProfileID
+	log.Info("%s left the repertoire at ply %d with %s (expected %s)",
+		deviation.DeviatedBy, deviation.Ply, deviation.MovePlayed, strings.Join(deviation.ExpectedMoves, ", "))
+	return deviation
 }
 
-// createFlashcards creates flashcards for the specified position indices
-func (s *analysisService) createFlashcards(
+// saveRepertoireDeviation stores the game's deviation and, when the player left
+// their preparation, creates a repertoire drill flashcard for that position.
+// Returns the number of flashcards created.
+func (s *analysisService) saveRepertoireDeviation(
 	ctx context.Context,
-	positionIDs []int64,
-	flashcardIndices []int,
+	result *analysisResult,
 	log *logger.Logger,
 ) int {
-	flashcardsCreated := 0
-	for _, idx := range flashcardIndices {
-		if idx < len(positionIDs) {
-			card := models.Flashcard{
-				PositionID:    positionIDs[idx],
-				DueAt:         time.Now(),
-				IntervalDays:  0,
-				EaseFactor:    2.5,
-				TimesReviewed: 0,
-				TimesCorrect:  0,
-			}
-			if _, err := s.flashcardRepo.Insert(ctx, card); err != nil {
-				log.Warn("failed to insert flashcard for position %d: %v", positionIDs[idx], err)
-			} else {
-				flashcardsCreated++
-				log.Debug("flashcard created: %+v", card)
-			}
+	d := result.deviation
+	if d == nil {
+		return 0
+	}
+	if err := s.repertoireRepo.SaveDeviation(ctx, *d); err != nil {
+		log.Warn("failed to save repertoire deviation: %v", err)
+		return 0
+	}
+	if d.DeviatedBy != models.DeviatedByPlayer {
+		return 0
+	}
+
+	idx := -1
+	for i, p := range result.positions {
+		if p.MoveNumber == d.Ply {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return 0
+	}
+	positionID := result.positions[idx].ID
+	for _, f := range result.flashcardIndices {
+		if f == idx {
+			// Flashcards are unique per position; the existing card already drills it
+			log.Debug("position %d already has a flashcard, skipping repertoire drill", positionID)
+			return 0
 		}
 	}
-	return flashcardsCreated
+
+	card := models.Flashcard{
+		PositionID: positionID,
+		DueAt:      time.Now(),
+		EaseFactor: 2.5,
+		Kind:       models.FlashcardKindRepertoire,
+	}
+	if _, err := s.flashcardRepo.Insert(ctx, card); err != nil {
+		log.Warn("failed to insert repertoire flashcard for position %d: %v", positionID, err)
+		return 0
+	}
+	log.Debug("repertoire flashcard created: %+v", card)
+	return 1
 }
 
 // finalizeAnalysis updates game status and refreshes stats
@@ -569,8 +1174,24 @@ func (s *analysisService) finalizeAnalysis(
 	result *analysisResult,
 	log *logger.Logger,
 ) {
-	log.Info("analysis completed: %d moves, %d blunders, %d mistakes, %d inaccuracies, %d flashcards created",
-		totalMoves, result.blunders, result.mistakes, result.inaccuracies, result.flashcardsCreated)
+	log.Info("analysis completed: %d moves, %d blunders, %d mistakes, %d misses, %d inaccuracies, %d flashcards created",
+		totalMoves, result.blunders, result.mistakes, result.misses, result.inaccuracies, result.flashcardsCreated)
+
+	cacheStats := s.pool.CacheStats()
+	log.Debug("eval cache: %d hits, %d misses (%.1f%% hit rate)", cacheStats.Hits, cacheStats.Misses, cacheStats.HitRate*100)
+
+	if len(result.positions) > 0 {
+		white, black := analysis.GameAccuracy(moveEvals(result.positions))
+		log.Info("accuracy: white=%.1f (acpl %.0f), black=%.1f (acpl %.0f)", white.Accuracy, white.ACPL, black.Accuracy, black.ACPL)
+		if err := s.gameRepo.UpdateAccuracy(ctx, gameID, models.GameAccuracy{
+			WhiteACPL:     white.ACPL,
+			BlackACPL:     black.ACPL,
+			WhiteAccuracy: white.Accuracy,
+			BlackAccuracy: black.Accuracy,
+		}); err != nil {
+			log.Warn("failed to save game accuracy: %v", err)
+		}
+	}
 
 	if err := s.gameRepo.UpdateStatus(ctx, gameID, "completed"); err != nil {
 		log.Error("failed to update game status to completed: %v", err)
@@ -581,6 +1202,28 @@ func (s *analysisService) finalizeAnalysis(
 	}
 }
 
+// moveEvals converts analyzed positions into the per-move evaluations used for accuracy
+func moveEvals(positions []models.Position) []analysis.MoveEval {
+	evals := make([]analysis.MoveEval, 0, len(positions))
+	for _, p := range positions {
+		evals = append(evals, analysis.MoveEval{
+			WhiteMove:  whiteToMove(p.FEN),
+			EvalBefore: p.EvalBefore,
+			MateBefore: p.MateBefore,
+			EvalAfter:  p.EvalAfter,
+			MateAfter:  p.MateAfter,
+		})
+	}
+	return evals
+}
+
+// whiteToMove reads the side to move from the FEN's active color field rather
+// than the ply, since games set up from a FEN may start with black to move.
+func whiteToMove(fen string) bool {
+	fields := strings.Fields(fen)
+	return len(fields) < 2 || fields[1] != "b"
+}
+
 // applyMoveToPosition applies a UCI move to a position and returns the new position
 func applyMoveToPosition(pos *chess.Position, moveUCI string) (*chess.Position, error) {
 	if len(moveUCI) < 4 {
@@ -0,0 +1,219 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/repository/sqlite"
+	"github.com/vytor/chessflash/internal/services"
+	"github.com/vytor/chessflash/internal/testutil"
+)
+
+// fakeEngine is a minimal UCI engine that answers every search with e2e4.
+const fakeEngine = `#!/bin/sh
+while read -r line; do
+  case "$line" in
+    uci) echo "uciok" ;;
+    isready) echo "readyok" ;;
+    go*)
+      echo "info depth 2 seldepth 3 multipv 1 score cp 20 nodes 120 pv e2e4 e7e5"
+      echo "bestmove e2e4"
+      ;;
+    quit) exit 0 ;;
+  esac
+done
+`
+
+// FENs before each ply of 1. e4 e5 2. Nf3 Nc6
+var resumeFENs = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+	"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+}
+
+var resumeMoves = []string{"e2e4", "e7e5", "g1f3", "b8c6"}
+
+// recordingFlashcardRepo counts flashcard inserts and their failures.
+type recordingFlashcardRepo struct {
+	repository.FlashcardRepository
+	inserts []int64
+	errs    []error
+}
+
+func (r *recordingFlashcardRepo) Insert(ctx context.Context, card models.Flashcard) (int64, error) {
+	id, err := r.FlashcardRepository.Insert(ctx, card)
+	r.inserts = append(r.inserts, card.PositionID)
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	return id, err
+}
+
+func newFakePool(t *testing.T) *analysis.EnginePool {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-stockfish")
+	require.NoError(t, os.WriteFile(path, []byte(fakeEngine), 0o755))
+	pool, err := analysis.NewEnginePool([]analysis.EngineConfig{{Name: "fake", Path: path, Instances: 1}}, "")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// TestAnalyzeGame_ResumeKeepsStoredFlashcards resumes a game whose stored
+// position where the player left the repertoire already has an engine card,
+// and checks that no second card is attempted for it.
+func TestAnalyzeGame_ResumeKeepsStoredFlashcards(t *testing.T) {
+	for name, stored := range map[string]int{
+		"all plies stored":  len(resumeMoves),
+		"last ply analyzed": len(resumeMoves) - 1,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := testutil.NewTestDB(t)
+			defer testutil.MustClose(t, db)
+
+			profileID, gameID := insertGame(t, db, "1. e4 e5 2. Nf3 Nc6 *")
+			positionRepo := sqlite.NewPositionRepository(db)
+			flashcardRepo := &recordingFlashcardRepo{FlashcardRepository: sqlite.NewFlashcardRepository(db)}
+			repertoireRepo := sqlite.NewRepertoireRepository(db)
+
+			// The repertoire expects 2. d4, so 2. Nf3 leaves it
+			_, err := repertoireRepo.InsertMoves(ctx, profileID, "white", []models.RepertoireMove{
+				{FEN: resumeFENs[0], Move: "e2e4"},
+				{FEN: resumeFENs[1], Move: "e7e5"},
+				{FEN: resumeFENs[2], Move: "d2d4"},
+			})
+			require.NoError(t, err)
+
+			var deviationID int64
+			for i := range stored {
+				classification := "good"
+				if i == 2 {
+					classification = "mistake"
+				}
+				id, err := positionRepo.Insert(ctx, models.Position{
+					GameID:         gameID,
+					MoveNumber:     i + 1,
+					FEN:            resumeFENs[i],
+					MovePlayed:     resumeMoves[i],
+					BestMove:       "d2d4",
+					Classification: classification,
+					CreatedAt:      time.Now(),
+				})
+				require.NoError(t, err)
+				if i == 2 {
+					deviationID = id
+				}
+			}
+			_, err = flashcardRepo.FlashcardRepository.Insert(ctx, models.Flashcard{
+				PositionID: deviationID,
+				DueAt:      time.Now(),
+				EaseFactor: 2.5,
+				Kind:       models.FlashcardKindEngine,
+			})
+			require.NoError(t, err)
+
+			svc := services.NewAnalysisService(
+				sqlite.NewGameRepository(db),
+				positionRepo,
+				flashcardRepo,
+				sqlite.NewStatsRepository(db),
+				repertoireRepo,
+				services.AnalysisConfig{StockfishDepth: 2},
+				newFakePool(t),
+			)
+			require.NoError(t, svc.AnalyzeGame(ctx, gameID, "fake"))
+
+			assert.NotContains(t, flashcardRepo.inserts, deviationID)
+			assert.Empty(t, flashcardRepo.errs)
+
+			cards, err := flashcardRepo.ListForGame(ctx, gameID)
+			require.NoError(t, err)
+			require.Len(t, cards, 1)
+			assert.Equal(t, models.FlashcardKindEngine, cards[0].Kind)
+
+			var status string
+			require.NoError(t, db.QueryRowContext(ctx, `SELECT analysis_status FROM games WHERE id = ?`, gameID).Scan(&status))
+			assert.Equal(t, "completed", status)
+		})
+	}
+}
+
+// TestAnalyzeGame_AccuracyFromSetUpPosition finishes a game set up with black
+// to move and checks that its first move counts for black.
+func TestAnalyzeGame_AccuracyFromSetUpPosition(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewTestDB(t)
+	defer testutil.MustClose(t, db)
+
+	_, gameID := insertGame(t, db, `[SetUp "1"]
+[FEN "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"]
+
+1... e5 2. Nf3 *`)
+	positionRepo := sqlite.NewPositionRepository(db)
+	gameRepo := sqlite.NewGameRepository(db)
+
+	// Black's 1... e5 throws away three pawns, white's 2. Nf3 keeps the eval
+	_, err := positionRepo.InsertBatch(ctx, []models.Position{
+		{GameID: gameID, MoveNumber: 1, FEN: resumeFENs[1], MovePlayed: "e7e5", BestMove: "c7c5",
+			EvalBefore: 30, EvalAfter: 330, EvalDiff: 300, Classification: "blunder", CreatedAt: time.Now()},
+		{GameID: gameID, MoveNumber: 2, FEN: resumeFENs[2], MovePlayed: "g1f3", BestMove: "g1f3",
+			EvalBefore: 330, EvalAfter: 330, Classification: "best", CreatedAt: time.Now()},
+	})
+	require.NoError(t, err)
+
+	svc := services.NewAnalysisService(
+		gameRepo,
+		positionRepo,
+		sqlite.NewFlashcardRepository(db),
+		sqlite.NewStatsRepository(db),
+		sqlite.NewRepertoireRepository(db),
+		services.AnalysisConfig{StockfishDepth: 2},
+		newFakePool(t),
+	)
+	require.NoError(t, svc.AnalyzeGame(ctx, gameID, "fake"))
+
+	game, err := gameRepo.Get(ctx, gameID)
+	require.NoError(t, err)
+	require.NotNil(t, game.WhiteAccuracy)
+	require.NotNil(t, game.BlackAccuracy)
+	assert.InDelta(t, 100, *game.WhiteAccuracy, 0.1)
+	assert.Less(t, *game.BlackAccuracy, 90.0)
+	require.NotNil(t, game.BlackACPL)
+	assert.Positive(t, *game.BlackACPL)
+}
+
+func insertGame(t *testing.T, db *sql.DB, pgn string) (int64, int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	res, err := db.ExecContext(ctx, `INSERT INTO profiles (username) VALUES (?)`, "resumer")
+	require.NoError(t, err)
+	profileID, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	gameID, err := sqlite.NewGameRepository(db).Insert(ctx, models.Game{
+		ProfileID:      profileID,
+		ChessComID:     "resume1",
+		PGN:            pgn,
+		TimeClass:      "blitz",
+		Result:         "win",
+		PlayedAs:       "white",
+		Opponent:       "opponent1",
+		PlayedAt:       time.Now(),
+		AnalysisStatus: "incomplete",
+	})
+	require.NoError(t, err)
+
+	return profileID, gameID
+}
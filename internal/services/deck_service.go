@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+// maxDeckNameLength bounds deck names
+const maxDeckNameLength = 60
+
+// DeckService handles flashcard deck business logic. A deck is a saved set
+// of tags; reviewing it reviews the due cards carrying all of them.
+type DeckService interface {
+	ListDecks(ctx context.Context, profileID int64) ([]models.FlashcardDeck, error)
+	GetDeck(ctx context.Context, id int64, profileID int64) (*models.FlashcardDeck, error)
+	CreateDeck(ctx context.Context, profileID int64, name string, tags []string) (*models.FlashcardDeck, error)
+	DeleteDeck(ctx context.Context, id int64, profileID int64) error
+}
+
+type deckService struct {
+	deckRepo      repository.DeckRepository
+	flashcardRepo repository.FlashcardRepository
+}
+
+// NewDeckService creates a new DeckService
+func NewDeckService(deckRepo repository.DeckRepository, flashcardRepo repository.FlashcardRepository) DeckService {
+	return &deckService{
+		deckRepo:      deckRepo,
+		flashcardRepo: flashcardRepo,
+	}
+}
+
+// ListDecks returns the profile's decks with how many of their cards are due.
+func (s *deckService) ListDecks(ctx context.Context, profileID int64) ([]models.FlashcardDeck, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("listing decks: profile_id=%d", profileID)
+
+	decks, err := s.deckRepo.ListByProfile(ctx, profileID)
+	if err != nil {
+		log.Error("failed to list decks: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	for i := range decks {
+		filter, err := flashcard.ParseFilter(decks[i].Tags, 0)
+		if err != nil {
+			// Tags are validated when the deck is created
+			log.Warn("deck %d has invalid tags: %v", decks[i].ID, err)
+			continue
+		}
+		if decks[i].DueCount, err = s.flashcardRepo.CountDue(ctx, profileID, filter); err != nil {
+			log.Error("failed to count due cards of deck %d: %v", decks[i].ID, err)
+			return nil, errors.NewInternalError(err)
+		}
+	}
+	return decks, nil
+}
+
+func (s *deckService) GetDeck(ctx context.Context, id int64, profileID int64) (*models.FlashcardDeck, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting deck: id=%d, profile_id=%d", id, profileID)
+
+	deck, err := s.deckRepo.Get(ctx, id, profileID)
+	if err != nil {
+		log.Error("failed to get deck: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	if deck == nil {
+		return nil, errors.NewNotFoundError("deck", id)
+	}
+	return deck, nil
+}
+
+// CreateDeck saves a named set of tags. The tags must make a valid filter and
+// the name must be unique for the profile.
+func (s *deckService) CreateDeck(ctx context.Context, profileID int64, name string, tags []string) (*models.FlashcardDeck, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("creating deck: profile_id=%d, name=%s, tags=%v", profileID, name, tags)
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.NewValidationError("name", "cannot be empty")
+	}
+	if len(name) > maxDeckNameLength {
+		return nil, errors.NewValidationError("name", fmt.Sprintf("cannot be longer than %d characters", maxDeckNameLength))
+	}
+
+	deckTags := []string{}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			deckTags = append(deckTags, tag)
+		}
+	}
+	if len(deckTags) == 0 {
+		return nil, errors.NewValidationError("tags", "a deck needs at least one tag")
+	}
+	if _, err := flashcard.ParseFilter(deckTags, 0); err != nil {
+		return nil, errors.NewValidationError("tags", err.Error())
+	}
+
+	existing, err := s.deckRepo.ListByProfile(ctx, profileID)
+	if err != nil {
+		log.Error("failed to list decks: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	for _, d := range existing {
+		if strings.EqualFold(d.Name, name) {
+			return nil, errors.NewValidationError("name", "a deck with this name already exists")
+		}
+	}
+
+	deck := models.FlashcardDeck{ProfileID: profileID, Name: name, Tags: deckTags}
+	if deck.ID, err = s.deckRepo.Insert(ctx, deck); err != nil {
+		log.Error("failed to create deck: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	log.Info("created deck %d (%s) with tags %v", deck.ID, name, deckTags)
+	return &deck, nil
+}
+
+func (s *deckService) DeleteDeck(ctx context.Context, id int64, profileID int64) error {
+	log := logger.FromContext(ctx)
+	log.Debug("deleting deck: id=%d, profile_id=%d", id, profileID)
+
+	if _, err := s.GetDeck(ctx, id, profileID); err != nil {
+		return err
+	}
+	if err := s.deckRepo.Delete(ctx, id, profileID); err != nil {
+		log.Error("failed to delete deck: %v", err)
+		return errors.NewInternalError(err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+// DrillService handles the "don't hang pieces" drill: positions where the
+// player left material en prise, answered with any move that does not. Unlike
+// flashcards the drill has no schedule and accepts moves the engine would not
+// pick, as long as they keep the material safe.
+type DrillService interface {
+	NextHangingDrill(ctx context.Context, profileID int64, after int64) (*models.HangingDrill, error)
+	CheckHangingDrill(ctx context.Context, profileID int64, positionID int64, move string) (*models.HangingDrillResult, error)
+	CountHangingDrills(ctx context.Context, profileID int64) (int, error)
+}
+
+type drillService struct {
+	positionRepo repository.PositionRepository
+}
+
+// NewDrillService creates a new DrillService
+func NewDrillService(positionRepo repository.PositionRepository) DrillService {
+	return &drillService{positionRepo: positionRepo}
+}
+
+// NextHangingDrill returns a random drill position, avoiding the one just
+// drilled (after) when there are others. It returns nil when the player has
+// not hung any material in analyzed games.
+func (s *drillService) NextHangingDrill(ctx context.Context, profileID int64, after int64) (*models.HangingDrill, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting next hanging drill: profile_id=%d, after=%d", profileID, after)
+
+	drill, err := s.positionRepo.RandomHanging(ctx, profileID, after)
+	if err == nil && drill == nil && after != 0 {
+		// The one just drilled is the only one
+		drill, err = s.positionRepo.RandomHanging(ctx, profileID, 0)
+	}
+	if err != nil {
+		log.Error("failed to get hanging drill: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	return drill, nil
+}
+
+// CheckHangingDrill judges a move tried on a drill position: it is safe when
+// it leaves no more material en prise than the position forced.
+func (s *drillService) CheckHangingDrill(ctx context.Context, profileID int64, positionID int64, move string) (*models.HangingDrillResult, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("checking hanging drill: profile_id=%d, position_id=%d, move=%s", profileID, positionID, move)
+
+	drill, err := s.positionRepo.GetHanging(ctx, positionID, profileID)
+	if err != nil {
+		log.Error("failed to get hanging drill: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	if drill == nil {
+		return nil, errors.NewNotFoundError("hanging drill", positionID)
+	}
+
+	move = strings.ToLower(strings.TrimSpace(move))
+	if !analysis.IsLegalMove(drill.FEN, move) {
+		return nil, errors.NewValidationError("move", "not a legal move")
+	}
+
+	hung := analysis.MaterialHung(drill.FEN, move)
+	result := &models.HangingDrillResult{
+		Move:         move,
+		Safe:         hung == 0,
+		MaterialHung: hung,
+		MovePlayed:   drill.MovePlayed,
+		BestMove:     drill.BestMove,
+	}
+	if san := analysis.UCIToSAN(drill.FEN, []string{move}); len(san) == 1 {
+		result.MoveSAN = san[0]
+	}
+	log.Debug("hanging drill move %s: safe=%v, material_hung=%d", move, result.Safe, hung)
+	return result, nil
+}
+
+func (s *drillService) CountHangingDrills(ctx context.Context, profileID int64) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("counting hanging drills: profile_id=%d", profileID)
+
+	count, err := s.positionRepo.CountHanging(ctx, profileID)
+	if err != nil {
+		log.Error("failed to count hanging drills: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+	return count, nil
+}
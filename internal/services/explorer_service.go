@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+
+	"github.com/corentings/chess/v2"
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/explorer"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repertoire"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+// explorerIndexBatch is how many games are indexed per query
+const explorerIndexBatch = 200
+
+// ExplorerService handles opening explorer business logic
+type ExplorerService interface {
+	IndexGames(ctx context.Context, profileID int64) (int, error)
+	GetPosition(ctx context.Context, filter models.ExplorerFilter, path []string) (*models.ExplorerPosition, error)
+}
+
+type explorerService struct {
+	openingTreeRepo repository.OpeningTreeRepository
+}
+
+// NewExplorerService creates a new ExplorerService
+func NewExplorerService(openingTreeRepo repository.OpeningTreeRepository) ExplorerService {
+	return &explorerService{openingTreeRepo: openingTreeRepo}
+}
+
+// IndexGames adds the opening moves of games not in the tree yet and returns
+// how many games were indexed. Games whose PGN cannot be parsed are marked as
+// indexed without moves so they are not retried.
+func (s *explorerService) IndexGames(ctx context.Context, profileID int64) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("indexing opening tree: profile_id=%d", profileID)
+
+	indexed := 0
+	for {
+		games, err := s.openingTreeRepo.UnindexedGames(ctx, profileID, explorerIndexBatch)
+		if err != nil {
+			log.Error("failed to list unindexed games: %v", err)
+			return indexed, errors.NewInternalError(err)
+		}
+
+		for _, g := range games {
+			moves, err := explorer.ExtractMoves(g.ID, g.PGN, explorer.MaxPly)
+			if err != nil {
+				log.Warn("failed to extract opening moves: game_id=%d, error=%v", g.ID, err)
+				moves = nil
+			}
+			if err := s.openingTreeRepo.IndexGame(ctx, g.ID, moves); err != nil {
+				log.Error("failed to index game: game_id=%d, error=%v", g.ID, err)
+				return indexed, errors.NewInternalError(err)
+			}
+			indexed++
+		}
+
+		if len(games) < explorerIndexBatch {
+			break
+		}
+	}
+
+	if indexed > 0 {
+		log.Info("indexed %d games into the opening tree", indexed)
+	}
+	return indexed, nil
+}
+
+// GetPosition returns the moves played from a position of the tree. The
+// position is filter.FEN when set, otherwise the one reached by playing path
+// from the start. Only games already indexed at import time are counted.
+func (s *explorerService) GetPosition(ctx context.Context, filter models.ExplorerFilter, path []string) (*models.ExplorerPosition, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting explorer position: profile_id=%d, fen=%s, path=%v", filter.ProfileID, filter.FEN, path)
+
+	if filter.Color != "" && !repertoire.IsValidColor(filter.Color) {
+		return nil, errors.NewValidationError("color", "must be white or black")
+	}
+
+	position := &models.ExplorerPosition{}
+	if filter.FEN != "" {
+		if _, err := chess.FEN(filter.FEN); err != nil {
+			return nil, errors.NewValidationError("fen", "is not a valid FEN")
+		}
+		filter.FEN = analysis.NormalizeFEN(filter.FEN)
+	} else {
+		fen, san, err := explorer.Replay(path)
+		if err != nil {
+			return nil, errors.NewValidationError("moves", err.Error())
+		}
+		filter.FEN = fen
+		position.Path = path
+		position.PathSAN = san
+	}
+	position.FEN = filter.FEN
+
+	moves, err := s.openingTreeRepo.PositionMoves(ctx, filter)
+	if err != nil {
+		log.Error("failed to get explorer moves: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	position.Moves = moves
+	for _, m := range moves {
+		position.Games += m.Games
+		position.Wins += m.Wins
+		position.Draws += m.Draws
+		position.Losses += m.Losses
+	}
+	return position, nil
+}
@@ -3,9 +3,13 @@ package services
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/vytor/chessflash/internal/analysis"
 	"github.com/vytor/chessflash/internal/errors"
 	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/jobs"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/repository"
@@ -14,25 +18,68 @@ import (
 // FlashcardService handles flashcard-related business logic
 type FlashcardService interface {
 	GetNextFlashcard(ctx context.Context, profileID int64) (*models.FlashcardWithPosition, error)
-	ReviewFlashcard(ctx context.Context, flashcardID int64, profileID int64, quality int, timeSeconds float64) error
+	NextSessionFlashcard(ctx context.Context, profileID int64, tags []string) (*models.FlashcardWithPosition, error)
+	ReviewSession(ctx context.Context, profileID int64, tags []string, limit int) (*models.ReviewSession, error)
+	SubmitReview(ctx context.Context, flashcardID int64, profileID int64, review flashcard.Review) (*flashcard.Grade, error)
+	PlayLineMove(ctx context.Context, flashcardID int64, profileID int64, played []string, move string) (*flashcard.Step, error)
 	CountFlashcardsByGame(ctx context.Context, gameID int64, profileID int64) (int, error)
 	ListFlashcardsByGame(ctx context.Context, gameID int64, profileID int64, limit int, offset int) ([]models.FlashcardWithPosition, int, error)
+	SetScheduler(ctx context.Context, profileID int64, scheduler string) error
+	SeedFSRSState(ctx context.Context, profileID int64) (int, error)
+	StartFSRSOptimization(ctx context.Context, profileID int64) (*models.FSRSOptimization, error)
+	LatestFSRSOptimization(ctx context.Context, profileID int64) (*models.FSRSOptimization, error)
+	ApplyFSRSOptimization(ctx context.Context, profileID int64, optimizationID int64) error
+	AddTag(ctx context.Context, flashcardID int64, profileID int64, tag string) (string, error)
+	RemoveTag(ctx context.Context, flashcardID int64, profileID int64, tag string) error
+	TagCounts(ctx context.Context, profileID int64) ([]models.FlashcardTagCount, error)
 }
 
+const (
+	// DefaultSessionSize is the number of cards in a review session when no
+	// limit is asked for
+	DefaultSessionSize = 20
+	// MaxSessionSize bounds the cards returned for a single review session
+	MaxSessionSize = 100
+)
+
 type flashcardService struct {
 	flashcardRepo repository.FlashcardRepository
+	profileRepo   repository.ProfileRepository
+	fsrsRepo      repository.FSRSOptimizationRepository
+	jobQueue      jobs.JobQueue
 }
 
 // NewFlashcardService creates a new FlashcardService
-func NewFlashcardService(flashcardRepo repository.FlashcardRepository) FlashcardService {
-	return &flashcardService{flashcardRepo: flashcardRepo}
+func NewFlashcardService(
+	flashcardRepo repository.FlashcardRepository,
+	profileRepo repository.ProfileRepository,
+	fsrsRepo repository.FSRSOptimizationRepository,
+	jobQueue jobs.JobQueue,
+) FlashcardService {
+	return &flashcardService{
+		flashcardRepo: flashcardRepo,
+		profileRepo:   profileRepo,
+		fsrsRepo:      fsrsRepo,
+		jobQueue:      jobQueue,
+	}
 }
 
 func (s *flashcardService) GetNextFlashcard(ctx context.Context, profileID int64) (*models.FlashcardWithPosition, error) {
+	return s.NextSessionFlashcard(ctx, profileID, nil)
+}
+
+// NextSessionFlashcard returns a due card carrying all the given tags, or nil
+// when none is due.
+func (s *flashcardService) NextSessionFlashcard(ctx context.Context, profileID int64, tags []string) (*models.FlashcardWithPosition, error) {
 	log := logger.FromContext(ctx)
-	log.Debug("getting next flashcard: profile_id=%d", profileID)
+	log.Debug("getting next flashcard: profile_id=%d, tags=%v", profileID, tags)
 
-	cards, err := s.flashcardRepo.NextFlashcards(ctx, profileID, 1)
+	filter, err := flashcard.ParseFilter(tags, 1)
+	if err != nil {
+		return nil, errors.NewValidationError("tag", err.Error())
+	}
+
+	cards, err := s.flashcardRepo.NextFlashcards(ctx, profileID, filter)
 	if err != nil {
 		log.Error("failed to get next flashcards: %v", err)
 		return nil, errors.NewInternalError(err)
@@ -52,37 +99,146 @@ func (s *flashcardService) GetNextFlashcard(ctx context.Context, profileID int64
 		return nil, errors.NewInternalError(err)
 	}
 
+	if card != nil {
+		decorateFlashcard(card)
+	}
 	return card, nil
 }
 
-func (s *flashcardService) ReviewFlashcard(ctx context.Context, flashcardID int64, profileID int64, quality int, timeSeconds float64) error {
+// ReviewSession picks up to limit due cards carrying all the given tags, in
+// random order, along with how many such cards are due in total.
+func (s *flashcardService) ReviewSession(ctx context.Context, profileID int64, tags []string, limit int) (*models.ReviewSession, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("building review session: profile_id=%d, tags=%v, limit=%d", profileID, tags, limit)
+
+	if limit <= 0 {
+		limit = DefaultSessionSize
+	}
+	if limit > MaxSessionSize {
+		return nil, errors.NewValidationError("limit", fmt.Sprintf("must be at most %d", MaxSessionSize))
+	}
+	filter, err := flashcard.ParseFilter(tags, limit)
+	if err != nil {
+		return nil, errors.NewValidationError("tag", err.Error())
+	}
+
+	due, err := s.flashcardRepo.CountDue(ctx, profileID, filter)
+	if err != nil {
+		log.Error("failed to count due flashcards: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	next, err := s.flashcardRepo.NextFlashcards(ctx, profileID, filter)
+	if err != nil {
+		log.Error("failed to get due flashcards: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	session := &models.ReviewSession{Tags: tags, Due: due, Cards: []models.FlashcardWithPosition{}}
+	if session.Tags == nil {
+		session.Tags = []string{}
+	}
+	ids := make([]int64, len(next))
+	for i, c := range next {
+		ids[i] = c.ID
+	}
+	cards, err := s.flashcardRepo.FlashcardsWithPosition(ctx, ids, profileID)
+	if err != nil {
+		log.Error("failed to load session flashcards: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	for i := range cards {
+		decorateFlashcard(&cards[i])
+		session.Cards = append(session.Cards, cards[i])
+	}
+	log.Debug("review session has %d of %d due cards", len(session.Cards), due)
+	return session, nil
+}
+
+// SubmitReview grades the moves attempted on a flashcard against its
+// position and accepted answers, then schedules the card with the derived
+// quality.
+func (s *flashcardService) SubmitReview(ctx context.Context, flashcardID int64, profileID int64, review flashcard.Review) (*flashcard.Grade, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("submitting flashcard review: flashcard_id=%d, attempts=%d, revealed=%v", flashcardID, len(review.Attempts), review.Revealed)
+
+	card, err := s.profileFlashcard(ctx, flashcardID, profileID)
+	if err != nil {
+		return nil, err
+	}
+	decorateFlashcard(card)
+
+	grade, err := flashcard.GradeReview(card.FEN, card.Line, card.AcceptableMoves, review)
+	if err != nil {
+		log.Warn("rejected flashcard review: %v", err)
+		return nil, errors.NewValidationError("moves", err.Error())
+	}
+
+	log.Debug("graded review: quality=%d, correct=%v, attempts=%d, time=%.1fs", grade.Quality, grade.Correct, grade.Attempts, grade.TimeSeconds)
+	if err := s.applyReview(ctx, card, profileID, grade.Quality, grade.TimeSeconds); err != nil {
+		return nil, err
+	}
+	return &grade, nil
+}
+
+// PlayLineMove checks a move played on a flashcard against its solution line
+// after the moves already played, answering with the opponent's reply.
+func (s *flashcardService) PlayLineMove(ctx context.Context, flashcardID int64, profileID int64, played []string, move string) (*flashcard.Step, error) {
 	log := logger.FromContext(ctx)
-	log.Debug("reviewing flashcard: flashcard_id=%d, quality=%d", flashcardID, quality)
+	log.Debug("playing flashcard line move: flashcard_id=%d, ply=%d, move=%s", flashcardID, len(played), move)
 
-	// Validate quality
-	if quality < 0 || quality > 5 {
-		return errors.NewValidationError("quality", "must be between 0 and 5")
+	card, err := s.profileFlashcard(ctx, flashcardID, profileID)
+	if err != nil {
+		return nil, err
+	}
+	decorateFlashcard(card)
+
+	step, err := flashcard.PlayStep(card.FEN, card.Line, card.AcceptableMoves, played, move)
+	if err != nil {
+		log.Warn("rejected flashcard line move: %v", err)
+		return nil, errors.NewValidationError("move", err.Error())
 	}
+	return &step, nil
+}
 
-	// Get flashcard and verify it belongs to profile
+// profileFlashcard loads a flashcard with its position, verifying it belongs
+// to the profile.
+func (s *flashcardService) profileFlashcard(ctx context.Context, flashcardID int64, profileID int64) (*models.FlashcardWithPosition, error) {
 	card, err := s.flashcardRepo.FlashcardWithPosition(ctx, flashcardID, profileID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return errors.NewNotFoundError("flashcard", flashcardID)
+			return nil, errors.NewNotFoundError("flashcard", flashcardID)
 		}
-		log.Error("failed to get flashcard: %v", err)
-		return errors.NewInternalError(err)
+		logger.FromContext(ctx).Error("failed to get flashcard: %v", err)
+		return nil, errors.NewInternalError(err)
 	}
 
 	if card == nil {
-		return errors.NewNotFoundError("flashcard", flashcardID)
+		return nil, errors.NewNotFoundError("flashcard", flashcardID)
 	}
+	return card, nil
+}
+
+// applyReview schedules the card with the profile's scheduler and records
+// the review.
+func (s *flashcardService) applyReview(ctx context.Context, card *models.FlashcardWithPosition, profileID int64, quality int, timeSeconds float64) error {
+	log := logger.FromContext(ctx)
 
-	// Apply spaced repetition algorithm
-	updated := flashcard.ApplyReview(card.Flashcard, quality)
+	profile, err := s.profileRepo.Get(ctx, profileID)
+	if err != nil {
+		log.Error("failed to get profile: %v", err)
+		return errors.NewInternalError(err)
+	}
+	if profile == nil {
+		return errors.NewNotFoundError("profile", profileID)
+	}
+
+	// Apply the profile's spaced repetition scheduler
+	scheduler := flashcard.NewScheduler(profile.Scheduler, flashcard.FSRSParamsFromWeights(profile.FSRSWeights))
+	updated := scheduler.Schedule(card.Flashcard, quality, time.Now())
 	updated.ID = card.ID
 
-	log.Debug("applied review, new interval=%d days, ease_factor=%.2f", updated.IntervalDays, updated.EaseFactor)
+	log.Debug("applied %s review, new interval=%d days, ease_factor=%.2f, stability=%.2f, difficulty=%.2f",
+		scheduler.Name(), updated.IntervalDays, updated.EaseFactor, updated.Stability, updated.Difficulty)
 
 	// Update flashcard
 	if err := s.flashcardRepo.Update(ctx, updated); err != nil {
@@ -130,5 +286,234 @@ func (s *flashcardService) ListFlashcardsByGame(ctx context.Context, gameID int6
 		return nil, 0, errors.NewInternalError(err)
 	}
 
+	for i := range cards {
+		decorateFlashcard(&cards[i])
+	}
 	return cards, totalCount, nil
 }
+
+func (s *flashcardService) SetScheduler(ctx context.Context, profileID int64, scheduler string) error {
+	log := logger.FromContext(ctx)
+	log.Debug("setting scheduler: profile_id=%d, scheduler=%s", profileID, scheduler)
+
+	if !flashcard.IsValidScheduler(scheduler) {
+		return errors.NewValidationError("scheduler", "must be sm2 or fsrs")
+	}
+
+	if err := s.profileRepo.UpdateScheduler(ctx, profileID, scheduler); err != nil {
+		log.Error("failed to update scheduler: %v", err)
+		return errors.NewInternalError(err)
+	}
+
+	if scheduler == flashcard.SchedulerFSRS {
+		if _, err := s.SeedFSRSState(ctx, profileID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SeedFSRSState replays the review history of every card without a FSRS
+// memory state, so cards reviewed before switching to FSRS keep what the
+// history says about them instead of starting over as new cards.
+func (s *flashcardService) SeedFSRSState(ctx context.Context, profileID int64) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("seeding FSRS state from review history: profile_id=%d", profileID)
+
+	history, err := s.flashcardRepo.UnseededReviewHistory(ctx, profileID)
+	if err != nil {
+		log.Error("failed to load review history: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+
+	return s.replayMemoryState(ctx, profileID, history)
+}
+
+func (s *flashcardService) StartFSRSOptimization(ctx context.Context, profileID int64) (*models.FSRSOptimization, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("starting FSRS optimization: profile_id=%d", profileID)
+
+	latest, err := s.fsrsRepo.Latest(ctx, profileID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	if latest != nil && (latest.Status == models.FSRSOptimizationPending || latest.Status == models.FSRSOptimizationRunning) {
+		log.Debug("optimization %d already in progress", latest.ID)
+		return latest, nil
+	}
+
+	id, err := s.fsrsRepo.Insert(ctx, profileID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	if err := s.jobQueue.EnqueueFSRSOptimization(profileID, id); err != nil {
+		log.Error("failed to enqueue FSRS optimization: %v", err)
+		now := time.Now()
+		failed := models.FSRSOptimization{ID: id, ProfileID: profileID, Status: models.FSRSOptimizationFailed, Error: err.Error(), CompletedAt: &now}
+		if updateErr := s.fsrsRepo.Update(ctx, failed); updateErr != nil {
+			log.Error("failed to record FSRS optimization failure: %v", updateErr)
+		}
+		return nil, errors.NewInternalError(err)
+	}
+
+	return s.fsrsRepo.Get(ctx, id)
+}
+
+func (s *flashcardService) LatestFSRSOptimization(ctx context.Context, profileID int64) (*models.FSRSOptimization, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting latest FSRS optimization: profile_id=%d", profileID)
+
+	latest, err := s.fsrsRepo.Latest(ctx, profileID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	return latest, nil
+}
+
+// ApplyFSRSOptimization stores the fitted weights on the profile. Profiles
+// already on FSRS get every card's memory state rebuilt with the new weights,
+// which reschedules the cards accordingly.
+func (s *flashcardService) ApplyFSRSOptimization(ctx context.Context, profileID int64, optimizationID int64) error {
+	log := logger.FromContext(ctx)
+	log.Debug("applying FSRS optimization: profile_id=%d, optimization_id=%d", profileID, optimizationID)
+
+	run, err := s.fsrsRepo.Get(ctx, optimizationID)
+	if err != nil {
+		return errors.NewInternalError(err)
+	}
+	if run == nil || run.ProfileID != profileID {
+		return errors.NewNotFoundError("optimization", optimizationID)
+	}
+	if run.Status != models.FSRSOptimizationCompleted || len(run.Weights) != flashcard.FSRSWeightCount {
+		return errors.NewValidationError("optimization", "has no fitted weights to apply")
+	}
+
+	if err := s.profileRepo.UpdateFSRSWeights(ctx, profileID, run.Weights); err != nil {
+		return errors.NewInternalError(err)
+	}
+	if err := s.fsrsRepo.MarkApplied(ctx, optimizationID); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	profile, err := s.profileRepo.Get(ctx, profileID)
+	if err != nil {
+		return errors.NewInternalError(err)
+	}
+	if profile == nil || profile.Scheduler != flashcard.SchedulerFSRS {
+		return nil
+	}
+
+	history, err := s.flashcardRepo.ReviewHistoryByProfile(ctx, profileID)
+	if err != nil {
+		log.Error("failed to load review history: %v", err)
+		return errors.NewInternalError(err)
+	}
+	_, err = s.replayMemoryState(ctx, profileID, history)
+	return err
+}
+
+// replayMemoryState rebuilds the FSRS memory state of the given cards from
+// their review history using the profile's weights.
+func (s *flashcardService) replayMemoryState(ctx context.Context, profileID int64, history map[int64][]models.ReviewHistory) (int, error) {
+	log := logger.FromContext(ctx)
+
+	profile, err := s.profileRepo.Get(ctx, profileID)
+	if err != nil {
+		log.Error("failed to get profile: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+	if profile == nil {
+		return 0, errors.NewNotFoundError("profile", profileID)
+	}
+
+	fsrs := flashcard.NewFSRS(flashcard.FSRSParamsFromWeights(profile.FSRSWeights))
+	seeded := 0
+	for cardID, reviews := range history {
+		card := fsrs.Replay(models.Flashcard{ID: cardID}, reviews)
+		if err := s.flashcardRepo.UpdateMemoryState(ctx, card); err != nil {
+			log.Error("failed to seed FSRS state for flashcard %d: %v", cardID, err)
+			return seeded, errors.NewInternalError(err)
+		}
+		seeded++
+	}
+
+	log.Info("seeded FSRS state for %d flashcards", seeded)
+	return seeded, nil
+}
+
+// decorateFlashcard fills in the moves that are accepted as correct answers,
+// so equally good alternatives to the engine's best move are not punished
+// (or the prepared moves, for repertoire drills), the solution line that
+// tactical cards are played out along, the best line in SAN so the answer can
+// be explained, the automatic tags, and the current recall probability for
+// cards scheduled by FSRS.
+func decorateFlashcard(card *models.FlashcardWithPosition) {
+	card.AcceptableMoves = flashcard.AcceptableMoves(card.FEN, card.BestMove, card.MovePlayed, card.Lines, flashcard.DefaultAlternativeToleranceCP)
+	if card.Kind == models.FlashcardKindRepertoire && len(card.RepertoireMoves) > 0 {
+		// Repertoire drills are answered with the prepared moves
+		card.AcceptableMoves = card.RepertoireMoves
+	}
+	if card.Kind != models.FlashcardKindRepertoire {
+		card.Line = flashcard.SolutionLine(card.FEN, card.BestMove, card.PV, card.Classification, card.MateBefore)
+	}
+	card.LineMoves = max(flashcard.LineMoves(card.Line), 1)
+	card.PVSAN = analysis.UCIToSAN(card.FEN, card.PV)
+	card.AutoTags = flashcard.AutoTags(*card)
+	if card.Tags == nil {
+		card.Tags = []string{}
+	}
+	card.Retrievability = flashcard.Retrievability(card.Flashcard, time.Now())
+}
+
+// AddTag puts a user tag on one of the profile's flashcards, returning the
+// tag as stored.
+func (s *flashcardService) AddTag(ctx context.Context, flashcardID int64, profileID int64, tag string) (string, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("tagging flashcard: flashcard_id=%d, tag=%s", flashcardID, tag)
+
+	tag, err := flashcard.NormalizeUserTag(tag)
+	if err != nil {
+		return "", errors.NewValidationError("tag", err.Error())
+	}
+	if _, err := s.profileFlashcard(ctx, flashcardID, profileID); err != nil {
+		return "", err
+	}
+	if err := s.flashcardRepo.AddTag(ctx, flashcardID, tag); err != nil {
+		log.Error("failed to add flashcard tag: %v", err)
+		return "", errors.NewInternalError(err)
+	}
+	return tag, nil
+}
+
+func (s *flashcardService) RemoveTag(ctx context.Context, flashcardID int64, profileID int64, tag string) error {
+	log := logger.FromContext(ctx)
+	log.Debug("untagging flashcard: flashcard_id=%d, tag=%s", flashcardID, tag)
+
+	tag, err := flashcard.NormalizeUserTag(tag)
+	if err != nil {
+		return errors.NewValidationError("tag", err.Error())
+	}
+	if _, err := s.profileFlashcard(ctx, flashcardID, profileID); err != nil {
+		return err
+	}
+	if err := s.flashcardRepo.RemoveTag(ctx, flashcardID, tag); err != nil {
+		log.Error("failed to remove flashcard tag: %v", err)
+		return errors.NewInternalError(err)
+	}
+	return nil
+}
+
+// TagCounts returns how many active cards carry each tag, automatic tags
+// included.
+func (s *flashcardService) TagCounts(ctx context.Context, profileID int64) ([]models.FlashcardTagCount, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("counting flashcard tags: profile_id=%d", profileID)
+
+	counts, err := s.flashcardRepo.TagCounts(ctx, profileID)
+	if err != nil {
+		log.Error("failed to count flashcard tags: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	return counts, nil
+}
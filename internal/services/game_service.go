@@ -3,12 +3,15 @@ package services
 import (
 	"context"
 	"database/sql"
+	stderrors "errors"
 
+	"github.com/vytor/chessflash/internal/analysis"
 	"github.com/vytor/chessflash/internal/errors"
 	"github.com/vytor/chessflash/internal/jobs"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/worker"
 )
 
 // GameService handles game-related business logic
@@ -17,6 +20,7 @@ type GameService interface {
 	ListGames(ctx context.Context, filter models.GameFilter) ([]models.Game, int, error)
 	GetPositionsForGame(ctx context.Context, gameID int64) ([]models.Position, error)
 	QueueGameAnalysis(ctx context.Context, gameID int64, profileID int64) error
+	QueueDeepenAnalysis(ctx context.Context, gameID int64, profileID int64, depth int) error
 	ResumeAnalysis(ctx context.Context, profileID int64) (int, error)
 	CountGamesNeedingAnalysis(ctx context.Context, profileID int64) (int, error)
 	CountGamesByStatus(ctx context.Context, profileID int64, status string) (int, error)
@@ -24,6 +28,8 @@ type GameService interface {
 	CountGamesForAnalysis(ctx context.Context, filter models.AnalysisFilter) (int, error)
 	QueueGamesForAnalysis(ctx context.Context, filter models.AnalysisFilter) (int, error)
 	CountGamesByStatusWithFilter(ctx context.Context, profileID int64, status string, filter models.AnalysisFilter) (int, error)
+	CountFailureReasons(ctx context.Context, profileID int64) (map[string]int, error)
+	RetryFailedGames(ctx context.Context, profileID int64, reason string) (int, error)
 }
 
 type gameService struct {
@@ -95,6 +101,9 @@ func (s *gameService) GetPositionsForGame(ctx context.Context, gameID int64) ([]
 		return nil, errors.NewInternalError(err)
 	}
 
+	for i := range positions {
+		positions[i].PVSAN = analysis.UCIToSAN(positions[i].FEN, positions[i].PV)
+	}
 	return positions, nil
 }
 
@@ -126,7 +135,38 @@ func (s *gameService) QueueGameAnalysis(ctx context.Context, gameID int64, profi
 		return nil
 	}
 
-	return s.jobQueue.EnqueueAnalysis(gameID)
+	if err := s.jobQueue.EnqueueAnalysis(gameID, "", worker.PriorityInteractive); err != nil && !stderrors.Is(err, jobs.ErrAlreadyQueued) {
+		return err
+	}
+	return nil
+}
+
+// QueueDeepenAnalysis queues an analyzed game to be searched again at depth.
+func (s *gameService) QueueDeepenAnalysis(ctx context.Context, gameID int64, profileID int64, depth int) error {
+	log := logger.FromContext(ctx)
+	log.Debug("queueing deeper analysis: game_id=%d, depth=%d", gameID, depth)
+
+	if depth < 1 || depth > 30 {
+		return errors.NewValidationError("depth", "must be between 1 and 30")
+	}
+
+	game, err := s.gameRepo.Get(ctx, gameID)
+	if err != nil {
+		log.Error("failed to get game: %v", err)
+		return errors.NewInternalError(err)
+	}
+	if game == nil || game.ProfileID != profileID {
+		return errors.NewNotFoundError("game", gameID)
+	}
+	if game.AnalysisStatus != "completed" {
+		return errors.NewValidationError("game", "must be analyzed before it can be deepened")
+	}
+
+	if err := s.jobQueue.EnqueueDeepenAnalysis(gameID, depth, ""); err != nil {
+		log.Error("failed to enqueue deeper analysis: %v", err)
+		return errors.NewInternalError(err)
+	}
+	return nil
 }
 
 func (s *gameService) ResumeAnalysis(ctx context.Context, profileID int64) (int, error) {
@@ -145,7 +185,7 @@ func (s *gameService) ResumeAnalysis(ctx context.Context, profileID int64) (int,
 	}
 
 	for _, g := range games {
-		if err := s.jobQueue.EnqueueAnalysis(g.ID); err != nil {
+		if err := s.jobQueue.EnqueueAnalysis(g.ID, "", worker.PriorityBackfill); err != nil && !stderrors.Is(err, jobs.ErrAlreadyQueued) {
 			log.Warn("failed to enqueue analysis for game %d: %v", g.ID, err)
 		}
 	}
@@ -223,7 +263,10 @@ func (s *gameService) QueueGamesForAnalysis(ctx context.Context, filter models.A
 	queuedCount := 0
 	rejectedCount := 0
 	for _, g := range games {
-		if err := s.jobQueue.EnqueueAnalysis(g.ID); err != nil {
+		if err := s.jobQueue.EnqueueAnalysis(g.ID, filter.Engine, worker.PriorityBackfill); err != nil {
+			if stderrors.Is(err, jobs.ErrAlreadyQueued) {
+				continue
+			}
 			if err.Error() == "job queue is full" {
 				rejectedCount++
 			}
@@ -268,3 +311,48 @@ func (s *gameService) CountGamesByStatusWithFilter(ctx context.Context, profileI
 
 	return count, nil
 }
+
+func (s *gameService) CountFailureReasons(ctx context.Context, profileID int64) (map[string]int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("counting failure reasons: profile_id=%d", profileID)
+
+	counts, err := s.gameRepo.CountFailureReasons(ctx, profileID)
+	if err != nil {
+		log.Error("failed to count failure reasons: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	return counts, nil
+}
+
+// RetryFailedGames queues the profile's games whose analysis failed for
+// reason to be analyzed again, ahead of the backfill and without waiting out
+// a retry backoff. It counts the games whose job was queued or moved up.
+func (s *gameService) RetryFailedGames(ctx context.Context, profileID int64, reason string) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("retrying failed games: profile_id=%d, reason=%s", profileID, reason)
+
+	if reason == "" {
+		return 0, errors.NewValidationError("failure_reason", "cannot be empty")
+	}
+
+	games, err := s.gameRepo.GamesForAnalysis(ctx, models.AnalysisFilter{ProfileID: profileID, FailureReason: reason})
+	if err != nil {
+		log.Error("failed to list failed games: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+
+	queued := 0
+	for _, g := range games {
+		if err := s.jobQueue.EnqueueAnalysis(g.ID, "", worker.PriorityInteractive); err != nil {
+			if !stderrors.Is(err, jobs.ErrAlreadyQueued) {
+				log.Warn("failed to enqueue analysis for game %d: %v", g.ID, err)
+			}
+			continue
+		}
+		queued++
+	}
+
+	log.Info("queued %d of %d failed games for analysis", queued, len(games))
+	return queued, nil
+}
@@ -2,24 +2,49 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/corentings/chess/v2"
+	"github.com/vytor/chessflash/internal/analysis"
+	"github.com/vytor/chessflash/internal/errors"
 	"github.com/vytor/chessflash/internal/jobs"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/pgn"
+	"github.com/vytor/chessflash/internal/repository"
+	"github.com/vytor/chessflash/internal/worker"
 )
 
+// defaultUploadTimeClass is used when an uploaded game has no usable
+// TimeControl tag; over-the-board games are usually played at long controls.
+const defaultUploadTimeClass = "rapid"
+
 // ImportService handles game import business logic
 type ImportService interface {
 	ImportGames(ctx context.Context, profile models.Profile)
+	ImportPGN(ctx context.Context, profile models.Profile, pgnText, playerName string) (*models.PGNImportResult, error)
 }
 
 type importService struct {
-	jobQueue jobs.JobQueue
+	jobQueue  jobs.JobQueue
+	gameRepo  repository.GameRepository
+	statsRepo repository.StatsRepository
+	explorer  ExplorerService
 }
 
 // NewImportService creates a new ImportService
-func NewImportService(jobQueue jobs.JobQueue) ImportService {
-	return &importService{jobQueue: jobQueue}
+func NewImportService(jobQueue jobs.JobQueue, gameRepo repository.GameRepository, statsRepo repository.StatsRepository, explorer ExplorerService) ImportService {
+	return &importService{
+		jobQueue:  jobQueue,
+		gameRepo:  gameRepo,
+		statsRepo: statsRepo,
+		explorer:  explorer,
+	}
 }
 
 func (s *importService) ImportGames(ctx context.Context, profile models.Profile) {
@@ -36,3 +61,202 @@ func (s *importService) ImportGames(ctx context.Context, profile models.Profile)
 		log.Error("failed to enqueue import job: %v", err)
 	}
 }
+
+// ImportPGN imports every game of a multi-game PGN document for the profile.
+// The profile's side is found by matching playerName (or the profile username
+// when empty) against the White/Black tags. Games are deduplicated by a hash of
+// their players, date, result and moves, indexed into the opening tree, and
+// queued for analysis.
+func (s *importService) ImportPGN(ctx context.Context, profile models.Profile, pgnText, playerName string) (*models.PGNImportResult, error) {
+	log := logger.FromContext(ctx).WithFields(map[string]any{
+		"username":   profile.Username,
+		"profile_id": profile.ID,
+	})
+
+	playerName = strings.TrimSpace(playerName)
+	if playerName == "" {
+		playerName = profile.Username
+	}
+
+	texts := pgn.SplitGames(pgnText)
+	if len(texts) == 0 {
+		return nil, errors.NewValidationError("pgn", "no games found")
+	}
+	log.Info("importing %d games from PGN upload", len(texts))
+
+	existing, err := s.gameRepo.GetExistingContentHashes(ctx, profile.ID)
+	if err != nil {
+		log.Error("failed to load existing content hashes: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	result := &models.PGNImportResult{Total: len(texts)}
+	var newGames []models.Game
+	for i, text := range texts {
+		game, err := buildUploadedGame(profile, playerName, text)
+		if err != nil {
+			log.Debug("skipping game %d: %v", i+1, err)
+			result.Errors = append(result.Errors, models.PGNImportError{Index: i + 1, Reason: err.Error()})
+			continue
+		}
+		if existing[game.ContentHash] {
+			result.Duplicates++
+			continue
+		}
+		existing[game.ContentHash] = true // avoid duplicates within the upload
+		newGames = append(newGames, game)
+	}
+
+	inserted, err := s.gameRepo.InsertBatch(ctx, newGames)
+	if err != nil {
+		log.Error("failed to insert uploaded games: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	result.Imported = len(inserted)
+	result.Duplicates += len(newGames) - len(inserted)
+	log.Info("imported %d games from PGN upload (%d duplicates, %d errors)", result.Imported, result.Duplicates, len(result.Errors))
+
+	for _, id := range inserted {
+		if err := s.jobQueue.EnqueueAnalysis(id, "", worker.PriorityImportFollowUp); err != nil {
+			// Games stay pending and are picked up by resume/backfill
+			log.Warn("failed to enqueue analysis for game %d: %v", id, err)
+		}
+	}
+
+	if result.Imported > 0 {
+		if err := s.statsRepo.RefreshProfileStats(ctx, profile.ID); err != nil {
+			log.Warn("failed to refresh cached stats after PGN import: %v", err)
+		}
+		if _, err := s.explorer.IndexGames(ctx, profile.ID); err != nil {
+			log.Warn("failed to index opening tree after PGN import: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// buildUploadedGame validates a single PGN game and converts it to a pending game.
+func buildUploadedGame(profile models.Profile, playerName, text string) (models.Game, error) {
+	headers := pgn.ParsePGNHeaders(text)
+
+	pgnOpt, err := chess.PGN(strings.NewReader(text))
+	if err != nil {
+		return models.Game{}, fmt.Errorf("invalid PGN: %w", err)
+	}
+	chessGame := chess.NewGame(pgnOpt)
+	moves := chessGame.Moves()
+	if len(moves) == 0 {
+		return models.Game{}, fmt.Errorf("game has no moves")
+	}
+
+	white, black := headers["White"], headers["Black"]
+	var playedAs, opponent string
+	switch {
+	case strings.EqualFold(white, playerName):
+		playedAs, opponent = "white", black
+	case strings.EqualFold(black, playerName):
+		playedAs, opponent = "black", white
+	default:
+		return models.Game{}, fmt.Errorf("player %q is neither White (%q) nor Black (%q)", playerName, white, black)
+	}
+
+	resultTag := headers["Result"]
+	if resultTag == "" || resultTag == "*" {
+		resultTag = chessGame.Outcome().String()
+	}
+	result, ok := resultForSide(resultTag, playedAs)
+	if !ok {
+		return models.Game{}, fmt.Errorf("game has no result")
+	}
+
+	uciMoves := make([]string, len(moves))
+	for i, m := range moves {
+		uciMoves[i] = analysis.MoveToUCI(m)
+	}
+	hash := contentHash(white, black, headers["Date"], headers["Round"], resultTag, uciMoves)
+
+	timeClass := pgn.TimeClassFromTimeControl(headers["TimeControl"])
+	if timeClass == "" {
+		timeClass = defaultUploadTimeClass
+	}
+
+	playerElo, opponentElo := headers["WhiteElo"], headers["BlackElo"]
+	if playedAs == "black" {
+		playerElo, opponentElo = opponentElo, playerElo
+	}
+	playerRating, _ := strconv.Atoi(playerElo)
+	opponentRating, _ := strconv.Atoi(opponentElo)
+
+	if opponent == "" {
+		opponent = "Unknown"
+	}
+
+	return models.Game{
+		ProfileID: profile.ID,
+		// chess_com_id is required and globally unique; uploads use a
+		// per-profile synthetic id derived from the content hash.
+		ChessComID:     fmt.Sprintf("pgn:%d:%s", profile.ID, hash),
+		PGN:            text,
+		TimeClass:      timeClass,
+		Result:         result,
+		PlayedAs:       playedAs,
+		Opponent:       opponent,
+		PlayerRating:   playerRating,
+		OpponentRating: opponentRating,
+		PlayedAt:       playedAtFromHeaders(headers),
+		ECOCode:        headers["ECO"],
+		OpeningName:    headers["Opening"],
+		OpeningURL:     headers["ECOUrl"],
+		AnalysisStatus: "pending",
+		ContentHash:    hash,
+	}, nil
+}
+
+// resultForSide converts a PGN result tag to win/draw/loss for the given side.
+func resultForSide(tag, playedAs string) (string, bool) {
+	switch tag {
+	case "1/2-1/2":
+		return "draw", true
+	case "1-0":
+		if playedAs == "white" {
+			return "win", true
+		}
+		return "loss", true
+	case "0-1":
+		if playedAs == "black" {
+			return "win", true
+		}
+		return "loss", true
+	}
+	return "", false
+}
+
+// contentHash identifies a game independently of PGN formatting, comments and
+// annotations.
+func contentHash(white, black, date, round, result string, uciMoves []string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.ToLower(white),
+		strings.ToLower(black),
+		date,
+		round,
+		result,
+		strings.Join(uciMoves, " "),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// playedAtFromHeaders reads the game date from UTCDate/UTCTime or Date tags,
+// falling back to the current time when no complete date is available.
+func playedAtFromHeaders(headers map[string]string) time.Time {
+	if d := headers["UTCDate"]; d != "" {
+		if t, err := time.Parse("2006.01.02 15:04:05", d+" "+headers["UTCTime"]); err == nil {
+			return t
+		}
+		if t, err := time.Parse("2006.01.02", d); err == nil {
+			return t
+		}
+	}
+	if t, err := time.Parse("2006.01.02", headers["Date"]); err == nil {
+		return t
+	}
+	return time.Now()
+}
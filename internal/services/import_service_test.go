@@ -0,0 +1,147 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/services"
+	"github.com/vytor/chessflash/internal/testutil/mocks"
+	"github.com/vytor/chessflash/internal/worker"
+)
+
+const uploadPGN = `[Event "Club Championship"]
+[Date "2024.03.09"]
+[Round "1"]
+[White "Jane Doe"]
+[Black "John Smith"]
+[Result "1-0"]
+[WhiteElo "1720"]
+[BlackElo "1650"]
+[TimeControl "5400+30"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 1-0
+
+[Event "Club Championship"]
+[Date "2024.03.16"]
+[Round "2"]
+[White "Mary Major"]
+[Black "jane doe"]
+[Result "1/2-1/2"]
+
+1. d4 {A comment} d5 2. c4 e6 1/2-1/2
+
+[Event "Club Championship"]
+[Date "2024.03.23"]
+[Round "3"]
+[White "Someone Else"]
+[Black "Another Player"]
+[Result "0-1"]
+
+1. e4 c5 0-1
+
+[Event "Broken"]
+[White "Jane Doe"]
+[Black "John Smith"]
+[Result "1-0"]
+
+1. e4 e4 1-0
+`
+
+func TestImportPGN(t *testing.T) {
+	ctx := context.Background()
+	profile := models.Profile{ID: 7, Username: "jdoe"}
+
+	gameRepo := new(mocks.MockGameRepository)
+	statsRepo := new(mocks.MockStatsRepository)
+	jobQueue := new(mocks.MockJobQueue)
+	explorer := new(mocks.MockExplorerService)
+
+	var inserted []models.Game
+	gameRepo.On("GetExistingContentHashes", ctx, int64(7)).Return(map[string]bool{}, nil)
+	gameRepo.On("InsertBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		inserted = args.Get(1).([]models.Game)
+	}).Return([]int64{101, 102}, nil)
+	jobQueue.On("EnqueueAnalysis", int64(101), "", worker.PriorityImportFollowUp).Return(nil)
+	jobQueue.On("EnqueueAnalysis", int64(102), "", worker.PriorityImportFollowUp).Return(nil)
+	statsRepo.On("RefreshProfileStats", ctx, int64(7)).Return(nil)
+	explorer.On("IndexGames", ctx, int64(7)).Return(2, nil)
+
+	svc := services.NewImportService(jobQueue, gameRepo, statsRepo, explorer)
+	result, err := svc.ImportPGN(ctx, profile, uploadPGN, "Jane Doe")
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 0, result.Duplicates)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Index)
+	assert.Equal(t, 4, result.Errors[1].Index)
+
+	require.Len(t, inserted, 2)
+	first := inserted[0]
+	assert.Equal(t, "white", first.PlayedAs)
+	assert.Equal(t, "John Smith", first.Opponent)
+	assert.Equal(t, "win", first.Result)
+	assert.Equal(t, "rapid", first.TimeClass)
+	assert.Equal(t, 1720, first.PlayerRating)
+	assert.Equal(t, 1650, first.OpponentRating)
+	assert.Equal(t, "pending", first.AnalysisStatus)
+	assert.NotEmpty(t, first.ContentHash)
+	assert.Equal(t, 2024, first.PlayedAt.Year())
+
+	second := inserted[1]
+	assert.Equal(t, "black", second.PlayedAs)
+	assert.Equal(t, "Mary Major", second.Opponent)
+	assert.Equal(t, "draw", second.Result)
+	assert.NotEqual(t, first.ContentHash, second.ContentHash)
+
+	gameRepo.AssertExpectations(t)
+	jobQueue.AssertExpectations(t)
+	statsRepo.AssertExpectations(t)
+	explorer.AssertExpectations(t)
+}
+
+func TestImportPGN_SkipsExistingContentHashes(t *testing.T) {
+	ctx := context.Background()
+	profile := models.Profile{ID: 7, Username: "jdoe"}
+
+	gameRepo := new(mocks.MockGameRepository)
+	statsRepo := new(mocks.MockStatsRepository)
+	jobQueue := new(mocks.MockJobQueue)
+	explorer := new(mocks.MockExplorerService)
+
+	// First import records the hashes of the valid games
+	var hashes = map[string]bool{}
+	gameRepo.On("GetExistingContentHashes", ctx, int64(7)).Return(hashes, nil).Once()
+	gameRepo.On("InsertBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		for _, g := range args.Get(1).([]models.Game) {
+			hashes[g.ContentHash] = true
+		}
+	}).Return([]int64{1, 2}, nil).Once()
+	jobQueue.On("EnqueueAnalysis", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	statsRepo.On("RefreshProfileStats", ctx, int64(7)).Return(nil)
+	explorer.On("IndexGames", ctx, int64(7)).Return(2, nil)
+
+	svc := services.NewImportService(jobQueue, gameRepo, statsRepo, explorer)
+	_, err := svc.ImportPGN(ctx, profile, uploadPGN, "Jane Doe")
+	require.NoError(t, err)
+
+	// Re-uploading the same file finds only duplicates
+	gameRepo.On("GetExistingContentHashes", ctx, int64(7)).Return(hashes, nil).Once()
+	gameRepo.On("InsertBatch", ctx, mock.Anything).Return([]int64(nil), nil).Once()
+
+	result, err := svc.ImportPGN(ctx, profile, uploadPGN, "Jane Doe")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 2, result.Duplicates)
+}
+
+func TestImportPGN_NoGames(t *testing.T) {
+	svc := services.NewImportService(new(mocks.MockJobQueue), new(mocks.MockGameRepository), new(mocks.MockStatsRepository), new(mocks.MockExplorerService))
+	_, err := svc.ImportPGN(context.Background(), models.Profile{ID: 1, Username: "jdoe"}, "   ", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+// JobService handles the durable background job queue
+type JobService interface {
+	ListJobs(ctx context.Context, state string, limit int) ([]models.Job, error)
+	CountJobs(ctx context.Context, queue string) (map[string]int, error)
+	RetryJob(ctx context.Context, id int64) error
+	ClearQueued(ctx context.Context, queue string) (int, error)
+	AnalysisQueuePosition(ctx context.Context, gameID int64) (int, error)
+}
+
+type jobService struct {
+	jobRepo repository.JobRepository
+}
+
+// NewJobService creates a new JobService
+func NewJobService(jobRepo repository.JobRepository) JobService {
+	return &jobService{jobRepo: jobRepo}
+}
+
+func (s *jobService) ListJobs(ctx context.Context, state string, limit int) ([]models.Job, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("listing jobs: state=%s", state)
+
+	jobs, err := s.jobRepo.List(ctx, []string{state}, limit)
+	if err != nil {
+		log.Error("failed to list jobs: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	return jobs, nil
+}
+
+// CountJobs counts the jobs of a queue, or of all queues when empty, by state.
+func (s *jobService) CountJobs(ctx context.Context, queue string) (map[string]int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("counting jobs: queue=%s", queue)
+
+	counts, err := s.jobRepo.CountByState(ctx, queue)
+	if err != nil {
+		log.Error("failed to count jobs: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	return counts, nil
+}
+
+// RetryJob queues a failed job again.
+func (s *jobService) RetryJob(ctx context.Context, id int64) error {
+	log := logger.FromContext(ctx)
+	log.Debug("retrying job: id=%d", id)
+
+	if err := s.jobRepo.Retry(ctx, id); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.NewNotFoundError("failed job", id)
+		}
+		log.Error("failed to retry job: %v", err)
+		return errors.NewInternalError(err)
+	}
+	return nil
+}
+
+// ClearQueued drops the jobs of a queue that have not started yet.
+func (s *jobService) ClearQueued(ctx context.Context, queue string) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("clearing queued jobs: queue=%s", queue)
+
+	n, err := s.jobRepo.DeleteQueued(ctx, queue)
+	if err != nil {
+		log.Error("failed to clear queued jobs: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+	return n, nil
+}
+
+// AnalysisQueuePosition returns how many queued analysis jobs run before the
+// game's, counting its own, or 0 when the game is not queued.
+func (s *jobService) AnalysisQueuePosition(ctx context.Context, gameID int64) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting analysis queue position: game_id=%d", gameID)
+
+	position, err := s.jobRepo.QueuePosition(ctx, models.JobQueueAnalysis, gameID)
+	if err != nil {
+		log.Error("failed to get analysis queue position: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+	return position, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/gamesource"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/repository"
@@ -13,7 +14,7 @@ import (
 // ProfileService handles profile-related business logic
 type ProfileService interface {
 	ListProfiles(ctx context.Context) ([]models.Profile, error)
-	CreateProfile(ctx context.Context, username string) (*models.Profile, error)
+	CreateProfile(ctx context.Context, username, platform string) (*models.Profile, error)
 	GetProfile(ctx context.Context, id int64) (*models.Profile, error)
 	DeleteProfile(ctx context.Context, id int64) error
 }
@@ -40,15 +41,22 @@ func (s *profileService) ListProfiles(ctx context.Context) ([]models.Profile, er
 	return profiles, nil
 }
 
-func (s *profileService) CreateProfile(ctx context.Context, username string) (*models.Profile, error) {
+func (s *profileService) CreateProfile(ctx context.Context, username, platform string) (*models.Profile, error) {
 	log := logger.FromContext(ctx)
-	log.Debug("creating profile: username=%s", username)
+	log.Debug("creating profile: username=%s, platform=%s", username, platform)
 
 	if username == "" {
 		return nil, errors.NewValidationError("username", "cannot be empty")
 	}
 
-	profile, err := s.profileRepo.Upsert(ctx, username)
+	if platform == "" {
+		platform = gamesource.PlatformChessCom
+	}
+	if !gamesource.IsValidPlatform(platform) {
+		return nil, errors.NewValidationError("platform", "must be chesscom or lichess")
+	}
+
+	profile, err := s.profileRepo.Upsert(ctx, username, platform)
 	if err != nil {
 		log.Error("failed to create profile: %v", err)
 		return nil, errors.NewInternalError(err)
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/flashcard"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/repository"
@@ -14,7 +15,7 @@ import (
 type PuzzleRushService interface {
 	StartRush(ctx context.Context, profileID int64, difficulty string) (*models.PuzzleRushSession, error)
 	GetCurrentSession(ctx context.Context, profileID int64) (*models.PuzzleRushSession, error)
-	SubmitAnswer(ctx context.Context, sessionID int64, profileID int64, flashcardID int64, quality int, timeSeconds float64) (*models.PuzzleRushSession, error)
+	SubmitAnswer(ctx context.Context, sessionID int64, profileID int64, flashcardID int64, review flashcard.Review) (*models.PuzzleRushSession, error)
 	EndRush(ctx context.Context, sessionID int64, profileID int64) error
 	GetStats(ctx context.Context, profileID int64) (*models.PuzzleRushStats, error)
 	GetBestScores(ctx context.Context, profileID int64) ([]models.PuzzleRushBestScore, error)
@@ -97,14 +98,9 @@ func (s *puzzleRushService) GetCurrentSession(ctx context.Context, profileID int
 	return session, nil
 }
 
-func (s *puzzleRushService) SubmitAnswer(ctx context.Context, sessionID int64, profileID int64, flashcardID int64, quality int, timeSeconds float64) (*models.PuzzleRushSession, error) {
+func (s *puzzleRushService) SubmitAnswer(ctx context.Context, sessionID int64, profileID int64, flashcardID int64, review flashcard.Review) (*models.PuzzleRushSession, error) {
 	log := logger.FromContext(ctx)
-	log.Debug("submitting puzzle rush answer: session_id=%d, flashcard_id=%d, quality=%d", sessionID, flashcardID, quality)
-
-	// Validate quality
-	if quality < 0 || quality > 5 {
-		return nil, errors.NewValidationError("quality", "must be between 0 and 5")
-	}
+	log.Debug("submitting puzzle rush answer: session_id=%d, flashcard_id=%d, attempts=%d", sessionID, flashcardID, len(review.Attempts))
 
 	// Get session and verify ownership
 	session, err := s.rushRepo.GetSession(ctx, sessionID)
@@ -122,8 +118,15 @@ func (s *puzzleRushService) SubmitAnswer(ctx context.Context, sessionID int64, p
 		return nil, errors.NewValidationError("session", "session is already completed")
 	}
 
-	// Determine if answer is correct (quality 3-5 = correct, 0-2 = mistake)
-	wasCorrect := quality >= 3
+	// Grade the answer and reschedule the card; a single wrong move fails
+	// a card in a rush
+	review.MaxMisses = 1
+	grade, err := s.flashcardSvc.SubmitReview(ctx, flashcardID, profileID, review)
+	if err != nil {
+		return nil, err
+	}
+	wasCorrect := grade.Correct
+	timeSeconds := grade.TimeSeconds
 
 	// Get attempt number (count existing attempts + 1)
 	attempts, err := s.rushRepo.GetSessionAttempts(ctx, sessionID)
@@ -163,12 +166,6 @@ func (s *puzzleRushService) SubmitAnswer(ctx context.Context, sessionID int64, p
 		log.Info("puzzle rush session completed: id=%d, score=%d, mistakes=%d", sessionID, session.Score, session.MistakesMade)
 	}
 
-	// Update flashcard using flashcard service (applies spaced repetition)
-	if err := s.flashcardSvc.ReviewFlashcard(ctx, flashcardID, profileID, quality, timeSeconds); err != nil {
-		log.Warn("failed to review flashcard: %v", err)
-		// Continue even if flashcard review fails
-	}
-
 	// Update session in database
 	if err := s.rushRepo.UpdateSession(ctx, *session); err != nil {
 		log.Error("failed to update session: %v", err)
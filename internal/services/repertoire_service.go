@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/logger"
+	"github.com/vytor/chessflash/internal/models"
+	"github.com/vytor/chessflash/internal/repertoire"
+	"github.com/vytor/chessflash/internal/repository"
+)
+
+// RepertoireService handles opening repertoire business logic
+type RepertoireService interface {
+	ImportPGN(ctx context.Context, profileID int64, color, pgnText string) (int, error)
+	ClearRepertoire(ctx context.Context, profileID int64, color string) error
+	GetSummary(ctx context.Context, profileID int64) ([]models.RepertoireSummary, error)
+	GetDeviations(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviation, error)
+	GetDeviationStats(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviationStat, error)
+}
+
+type repertoireService struct {
+	repertoireRepo repository.RepertoireRepository
+}
+
+// NewRepertoireService creates a new RepertoireService
+func NewRepertoireService(repertoireRepo repository.RepertoireRepository) RepertoireService {
+	return &repertoireService{repertoireRepo: repertoireRepo}
+}
+
+// ImportPGN adds every move of the PGN's lines and variations to the
+// repertoire of color and returns how many were new.
+func (s *repertoireService) ImportPGN(ctx context.Context, profileID int64, color, pgnText string) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("importing repertoire PGN: profile_id=%d, color=%s", profileID, color)
+
+	if !repertoire.IsValidColor(color) {
+		return 0, errors.NewValidationError("color", "must be white or black")
+	}
+	if strings.TrimSpace(pgnText) == "" {
+		return 0, errors.NewValidationError("pgn", "cannot be empty")
+	}
+
+	moves, err := repertoire.ParsePGN(pgnText)
+	if err != nil {
+		log.Warn("failed to parse repertoire PGN: %v", err)
+		return 0, errors.NewValidationError("pgn", err.Error())
+	}
+	if len(moves) == 0 {
+		return 0, errors.NewValidationError("pgn", "contains no moves")
+	}
+
+	added, err := s.repertoireRepo.InsertMoves(ctx, profileID, color, moves)
+	if err != nil {
+		log.Error("failed to store repertoire moves: %v", err)
+		return 0, errors.NewInternalError(err)
+	}
+
+	log.Info("imported %s repertoire: %d moves parsed, %d new", color, len(moves), added)
+	return added, nil
+}
+
+func (s *repertoireService) ClearRepertoire(ctx context.Context, profileID int64, color string) error {
+	log := logger.FromContext(ctx)
+	log.Debug("clearing repertoire: profile_id=%d, color=%s", profileID, color)
+
+	if !repertoire.IsValidColor(color) {
+		return errors.NewValidationError("color", "must be white or black")
+	}
+
+	if err := s.repertoireRepo.DeleteMoves(ctx, profileID, color); err != nil {
+		log.Error("failed to clear repertoire: %v", err)
+		return errors.NewInternalError(err)
+	}
+	return nil
+}
+
+func (s *repertoireService) GetSummary(ctx context.Context, profileID int64) ([]models.RepertoireSummary, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting repertoire summary: profile_id=%d", profileID)
+
+	summary, err := s.repertoireRepo.Summary(ctx, profileID)
+	if err != nil {
+		log.Error("failed to get repertoire summary: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	// Always report both colors so empty repertoires show up too
+	byColor := map[string]models.RepertoireSummary{}
+	for _, sum := range summary {
+		byColor[sum.Color] = sum
+	}
+	result := make([]models.RepertoireSummary, 0, 2)
+	for _, color := range []string{repertoire.White, repertoire.Black} {
+		sum := byColor[color]
+		sum.Color = color
+		result = append(result, sum)
+	}
+	return result, nil
+}
+
+func (s *repertoireService) GetDeviations(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviation, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting repertoire deviations: profile_id=%d, limit=%d", profileID, limit)
+
+	deviations, err := s.repertoireRepo.ListDeviations(ctx, profileID, limit)
+	if err != nil {
+		log.Error("failed to get repertoire deviations: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	return deviations, nil
+}
+
+func (s *repertoireService) GetDeviationStats(ctx context.Context, profileID int64, limit int) ([]models.RepertoireDeviationStat, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting repertoire deviation stats: profile_id=%d, limit=%d", profileID, limit)
+
+	stats, err := s.repertoireRepo.DeviationStats(ctx, profileID, limit)
+	if err != nil {
+		log.Error("failed to get repertoire deviation stats: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+	return stats, nil
+}
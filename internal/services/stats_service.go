@@ -17,11 +17,14 @@ type StatsService interface {
 	GetTimeClassStats(ctx context.Context, profileID int64, dateCutoff *time.Time) ([]models.TimeClassStat, error)
 	GetColorStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.ColorStat, error)
 	GetMonthlyStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MonthlyStat, error)
+	GetAccuracyOverTime(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.AccuracyTrendStat, error)
+	GetAccuracyByOpening(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time, limit int) ([]models.OpeningAccuracyStat, error)
 	GetMistakePhaseStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MistakePhaseStat, error)
 	GetRatingStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.RatingStat, error)
 	GetSummaryStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) (*models.SummaryStat, error)
 	GetFlashcardStats(ctx context.Context, profileID int64) (*models.FlashcardStat, error)
 	GetFlashcardClassificationStats(ctx context.Context, profileID int64) ([]models.FlashcardClassificationStat, error)
+	GetFlashcardMotifStats(ctx context.Context, profileID int64) ([]models.FlashcardMotifStat, error)
 	GetFlashcardPhaseStats(ctx context.Context, profileID int64) ([]models.FlashcardPhaseStat, error)
 	GetFlashcardOpeningStats(ctx context.Context, profileID int64, limit int) ([]models.FlashcardOpeningStat, error)
 	GetFlashcardTimeStats(ctx context.Context, profileID int64) (*models.FlashcardTimeStat, error)
@@ -114,6 +117,32 @@ func (s *statsService) GetMonthlyStats(ctx context.Context, profileID int64, tim
 	return stats, nil
 }
 
+func (s *statsService) GetAccuracyOverTime(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.AccuracyTrendStat, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting accuracy over time: profile_id=%d, time_class=%s, date_cutoff=%v", profileID, timeClass, dateCutoff)
+
+	stats, err := s.statsRepo.AccuracyOverTime(ctx, profileID, timeClass, dateCutoff)
+	if err != nil {
+		log.Error("failed to get accuracy over time: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	return stats, nil
+}
+
+func (s *statsService) GetAccuracyByOpening(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time, limit int) ([]models.OpeningAccuracyStat, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting accuracy by opening: profile_id=%d, time_class=%s, date_cutoff=%v, limit=%d", profileID, timeClass, dateCutoff, limit)
+
+	stats, err := s.statsRepo.AccuracyByOpening(ctx, profileID, timeClass, dateCutoff, limit)
+	if err != nil {
+		log.Error("failed to get accuracy by opening: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	return stats, nil
+}
+
 func (s *statsService) GetMistakePhaseStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MistakePhaseStat, error) {
 	log := logger.FromContext(ctx)
 	log.Debug("getting mistake phase stats: profile_id=%d, time_class=%s, date_cutoff=%v", profileID, timeClass, dateCutoff)
@@ -179,6 +208,19 @@ func (s *statsService) GetFlashcardClassificationStats(ctx context.Context, prof
 	return stats, nil
 }
 
+func (s *statsService) GetFlashcardMotifStats(ctx context.Context, profileID int64) ([]models.FlashcardMotifStat, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("getting flashcard motif stats: profile_id=%d", profileID)
+
+	stats, err := s.statsRepo.FlashcardMotifStats(ctx, profileID)
+	if err != nil {
+		log.Error("failed to get motif stats: %v", err)
+		return nil, errors.NewInternalError(err)
+	}
+
+	return stats, nil
+}
+
 func (s *statsService) GetFlashcardPhaseStats(ctx context.Context, profileID int64) ([]models.FlashcardPhaseStat, error) {
 	log := logger.FromContext(ctx)
 	log.Debug("getting flashcard phase stats: profile_id=%d", profileID)
@@ -0,0 +1,8 @@
+-- Scheduler used for a profile's flashcards (sm2, fsrs)
+ALTER TABLE profiles ADD COLUMN scheduler TEXT NOT NULL DEFAULT 'sm2';
+
+-- FSRS memory state per flashcard; NULL until seeded from review_history or
+-- first reviewed with FSRS
+ALTER TABLE flashcards ADD COLUMN stability REAL;
+ALTER TABLE flashcards ADD COLUMN difficulty REAL;
+ALTER TABLE flashcards ADD COLUMN last_reviewed_at DATETIME;
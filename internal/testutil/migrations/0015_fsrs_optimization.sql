@@ -0,0 +1,22 @@
+-- FSRS weights fitted to the profile's review log (JSON array); NULL uses the defaults
+ALTER TABLE profiles ADD COLUMN fsrs_weights TEXT;
+
+-- Runs of the FSRS weight optimizer
+CREATE TABLE IF NOT EXISTS fsrs_optimizations (
+    id INTEGER PRIMARY KEY,
+    profile_id INTEGER NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, running, completed, failed
+    review_count INTEGER NOT NULL DEFAULT 0,
+    log_loss_before REAL NOT NULL DEFAULT 0,
+    log_loss_after REAL NOT NULL DEFAULT 0,
+    observed_retention REAL NOT NULL DEFAULT 0,
+    predicted_retention REAL NOT NULL DEFAULT 0,
+    weights TEXT,          -- JSON array of fitted weights
+    retention_curve TEXT,  -- JSON array of retention points
+    error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME,
+    applied_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_fsrs_optimizations_profile ON fsrs_optimizations(profile_id, created_at);
@@ -0,0 +1,6 @@
+-- Per-side average centipawn loss and accuracy (0-100), computed when analysis
+-- completes; NULL until the game has been analyzed
+ALTER TABLE games ADD COLUMN white_acpl REAL;
+ALTER TABLE games ADD COLUMN black_acpl REAL;
+ALTER TABLE games ADD COLUMN white_accuracy REAL;
+ALTER TABLE games ADD COLUMN black_accuracy REAL;
@@ -0,0 +1,9 @@
+-- Engine evaluations keyed by normalized FEN (no move counters), reused across games
+CREATE TABLE IF NOT EXISTS eval_cache (
+    fen TEXT NOT NULL,
+    depth INTEGER NOT NULL,
+    multipv INTEGER NOT NULL,
+    result TEXT NOT NULL, -- JSON encoded analysis.EvalResult
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (fen, depth, multipv)
+);
@@ -0,0 +1,7 @@
+-- Why a game's analysis last failed (see errors.Reason*), cleared when its
+-- status changes again. Games that failed before reasons were recorded are
+-- marked unknown so they can still be listed and retried together.
+ALTER TABLE games ADD COLUMN failure_reason TEXT;
+UPDATE games SET failure_reason = 'unknown' WHERE analysis_status = 'failed';
+
+CREATE INDEX IF NOT EXISTS idx_games_failure_reason ON games(profile_id, failure_reason) WHERE failure_reason IS NOT NULL;
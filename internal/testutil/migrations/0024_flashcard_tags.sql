@@ -0,0 +1,21 @@
+-- Tags the user put on flashcards. Automatic tags (ECO code, opening, phase,
+-- classification, time class) are not stored: they are derived from the
+-- card's position and game when filtering.
+CREATE TABLE IF NOT EXISTS flashcard_tags (
+    flashcard_id INTEGER NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL COLLATE NOCASE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (flashcard_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flashcard_tags_tag ON flashcard_tags(tag);
+
+-- Decks are named tag filters: a deck holds the cards that have all its tags
+CREATE TABLE IF NOT EXISTS flashcard_decks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    profile_id INTEGER NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    tags TEXT NOT NULL, -- JSON array of tags
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(profile_id, name)
+);
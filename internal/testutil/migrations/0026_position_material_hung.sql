@@ -0,0 +1,7 @@
+-- Material, in pawns, a move left en prise by static exchange evaluation,
+-- recorded when the engine also judged the move an error (sound sacrifices
+-- keep 0). Positions analyzed before this column existed keep 0 until they
+-- are analyzed again or deepened.
+ALTER TABLE positions ADD COLUMN material_hung INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_positions_material_hung ON positions(material_hung) WHERE material_hung > 0;
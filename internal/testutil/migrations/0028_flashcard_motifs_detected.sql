@@ -0,0 +1,6 @@
+-- Set once a flashcard's motifs were detected (even when it has none), so
+-- engine cards created before motif detection are tagged by a startup pass
+ALTER TABLE flashcards ADD COLUMN motifs_detected INTEGER NOT NULL DEFAULT 0;
+
+UPDATE flashcards SET motifs_detected = 1
+WHERE kind != 'engine' OR id IN (SELECT flashcard_id FROM flashcard_motifs);
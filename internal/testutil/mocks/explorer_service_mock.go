@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/vytor/chessflash/internal/models"
+)
+
+// MockExplorerService is a mock implementation of services.ExplorerService
+type MockExplorerService struct {
+	mock.Mock
+}
+
+func (m *MockExplorerService) IndexGames(ctx context.Context, profileID int64) (int, error) {
+	args := m.Called(ctx, profileID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExplorerService) GetPosition(ctx context.Context, filter models.ExplorerFilter, path []string) (*models.ExplorerPosition, error) {
+	args := m.Called(ctx, filter, path)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExplorerPosition), args.Error(1)
+}
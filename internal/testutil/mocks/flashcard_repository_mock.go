@@ -22,14 +22,32 @@ func (m *MockFlashcardRepository) Update(ctx context.Context, flashcard models.F
 	return args.Error(0)
 }
 
-func (m *MockFlashcardRepository) NextFlashcards(ctx context.Context, profileID int64, limit int) ([]models.Flashcard, error) {
-	args := m.Called(ctx, profileID, limit)
+func (m *MockFlashcardRepository) SetMotifs(ctx context.Context, id int64, motifs []string) error {
+	args := m.Called(ctx, id, motifs)
+	return args.Error(0)
+}
+
+func (m *MockFlashcardRepository) UndetectedMotifCards(ctx context.Context, limit int) ([]models.FlashcardWithPosition, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FlashcardWithPosition), args.Error(1)
+}
+
+func (m *MockFlashcardRepository) NextFlashcards(ctx context.Context, profileID int64, filter models.FlashcardFilter) ([]models.Flashcard, error) {
+	args := m.Called(ctx, profileID, filter)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]models.Flashcard), args.Error(1)
 }
 
+func (m *MockFlashcardRepository) CountDue(ctx context.Context, profileID int64, filter models.FlashcardFilter) (int, error) {
+	args := m.Called(ctx, profileID, filter)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockFlashcardRepository) FlashcardWithPosition(ctx context.Context, id int64, profileID int64) (*models.FlashcardWithPosition, error) {
 	args := m.Called(ctx, id, profileID)
 	if args.Get(0) == nil {
@@ -38,7 +56,75 @@ func (m *MockFlashcardRepository) FlashcardWithPosition(ctx context.Context, id
 	return args.Get(0).(*models.FlashcardWithPosition), args.Error(1)
 }
 
+func (m *MockFlashcardRepository) FlashcardsWithPosition(ctx context.Context, ids []int64, profileID int64) ([]models.FlashcardWithPosition, error) {
+	args := m.Called(ctx, ids, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FlashcardWithPosition), args.Error(1)
+}
+
 func (m *MockFlashcardRepository) InsertReviewHistory(ctx context.Context, flashcardID int64, quality int, timeSeconds float64) error {
 	args := m.Called(ctx, flashcardID, quality, timeSeconds)
 	return args.Error(0)
 }
+
+func (m *MockFlashcardRepository) ReviewHistoryByProfile(ctx context.Context, profileID int64) (map[int64][]models.ReviewHistory, error) {
+	args := m.Called(ctx, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64][]models.ReviewHistory), args.Error(1)
+}
+
+func (m *MockFlashcardRepository) UnseededReviewHistory(ctx context.Context, profileID int64) (map[int64][]models.ReviewHistory, error) {
+	args := m.Called(ctx, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int64][]models.ReviewHistory), args.Error(1)
+}
+
+func (m *MockFlashcardRepository) UpdateMemoryState(ctx context.Context, flashcard models.Flashcard) error {
+	args := m.Called(ctx, flashcard)
+	return args.Error(0)
+}
+
+func (m *MockFlashcardRepository) ListForGame(ctx context.Context, gameID int64) ([]models.Flashcard, error) {
+	args := m.Called(ctx, gameID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Flashcard), args.Error(1)
+}
+
+func (m *MockFlashcardRepository) SetRetired(ctx context.Context, id int64, retired bool) error {
+	args := m.Called(ctx, id, retired)
+	return args.Error(0)
+}
+
+func (m *MockFlashcardRepository) AddTag(ctx context.Context, flashcardID int64, tag string) error {
+	args := m.Called(ctx, flashcardID, tag)
+	return args.Error(0)
+}
+
+func (m *MockFlashcardRepository) RemoveTag(ctx context.Context, flashcardID int64, tag string) error {
+	args := m.Called(ctx, flashcardID, tag)
+	return args.Error(0)
+}
+
+func (m *MockFlashcardRepository) ListTags(ctx context.Context, flashcardID int64) ([]string, error) {
+	args := m.Called(ctx, flashcardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockFlashcardRepository) TagCounts(ctx context.Context, profileID int64) ([]models.FlashcardTagCount, error) {
+	args := m.Called(ctx, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FlashcardTagCount), args.Error(1)
+}
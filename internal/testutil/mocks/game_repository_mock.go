@@ -51,11 +51,29 @@ func (m *MockGameRepository) UpdateStatus(ctx context.Context, id int64, status
 	return args.Error(0)
 }
 
+func (m *MockGameRepository) UpdateFailure(ctx context.Context, id int64, status, reason string) error {
+	args := m.Called(ctx, id, status, reason)
+	return args.Error(0)
+}
+
+func (m *MockGameRepository) CountFailureReasons(ctx context.Context, profileID int64) (map[string]int, error) {
+	args := m.Called(ctx, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
 func (m *MockGameRepository) UpdateOpening(ctx context.Context, id int64, ecoCode, openingName string) error {
 	args := m.Called(ctx, id, ecoCode, openingName)
 	return args.Error(0)
 }
 
+func (m *MockGameRepository) UpdateAccuracy(ctx context.Context, id int64, acc models.GameAccuracy) error {
+	args := m.Called(ctx, id, acc)
+	return args.Error(0)
+}
+
 func (m *MockGameRepository) ResetProcessingToPending(ctx context.Context, profileID int64) error {
 	args := m.Called(ctx, profileID)
 	return args.Error(0)
@@ -100,6 +118,14 @@ func (m *MockGameRepository) GetExistingChessComIDs(ctx context.Context, profile
 	return args.Get(0).(map[string]bool), args.Error(1)
 }
 
+func (m *MockGameRepository) GetExistingContentHashes(ctx context.Context, profileID int64) (map[string]bool, error) {
+	args := m.Called(ctx, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
 func (m *MockGameRepository) CountByStatus(ctx context.Context, profileID int64, status string) (int, error) {
 	args := m.Called(ctx, profileID, status)
 	return args.Int(0), args.Error(1)
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"github.com/stretchr/testify/mock"
+	"github.com/vytor/chessflash/internal/worker"
 )
 
 // MockJobQueue is a mock implementation of jobs.JobQueue
@@ -9,8 +10,13 @@ type MockJobQueue struct {
 	mock.Mock
 }
 
-func (m *MockJobQueue) EnqueueAnalysis(gameID int64) error {
-	args := m.Called(gameID)
+func (m *MockJobQueue) EnqueueAnalysis(gameID int64, engine string, priority worker.Priority) error {
+	args := m.Called(gameID, engine, priority)
+	return args.Error(0)
+}
+
+func (m *MockJobQueue) EnqueueDeepenAnalysis(gameID int64, depth int, engine string) error {
+	args := m.Called(gameID, depth, engine)
 	return args.Error(0)
 }
 
@@ -18,3 +24,8 @@ func (m *MockJobQueue) EnqueueImport(profileID int64, username string) error {
 	args := m.Called(profileID, username)
 	return args.Error(0)
 }
+
+func (m *MockJobQueue) EnqueueFSRSOptimization(profileID, optimizationID int64) error {
+	args := m.Called(profileID, optimizationID)
+	return args.Error(0)
+}
@@ -32,3 +32,42 @@ func (m *MockPositionRepository) PositionsForGame(ctx context.Context, gameID in
 	}
 	return args.Get(0).([]models.Position), args.Error(1)
 }
+
+func (m *MockPositionRepository) UpdateAnalysis(ctx context.Context, positions []models.Position) error {
+	args := m.Called(ctx, positions)
+	return args.Error(0)
+}
+
+func (m *MockPositionRepository) UncheckedMaterialHung(ctx context.Context, limit int) ([]models.Position, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Position), args.Error(1)
+}
+
+func (m *MockPositionRepository) SetMaterialHung(ctx context.Context, id int64, pawns int) error {
+	args := m.Called(ctx, id, pawns)
+	return args.Error(0)
+}
+
+func (m *MockPositionRepository) RandomHanging(ctx context.Context, profileID int64, excludeID int64) (*models.HangingDrill, error) {
+	args := m.Called(ctx, profileID, excludeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HangingDrill), args.Error(1)
+}
+
+func (m *MockPositionRepository) GetHanging(ctx context.Context, id int64, profileID int64) (*models.HangingDrill, error) {
+	args := m.Called(ctx, id, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HangingDrill), args.Error(1)
+}
+
+func (m *MockPositionRepository) CountHanging(ctx context.Context, profileID int64) (int, error) {
+	args := m.Called(ctx, profileID)
+	return args.Int(0), args.Error(1)
+}
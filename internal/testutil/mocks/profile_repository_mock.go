@@ -29,8 +29,8 @@ func (m *MockProfileRepository) List(ctx context.Context) ([]models.Profile, err
 	return args.Get(0).([]models.Profile), args.Error(1)
 }
 
-func (m *MockProfileRepository) Upsert(ctx context.Context, username string) (*models.Profile, error) {
-	args := m.Called(ctx, username)
+func (m *MockProfileRepository) Upsert(ctx context.Context, username, platform string) (*models.Profile, error) {
+	args := m.Called(ctx, username, platform)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -42,6 +42,16 @@ func (m *MockProfileRepository) UpdateSync(ctx context.Context, id int64, t time
 	return args.Error(0)
 }
 
+func (m *MockProfileRepository) UpdateScheduler(ctx context.Context, id int64, scheduler string) error {
+	args := m.Called(ctx, id, scheduler)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) UpdateFSRSWeights(ctx context.Context, id int64, weights []float64) error {
+	args := m.Called(ctx, id, weights)
+	return args.Error(0)
+}
+
 func (m *MockProfileRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
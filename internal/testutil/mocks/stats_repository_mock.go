@@ -63,6 +63,22 @@ func (m *MockStatsRepository) MonthlyStats(ctx context.Context, profileID int64,
 	return args.Get(0).([]models.MonthlyStat), args.Error(1)
 }
 
+func (m *MockStatsRepository) AccuracyOverTime(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.AccuracyTrendStat, error) {
+	args := m.Called(ctx, profileID, timeClass, dateCutoff)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AccuracyTrendStat), args.Error(1)
+}
+
+func (m *MockStatsRepository) AccuracyByOpening(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time, limit int) ([]models.OpeningAccuracyStat, error) {
+	args := m.Called(ctx, profileID, timeClass, dateCutoff, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.OpeningAccuracyStat), args.Error(1)
+}
+
 func (m *MockStatsRepository) MistakePhaseStats(ctx context.Context, profileID int64, timeClass string, dateCutoff *time.Time) ([]models.MistakePhaseStat, error) {
 	args := m.Called(ctx, profileID, timeClass, dateCutoff)
 	if args.Get(0) == nil {
@@ -95,6 +111,14 @@ func (m *MockStatsRepository) FlashcardClassificationStats(ctx context.Context,
 	return args.Get(0).([]models.FlashcardClassificationStat), args.Error(1)
 }
 
+func (m *MockStatsRepository) FlashcardMotifStats(ctx context.Context, profileID int64) ([]models.FlashcardMotifStat, error) {
+	args := m.Called(ctx, profileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FlashcardMotifStat), args.Error(1)
+}
+
 func (m *MockStatsRepository) FlashcardPhaseStats(ctx context.Context, profileID int64) ([]models.FlashcardPhaseStat, error) {
 	args := m.Called(ctx, profileID)
 	if args.Get(0) == nil {
@@ -28,6 +28,26 @@ func NewTestDB(t *testing.T) *sql.DB {
 		"migrations/0006_add_performance_indexes.sql",
 		"migrations/0008_add_unique_position_constraint.sql",
 		"migrations/0009_add_unique_flashcard_position.sql",
+		"migrations/0010_position_lines.sql",
+		"migrations/0011_position_pv.sql",
+		"migrations/0012_profile_platform.sql",
+		"migrations/0013_game_content_hash.sql",
+		"migrations/0014_fsrs_scheduler.sql",
+		"migrations/0015_fsrs_optimization.sql",
+		"migrations/0016_game_accuracy.sql",
+		"migrations/0017_repertoire.sql",
+		"migrations/0018_opening_tree.sql",
+		"migrations/0019_eval_cache.sql",
+		"migrations/0020_engine_profiles.sql",
+		"migrations/0021_deepen_analysis.sql",
+		"migrations/0022_jobs.sql",
+		"migrations/0023_failure_reasons.sql",
+		"migrations/0024_flashcard_tags.sql",
+		"migrations/0025_flashcard_motifs.sql",
+		"migrations/0026_position_material_hung.sql",
+		"migrations/0027_profile_platform_key.sql",
+		"migrations/0028_flashcard_motifs_detected.sql",
+		"migrations/0029_position_material_hung_checked.sql",
 	}
 
 	for _, migration := range migrations {
@@ -5,5 +5,11 @@ import "context"
 // AnalysisServiceInterface defines the interface for game analysis
 // This avoids import cycles by not importing the services package
 type AnalysisServiceInterface interface {
-	AnalyzeGame(ctx context.Context, gameID int64) error
+	AnalyzeGame(ctx context.Context, gameID int64, engine string) error
+	DeepenAnalysis(ctx context.Context, gameID int64, depth int, engine string) error
+}
+
+// OpeningIndexer adds newly imported games to the opening explorer tree
+type OpeningIndexer interface {
+	IndexGames(ctx context.Context, profileID int64) (int, error)
 }
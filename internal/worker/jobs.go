@@ -2,12 +2,14 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/vytor/chessflash/internal/chesscom"
+	"github.com/vytor/chessflash/internal/errors"
+	"github.com/vytor/chessflash/internal/flashcard"
+	"github.com/vytor/chessflash/internal/gamesource"
 	"github.com/vytor/chessflash/internal/logger"
 	"github.com/vytor/chessflash/internal/models"
 	"github.com/vytor/chessflash/internal/pgn"
@@ -17,90 +19,127 @@ import (
 type AnalyzeGameJob struct {
 	AnalysisService AnalysisServiceInterface
 	GameID          int64
+	Engine          string // engine profile, empty for the default
 }
 
 func (j *AnalyzeGameJob) Name() string { return "analyze_game" }
 
 func (j *AnalyzeGameJob) Run(ctx context.Context) error {
-	return j.AnalysisService.AnalyzeGame(ctx, j.GameID)
+	return j.AnalysisService.AnalyzeGame(ctx, j.GameID, j.Engine)
 }
 
-// ImportGamesJob fetches recent archives, inserts games, and enqueues analysis.
-type ImportGamesJob struct {
-	GameRepo       repository.GameRepository
-	ProfileRepo    repository.ProfileRepository
-	StatsRepo      repository.StatsRepository
-	ChessClient    *chesscom.Client
-	Profile        models.Profile
-	AnalysisPool   *Pool
-	StockfishPath  string
-	StockfishDepth int
-	ArchiveLimit   int
-	MaxConcurrent  int
+// DeepenAnalysisJob re-analyzes an already analyzed game at a higher depth.
+type DeepenAnalysisJob struct {
+	AnalysisService AnalysisServiceInterface
+	GameID          int64
+	Depth           int
+	Engine          string // engine profile, empty for the default
 }
 
-func (j *ImportGamesJob) Name() string { return "import_games" }
+func (j *DeepenAnalysisJob) Name() string { return "deepen_analysis" }
 
-func (j *ImportGamesJob) Run(ctx context.Context) error {
+func (j *DeepenAnalysisJob) Run(ctx context.Context) error {
+	return j.AnalysisService.DeepenAnalysis(ctx, j.GameID, j.Depth, j.Engine)
+}
+
+// OptimizeFSRSJob fits FSRS weights to a profile's review log and records the
+// result on the optimization run. The weights are only used once applied.
+type OptimizeFSRSJob struct {
+	FlashcardRepo    repository.FlashcardRepository
+	ProfileRepo      repository.ProfileRepository
+	OptimizationRepo repository.FSRSOptimizationRepository
+	ProfileID        int64
+	OptimizationID   int64
+}
+
+func (j *OptimizeFSRSJob) Name() string { return "optimize_fsrs" }
+
+func (j *OptimizeFSRSJob) Run(ctx context.Context) error {
 	log := logger.FromContext(ctx).WithFields(map[string]any{
-		"username":   j.Profile.Username,
-		"profile_id": j.Profile.ID,
+		"profile_id":      j.ProfileID,
+		"optimization_id": j.OptimizationID,
 	})
-	log.Info("starting background import")
+	log.Info("starting fsrs optimization")
 
-	archives, err := j.ChessClient.FetchArchives(ctx, j.Profile.Username)
-	if err != nil {
-		log.Error("failed to fetch archives: %v", err)
+	run := models.FSRSOptimization{ID: j.OptimizationID, ProfileID: j.ProfileID, Status: models.FSRSOptimizationRunning}
+	if err := j.OptimizationRepo.Update(ctx, run); err != nil {
 		return err
 	}
 
-	if j.Profile.LastSyncAt != nil {
-		archives = filterArchivesByDate(archives, *j.Profile.LastSyncAt)
-		log.Info("filtered archives to %d based on last_sync_at", len(archives))
+	fail := func(err error) error {
+		now := time.Now()
+		run.Status = models.FSRSOptimizationFailed
+		run.Error = err.Error()
+		run.CompletedAt = &now
+		if updateErr := j.OptimizationRepo.Update(ctx, run); updateErr != nil {
+			log.Error("failed to record fsrs optimization failure: %v", updateErr)
+		}
+		return err
 	}
 
-	// ArchiveLimit of 0 means fetch all archives
-	if j.ArchiveLimit > 0 && len(archives) > j.ArchiveLimit {
-		archives = archives[len(archives)-j.ArchiveLimit:]
-		log.Debug("limiting to last %d archives", j.ArchiveLimit)
+	profile, err := j.ProfileRepo.Get(ctx, j.ProfileID)
+	if err != nil {
+		log.Error("failed to load profile: %v", err)
+		return fail(err)
+	}
+	if profile == nil {
+		return fail(fmt.Errorf("profile %d not found", j.ProfileID))
 	}
-	log.Info("fetching %d archives in parallel", len(archives))
 
-	maxConc := j.MaxConcurrent
-	if maxConc <= 0 {
-		maxConc = 10
+	history, err := j.FlashcardRepo.ReviewHistoryByProfile(ctx, j.ProfileID)
+	if err != nil {
+		log.Error("failed to load review history: %v", err)
+		return fail(err)
 	}
-	log.Debug("using %d concurrent workers for archive fetching", maxConc)
 
-	type archiveResult struct {
-		games []chesscom.MonthlyGame
-		err   error
+	fit, err := flashcard.OptimizeFSRS(ctx, history, flashcard.FSRSParamsFromWeights(profile.FSRSWeights))
+	if err != nil {
+		log.Warn("fsrs optimization failed: %v", err)
+		return fail(err)
 	}
 
-	results := make(chan archiveResult, len(archives))
-	sem := make(chan struct{}, maxConc)
+	now := time.Now()
+	run.Status = models.FSRSOptimizationCompleted
+	run.ReviewCount = fit.ReviewCount
+	run.LogLossBefore = fit.LogLossBefore
+	run.LogLossAfter = fit.LogLossAfter
+	run.ObservedRetention = fit.ObservedRetention
+	run.PredictedRetention = fit.PredictedRetention
+	run.Weights = fit.Weights
+	run.RetentionCurve = fit.RetentionCurve
+	run.CompletedAt = &now
+	if err := j.OptimizationRepo.Update(ctx, run); err != nil {
+		log.Error("failed to store fsrs optimization result: %v", err)
+		return err
+	}
 
-	var wg sync.WaitGroup
-	for _, url := range archives {
-		wg.Add(1)
-		go func(archiveURL string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
+	log.Info("fsrs optimization completed: reviews=%d, log_loss %.4f -> %.4f", fit.ReviewCount, fit.LogLossBefore, fit.LogLossAfter)
+	return nil
+}
 
-			monthly, err := j.ChessClient.FetchMonthly(ctx, archiveURL)
-			select {
-			case results <- archiveResult{games: monthly, err: err}:
-			case <-ctx.Done():
-				return
-			}
-		}(url)
-	}
+// ImportGamesJob fetches the profile's games from its platform, inserts new
+// games, indexes their openings, and enqueues analysis.
+type ImportGamesJob struct {
+	GameRepo       repository.GameRepository
+	ProfileRepo    repository.ProfileRepository
+	StatsRepo      repository.StatsRepository
+	Explorer       OpeningIndexer
+	Source         gamesource.Source
+	Profile        models.Profile
+	AnalysisPool   *Pool
+	StockfishPath  string
+	StockfishDepth int
+}
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+func (j *ImportGamesJob) Name() string { return "import_games" }
+
+func (j *ImportGamesJob) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithFields(map[string]any{
+		"username":   j.Profile.Username,
+		"profile_id": j.Profile.ID,
+		"platform":   j.Source.Platform(),
+	})
+	log.Info("starting background import")
 
 	existingIDs, err := j.GameRepo.GetExistingChessComIDs(ctx, j.Profile.ID)
 	if err != nil {
@@ -108,128 +147,101 @@ func (j *ImportGamesJob) Run(ctx context.Context) error {
 		existingIDs = map[string]bool{}
 	}
 
-	var monthlyGames []chesscom.MonthlyGame
-	var totalGames int
-	for res := range results {
+	var since time.Time
+	if j.Profile.LastSyncAt != nil {
+		since = *j.Profile.LastSyncAt
+	}
+
+	var newGames []models.Game
+	err = j.Source.FetchGames(ctx, j.Profile.Username, since, func(g gamesource.Game) error {
 		if ctx.Err() != nil {
-			log.Warn("import cancelled: %v", ctx.Err())
 			return ctx.Err()
 		}
-		if res.err != nil {
-			log.Error("failed to fetch monthly games: %v", res.err)
-			continue
+		if g.ID == "" || existingIDs[g.ID] {
+			return nil
 		}
+		existingIDs[g.ID] = true // avoid duplicates in batch
 
-		monthlyGames = append(monthlyGames, res.games...)
-	}
-
-	if len(monthlyGames) == 0 {
-		log.Info("no monthly games fetched")
+		newGames = append(newGames, j.buildGame(g))
 		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		log.Warn("import cancelled: %v", ctx.Err())
+		return ctx.Err()
+	}
+	// Games fetched before a failure are kept, but the sync time stays put
+	// so that the retried import fetches what was missed
+	fetchErr := err
+	if fetchErr != nil {
+		log.Error("failed to fetch games: %v", fetchErr)
 	}
 
-	var newGames []models.Game
-	for _, mg := range monthlyGames {
-		gameID := pgn.ExtractGameID(mg.URL)
-		if existingIDs[gameID] {
-			continue
-		}
-		existingIDs[gameID] = true // avoid duplicates in batch
-
-		gameMeta := pgn.ParsePGNHeaders(mg.PGN)
-		playedAs, opponent, result := chesscom.DeriveResult(strings.ToLower(j.Profile.Username), mg)
-
-		// Extract ratings from PGN headers (best effort).
-		var playerRating, opponentRating int
-		if playedAs == "white" {
-			playerRating, _ = strconv.Atoi(gameMeta["WhiteElo"])
-			opponentRating, _ = strconv.Atoi(gameMeta["BlackElo"])
-		} else {
-			playerRating, _ = strconv.Atoi(gameMeta["BlackElo"])
-			opponentRating, _ = strconv.Atoi(gameMeta["WhiteElo"])
-		}
-		if playerRating == 0 || opponentRating == 0 {
-			if playedAs == "white" {
-				if playerRating == 0 {
-					playerRating = mg.White.Rating
-				}
-				if opponentRating == 0 {
-					opponentRating = mg.Black.Rating
-				}
-			} else {
-				if playerRating == 0 {
-					playerRating = mg.Black.Rating
-				}
-				if opponentRating == 0 {
-					opponentRating = mg.White.Rating
-				}
-			}
-		}
-
-		game := models.Game{
-			ProfileID:      j.Profile.ID,
-			ChessComID:     gameID,
-			PGN:            mg.PGN,
-			TimeClass:      mg.TimeClass,
-			Result:         result,
-			PlayedAs:       playedAs,
-			Opponent:       opponent,
-			PlayerRating:   playerRating,
-			OpponentRating: opponentRating,
-			PlayedAt:       time.Unix(mg.EndTime, 0),
-			ECOCode:        gameMeta["ECO"],
-			OpeningName:    gameMeta["Opening"],
-			OpeningURL:     gameMeta["ECOUrl"],
-			AnalysisStatus: "pending",
+	if len(newGames) == 0 {
+		if fetchErr == nil {
+			log.Info("no new games fetched")
 		}
-		newGames = append(newGames, game)
+		return fetchErr
 	}
 
 	inserted, err := j.GameRepo.InsertBatch(ctx, newGames)
 	if err != nil {
 		log.Error("failed to batch insert games: %v", err)
-		return err
+		return errors.NewRetryableError(errors.ReasonStorageError, err)
 	}
 
-	totalGames = len(inserted)
-	log.Info("imported %d new games", totalGames)
-	if err := j.ProfileRepo.UpdateSync(ctx, j.Profile.ID, time.Now()); err != nil {
-		log.Warn("failed to update profile sync time: %v", err)
+	log.Info("imported %d new games", len(inserted))
+	if fetchErr == nil {
+		if err := j.ProfileRepo.UpdateSync(ctx, j.Profile.ID, time.Now()); err != nil {
+			log.Warn("failed to update profile sync time: %v", err)
+		}
 	}
 
 	if err := j.StatsRepo.RefreshProfileStats(ctx, j.Profile.ID); err != nil {
 		log.Warn("failed to refresh cached stats after import: %v", err)
 	}
-	return nil
+	// Games left unindexed are picked up by the startup pass
+	if _, err := j.Explorer.IndexGames(ctx, j.Profile.ID); err != nil {
+		log.Warn("failed to index opening tree after import: %v", err)
+	}
+	return fetchErr
 }
 
-// filterArchivesByDate keeps archives from the given month/year onwards.
-// Archive URLs look like: https://api.chess.com/pub/player/{username}/games/YYYY/MM
-func filterArchivesByDate(archives []string, since time.Time) []string {
-	if since.IsZero() {
-		return archives
+// buildGame converts a fetched game into a pending game for the profile.
+func (j *ImportGamesJob) buildGame(g gamesource.Game) models.Game {
+	gameMeta := pgn.ParsePGNHeaders(g.PGN)
+	playedAs, opponent, result := gamesource.DeriveResult(strings.ToLower(j.Profile.Username), g)
+
+	// Extract ratings from PGN headers (best effort), falling back to the
+	// ratings reported by the platform.
+	player, opp := g.White, g.Black
+	playerElo, opponentElo := "WhiteElo", "BlackElo"
+	if playedAs == "black" {
+		player, opp = g.Black, g.White
+		playerElo, opponentElo = "BlackElo", "WhiteElo"
+	}
+	playerRating, _ := strconv.Atoi(gameMeta[playerElo])
+	opponentRating, _ := strconv.Atoi(gameMeta[opponentElo])
+	if playerRating == 0 {
+		playerRating = player.Rating
+	}
+	if opponentRating == 0 {
+		opponentRating = opp.Rating
 	}
-	sinceMonth := time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, time.UTC)
-
-	var filtered []string
-	for _, url := range archives {
-		parts := strings.Split(strings.TrimSuffix(url, "/"), "/")
-		if len(parts) < 2 {
-			continue
-		}
-		yearStr := parts[len(parts)-2]
-		monthStr := parts[len(parts)-1]
 
-		year, err1 := strconv.Atoi(yearStr)
-		monthInt, err2 := strconv.Atoi(monthStr)
-		if err1 != nil || err2 != nil {
-			continue
-		}
-		archiveMonth := time.Date(year, time.Month(monthInt), 1, 0, 0, 0, 0, time.UTC)
-		if archiveMonth.Before(sinceMonth) {
-			continue
-		}
-		filtered = append(filtered, url)
+	return models.Game{
+		ProfileID:      j.Profile.ID,
+		ChessComID:     g.ID,
+		PGN:            g.PGN,
+		TimeClass:      g.TimeClass,
+		Result:         result,
+		PlayedAs:       playedAs,
+		Opponent:       opponent,
+		PlayerRating:   playerRating,
+		OpponentRating: opponentRating,
+		PlayedAt:       g.EndTime,
+		ECOCode:        gameMeta["ECO"],
+		OpeningName:    gameMeta["Opening"],
+		OpeningURL:     gameMeta["ECOUrl"],
+		AnalysisStatus: "pending",
 	}
-	return filtered
 }
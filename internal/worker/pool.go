@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vytor/chessflash/internal/logger"
@@ -22,6 +23,7 @@ type Pool struct {
 	cancel   context.CancelFunc
 	mu       sync.Mutex
 	stopped  bool
+	active   atomic.Int32 // workers running a job
 	log      *logger.Logger
 }
 
@@ -87,7 +89,10 @@ func (p *Pool) spawnWorkers(ctx context.Context) {
 					// Create a context with the logger for the job
 					jobCtx := logger.NewContext(ctx, jobLog)
 
-					if err := job.Run(jobCtx); err != nil {
+					p.active.Add(1)
+					err := job.Run(jobCtx)
+					p.active.Add(-1)
+					if err != nil {
 						jobLog.Error("job failed after %v: %v", time.Since(start), err)
 					} else {
 						jobLog.Info("job completed in %v", time.Since(start))
@@ -227,6 +232,12 @@ func (p *Pool) QueueCapacity() int {
 	return p.queue
 }
 
+// Idle returns how many submitted jobs would start right away: workers
+// neither running a job nor about to pick up a queued one.
+func (p *Pool) Idle() int {
+	return max(p.workers-int(p.active.Load())-len(p.jobs), 0)
+}
+
 // WorkerCount returns the number of workers in the pool.
 func (p *Pool) WorkerCount() int {
 	return p.workers
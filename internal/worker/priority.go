@@ -0,0 +1,27 @@
+package worker
+
+// Priority orders jobs waiting in the same queue: higher priorities start
+// first.
+type Priority int
+
+const (
+	// PriorityBackfill is bulk work such as analyzing every game of a filter set
+	PriorityBackfill Priority = iota
+	// PriorityImportFollowUp is work queued because new games were imported
+	PriorityImportFollowUp
+	// PriorityInteractive is work a user asked for and is waiting on
+	PriorityInteractive
+)
+
+// String returns the name of the priority class.
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackfill:
+		return "backfill"
+	case PriorityImportFollowUp:
+		return "import follow-up"
+	case PriorityInteractive:
+		return "interactive"
+	}
+	return "unknown"
+}
@@ -0,0 +1,129 @@
+// "Don't hang pieces" drill: any move that keeps the material safe is correct
+import { initializeChessground, getLegalMoves, moveToUci, setupPlayerNames } from '../flashcard/board.js';
+
+let drill = null;
+let chess = null;
+let cg = null;
+let answered = false;
+let checking = false;
+const score = { safe: 0, hung: 0, streak: 0 };
+
+const feedbackBox = document.getElementById('drill-feedback-box');
+const feedbackEl = document.getElementById('drill-feedback');
+const contextEl = document.getElementById('drill-context');
+const nextBtn = document.getElementById('drill-next');
+
+function pawns(n) {
+  return n === 1 ? '1 pawn' : `${n} pawns`;
+}
+
+function setFeedback(text, kind) {
+  feedbackEl.textContent = text;
+  feedbackBox.classList.remove('is-success', 'is-danger');
+  if (kind) feedbackBox.classList.add(kind);
+}
+
+function updateScore() {
+  document.getElementById('drill-safe').textContent = score.safe;
+  document.getElementById('drill-hung').textContent = score.hung;
+  document.getElementById('drill-streak').textContent = score.streak;
+}
+
+function arrow(move, brush) {
+  if (!move || move.length < 4) return null;
+  return { orig: move.substring(0, 2), dest: move.substring(2, 4), brush };
+}
+
+async function loadNext() {
+  nextBtn.disabled = true;
+  const after = drill ? drill.position_id : 0;
+  try {
+    const response = await fetch(`/api/drills/hanging/next?after=${after}`);
+    if (!response.ok) throw new Error(`status ${response.status}`);
+    const data = await response.json();
+    if (!data.drill) {
+      setFeedback('No positions to drill.', null);
+      return;
+    }
+    showDrill(data.drill);
+  } catch (error) {
+    console.error('Failed to load drill:', error);
+    setFeedback('Failed to load the next position. Please try again.', 'is-danger');
+    nextBtn.disabled = false;
+  }
+}
+
+function showDrill(d) {
+  drill = d;
+  answered = false;
+  const sideToMove = d.fen.split(' ')[1] === 'w' ? 'white' : 'black';
+  const prev = d.prev_move_played;
+  const lastMove = prev && prev.length >= 4 ? [prev.substring(0, 2), prev.substring(2, 4)] : undefined;
+
+  chess = new Chess(d.fen);
+  if (cg) {
+    cg.set({
+      fen: d.fen,
+      orientation: sideToMove,
+      turnColor: sideToMove,
+      lastMove: lastMove,
+      movable: { free: false, color: sideToMove, dests: getLegalMoves(chess) }
+    });
+    cg.setShapes([]);
+  } else {
+    cg = initializeChessground(document.getElementById('drill-board'), d.fen, sideToMove, lastMove, chess, handleMove);
+  }
+
+  // The board flips with the side to move, so the name icons are reset
+  ['icon-top', 'icon-bottom'].forEach(id => document.getElementById(id).classList.remove('white', 'black'));
+  setupPlayerNames(sideToMove, d.white_player, d.black_player);
+  const moveNumber = Math.ceil(d.move_number / 2);
+  contextEl.textContent = `Move ${moveNumber} of your ${d.time_class} game, where you left ${pawns(d.material_hung)} en prise`;
+  setFeedback('', null);
+}
+
+async function handleMove(orig, dest) {
+  if (answered || checking || !drill) return;
+  const move = chess.move({ from: orig, to: dest, promotion: 'q' });
+  if (!move) return;
+  const moveUci = moveToUci(move);
+  cg.set({ movable: { color: undefined } });
+
+  checking = true;
+  let result;
+  try {
+    const formData = new FormData();
+    formData.append('position_id', drill.position_id);
+    formData.append('move', moveUci);
+    const response = await fetch('/api/drills/hanging/check', { method: 'POST', body: formData });
+    if (!response.ok) throw new Error(`status ${response.status}`);
+    result = await response.json();
+  } catch (error) {
+    console.error('Failed to check move:', error);
+    setFeedback('Failed to check the move. Please try again.', 'is-danger');
+    checking = false;
+    chess.undo();
+    cg.set({ fen: chess.fen(), movable: { color: chess.turn() === 'w' ? 'white' : 'black', dests: getLegalMoves(chess) } });
+    return;
+  }
+  checking = false;
+  answered = true;
+
+  const shapes = [arrow(result.move_played, 'red')];
+  if (result.safe) {
+    score.safe++;
+    score.streak++;
+    setFeedback(`Safe! ${result.move_san} keeps your material. In the game you played ${result.move_played} (red).`, 'is-success');
+  } else {
+    score.hung++;
+    score.streak = 0;
+    shapes.push(arrow(result.best_move, 'green'));
+    setFeedback(`${result.move_san} leaves ${pawns(result.material_hung)} en prise. The engine's move was ${result.best_move} (green).`, 'is-danger');
+  }
+  cg.setShapes(shapes.filter(Boolean));
+  updateScore();
+  nextBtn.disabled = false;
+}
+
+nextBtn.addEventListener('click', loadNext);
+loadNext();
@@ -1,5 +1,5 @@
 // Chessground initialization and move handling
-import { updateEvalBar, showEvalLoading, hideEvalLoading } from './eval-bar.js';
+import { updateEvalBar, showEvalLoading, hideEvalLoading, streamEval, stopEvalStream, showEvalDepth } from './eval-bar.js';
 
 export function getLegalMoves(chess) {
   const dests = new Map();
@@ -26,6 +26,24 @@ export function moveToUci(moveObj) {
   return `${moveObj.from}${moveObj.to}${promo}`;
 }
 
+// Formats SAN moves with move numbers, starting from the side to move in fen
+export function formatLine(fen, sanMoves) {
+  const parts = fen.split(" ");
+  let moveNumber = parseInt(parts[5], 10) || 1;
+  let whiteToMove = parts[1] !== "b";
+  const out = [];
+  sanMoves.forEach((san, i) => {
+    if (whiteToMove) {
+      out.push(`${moveNumber}. ${san}`);
+    } else {
+      out.push(i === 0 ? `${moveNumber}... ${san}` : san);
+      moveNumber++;
+    }
+    whiteToMove = !whiteToMove;
+  });
+  return out.join(" ");
+}
+
 export function resetBoard(chess, initialFen, lastMove, sideToMove, cg, evalFill, evalLabel, evalBefore, mateBefore) {
   // Reset chess.js to initial position
   chess.load(initialFen);
@@ -43,17 +61,55 @@ export function resetBoard(chess, initialFen, lastMove, sideToMove, cg, evalFill
   // Clear any drawn shapes (arrows)
   cg.setShapes([]);
   // Reset eval bar to initial position
+  stopEvalStream();
+  showEvalDepth(evalLabel, 0);
   updateEvalBar(evalFill, evalLabel, evalBefore, mateBefore);
 }
 
+// Asks the server whether a move follows the card's solution line, given the
+// moves already played on it (replies included)
+export async function checkLineMove(cardId, played, move) {
+  const formData = new FormData();
+  played.forEach(m => formData.append('played', m));
+  formData.append('move', move);
+
+  const response = await fetch(`/flashcards/${cardId}/move`, {
+    method: 'POST',
+    headers: { 'Accept': 'application/json' },
+    body: formData
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error((data.error && data.error.message) || 'Unknown error');
+  }
+  return data;
+}
+
+// Plays the opponent's reply in a solution line on both boards
+export function playReply(chess, cg, reply, sideToMove) {
+  const move = chess.move({ from: reply.substring(0, 2), to: reply.substring(2, 4), promotion: reply[4] || 'q' });
+  if (!move) return;
+  cg.set({
+    fen: chess.fen(),
+    lastMove: [move.from, move.to],
+    turnColor: sideToMove,
+    movable: {
+      free: false,
+      color: sideToMove,
+      dests: getLegalMoves(chess)
+    }
+  });
+}
+
 export async function handleMove(
-  orig, dest, chess, cg, bestMove, maxAttempts,
+  orig, dest, chess, cg, checkMove, maxAttempts,
   attemptCount, setAttemptCount, isCompleted,
   evalFill, evalLabel, evalAfter, mateAfter,
   revealResult, attemptIndicator, attemptCountDisplay
 ) {
   if (isCompleted()) return;
 
+  const fenBefore = chess.fen();
   const move = chess.move({ from: orig, to: dest, promotion: 'q' });
   if (!move) {
     // Revert the move if invalid
@@ -61,16 +117,31 @@ export async function handleMove(
     return;
   }
 
-  // Update chessground FEN to match chess.js
-  cg.set({ fen: chess.fen() });
+  // Update chessground FEN to match chess.js, holding the board until the
+  // move has been checked
+  cg.set({ fen: chess.fen(), movable: { color: undefined } });
 
   const played = moveToUci(move);
-  const isCorrect = played === bestMove;
-  const newAttemptCount = attemptCount + 1;
+  let step;
+  try {
+    step = await checkMove(played);
+  } catch (error) {
+    console.error("Error checking move:", error);
+    // Take the move back so it can be played again
+    chess.load(fenBefore);
+    cg.set({
+      fen: fenBefore,
+      movable: { color: move.color === 'w' ? 'white' : 'black', dests: getLegalMoves(chess) }
+    });
+    return;
+  }
+  const isCorrect = step.correct;
+  // Only wrong moves use up attempts, a line may take several right ones
+  const newAttemptCount = isCorrect ? attemptCount : attemptCount + 1;
   setAttemptCount(newAttemptCount);
   
   // Update attempt indicator
-  if (attemptIndicator && attemptCountDisplay) {
+  if (attemptIndicator && attemptCountDisplay && !isCorrect) {
     attemptIndicator.classList.remove("is-hidden");
     attemptCountDisplay.textContent = `${newAttemptCount}/${maxAttempts}`;
   }
@@ -78,47 +149,38 @@ export async function handleMove(
   // Show loading state on eval bar
   showEvalLoading(evalLabel);
   
-  // Get evaluation for the user's actual move
-  try {
-    const response = await fetch(`/api/evaluate?fen=${encodeURIComponent(chess.fen())}`);
-    if (response.ok) {
-      const data = await response.json();
-      const userEval = data.cp / 100; // Convert centipawns to pawns
-      const userMate = data.mate;
-      
-      // Update eval bar with the user's move evaluation
-      hideEvalLoading(evalLabel);
-      updateEvalBar(evalFill, evalLabel, userEval, userMate);
-      
-      // If the move is correct, add visual feedback
+  // Stream the evaluation of the user's actual move, updating the bar as the
+  // engine searches deeper. The answer is graded without waiting for it.
+  const showEval = (data) => {
+    hideEvalLoading(evalLabel);
+    showEvalDepth(evalLabel, data.depth);
+    updateEvalBar(evalFill, evalLabel, data.cp / 100, data.mate); // centipawns to pawns
+  };
+  streamEval(chess.fen(), showEval)
+    .then((data) => {
+      showEval(data);
       if (isCorrect) {
+        // Briefly highlight the eval bar in green
+        evalLabel.style.transition = "background-color 0.3s";
+        evalLabel.style.backgroundColor = "#d4edda";
         setTimeout(() => {
-          // Briefly highlight the eval bar in green
-          evalLabel.style.transition = "background-color 0.3s";
-          evalLabel.style.backgroundColor = "#d4edda";
-          setTimeout(() => {
-            evalLabel.style.backgroundColor = "";
-          }, 500);
-        }, 100);
+          evalLabel.style.backgroundColor = "";
+        }, 500);
       }
-    } else {
-      // Fallback: if evaluation fails, show the expected eval for correct moves
+    })
+    .catch((error) => {
+      console.error("Error evaluating position:", error);
+      // Fallback: show the expected eval for correct moves
       hideEvalLoading(evalLabel);
       if (isCorrect) {
         updateEvalBar(evalFill, evalLabel, evalAfter, mateAfter);
       }
-      console.error("Failed to evaluate position");
-    }
-  } catch (error) {
-    console.error("Error evaluating position:", error);
-    // Fallback behavior
-    hideEvalLoading(evalLabel);
-    if (isCorrect) {
-      updateEvalBar(evalFill, evalLabel, evalAfter, mateAfter);
-    }
-  }
+    });
   
-  if (isCorrect) {
+  if (isCorrect && !step.done) {
+    // Right move in a longer line - the opponent replies next
+    revealResult(true, played, false, step.reply);
+  } else if (isCorrect) {
     // Correct answer - show full feedback and complete
     revealResult(true, played, true);
   } else if (newAttemptCount >= maxAttempts) {
@@ -133,7 +195,7 @@ export async function handleMove(
     }, 1500);
   }
   
-  return { isCorrect, played, newAttemptCount };
+  return { isCorrect, played, newAttemptCount, step };
 }
 
 export function initializeChessground(
@@ -54,3 +54,41 @@ export function showEvalLoading(evalLabel) {
 export function hideEvalLoading(evalLabel) {
   evalLabel.style.opacity = "1";
 }
+
+// Live evaluation stream of the position currently shown, if any
+let activeStream = null;
+
+export function stopEvalStream() {
+  if (activeStream) {
+    activeStream.close();
+    activeStream = null;
+  }
+}
+
+// Streams the evaluation of fen from /api/evaluate/stream, calling onInfo with
+// { depth, cp, mate } as the engine searches deeper. Resolves with the final
+// evaluation; starting another stream or stopEvalStream() cancels this one.
+export function streamEval(fen, onInfo) {
+  stopEvalStream();
+  return new Promise((resolve, reject) => {
+    const source = new EventSource(`/api/evaluate/stream?fen=${encodeURIComponent(fen)}`);
+    activeStream = source;
+    const finish = () => {
+      source.close();
+      if (activeStream === source) activeStream = null;
+    };
+    source.addEventListener("info", (e) => onInfo(JSON.parse(e.data)));
+    source.addEventListener("done", (e) => {
+      finish();
+      resolve(JSON.parse(e.data));
+    });
+    source.addEventListener("error", (e) => {
+      finish();
+      reject(new Error(e.data ? JSON.parse(e.data).error : "evaluation stream failed"));
+    });
+  });
+}
+
+export function showEvalDepth(evalLabel, depth) {
+  evalLabel.title = depth ? `Depth ${depth}` : "";
+}
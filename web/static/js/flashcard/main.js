@@ -1,11 +1,15 @@
 // Main entry point for flashcard functionality
 import { updateEvalBar } from './eval-bar.js';
-import { showReviewForm } from './review.js';
+import { submitReview } from './review.js';
+import { initTags } from './tags.js';
 import { 
   initializeChessground, 
   resetBoard, 
   handleMove as handleMoveBoard,
-  setupPlayerNames 
+  checkLineMove,
+  playReply,
+  setupPlayerNames,
+  formatLine
 } from './board.js';
 
 function initBoard() {
@@ -38,7 +42,13 @@ function initBoard() {
     return;
   }
   
-  const bestMove = cardData.bestMove.trim();
+  const cardId = cardData.id;
+  const lineMoves = cardData.lineMoves || 1; // moves the solution asks for
+  const isRepertoire = cardData.kind === "repertoire";
+  // Repertoire drills are answered with the prepared move rather than the engine's
+  const bestMove = isRepertoire && Array.isArray(cardData.acceptableMoves) && cardData.acceptableMoves.length > 0
+    ? cardData.acceptableMoves[0]
+    : cardData.bestMove.trim();
   const mateBefore = cardData.mateBefore;
   const mateAfter = cardData.mateAfter;
   const evalBefore = parseFloat(cardData.evalBefore) / 100;
@@ -49,6 +59,9 @@ function initBoard() {
   const prevMovePlayed = cardData.prevMovePlayed;
   const whitePlayer = cardData.whitePlayer;
   const blackPlayer = cardData.blackPlayer;
+  const bestLine = Array.isArray(cardData.pvSan) && cardData.pvSan.length > 0
+    ? formatLine(fen, cardData.pvSan)
+    : "";
   
   // Parse the last move for highlighting (opponent's move that led to this position)
   let lastMove = null;
@@ -92,14 +105,17 @@ function initBoard() {
     return;
   }
   
-  const initialFen = fen; // Store initial position for resetting after wrong moves
+  let stepFen = fen; // Position to reset to after wrong moves
+  let stepLastMove = lastMove;
+  const linePlayed = []; // Solution moves and replies played so far
   const sideToMove = fen.split(" ")[1] === "w" ? "white" : "black";
-  const maxAttempts = 3;
+  const maxAttempts = 3; // wrong moves that fail the card, as graded on the server
   
   let attemptCount = 0;
   let isCompleted = false; // Track if card is completed (correct answer or show answer clicked)
   let cardStartTime = Date.now(); // Track when card is displayed
   let wasCorrect = false; // Track if the answer was correct
+  const attempts = []; // Moves tried, sent to the server for grading
   let showAnswerClicked = false; // Track if user clicked "Show answer"
   let cg = null;
 
@@ -112,6 +128,9 @@ function initBoard() {
   }
 
   function contextualPrompt() {
+    if (isRepertoire) return "Play the move from your repertoire";
+    if (lineMoves > 1) return `Find the winning line (${lineMoves} moves)`;
+
     // Determine if user is playing as white or black
     const isUserWhite = sideToMove === "white";
     
@@ -135,24 +154,54 @@ function initBoard() {
   }
 
   function classificationNote() {
+    if (isRepertoire) return "You left your opening preparation here.";
+
+    let note = "";
     switch (classification) {
-      case "blunder": return "You missed a critical idea here.";
-      case "mistake": return "There was a better option.";
-      case "inaccuracy": return "A small improvement was possible.";
-      default: return "";
+      case "blunder": note = "You missed a critical idea here."; break;
+      case "mistake": note = "There was a better option."; break;
+      case "missed_mate": note = "You had a forced mate here."; break;
+      case "allowed_mate": note = "This move allowed a forced mate."; break;
+      case "miss": note = "Your opponent had just slipped up and you didn't punish it."; break;
+      case "inaccuracy": note = "A small improvement was possible."; break;
+    }
+    if (bestLine) {
+      note = note ? `${note} Best line: ${bestLine}` : `Best line: ${bestLine}`;
     }
+    return note;
   }
 
-  function revealResult(isCorrect, moveUci, showFullFeedback) {
+  function revealResult(isCorrect, moveUci, showFullFeedback, reply) {
     const loss = Math.abs(evalDiff).toFixed(1);
     
-    // Track correctness
+    // Track correctness and the attempt for grading
     wasCorrect = isCorrect;
+    attempts.push({ move: moveUci, seconds: (Date.now() - cardStartTime) / 1000 });
     
-    if (isCorrect) {
+    if (isCorrect && !showFullFeedback) {
+      // Right move in a longer line - play the reply and wait for the next move
+      linePlayed.push(moveUci, reply);
+      const movesLeft = lineMoves - linePlayed.length / 2;
+      feedbackEl.textContent = `Good move! Keep going (${movesLeft} more ${movesLeft === 1 ? 'move' : 'moves'} to find).`;
+      feedbackEl.classList.remove("has-text-danger", "has-text-info");
+      feedbackEl.classList.add("has-text-success");
+      lossEl.textContent = "";
+      metaEl.textContent = "";
+      setTimeout(() => {
+        playReply(chess, cg, reply, sideToMove);
+        stepFen = chess.fen();
+        stepLastMove = [reply.substring(0, 2), reply.substring(2, 4)];
+      }, 700);
+    } else if (isCorrect) {
       // Correct answer
       isCompleted = true;
-      feedbackEl.textContent = "Excellent! You found the best move.";
+      if (linePlayed.length > 0) {
+        feedbackEl.textContent = "Excellent! You played out the whole line.";
+      } else {
+        feedbackEl.textContent = moveUci === bestMove
+          ? "Excellent! You found the best move."
+          : `Excellent! That move is as good as the best move (${bestMove}).`;
+      }
       feedbackEl.classList.remove("has-text-danger", "has-text-info");
       feedbackEl.classList.add("has-text-success");
       lossEl.textContent = "";
@@ -165,7 +214,7 @@ function initBoard() {
       }
       
       // Auto-submit review (user got it right, no need to review)
-      showReviewForm(reviewForm, cardStartTime, wasCorrect, attemptCount, showAnswerClicked, true, maxAttempts);
+      submitReview(reviewForm, cardStartTime, attempts, showAnswerClicked, true);
       cg.set({ movable: { color: undefined } });
     } else if (showFullFeedback) {
       // Max attempts reached - show full feedback
@@ -180,8 +229,8 @@ function initBoard() {
         feedbackBox.classList.add("error");
       }
       
-      // Draw arrow for best move
-      if (bestMove && bestMove.length >= 4) {
+      // Draw arrow for best move, unless the line has moved on from it
+      if (linePlayed.length === 0 && bestMove && bestMove.length >= 4) {
         const from = bestMove.substring(0, 2);
         const to = bestMove.substring(2, 4);
         cg.setShapes([{ orig: from, dest: to, brush: 'green' }]);
@@ -197,7 +246,7 @@ function initBoard() {
       metaEl.textContent = classificationNote();
       
       // Show "Next card" button (user failed, let them review)
-      showReviewForm(reviewForm, cardStartTime, wasCorrect, attemptCount, showAnswerClicked, false, maxAttempts);
+      submitReview(reviewForm, cardStartTime, attempts, showAnswerClicked, false);
       cg.set({ movable: { color: undefined } });
     } else {
       // Brief feedback for wrong moves (still have attempts left)
@@ -211,8 +260,9 @@ function initBoard() {
   }
 
   async function handleMoveCallback(orig, dest) {
+    const checkMove = (move) => checkLineMove(cardId, linePlayed, move);
     const result = await handleMoveBoard(
-      orig, dest, chess, cg, bestMove, maxAttempts,
+      orig, dest, chess, cg, checkMove, maxAttempts,
       attemptCount, setAttemptCount, getIsCompleted,
       evalFill, evalLabel, evalAfter, mateAfter,
       revealResult, attemptIndicator, attemptCountDisplay
@@ -221,7 +271,7 @@ function initBoard() {
     if (result && !result.isCorrect && result.newAttemptCount < maxAttempts) {
       // Reset board after a short delay
       setTimeout(() => {
-        resetBoard(chess, initialFen, lastMove, sideToMove, cg, evalFill, evalLabel, evalBefore, mateBefore);
+        resetBoard(chess, stepFen, stepLastMove, sideToMove, cg, evalFill, evalLabel, evalBefore, mateBefore);
       }, 1500);
     }
   }
@@ -241,8 +291,8 @@ function initBoard() {
     wasCorrect = false;
     isCompleted = true;
     
-    // Draw arrow for best move
-    if (bestMove && bestMove.length >= 4) {
+    // Draw arrow for best move, unless the line has moved on from it
+    if (linePlayed.length === 0 && bestMove && bestMove.length >= 4) {
       const from = bestMove.substring(0, 2);
       const to = bestMove.substring(2, 4);
       cg.setShapes([{ orig: from, dest: to, brush: 'green' }]);
@@ -261,7 +311,7 @@ function initBoard() {
     }
     
     // Show "Next card" button (user revealed answer, let them review)
-    showReviewForm(reviewForm, cardStartTime, wasCorrect, attemptCount, showAnswerClicked, false, maxAttempts);
+    submitReview(reviewForm, cardStartTime, attempts, showAnswerClicked, false);
     cg.set({ movable: { color: undefined } });
   });
 
@@ -270,6 +320,7 @@ function initBoard() {
   // Initialize eval bar immediately to avoid showing +0.0
   updateEvalBar(evalFill, evalLabel, evalBefore, mateBefore);
   setupPlayerNames(sideToMove, whitePlayer, blackPlayer);
+  initTags(cardId);
 }
 
 // Wait for window load to ensure all scripts are loaded
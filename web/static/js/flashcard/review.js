@@ -1,174 +1,107 @@
-// Review form submission and quality calculation
-export function calculateQuality(isCorrect, attemptCount, timeSeconds, showAnswerClicked, maxAttempts) {
-  // If user clicked "Show answer", it's definitely "Again"
-  if (showAnswerClicked) {
-    return 0; // Again
-  }
-  
-  // If wrong after max attempts, it's "Again"
-  if (!isCorrect && attemptCount >= maxAttempts) {
-    return 0; // Again
+// Review submission: the server checks the attempted moves and grades the review
+
+// Quality labels for display
+const qualityLabels = {
+  0: "Again",
+  1: "Hard",
+  2: "Good",
+  3: "Easy"
+};
+
+// Quality emojis
+const qualityEmoji = {
+  0: '🔄',
+  1: '⚠️',
+  2: '✅',
+  3: '⭐'
+};
+
+export async function submitReview(reviewForm, cardStartTime, attempts, showAnswerClicked, autoAdvance) {
+  // Calculate time taken
+  const timeElapsed = cardStartTime ? (Date.now() - cardStartTime) / 1000 : 0;
+
+  const ratingMessage = document.getElementById("auto-rating-message");
+  const nextCardContainer = document.getElementById('next-card-container');
+  if (nextCardContainer) {
+    nextCardContainer.innerHTML = '';
   }
-  
-  // If correct on first try
-  if (isCorrect && attemptCount === 1) {
-    if (timeSeconds < 10) {
-      return 3; // Easy - fast and correct
-    } else if (timeSeconds < 30) {
-      return 2; // Good - correct but took some time
-    } else {
-      return 1; // Hard - correct but slow
+
+  // game_id and card_index come from the form, or from the URL for
+  // game-filtered flashcards rendered without them
+  const formData = new FormData(reviewForm);
+  const urlParams = new URLSearchParams(window.location.search);
+  for (const name of ['game_id', 'card_index']) {
+    if (!formData.get(name) && urlParams.get(name)) {
+      formData.set(name, urlParams.get(name));
     }
   }
-  
-  // If correct but took multiple attempts
-  if (isCorrect && attemptCount > 1) {
-    if (timeSeconds < 20) {
-      return 2; // Good - eventually got it quickly
-    } else {
-      return 1; // Hard - took multiple tries and time
+
+  // Every move tried, in order, with the seconds since the card was shown
+  attempts.forEach(attempt => {
+    formData.append('move', attempt.move);
+    formData.append('move_seconds', attempt.seconds.toFixed(2));
+  });
+  formData.append('revealed', showAnswerClicked ? 'true' : 'false');
+  formData.append('time_seconds', timeElapsed.toFixed(2));
+
+  // Show the form
+  reviewForm.classList.remove("is-hidden");
+
+  let data;
+  try {
+    const response = await fetch(reviewForm.action, {
+      method: 'POST',
+      headers: { 'Accept': 'application/json' },
+      body: formData
+    });
+    data = await response.json();
+    if (!response.ok) {
+      throw new Error((data.error && data.error.message) || 'Unknown error');
     }
+  } catch (e) {
+    console.error('Failed to submit review:', e);
+    if (ratingMessage) {
+      ratingMessage.textContent = `Failed to save review: ${e.message}`;
+    }
+    return;
   }
-  
-  // Default fallback (shouldn't reach here normally)
-  return 1; // Hard
-}
 
-export function showReviewForm(reviewForm, cardStartTime, wasCorrect, attemptCount, showAnswerClicked, autoSubmit, maxAttempts) {
-  // Calculate time taken
-  const timeElapsed = cardStartTime ? (Date.now() - cardStartTime) / 1000 : 0;
-  
-  // Calculate quality automatically
-  const quality = calculateQuality(wasCorrect, attemptCount, timeElapsed, showAnswerClicked, maxAttempts);
-  
-  // Quality labels for display
-  const qualityLabels = {
-    0: "Again",
-    1: "Hard", 
-    2: "Good",
-    3: "Easy"
-  };
-  
-  // Quality emojis
-  const qualityEmoji = {
-    0: '🔄',
-    1: '⚠️',
-    2: '✅',
-    3: '⭐'
-  };
-  
-  // Show feedback about auto-rating
-  const ratingMessage = document.getElementById("auto-rating-message");
+  const grade = data.grade;
+  const attemptCount = grade.attempts;
+
+  // Show feedback about the rating the server gave
   if (ratingMessage) {
     ratingMessage.innerHTML = `
-      <span class="has-text-weight-semibold">${qualityEmoji[quality]} ${qualityLabels[quality]}</span>
+      <span class="has-text-weight-semibold">${qualityEmoji[grade.quality]} ${qualityLabels[grade.quality]}</span>
       <span class="has-text-grey is-size-7 ml-2">
-        ${timeElapsed.toFixed(1)}s • ${attemptCount} attempt${attemptCount !== 1 ? 's' : ''}
+        ${grade.time_seconds.toFixed(1)}s • ${attemptCount} attempt${attemptCount !== 1 ? 's' : ''}
       </span>
     `;
   }
-  
+
   // Update rating badge styling
   const ratingBadge = document.getElementById('rating-badge');
   if (ratingBadge) {
-    ratingBadge.className = `rating-badge ${qualityLabels[quality].toLowerCase()}`;
-  }
-  
-  // Clear existing inputs and buttons (in case form is shown multiple times)
-  // But preserve game_id and card_index if they exist (for game-filtered flashcards)
-  const existingInputs = reviewForm.querySelectorAll('input[type="hidden"]');
-  let gameIdValue = null;
-  let cardIndexValue = null;
-  
-  // Preserve game_id and card_index values, remove only other inputs
-  existingInputs.forEach(input => {
-    if (input.name === 'game_id') {
-      gameIdValue = input.value;
-      // Keep this input, don't remove it
-    } else if (input.name === 'card_index') {
-      cardIndexValue = input.value;
-      // Keep this input, don't remove it
-    } else {
-      // Remove only non-game inputs (time_seconds, attempt_count, quality, etc.)
-      input.remove();
-    }
-  });
-  
-  // If game_id/card_index weren't in form, try to get them from URL
-  if (!gameIdValue || !cardIndexValue) {
-    const urlParams = new URLSearchParams(window.location.search);
-    if (!gameIdValue) {
-      gameIdValue = urlParams.get('game_id');
-      if (gameIdValue) {
-        // Add game_id input if we got it from URL
-        const gameIdInput = document.createElement('input');
-        gameIdInput.type = 'hidden';
-        gameIdInput.name = 'game_id';
-        gameIdInput.value = gameIdValue;
-        reviewForm.appendChild(gameIdInput);
-      }
-    }
-    if (!cardIndexValue) {
-      cardIndexValue = urlParams.get('card_index');
-      if (cardIndexValue) {
-        // Add card_index input if we got it from URL
-        const cardIndexInput = document.createElement('input');
-        cardIndexInput.type = 'hidden';
-        cardIndexInput.name = 'card_index';
-        cardIndexInput.value = cardIndexValue;
-        reviewForm.appendChild(cardIndexInput);
-      }
-    }
+    ratingBadge.className = `rating-badge ${qualityLabels[grade.quality].toLowerCase()}`;
   }
-  
-  const nextCardContainer = document.getElementById('next-card-container');
-  if (nextCardContainer) {
-    nextCardContainer.innerHTML = '';
-  }
-  
-  // Add hidden inputs
-  const timeInput = document.createElement('input');
-  timeInput.type = 'hidden';
-  timeInput.name = 'time_seconds';
-  timeInput.value = timeElapsed.toFixed(2);
-  reviewForm.appendChild(timeInput);
-  
-  const attemptInput = document.createElement('input');
-  attemptInput.type = 'hidden';
-  attemptInput.name = 'attempt_count';
-  attemptInput.value = attemptCount;
-  reviewForm.appendChild(attemptInput);
-  
-  // Add hidden quality input
-  const qualityInput = document.createElement('input');
-  qualityInput.type = 'hidden';
-  qualityInput.name = 'quality';
-  qualityInput.value = quality;
-  reviewForm.appendChild(qualityInput);
-  
-  // Show the form
-  reviewForm.classList.remove("is-hidden");
-  
-  if (autoSubmit) {
-    // Auto-submit after a short delay to show the feedback
+
+  if (autoAdvance) {
+    // Move on after a short delay to show the feedback
     setTimeout(() => {
-      reviewForm.submit();
-    }, 1500); // Show feedback for 1.5 seconds before auto-submitting
-  } else {
-    // Add "Next card" button for manual submission
-    const nextCardContainer = document.getElementById('next-card-container');
-    if (nextCardContainer) {
-      const nextButton = document.createElement('button');
-      nextButton.type = 'submit';
-      nextButton.className = 'button is-primary is-medium is-fullwidth';
-      nextButton.innerHTML = `
-        <span>Continue to Next Card</span>
-        <span class="icon is-small">
-          <i class="fas fa-arrow-right"></i>
-        </span>
-      `;
-      nextButton.style.animation = 'fadeInUp 0.3s ease';
-      nextCardContainer.appendChild(nextButton);
-    }
+      window.location.href = data.next;
+    }, 1500); // Show feedback for 1.5 seconds before moving on
+  } else if (nextCardContainer) {
+    // Add "Next card" button for moving on manually
+    const nextButton = document.createElement('a');
+    nextButton.href = data.next;
+    nextButton.className = 'button is-primary is-medium is-fullwidth';
+    nextButton.innerHTML = `
+      <span>Continue to Next Card</span>
+      <span class="icon is-small">
+        <i class="fas fa-arrow-right"></i>
+      </span>
+    `;
+    nextButton.style.animation = 'fadeInUp 0.3s ease';
+    nextCardContainer.appendChild(nextButton);
   }
 }
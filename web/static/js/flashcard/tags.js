@@ -0,0 +1,72 @@
+// User tags on the current flashcard: added and removed without reloading,
+// since reloading the review page would move on to another card
+
+async function postTag(url, tag) {
+  const formData = new FormData();
+  formData.append('tag', tag);
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: { 'Accept': 'application/json' },
+    body: formData
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error((data.error && data.error.message) || 'Unknown error');
+  }
+  return data.tag;
+}
+
+function tagElement(cardId, tag) {
+  const el = document.createElement('span');
+  el.className = 'tag is-info is-light';
+  el.dataset.tag = tag;
+  el.textContent = tag;
+
+  const remove = document.createElement('button');
+  remove.type = 'button';
+  remove.className = 'delete is-small';
+  remove.setAttribute('aria-label', `Remove tag ${tag}`);
+  el.appendChild(remove);
+  bindRemove(cardId, el);
+  return el;
+}
+
+function bindRemove(cardId, el) {
+  const remove = el.querySelector('.delete');
+  if (!remove) return;
+  remove.addEventListener('click', async () => {
+    try {
+      await postTag(`/flashcards/${cardId}/tags/remove`, el.dataset.tag);
+      el.remove();
+    } catch (e) {
+      console.error('Failed to remove tag:', e);
+    }
+  });
+}
+
+export function initTags(cardId) {
+  const container = document.getElementById('card-tags');
+  const form = document.getElementById('tag-form');
+  if (!container || !form) return;
+
+  container.querySelectorAll('[data-tag]').forEach(el => bindRemove(cardId, el));
+
+  form.addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const input = form.querySelector('input[name="tag"]');
+    try {
+      const tag = await postTag(form.action, input.value);
+      if (!container.querySelector(`[data-tag="${CSS.escape(tag)}"]`)) {
+        container.appendChild(tagElement(cardId, tag));
+      }
+      input.value = '';
+      input.setCustomValidity('');
+    } catch (e) {
+      input.setCustomValidity(e.message);
+      input.reportValidity();
+    }
+  });
+  form.querySelector('input[name="tag"]').addEventListener('input', (event) => {
+    event.target.setCustomValidity('');
+  });
+}
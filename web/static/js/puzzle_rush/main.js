@@ -3,7 +3,11 @@ import {
   initializeChessground, 
   resetBoard, 
   handleMove as handleMoveBoard,
-  setupPlayerNames 
+  checkLineMove,
+  playReply,
+  moveToUci,
+  setupPlayerNames,
+  formatLine
 } from '../flashcard/board.js';
 import { updateEvalBar } from '../flashcard/eval-bar.js';
 
@@ -112,15 +116,20 @@ async function startRush(difficulty) {
   }
 }
 
-async function submitAnswer(flashcardId, quality, timeSeconds) {
+// Sends the moves tried on a card; the server grades them
+async function submitAnswer(flashcardId, attempts, revealed, timeSeconds) {
   if (!currentSession) return;
   
   try {
     const formData = new FormData();
     formData.append('session_id', currentSession.id);
     formData.append('flashcard_id', flashcardId);
-    formData.append('quality', quality);
-    formData.append('time_seconds', timeSeconds);
+    attempts.forEach(attempt => {
+      formData.append('move', attempt.move);
+      formData.append('move_seconds', attempt.seconds.toFixed(2));
+    });
+    formData.append('revealed', revealed ? 'true' : 'false');
+    formData.append('time_seconds', timeSeconds.toFixed(2));
     
     const response = await fetch('/puzzle-rush/answer', {
       method: 'POST',
@@ -263,11 +272,17 @@ function loadFlashcard(card) {
   }
   
   const sideToMove = fen.split(" ")[1] === "w" ? "white" : "black";
+  const lineMoves = card.line_moves || 1; // moves the solution asks for
   cardStartTime = Date.now();
   let isCompleted = false;
   let wasCorrect = false;
+  let checking = false; // a move is being checked by the server
+  const attempts = []; // moves tried, sent for grading
+  const linePlayed = []; // solution moves and replies played so far
   
   function contextualPrompt() {
+    if (lineMoves > 1) return `Find the winning line (${lineMoves} moves)`;
+
     const isUserWhite = sideToMove === "white";
     const userEval = isUserWhite ? evalBefore : -evalBefore;
     
@@ -287,7 +302,7 @@ function loadFlashcard(card) {
     return "Find the best move in this balanced position";
   }
   
-  function revealResult(isCorrect, moveUci) {
+  function revealResult(isCorrect, revealed) {
     if (isCompleted) return;
     
     wasCorrect = isCorrect;
@@ -295,28 +310,26 @@ function loadFlashcard(card) {
     
     const timeSeconds = (Date.now() - cardStartTime) / 1000;
     
-    // Determine quality (0-5)
-    // For puzzle rush, we simplify: correct = 4 (Good), incorrect = 1 (Hard)
-    const quality = isCorrect ? 4 : 1;
-    
     const feedbackEl = document.getElementById('feedback-text-rush');
     const feedbackBox = document.getElementById('feedback-box-rush');
     
     if (isCorrect) {
-      feedbackEl.textContent = "Correct! Great move.";
+      feedbackEl.textContent = linePlayed.length > 0 ? "Correct! You played out the whole line." : "Correct! Great move.";
       feedbackEl.classList.remove("has-text-danger");
       feedbackEl.classList.add("has-text-success");
       feedbackBox.classList.remove("error");
       feedbackBox.classList.add("success");
     } else {
-      feedbackEl.textContent = `Incorrect. Best move was ${bestMove}.`;
+      feedbackEl.textContent = card.pv_san && card.pv_san.length > 0
+        ? `Incorrect. Best line was ${formatLine(fen, card.pv_san)}.`
+        : `Incorrect. Best move was ${bestMove}.`;
       feedbackEl.classList.remove("has-text-success");
       feedbackEl.classList.add("has-text-danger");
       feedbackBox.classList.remove("success");
       feedbackBox.classList.add("error");
       
-      // Draw arrow for best move
-      if (bestMove && bestMove.length >= 4) {
+      // Draw arrow for best move, unless the line has moved on from it
+      if (linePlayed.length === 0 && bestMove && bestMove.length >= 4) {
         const from = bestMove.substring(0, 2);
         const to = bestMove.substring(2, 4);
         cg.setShapes([{ orig: from, dest: to, brush: 'green' }]);
@@ -328,24 +341,41 @@ function loadFlashcard(card) {
     
     // Submit answer after a short delay
     setTimeout(() => {
-      submitAnswer(card.id, quality, timeSeconds);
+      submitAnswer(card.id, attempts, revealed, timeSeconds);
     }, 1500);
   }
   
   async function handleMoveCallback(orig, dest) {
-    if (isCompleted) return;
+    if (isCompleted || checking) return;
     
     const move = chess.move({ from: orig, to: dest, promotion: 'q' });
     if (!move) return;
     
-    const moveUci = orig + dest;
-    const isCorrect = moveUci.toLowerCase() === bestMove.toLowerCase();
+    const moveUci = moveToUci(move);
+    attempts.push({ move: moveUci, seconds: (Date.now() - cardStartTime) / 1000 });
+    cg.set({ movable: { color: undefined } });
+    
+    // The server checks the move against the solution line
+    let step;
+    checking = true;
+    try {
+      step = await checkLineMove(card.id, linePlayed, moveUci);
+    } catch (error) {
+      console.error('Error checking move:', error);
+      step = { correct: false };
+    }
+    checking = false;
     
-    if (isCorrect) {
-      revealResult(true, moveUci);
-    } else {
-      revealResult(false, moveUci);
+    if (step.correct && !step.done) {
+      // Right move in a longer line - play the reply and wait for the next move
+      linePlayed.push(moveUci, step.reply);
+      const movesLeft = lineMoves - linePlayed.length / 2;
+      document.getElementById('feedback-text-rush').textContent =
+        `Good move! Keep going (${movesLeft} more ${movesLeft === 1 ? 'move' : 'moves'} to find).`;
+      setTimeout(() => playReply(chess, cg, step.reply, sideToMove), 500);
+      return;
     }
+    revealResult(step.correct, false);
   }
   
   // Initialize board
@@ -362,7 +392,7 @@ function loadFlashcard(card) {
   document.getElementById('show-answer-rush').addEventListener('click', function() {
     if (isCompleted) return;
     wasCorrect = false;
-    revealResult(false, '');
+    revealResult(false, true);
   });
   
   // Set prompt